@@ -1,14 +1,31 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"test_mekari/internal/auth"
+	"test_mekari/internal/blob"
+	"test_mekari/internal/collab"
+	"test_mekari/internal/events"
 	"test_mekari/internal/handler"
+	"test_mekari/internal/migrations"
+	"test_mekari/internal/reminder"
 	"test_mekari/internal/repository"
 	"test_mekari/internal/routes"
+	"test_mekari/internal/search"
 	"test_mekari/internal/service"
+	"test_mekari/internal/webhook"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +36,9 @@ func main() {
 		log.Println("⚠️  No .env file found, using default values")
 	}
 
+	autoMigrate := flag.Bool("auto-migrate", os.Getenv("AUTO_MIGRATE") == "true", "apply pending SQL migrations on startup")
+	flag.Parse()
+
 	// Get port from environment or use default
 	port := os.Getenv("APP_PORT")
 	if port == "" {
@@ -26,12 +46,78 @@ func main() {
 	}
 
 	// Initialize layers (Dependency Injection)
-	todoRepo := repository.NewTodoRepository()
-	todoService := service.NewTodoService(todoRepo)
-	todoHandler := handler.NewTodoHandler(todoService)
+	backend, closeStore := openStore(*autoMigrate)
+	defer closeStore()
+
+	// Every todo change made through store is published to live subscribers
+	// and kept in the search index
+	index := search.NewIndex()
+	started := time.Now()
+	todos, comments, err := index.Rebuild(backend)
+	if err != nil {
+		log.Fatal("❌ Failed to build the search index:", err)
+	}
+	log.Printf("🔎 Search: indexed %d todos and %d comments in %v", todos, comments, time.Since(started).Round(time.Millisecond))
+	bus := events.NewBus(eventReplaySize())
+	store := search.NewIndexingStore(events.NewPublishingStore(backend, bus), index)
+
+	tokenTTL := 24 * time.Hour
+	if v := os.Getenv("AUTH_TOKEN_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("❌ Invalid AUTH_TOKEN_TTL:", err)
+		}
+		tokenTTL = ttl
+	}
+	authService, err := service.NewAuthService(
+		store,
+		auth.NewTokenSigner(authSecret(), tokenTTL),
+		auth.NewSessionStore(tokenTTL),
+	)
+	if err != nil {
+		log.Fatal("❌ Failed to initialize auth:", err)
+	}
+
+	blobDir := envOr("BLOB_DIR", "data/blobs")
+	blobs, err := blob.NewLocalStore(blobDir)
+	if err != nil {
+		log.Fatal("❌ Failed to open blob storage:", err)
+	}
+	staging, err := blob.NewStaging(envOr("UPLOAD_DIR", "data/uploads"))
+	if err != nil {
+		log.Fatal("❌ Failed to open upload staging:", err)
+	}
+	log.Printf("📎 Attachments: %s (max %d bytes)", blobDir, attachmentMaxSize())
+	attachmentService := service.NewAttachmentService(store, blobs, staging, attachmentMaxSize())
+	if resumed, err := attachmentService.RestoreUploads(); err != nil {
+		log.Fatal("❌ Failed to restore uploads:", err)
+	} else if resumed > 0 {
+		log.Printf("📎 Resuming %d unfinished uploads", resumed)
+	}
+
+	todoService := service.NewTodoService(store)
+	eventService := service.NewEventService(bus, store)
+	hub := collab.NewHub(bus, editLockTTL())
+	webhooks := webhookConfig()
+	handlers := routes.Handlers{
+		Todo:         handler.NewTodoHandler(todoService),
+		List:         handler.NewListHandler(service.NewListService(store)),
+		Label:        handler.NewLabelHandler(service.NewLabelService(store)),
+		Comment:      handler.NewCommentHandler(service.NewCommentService(store)),
+		Attachment:   handler.NewAttachmentHandler(attachmentService),
+		Notification: handler.NewNotificationHandler(service.NewNotificationService(store)),
+		Event:        handler.NewEventHandler(eventService),
+		Collab:       handler.NewCollabHandler(service.NewCollabService(hub, eventService, store)),
+		Webhook:      handler.NewWebhookHandler(service.NewWebhookService(store, webhook.NewAddressFilter(webhooks.AllowedNetworks))),
+		Search:       handler.NewSearchHandler(service.NewSearchService(index, store)),
+		View:         handler.NewViewHandler(service.NewViewService(store, todoService)),
+		User:         handler.NewUserHandler(service.NewUserService(store)),
+		Workspace:    handler.NewWorkspaceHandler(service.NewWorkspaceService(store)),
+		Auth:         handler.NewAuthHandler(authService),
+	}
 
 	// Setup routes
-	router := routes.SetupRoutes(todoHandler)
+	router := routes.SetupRoutes(handlers, authService)
 
 	// Start server
 	log.Printf("🚀 Server starting on port %s...", port)
@@ -39,7 +125,291 @@ func main() {
 	log.Printf("💚 Health check: http://localhost:%s/health", port)
 	log.Printf("📚 API docs: http://localhost:%s/", port)
 
-	if err := http.ListenAndServe(":"+port, router); err != nil {
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	// Shutdown does not wait for event streams and WebSockets to end on their
+	// own, close them. The hub goes first so connections tell clients why.
+	server.RegisterOnShutdown(func() {
+		hub.Close()
+		bus.Close()
+	})
+
+	// Shut down gracefully on Ctrl+C / SIGTERM so storage can be flushed and closed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Fire todo reminders in the background until shutdown
+	scheduler := reminder.NewScheduler(store, reminder.LogNotifier{}, reminderInterval())
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		scheduler.Run(ctx)
+	}()
+	// Discard expired uploads and unused attachment files in the background
+	sweeperDone := make(chan struct{})
+	go func() {
+		defer close(sweeperDone)
+		attachmentService.RunSweeper(ctx, blobSweepInterval())
+	}()
+	// Release editing locks whose holders went quiet
+	go hub.Run(ctx, 5*time.Second)
+	// Deliver todo events to webhooks, retrying failed deliveries. It stops
+	// last, so it queues the events of every change made before the shutdown.
+	dispatcher := webhook.NewDispatcher(store, bus, webhooks)
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+	dispatcherDone := make(chan struct{})
+	go func() {
+		defer close(dispatcherDone)
+		dispatcher.Run(dispatcherCtx)
+	}()
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Println("🛑 Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal("❌ Server failed to start:", err)
 	}
+	// The store is closed on return, wait until the requests in flight and the
+	// background jobs stopped using it
+	<-shutdownDone
+	<-schedulerDone
+	<-sweeperDone
+	stopDispatcher()
+	<-dispatcherDone
+}
+
+// openStore selects the storage backend from STORAGE_DRIVER (memory or sqlite)
+func openStore(autoMigrate bool) (repository.Store, func()) {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = "memory"
+	}
+
+	switch driver {
+	case "memory":
+		var journal *repository.Journal
+		if dir := os.Getenv("MEMORY_JOURNAL_DIR"); dir != "" {
+			snapshotEvery := 1000
+			if v := os.Getenv("JOURNAL_SNAPSHOT_EVERY"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					log.Fatal("❌ Invalid JOURNAL_SNAPSHOT_EVERY:", err)
+				}
+				snapshotEvery = n
+			}
+
+			var err error
+			journal, err = repository.OpenJournal(dir, snapshotEvery)
+			if err != nil {
+				log.Fatal("❌ Failed to open journal:", err)
+			}
+			log.Printf("💾 Storage: in-memory with journal (%s)", dir)
+		} else {
+			log.Println("💾 Storage: in-memory (data is lost on restart)")
+		}
+
+		repo, err := repository.NewTodoRepository(journal)
+		if err != nil {
+			log.Fatal("❌ Failed to replay journal:", err)
+		}
+		return repo, func() {
+			if err := repo.Close(); err != nil {
+				log.Println("⚠️  Failed to close journal:", err)
+			}
+		}
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "data/todo.db"
+		}
+		db, err := repository.OpenSQLite(path)
+		if err != nil {
+			log.Fatal("❌ Failed to open SQLite database:", err)
+		}
+		checkMigrations(db, autoMigrate)
+
+		repo, err := repository.NewSQLiteRepository(db)
+		if err != nil {
+			log.Fatal("❌ Failed to open SQLite database:", err)
+		}
+		log.Printf("💾 Storage: SQLite (%s)", path)
+		return repo, func() { repo.Close() }
+	default:
+		log.Fatalf("❌ Unknown STORAGE_DRIVER %q (expected memory or sqlite)", driver)
+		return nil, nil
+	}
+}
+
+// checkMigrations refuses to start on an outdated schema unless autoMigrate is set
+func checkMigrations(db *sql.DB, autoMigrate bool) {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal("❌ Failed to load migrations:", err)
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		log.Fatal("❌ Failed to check migrations:", err)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if !autoMigrate {
+		for _, m := range pending {
+			log.Printf("⏳ Pending migration %04d_%s", m.Version, m.Name)
+		}
+		log.Fatal("❌ Database schema is out of date. Run `go run ./cmd/migrate up` or start with -auto-migrate (AUTO_MIGRATE=true)")
+	}
+
+	applied, err := migrator.Up(false)
+	for _, m := range applied {
+		log.Printf("✅ Applied migration %04d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatal("❌ Migration failed:", err)
+	}
+}
+
+// reminderInterval returns how often due reminders are checked, from REMINDER_INTERVAL (default 30s)
+func reminderInterval() time.Duration {
+	v := os.Getenv("REMINDER_INTERVAL")
+	if v == "" {
+		return 30 * time.Second
+	}
+
+	interval, err := time.ParseDuration(v)
+	if err != nil || interval <= 0 {
+		log.Fatal("❌ Invalid REMINDER_INTERVAL (expected a duration like 30s):", v)
+	}
+	return interval
+}
+
+// eventReplaySize returns how many events are kept for clients resuming with Last-Event-ID, from EVENT_REPLAY_SIZE (default 1000)
+func eventReplaySize() int {
+	v := os.Getenv("EVENT_REPLAY_SIZE")
+	if v == "" {
+		return 1000
+	}
+
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		log.Fatal("❌ Invalid EVENT_REPLAY_SIZE (expected a positive number):", v)
+	}
+	return size
+}
+
+// editLockTTL returns how long an editing lock outlives the last heartbeat of its holder, from EDIT_LOCK_TTL (default 45s)
+func editLockTTL() time.Duration {
+	v := os.Getenv("EDIT_LOCK_TTL")
+	if v == "" {
+		return 45 * time.Second
+	}
+
+	ttl, err := time.ParseDuration(v)
+	if err != nil || ttl <= 0 {
+		log.Fatal("❌ Invalid EDIT_LOCK_TTL (expected a duration like 45s):", v)
+	}
+	return ttl
+}
+
+// webhookConfig returns the webhook delivery settings, from WEBHOOK_MAX_ATTEMPTS
+// (default 8), WEBHOOK_RETRY_BASE (default 1m, doubled after every failed
+// attempt up to 1h), WEBHOOK_TIMEOUT (default 10s), WEBHOOK_RETENTION (default
+// 168h) and WEBHOOK_ALLOWED_NETWORKS (default none)
+func webhookConfig() webhook.Config {
+	config := webhook.DefaultConfig()
+	if v := os.Getenv("WEBHOOK_ALLOWED_NETWORKS"); v != "" {
+		networks, err := webhook.ParseNetworks(v)
+		if err != nil {
+			log.Fatal("❌ Invalid WEBHOOK_ALLOWED_NETWORKS (expected networks like 10.1.0.0/16,192.168.1.20):", v)
+		}
+		config.AllowedNetworks = networks
+	}
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatal("❌ Invalid WEBHOOK_MAX_ATTEMPTS (expected a positive number):", v)
+		}
+		config.MaxAttempts = n
+	}
+	for _, setting := range []struct {
+		key   string
+		value *time.Duration
+	}{
+		{"WEBHOOK_RETRY_BASE", &config.RetryBase},
+		{"WEBHOOK_TIMEOUT", &config.Timeout},
+		{"WEBHOOK_RETENTION", &config.Retention},
+	} {
+		v := os.Getenv(setting.key)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("❌ Invalid %s (expected a duration like 30s): %s", setting.key, v)
+		}
+		*setting.value = d
+	}
+	if config.RetryMax < config.RetryBase {
+		config.RetryMax = config.RetryBase
+	}
+	return config
+}
+
+// attachmentMaxSize returns the largest attachment accepted, from ATTACHMENT_MAX_SIZE in bytes (default 10 MiB)
+func attachmentMaxSize() int64 {
+	v := os.Getenv("ATTACHMENT_MAX_SIZE")
+	if v == "" {
+		return 10 << 20
+	}
+
+	size, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || size <= 0 {
+		log.Fatal("❌ Invalid ATTACHMENT_MAX_SIZE (expected a number of bytes):", v)
+	}
+	return size
+}
+
+// blobSweepInterval returns how often unused attachment files are removed, from BLOB_SWEEP_INTERVAL (default 1h)
+func blobSweepInterval() time.Duration {
+	v := os.Getenv("BLOB_SWEEP_INTERVAL")
+	if v == "" {
+		return time.Hour
+	}
+
+	interval, err := time.ParseDuration(v)
+	if err != nil || interval <= 0 {
+		log.Fatal("❌ Invalid BLOB_SWEEP_INTERVAL (expected a duration like 1h):", v)
+	}
+	return interval
+}
+
+// envOr returns the environment variable key, or fallback when it is empty
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// authSecret returns the token signing key from AUTH_SECRET. Without it a random
+// key is generated, which means tokens stop working after every restart.
+func authSecret() []byte {
+	if secret := os.Getenv("AUTH_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	log.Println("⚠️  AUTH_SECRET not set, using a random key (tokens are invalidated on restart)")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal("❌ Failed to generate auth secret:", err)
+	}
+	return secret
 }
@@ -0,0 +1,109 @@
+// Command migrate applies, rolls back and reports SQL schema migrations.
+//
+// Usage:
+//
+//	migrate [-db path] [-dry-run] up
+//	migrate [-db path] [-dry-run] [-steps n] down
+//	migrate [-db path] status
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"test_mekari/internal/migrations"
+	"test_mekari/internal/repository"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load .env file (if exists) so the same SQLITE_PATH as the server is used
+	godotenv.Load()
+
+	defaultPath := os.Getenv("SQLITE_PATH")
+	if defaultPath == "" {
+		defaultPath = "data/todo.db"
+	}
+
+	dbPath := flag.String("db", defaultPath, "SQLite database file")
+	dryRun := flag.Bool("dry-run", false, "print what would run without changing the database")
+	steps := flag.Int("steps", 1, "number of migrations to roll back with down")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: migrate [flags] up|down|status")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := repository.OpenSQLite(*dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	prefix := ""
+	if *dryRun {
+		prefix = "[dry-run] "
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(*dryRun)
+		for _, m := range applied {
+			fmt.Printf("%sup   %04d_%s\n", prefix, m.Version, m.Name)
+			if *dryRun {
+				fmt.Println(m.Up)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Nothing to migrate, schema is up to date")
+		}
+	case "down":
+		rolledBack, err := migrator.Down(*steps, *dryRun)
+		for _, m := range rolledBack {
+			fmt.Printf("%sdown %04d_%s\n", prefix, m.Version, m.Name)
+			if *dryRun {
+				fmt.Println(m.Down)
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("Nothing to roll back")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+				if !s.AppliedAt.IsZero() {
+					state += " " + s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordRoundTrip(t *testing.T) {
+	encoded, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("HashPassword = %s, want a PHC argon2id string", encoded)
+	}
+	if ok, err := VerifyPassword("correct horse", encoded); !ok || err != nil {
+		t.Errorf("VerifyPassword(right password) = %v, %v, want true", ok, err)
+	}
+	if ok, err := VerifyPassword("correct horse ", encoded); ok || err != nil {
+		t.Errorf("VerifyPassword(wrong password) = %v, %v, want false without an error", ok, err)
+	}
+
+	// A random salt makes every hash of the same password different
+	again, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == encoded {
+		t.Error("two hashes of one password are equal")
+	}
+}
+
+func TestVerifyPasswordMalformed(t *testing.T) {
+	valid, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, "$")
+	with := func(i int, part string) string {
+		changed := append([]string{}, parts...)
+		changed[i] = part
+		return strings.Join(changed, "$")
+	}
+
+	for _, encoded := range []string{
+		"",
+		"secret",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdA",
+		valid + "$extra",
+		with(1, "argon2i"),
+		with(1, "bcrypt"),
+		with(2, "v=16"),
+		with(2, "version"),
+		with(3, "m=lots,t=3,p=2"),
+		with(3, "t=3"),
+		with(4, "not base64!"),
+		with(5, "not base64!"),
+	} {
+		if ok, err := VerifyPassword("secret", encoded); ok || err != ErrInvalidHash {
+			t.Errorf("VerifyPassword(%q) = %v, %v, want ErrInvalidHash", encoded, ok, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found or expired")
+)
+
+// session is a server-side login bound to a cookie
+type session struct {
+	userID    int
+	expiresAt time.Time
+}
+
+// SessionStore keeps cookie sessions in memory.
+// Sessions are revocable (logout) but do not survive a restart.
+type SessionStore struct {
+	sessions map[string]session
+	ttl      time.Duration
+	mu       sync.Mutex
+}
+
+// NewSessionStore creates a session store; sessions expire ttl after creation
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	return &SessionStore{
+		sessions: make(map[string]session),
+		ttl:      ttl,
+	}
+}
+
+// Create starts a session for userID and returns its random ID and expiry
+func (s *SessionStore) Create(userID int) (string, time.Time, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	id := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	s.sessions[id] = session{userID: userID, expiresAt: expiresAt}
+	return id, expiresAt, nil
+}
+
+// Lookup returns the user ID of a live session
+func (s *SessionStore) Lookup(id string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, exists := s.sessions[id]
+	if !exists || time.Now().After(sess.expiresAt) {
+		delete(s.sessions, id)
+		return 0, ErrSessionNotFound
+	}
+	return sess.userID, nil
+}
+
+// Delete ends a session
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+}
+
+// pruneLocked drops expired sessions (lock must be held)
+func (s *SessionStore) pruneLocked() {
+	now := time.Now()
+	for id, sess := range s.sessions {
+		if now.After(sess.expiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
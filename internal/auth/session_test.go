@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionLookupAndDelete(t *testing.T) {
+	sessions := NewSessionStore(time.Hour)
+	first, expiresAt, err := sessions.Create(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("session expires in %v, want an hour", until)
+	}
+	second, _, err := sessions.Create(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("two sessions share an ID")
+	}
+
+	if userID, err := sessions.Lookup(first); userID != 7 || err != nil {
+		t.Errorf("Lookup = %d, %v, want 7", userID, err)
+	}
+	if _, err := sessions.Lookup("unknown"); err != ErrSessionNotFound {
+		t.Errorf("Lookup(unknown) = %v, want ErrSessionNotFound", err)
+	}
+
+	// Deleting one session leaves the other
+	sessions.Delete(first)
+	if _, err := sessions.Lookup(first); err != ErrSessionNotFound {
+		t.Errorf("Lookup after Delete = %v, want ErrSessionNotFound", err)
+	}
+	if userID, err := sessions.Lookup(second); userID != 7 || err != nil {
+		t.Errorf("Lookup of the other session = %d, %v, want 7", userID, err)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	sessions := NewSessionStore(-time.Second)
+	id, _, err := sessions.Create(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Lookup(id); err != ErrSessionNotFound {
+		t.Errorf("Lookup(expired) = %v, want ErrSessionNotFound", err)
+	}
+
+	// Creating a session prunes the expired ones
+	for i := 0; i < 3; i++ {
+		if _, _, err := sessions.Create(7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sessions.sessions) != 1 {
+		t.Errorf("%d sessions kept, want only the newest of the expired ones", len(sessions.sessions))
+	}
+}
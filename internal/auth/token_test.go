@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestTokenRoundTrip(t *testing.T) {
+	signer := NewTokenSigner(secret, time.Hour)
+	token, expiresAt, err := signer.Issue(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("token expires in %v, want an hour", until)
+	}
+	if userID, err := signer.Verify(token); userID != 42 || err != nil {
+		t.Errorf("Verify = %d, %v, want 42", userID, err)
+	}
+}
+
+func TestTokenTampered(t *testing.T) {
+	signer := NewTokenSigner(secret, time.Hour)
+	token, _, err := signer.Issue(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, signature, _ := strings.Cut(token, ".")
+
+	// The same claims for another user, under the original signature
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		t.Fatal(err)
+	}
+	c.Subject = 1
+	forged, _ := json.Marshal(c)
+	otherUser := base64.RawURLEncoding.EncodeToString(forged) + "." + signature
+
+	flipped := []byte(signature)
+	flipped[0] ^= 1
+	badSignature := encoded + "." + string(flipped)
+
+	other, _, err := NewTokenSigner([]byte("another secret"), time.Hour).Issue(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"tampered payload":   otherUser,
+		"tampered signature": badSignature,
+		"no signature":       encoded,
+		"empty signature":    encoded + ".",
+		"other secret":       other,
+		"empty":              "",
+	} {
+		if userID, err := signer.Verify(token); err != ErrInvalidToken {
+			t.Errorf("%s: Verify = %d, %v, want ErrInvalidToken", name, userID, err)
+		}
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	expired, _, err := NewTokenSigner(secret, -time.Second).Issue(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := NewTokenSigner(secret, time.Hour).Verify(expired); err != ErrExpiredToken {
+		t.Errorf("Verify(expired) = %d, %v, want ErrExpiredToken", userID, err)
+	}
+}
@@ -0,0 +1,38 @@
+// Package blob stores the contents of uploaded files.
+//
+// Contents are immutable and content-addressed: a blob's key is the hex
+// SHA-256 of its bytes, so the same file uploaded twice is stored once and
+// callers can share blobs freely. Blobs are never deleted one by one; Sweep
+// removes the ones nothing refers to anymore.
+package blob
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("file not found")
+	ErrTooLarge = errors.New("file is too large")
+)
+
+// Blob describes stored content
+type Blob struct {
+	Key  string // hex SHA-256 of the content
+	Size int64
+}
+
+// Store is a blob storage backend
+type Store interface {
+	// Put stores the content of r, returning ErrTooLarge (and storing nothing)
+	// when it is longer than maxSize bytes. Putting content that is stored
+	// already keeps the one copy and counts as storing it again for Sweep.
+	Put(r io.Reader, maxSize int64) (Blob, error)
+	// Open returns the content stored under key, ErrNotFound if there is none
+	Open(key string) (io.ReadSeekCloser, error)
+	// Sweep deletes every blob for which inUse is false and that was last
+	// stored before cutoff, returning how many it deleted. The cutoff protects
+	// blobs that were just stored and are not referenced yet.
+	Sweep(inUse func(key string) bool, cutoff time.Time) (int, error)
+}
@@ -0,0 +1,138 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LocalStore keeps blobs as files below a directory, in subdirectories named
+// after the first two characters of their key
+type LocalStore struct {
+	dir string
+	// mu orders Put against Sweep, so a blob being stored again is never swept
+	mu sync.Mutex
+}
+
+// NewLocalStore opens (or creates) a store in dir
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0o755); err != nil {
+		return nil, fmt.Errorf("create blob directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Put streams r into a temporary file while hashing it, then moves it into place
+func (s *LocalStore) Put(r io.Reader, maxSize int64) (Blob, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, "tmp"), "put-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return Blob{}, err
+	}
+	if size > maxSize {
+		return Blob{}, ErrTooLarge
+	}
+	if err := tmp.Sync(); err != nil {
+		return Blob{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Blob{}, err
+	}
+
+	blob := Blob{Key: hex.EncodeToString(hash.Sum(nil)), Size: size}
+	path := s.path(blob.Key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Stored already: keep that copy, but mark it as just stored
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return blob, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Blob{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Blob{}, err
+	}
+	return blob, nil
+}
+
+// Open opens the file of a blob
+func (s *LocalStore) Open(key string) (io.ReadSeekCloser, error) {
+	if !validKey(key) {
+		return nil, ErrNotFound
+	}
+	file, err := os.Open(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Sweep walks the store and deletes the unused blobs stored before cutoff,
+// along with temporary files of Puts that never finished
+func (s *LocalStore) Sweep(inUse func(key string) bool, cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+
+		key := entry.Name()
+		if filepath.Base(filepath.Dir(path)) == "tmp" {
+			return os.Remove(path)
+		}
+		if !validKey(key) || inUse(key) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// path returns the file of key
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key)
+}
+
+// validKey reports whether key is a hex SHA-256, so it can never escape the store's directory
+func validKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
@@ -0,0 +1,122 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *LocalStore {
+	t.Helper()
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// put stores content, failing the test on an error
+func put(t *testing.T, store *LocalStore, content string) Blob {
+	t.Helper()
+	blob, err := store.Put(strings.NewReader(content), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
+
+// age sets the modification time of a file d into the past
+func age(t *testing.T, path string, d time.Duration) {
+	t.Helper()
+	then := time.Now().Add(-d)
+	if err := os.Chtimes(path, then, then); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalStorePut(t *testing.T) {
+	store := newTestStore(t)
+	blob := put(t, store, "hello")
+	sum := sha256.Sum256([]byte("hello"))
+	if blob.Key != hex.EncodeToString(sum[:]) || blob.Size != 5 {
+		t.Errorf("put = %+v, want the SHA-256 of the content and 5 bytes", blob)
+	}
+	if again := put(t, store, "hello"); again != blob {
+		t.Errorf("put again = %+v, want %+v", again, blob)
+	}
+
+	file, err := store.Open(blob.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(content) != "hello" {
+		t.Errorf("open = %q (err %v), want %q", content, err, "hello")
+	}
+
+	if _, err := store.Put(strings.NewReader(strings.Repeat("x", 101)), 100); err != ErrTooLarge {
+		t.Errorf("put of 101 bytes: err = %v, want ErrTooLarge", err)
+	}
+	if tmp, _ := os.ReadDir(filepath.Join(store.dir, "tmp")); len(tmp) != 0 {
+		t.Errorf("%d temporary files left behind, want none", len(tmp))
+	}
+
+	for _, key := range []string{hex.EncodeToString(make([]byte, sha256.Size)), "../tmp", ""} {
+		if _, err := store.Open(key); err != ErrNotFound {
+			t.Errorf("open %q: err = %v, want ErrNotFound", key, err)
+		}
+	}
+}
+
+func TestLocalStoreSweep(t *testing.T) {
+	store := newTestStore(t)
+	used := put(t, store, "used")
+	unused := put(t, store, "unused")
+	fresh := put(t, store, "fresh")
+	stored := put(t, store, "stored again")
+	for _, blob := range []Blob{used, unused, stored} {
+		age(t, store.path(blob.Key), 2*time.Hour)
+	}
+	// Putting a blob again protects it like a fresh one
+	put(t, store, "stored again")
+
+	staleTmp := filepath.Join(store.dir, "tmp", "put-stale")
+	freshTmp := filepath.Join(store.dir, "tmp", "put-fresh")
+	for _, path := range []string{staleTmp, freshTmp} {
+		if err := os.WriteFile(path, []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age(t, staleTmp, 2*time.Hour)
+
+	deleted, err := store.Sweep(func(key string) bool { return key == used.Key }, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d blobs, want 1", deleted)
+	}
+	for _, check := range []struct {
+		name string
+		path string
+		kept bool
+	}{
+		{"used", store.path(used.Key), true},
+		{"unused", store.path(unused.Key), false},
+		{"fresh", store.path(fresh.Key), true},
+		{"stored again", store.path(stored.Key), true},
+		{"stale temporary file", staleTmp, false},
+		{"fresh temporary file", freshTmp, true},
+	} {
+		_, err := os.Stat(check.path)
+		if exists := err == nil; exists != check.kept {
+			t.Errorf("%s exists = %v, want %v", check.name, exists, check.kept)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package blob
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOffsetMismatch is returned by Append when a chunk does not start where the staged content ends
+var ErrOffsetMismatch = errors.New("chunk does not start at the upload offset")
+
+// stagingDir is the subdirectory of the upload directory Staging owns
+const stagingDir = "staging"
+
+// Suffixes of the files of a staged upload: its content and its description
+const (
+	contentSuffix = ".part"
+	metaSuffix    = ".json"
+	tmpSuffix     = ".tmp"
+)
+
+// Staging keeps the content of chunked uploads on local disk until all chunks
+// have arrived and the whole file can be Put into a Store. Every upload also
+// stores a description of its own, so uploads survive a restart. It does not
+// serialize access to one upload; callers append to an upload one chunk at a time.
+type Staging struct {
+	dir string
+}
+
+// Staged is an upload found in staging
+type Staged struct {
+	ID   string
+	Meta []byte // the description given to Create
+	Size int64  // bytes staged so far
+}
+
+// NewStaging opens (or creates) a staging area in a subdirectory of dir. Only
+// the files of uploads are ever touched, anything else in dir is left alone.
+func NewStaging(dir string) (*Staging, error) {
+	dir = filepath.Join(dir, stagingDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create staging directory: %w", err)
+	}
+	return &Staging{dir: dir}, nil
+}
+
+// Create starts an empty upload described by meta and returns its random ID
+func (s *Staging) Create(meta []byte) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(raw)
+
+	file, err := os.OpenFile(s.path(id, contentSuffix), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	// The description is moved into place whole, an upload without one is incomplete
+	tmp := s.path(id, metaSuffix+tmpSuffix)
+	if err := os.WriteFile(tmp, meta, 0o644); err != nil {
+		s.Remove(id)
+		return "", err
+	}
+	if err := os.Rename(tmp, s.path(id, metaSuffix)); err != nil {
+		os.Remove(tmp)
+		s.Remove(id)
+		return "", err
+	}
+	return id, nil
+}
+
+// Uploads returns the uploads staged by earlier runs. Files of uploads whose
+// creation did not complete are removed; files Staging did not write are ignored.
+func (s *Staging) Uploads() ([]Staged, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var staged []Staged
+	for _, entry := range entries {
+		name := entry.Name()
+		id, suffix, ok := strings.Cut(name, ".")
+		if !ok || !validID(id) || !entry.Type().IsRegular() {
+			continue
+		}
+		switch "." + suffix {
+		case metaSuffix + tmpSuffix:
+			os.Remove(filepath.Join(s.dir, name))
+		case contentSuffix:
+			if _, err := os.Stat(s.path(id, metaSuffix)); errors.Is(err, fs.ErrNotExist) {
+				os.Remove(filepath.Join(s.dir, name))
+			}
+		case metaSuffix:
+			meta, err := os.ReadFile(filepath.Join(s.dir, name))
+			if err != nil {
+				return nil, err
+			}
+			info, err := os.Stat(s.path(id, contentSuffix))
+			if errors.Is(err, fs.ErrNotExist) {
+				os.Remove(filepath.Join(s.dir, name))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			staged = append(staged, Staged{ID: id, Meta: meta, Size: info.Size()})
+		}
+	}
+	return staged, nil
+}
+
+// Append adds the content of r to upload id, which must currently hold
+// exactly offset bytes, and returns the new size. At most limit bytes are
+// staged in total; ErrTooLarge leaves the upload as it was.
+func (s *Staging) Append(id string, offset int64, r io.Reader, limit int64) (int64, error) {
+	file, err := os.OpenFile(s.path(id, contentSuffix), os.O_WRONLY, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != offset {
+		return info.Size(), ErrOffsetMismatch
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(file, io.LimitReader(r, limit-offset+1))
+	if err == nil && offset+written > limit {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		// A partly written chunk is dropped, the client resends it whole
+		if truncErr := file.Truncate(offset); truncErr != nil {
+			return 0, truncErr
+		}
+		return offset, err
+	}
+	return offset + written, file.Sync()
+}
+
+// Open opens the staged content of an upload for reading
+func (s *Staging) Open(id string) (io.ReadSeekCloser, error) {
+	file, err := os.Open(s.path(id, contentSuffix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Remove discards an upload
+func (s *Staging) Remove(id string) error {
+	err := os.Remove(s.path(id, contentSuffix))
+	if errors.Is(err, fs.ErrNotExist) {
+		err = ErrNotFound
+	}
+	if metaErr := os.Remove(s.path(id, metaSuffix)); metaErr != nil && !errors.Is(metaErr, fs.ErrNotExist) {
+		return metaErr
+	}
+	return err
+}
+
+// path returns a file of upload id; IDs that are not hex never reach the disk
+func (s *Staging) path(id, suffix string) string {
+	if !validID(id) {
+		return filepath.Join(s.dir, "invalid")
+	}
+	return filepath.Join(s.dir, id+suffix)
+}
+
+// validID reports whether id looks like an ID Create returns
+func validID(id string) bool {
+	_, err := hex.DecodeString(id)
+	return err == nil && len(id) == 32
+}
@@ -0,0 +1,156 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingReader returns its content and then fails, like a dropped connection
+type failingReader struct {
+	content io.Reader
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.content.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func newTestStaging(t *testing.T, dir string) *Staging {
+	t.Helper()
+	staging, err := NewStaging(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return staging
+}
+
+// stagedContent returns what upload id holds so far
+func stagedContent(t *testing.T, staging *Staging, id string) string {
+	t.Helper()
+	file, err := staging.Open(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestStagingAppend(t *testing.T) {
+	staging := newTestStaging(t, t.TempDir())
+	id, err := staging.Create([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const limit = 11
+
+	steps := []struct {
+		name   string
+		offset int64
+		chunk  io.Reader
+		size   int64
+		err    error
+	}{
+		{"first chunk", 0, strings.NewReader("hello"), 5, nil},
+		{"chunk sent again", 0, strings.NewReader("hello"), 5, ErrOffsetMismatch},
+		{"chunk past the end", 7, strings.NewReader("rld"), 5, ErrOffsetMismatch},
+		{"interrupted chunk is dropped", 5, &failingReader{strings.NewReader(" wo")}, 5, errors.New("connection reset")},
+		{"chunk beyond the limit", 5, strings.NewReader(" world!"), 5, ErrTooLarge},
+		{"last chunk", 5, strings.NewReader(" world"), 11, nil},
+	}
+	for _, step := range steps {
+		size, err := staging.Append(id, step.offset, step.chunk, limit)
+		if (err == nil) != (step.err == nil) || err != nil && err.Error() != step.err.Error() {
+			t.Fatalf("%s: err = %v, want %v", step.name, err, step.err)
+		}
+		if size != step.size {
+			t.Fatalf("%s: size = %d, want %d", step.name, size, step.size)
+		}
+	}
+	if got := stagedContent(t, staging, id); got != "hello world" {
+		t.Errorf("staged %q, want %q", got, "hello world")
+	}
+
+	if _, err := staging.Append("0123456789abcdef0123456789abcdef", 0, strings.NewReader("x"), limit); err != ErrNotFound {
+		t.Errorf("append to an unknown upload: err = %v, want ErrNotFound", err)
+	}
+	if _, err := staging.Append("../../etc/passwd", 0, strings.NewReader("x"), limit); err != ErrNotFound {
+		t.Errorf("append to an invalid ID: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStagingUploads(t *testing.T) {
+	dir := t.TempDir()
+	staging := newTestStaging(t, dir)
+	first, err := staging.Create([]byte(`{"file_name":"a.txt"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := staging.Append(first, 0, strings.NewReader("abc"), 10); err != nil {
+		t.Fatal(err)
+	}
+	second, err := staging.Create([]byte(`{"file_name":"b.txt"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := staging.Create([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := staging.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := staging.Remove(removed); err != ErrNotFound {
+		t.Errorf("second remove: err = %v, want ErrNotFound", err)
+	}
+
+	// Leftovers of creations that did not complete, and files of others
+	stagingPath := filepath.Join(dir, stagingDir)
+	leftovers := map[string]bool{
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa.part":     false,
+		"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb.json.tmp": false,
+		"cccccccccccccccccccccccccccccccc.json":     false,
+		"notes.txt":                                 true,
+		"dddd.part":                                 true,
+	}
+	for name := range leftovers {
+		if err := os.WriteFile(filepath.Join(stagingPath, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A restart finds the uploads again
+	uploads, err := newTestStaging(t, dir).Uploads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Staged)
+	for _, upload := range uploads {
+		got[upload.ID] = upload
+	}
+	if len(got) != 2 {
+		t.Fatalf("found %d uploads, want 2: %v", len(got), uploads)
+	}
+	if upload := got[first]; string(upload.Meta) != `{"file_name":"a.txt"}` || upload.Size != 3 {
+		t.Errorf("first upload = %s with %d bytes, want a.txt with 3", upload.Meta, upload.Size)
+	}
+	if upload := got[second]; string(upload.Meta) != `{"file_name":"b.txt"}` || upload.Size != 0 {
+		t.Errorf("second upload = %s with %d bytes, want b.txt with 0", upload.Meta, upload.Size)
+	}
+	for name, kept := range leftovers {
+		_, err := os.Stat(filepath.Join(stagingPath, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s exists = %v, want %v", name, exists, kept)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package collab tracks who is watching a workspace live and which todos they
+// are editing.
+//
+// Presence and edit locks are kept in memory only. Every change to them is
+// published on the event bus next to the todo events, so the WebSocket
+// channel and the Server-Sent Events stream see the same history.
+package collab
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"test_mekari/internal/events"
+	"test_mekari/internal/models"
+)
+
+var (
+	ErrLocked      = errors.New("todo is being edited by another user")
+	ErrLockNotHeld = errors.New("todo is not being edited by this user")
+)
+
+// Hub holds the presence and edit locks of every workspace
+type Hub struct {
+	bus     *events.Bus
+	lockTTL time.Duration
+	// now is the clock locks are taken and renewed by
+	now func() time.Time
+
+	mu       sync.Mutex
+	watchers map[int]map[int]*watcher // workspace ID -> user ID
+	locks    map[int]*lock            // todo ID
+	done     chan struct{}
+	closed   bool
+}
+
+// watcher is a user present in a workspace through one or more connections
+type watcher struct {
+	name  string
+	conns int
+}
+
+// lock is an edit lock, held until expiresAt unless its holder renews it
+type lock struct {
+	events.Lock
+	workspaceID int
+	expiresAt   time.Time
+}
+
+// NewHub creates a hub publishing on bus. Edit locks expire lockTTL after
+// their holder was last heard from.
+func NewHub(bus *events.Bus, lockTTL time.Duration) *Hub {
+	return &Hub{
+		bus:      bus,
+		lockTTL:  lockTTL,
+		now:      time.Now,
+		watchers: make(map[int]map[int]*watcher),
+		locks:    make(map[int]*lock),
+		done:     make(chan struct{}),
+	}
+}
+
+// Join marks user present in a workspace for one more connection
+func (h *Hub) Join(workspaceID int, user *models.User) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	users := h.watchers[workspaceID]
+	if users == nil {
+		users = make(map[int]*watcher)
+		h.watchers[workspaceID] = users
+	}
+	if w := users[user.ID]; w != nil {
+		w.conns++
+		return
+	}
+	users[user.ID] = &watcher{name: user.Name, conns: 1}
+	h.publish(events.PresenceJoined, workspaceID, &events.Presence{UserID: user.ID, Name: user.Name}, nil)
+}
+
+// Leave ends one connection of a user to a workspace. When it was the last,
+// the user leaves and their edit locks in the workspace are released.
+func (h *Hub) Leave(workspaceID, userID int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	users := h.watchers[workspaceID]
+	w := users[userID]
+	if w == nil {
+		return
+	}
+	if w.conns--; w.conns > 0 {
+		return
+	}
+
+	delete(users, userID)
+	if len(users) == 0 {
+		delete(h.watchers, workspaceID)
+	}
+	for todoID, l := range h.locks {
+		if l.workspaceID == workspaceID && l.UserID == userID {
+			h.release(todoID)
+		}
+	}
+	h.publish(events.PresenceLeft, workspaceID, &events.Presence{UserID: userID, Name: w.name}, nil)
+}
+
+// Presence returns the users watching a workspace, ordered by ID
+func (h *Hub) Presence(workspaceID int) []events.Presence {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	presence := make([]events.Presence, 0, len(h.watchers[workspaceID]))
+	for userID, w := range h.watchers[workspaceID] {
+		presence = append(presence, events.Presence{UserID: userID, Name: w.name})
+	}
+	sort.Slice(presence, func(i, j int) bool { return presence[i].UserID < presence[j].UserID })
+	return presence
+}
+
+// Locks returns the edit locks held in a workspace, ordered by todo ID
+func (h *Hub) Locks(workspaceID int) []events.Lock {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	locks := make([]events.Lock, 0)
+	for _, l := range h.locks {
+		if l.workspaceID == workspaceID {
+			locks = append(locks, l.Lock)
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].TodoID < locks[j].TodoID })
+	return locks
+}
+
+// Lock takes the edit lock on a todo for user, or renews it when they already
+// hold it. While another user holds it ErrLocked is returned with their lock.
+func (h *Hub) Lock(workspaceID, todoID int, user *models.User) (events.Lock, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	if l := h.locks[todoID]; l != nil {
+		if l.UserID != user.ID {
+			return l.Lock, ErrLocked
+		}
+		l.expiresAt = now.Add(h.lockTTL)
+		return l.Lock, nil
+	}
+
+	l := &lock{
+		Lock:        events.Lock{TodoID: todoID, UserID: user.ID, Name: user.Name, Since: now},
+		workspaceID: workspaceID,
+		expiresAt:   now.Add(h.lockTTL),
+	}
+	h.locks[todoID] = l
+	h.publish(events.TodoEditing, workspaceID, nil, &l.Lock)
+	return l.Lock, nil
+}
+
+// Unlock releases the edit lock user holds on a todo
+func (h *Hub) Unlock(workspaceID, todoID, userID int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l := h.locks[todoID]
+	if l == nil || l.workspaceID != workspaceID || l.UserID != userID {
+		return ErrLockNotHeld
+	}
+	h.release(todoID)
+	return nil
+}
+
+// Touch renews every edit lock user holds in a workspace
+func (h *Hub) Touch(workspaceID, userID int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	expiresAt := h.now().Add(h.lockTTL)
+	for _, l := range h.locks {
+		if l.workspaceID == workspaceID && l.UserID == userID {
+			l.expiresAt = expiresAt
+		}
+	}
+}
+
+// Expire releases the edit locks that were not renewed in time
+func (h *Hub) Expire(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for todoID, l := range h.locks {
+		if !now.Before(l.expiresAt) {
+			h.release(todoID)
+		}
+	}
+}
+
+// Run expires edit locks every interval until ctx is cancelled
+func (h *Hub) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.Expire(now)
+		}
+	}
+}
+
+// Done is closed when the hub shuts down, connections should end then
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Close tells every connection to end
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+}
+
+// release removes the lock on a todo and announces it (lock must be held)
+func (h *Hub) release(todoID int) {
+	l := h.locks[todoID]
+	delete(h.locks, todoID)
+	h.publish(events.TodoReleased, l.workspaceID, nil, &l.Lock)
+}
+
+// publish announces a presence or lock change (lock must be held, so events
+// are published in the order the changes happened)
+func (h *Hub) publish(eventType events.Type, workspaceID int, presence *events.Presence, lock *events.Lock) {
+	h.bus.Publish(events.Event{Type: eventType, WorkspaceID: workspaceID, Presence: presence, Lock: lock})
+}
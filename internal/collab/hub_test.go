@@ -0,0 +1,283 @@
+package collab
+
+import (
+	"testing"
+	"time"
+
+	"test_mekari/internal/events"
+	"test_mekari/internal/models"
+)
+
+const lockTTL = time.Minute
+
+// clock is a time the test moves forward by hand
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// fakeClient is a user connected to a workspace, seeing the events published there
+type fakeClient struct {
+	t    *testing.T
+	user *models.User
+	sub  *events.Subscription
+}
+
+// newTestHub returns a hub on a fresh bus with a hand-driven clock
+func newTestHub(t *testing.T) (*Hub, *clock) {
+	t.Helper()
+	c := &clock{t: time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)}
+	hub := NewHub(events.NewBus(100), lockTTL)
+	hub.now = c.now
+	return hub, c
+}
+
+// connect joins user to a workspace of hub, like a WebSocket connection does
+func connect(t *testing.T, hub *Hub, workspaceID int, user *models.User) *fakeClient {
+	t.Helper()
+	sub, _, _, err := hub.bus.Subscribe(events.Filter{WorkspaceID: workspaceID}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sub.Cancel)
+	hub.Join(workspaceID, user)
+	return &fakeClient{t: t, user: user, sub: sub}
+}
+
+// received returns the events delivered to the client since it last looked
+func (c *fakeClient) received() []events.Event {
+	var received []events.Event
+	for {
+		select {
+		case e := <-c.sub.C:
+			received = append(received, e)
+		default:
+			return received
+		}
+	}
+}
+
+// expect checks the types of the events delivered since the client last looked
+func (c *fakeClient) expect(types ...events.Type) []events.Event {
+	c.t.Helper()
+	received := c.received()
+	if len(received) != len(types) {
+		c.t.Fatalf("%s received %d events %v, want %v", c.user.Name, len(received), eventTypes(received), types)
+	}
+	for i, e := range received {
+		if e.Type != types[i] {
+			c.t.Fatalf("%s received %v, want %v", c.user.Name, eventTypes(received), types)
+		}
+	}
+	return received
+}
+
+func eventTypes(received []events.Event) []events.Type {
+	types := make([]events.Type, len(received))
+	for i, e := range received {
+		types[i] = e.Type
+	}
+	return types
+}
+
+var (
+	alice = &models.User{ID: 1, Name: "Alice"}
+	bob   = &models.User{ID: 2, Name: "Bob"}
+)
+
+func TestLockContention(t *testing.T) {
+	hub, clock := newTestHub(t)
+	a := connect(t, hub, 1, alice)
+	b := connect(t, hub, 1, bob)
+	a.expect(events.PresenceJoined, events.PresenceJoined)
+	b.expect(events.PresenceJoined)
+
+	lock, err := hub.Lock(1, 10, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.TodoID != 10 || lock.UserID != alice.ID || lock.Name != "Alice" || !lock.Since.Equal(clock.t) {
+		t.Errorf("lock = %+v, want todo 10 held by Alice since now", lock)
+	}
+	editing := b.expect(events.TodoEditing)[0]
+	if editing.Lock == nil || *editing.Lock != lock {
+		t.Errorf("editing event carries %+v, want %+v", editing.Lock, lock)
+	}
+	a.expect(events.TodoEditing)
+
+	// Bob can neither take nor release Alice's lock
+	held, err := hub.Lock(1, 10, bob)
+	if err != ErrLocked || held != lock {
+		t.Errorf("Bob locking: %+v, %v; want Alice's lock and ErrLocked", held, err)
+	}
+	if err := hub.Unlock(1, 10, bob.ID); err != ErrLockNotHeld {
+		t.Errorf("Bob unlocking: err = %v, want ErrLockNotHeld", err)
+	}
+	if err := hub.Unlock(2, 10, alice.ID); err != ErrLockNotHeld {
+		t.Errorf("unlocking in another workspace: err = %v, want ErrLockNotHeld", err)
+	}
+	b.expect()
+
+	// Other todos are free
+	if _, err := hub.Lock(1, 11, bob); err != nil {
+		t.Errorf("Bob locking another todo: %v", err)
+	}
+	b.expect(events.TodoEditing)
+
+	if err := hub.Unlock(1, 10, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	released := b.expect(events.TodoReleased)[0]
+	if released.Lock == nil || released.Lock.TodoID != 10 || released.Lock.UserID != alice.ID {
+		t.Errorf("released event carries %+v, want Alice's lock on todo 10", released.Lock)
+	}
+	if _, err := hub.Lock(1, 10, bob); err != nil {
+		t.Errorf("Bob locking the released todo: %v", err)
+	}
+	if locks := hub.Locks(1); len(locks) != 2 || locks[0].TodoID != 10 || locks[0].UserID != bob.ID || locks[1].TodoID != 11 {
+		t.Errorf("locks = %+v, want Bob's on todos 10 and 11", locks)
+	}
+}
+
+func TestLockRenewal(t *testing.T) {
+	hub, clock := newTestHub(t)
+	a := connect(t, hub, 1, alice)
+	lock, err := hub.Lock(1, 10, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.expect(events.PresenceJoined, events.TodoEditing)
+
+	// Locking again renews the lock without announcing it again
+	clock.advance(lockTTL - time.Second)
+	renewed, err := hub.Lock(1, 10, alice)
+	if err != nil || renewed != lock {
+		t.Errorf("locking again: %+v, %v; want the same lock", renewed, err)
+	}
+	clock.advance(lockTTL - time.Second)
+	hub.Expire(clock.t)
+	a.expect()
+
+	// So does a heartbeat, for every lock of the user in the workspace
+	if _, err := hub.Lock(1, 11, alice); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(time.Second)
+	hub.Touch(1, alice.ID)
+	hub.Touch(2, alice.ID)
+	hub.Touch(1, bob.ID)
+	clock.advance(lockTTL - time.Second)
+	hub.Expire(clock.t)
+	a.expect(events.TodoEditing)
+	if locks := hub.Locks(1); len(locks) != 2 {
+		t.Fatalf("locks after renewal = %+v, want both", locks)
+	}
+
+	clock.advance(time.Second)
+	hub.Expire(clock.t)
+	a.expect(events.TodoReleased, events.TodoReleased)
+}
+
+func TestLockExpiry(t *testing.T) {
+	hub, clock := newTestHub(t)
+	a := connect(t, hub, 1, alice)
+	b := connect(t, hub, 1, bob)
+	if _, err := hub.Lock(1, 10, alice); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(lockTTL / 2)
+	if _, err := hub.Lock(1, 11, alice); err != nil {
+		t.Fatal(err)
+	}
+	a.received()
+	b.received()
+
+	clock.advance(lockTTL / 2)
+	hub.Expire(clock.t)
+	released := b.expect(events.TodoReleased)[0]
+	if released.Lock.TodoID != 10 {
+		t.Errorf("released the lock on todo %d, want the older one on 10", released.Lock.TodoID)
+	}
+	a.expect(events.TodoReleased)
+
+	lock, err := hub.Lock(1, 10, bob)
+	if err != nil {
+		t.Fatalf("Bob locking the expired todo: %v", err)
+	}
+	if !lock.Since.Equal(clock.t) {
+		t.Errorf("Bob's lock since %v, want now", lock.Since)
+	}
+	if _, err := hub.Lock(1, 11, bob); err != ErrLocked {
+		t.Errorf("Bob locking the todo still held: err = %v, want ErrLocked", err)
+	}
+}
+
+func TestLeaveReleasesLocks(t *testing.T) {
+	hub, _ := newTestHub(t)
+	a := connect(t, hub, 1, alice)
+	// A second connection of Alice's, and one to another workspace
+	hub.Join(1, alice)
+	other := connect(t, hub, 2, alice)
+	b := connect(t, hub, 1, bob)
+	for _, lock := range []struct{ workspaceID, todoID int }{{1, 10}, {1, 11}, {2, 20}} {
+		if _, err := hub.Lock(lock.workspaceID, lock.todoID, alice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := hub.Lock(1, 12, bob); err != nil {
+		t.Fatal(err)
+	}
+	a.received()
+	b.received()
+	other.received()
+
+	// Closing one of two connections changes nothing
+	hub.Leave(1, alice.ID)
+	b.expect()
+	if presence := hub.Presence(1); len(presence) != 2 {
+		t.Errorf("presence = %+v, want Alice and Bob", presence)
+	}
+
+	// Closing the last one releases Alice's locks in that workspace only
+	hub.Leave(1, alice.ID)
+	received := b.expect(events.TodoReleased, events.TodoReleased, events.PresenceLeft)
+	releasedIDs := map[int]bool{received[0].Lock.TodoID: true, received[1].Lock.TodoID: true}
+	if !releasedIDs[10] || !releasedIDs[11] {
+		t.Errorf("released the locks on %v, want 10 and 11", releasedIDs)
+	}
+	if left := received[2].Presence; left == nil || *left != (events.Presence{UserID: alice.ID, Name: "Alice"}) {
+		t.Errorf("presence.left carries %+v, want Alice", left)
+	}
+	if locks := hub.Locks(1); len(locks) != 1 || locks[0].UserID != bob.ID {
+		t.Errorf("locks in workspace 1 = %+v, want Bob's only", locks)
+	}
+	if locks := hub.Locks(2); len(locks) != 1 || locks[0].TodoID != 20 {
+		t.Errorf("locks in workspace 2 = %+v, want Alice's on todo 20", locks)
+	}
+	other.expect()
+	if presence := hub.Presence(1); len(presence) != 1 || presence[0].UserID != bob.ID {
+		t.Errorf("presence = %+v, want Bob only", presence)
+	}
+
+	// Leaving again, or without having joined, does nothing
+	hub.Leave(1, alice.ID)
+	hub.Leave(3, bob.ID)
+	b.expect()
+}
+
+func TestJoinAnnouncesOnce(t *testing.T) {
+	hub, _ := newTestHub(t)
+	b := connect(t, hub, 1, bob)
+	hub.Join(1, alice)
+	hub.Join(1, alice)
+	joined := b.expect(events.PresenceJoined, events.PresenceJoined)
+	if joined[1].Presence.UserID != alice.ID {
+		t.Errorf("joined %+v, want Alice", joined[1].Presence)
+	}
+	if presence := hub.Presence(1); len(presence) != 2 || presence[0].UserID != alice.ID || presence[1].UserID != bob.ID {
+		t.Errorf("presence = %+v, want Alice and Bob by ID", presence)
+	}
+}
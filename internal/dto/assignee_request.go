@@ -0,0 +1,6 @@
+package dto
+
+// AssigneeRequest is the body of POST /todos/{id}/assignees
+type AssigneeRequest struct {
+	UserID *int `json:"user_id"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// UploadRequest is the body of POST /todos/{id}/attachments/uploads, which
+// starts a resumable upload of a file of Size bytes
+type UploadRequest struct {
+	FileName string `json:"file_name"`
+	Size     int64  `json:"size"`
+}
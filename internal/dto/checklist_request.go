@@ -0,0 +1,9 @@
+package dto
+
+// ChecklistItemRequest is the body of POST /todos/{id}/checklist and
+// PATCH /todos/{id}/checklist/{cid}. Text is required when adding an item;
+// on update, fields left out keep their values.
+type ChecklistItemRequest struct {
+	Text *string `json:"text"`
+	Done *bool   `json:"done"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// CommentRequest is the body of POST /todos/{id}/comments and
+// PUT /todos/{id}/comments/{cmid}. ReplyToID answers another comment on the
+// same todo; it is ignored on update, replies never move.
+type CommentRequest struct {
+	Body      string `json:"body"`
+	ReplyToID int    `json:"reply_to_id"`
+}
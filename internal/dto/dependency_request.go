@@ -0,0 +1,7 @@
+package dto
+
+// DependencyRequest is the body of POST /todos/{id}/dependencies: the todo
+// with BlockerID has to be done before the todo can be
+type DependencyRequest struct {
+	BlockerID *int `json:"blocker_id"`
+}
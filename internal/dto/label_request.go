@@ -0,0 +1,8 @@
+package dto
+
+// LabelRequest is the body of POST /labels and PUT /labels/{lbid}.
+// Color is a "#rrggbb" hex color; it defaults to grey when left empty.
+type LabelRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+// ListRequest is the body of POST /lists and PUT /lists/{lid}
+type ListRequest struct {
+	Name string `json:"name"`
+}
@@ -1,7 +1,57 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateTodoRequest is the body of POST /todos and PUT /todos/{id}.
+// The owner is always the authenticated user, never taken from the body.
+// ListID 0 means no list; leaving it out keeps the current list on update.
+// DueAt and RemindAt are RFC 3339 timestamps; on update, leaving them out
+// keeps the current value and null clears it.
+// Recurrence is an RRULE such as "FREQ=WEEKLY;BYDAY=MO"; leaving it out
+// keeps the current rule on update and "" stops the series.
+// Priority and LabelIDs left out keep their values on update ("none" and
+// no labels on create); LabelIDs replaces the whole set of labels.
+// ParentID makes the todo a subtask of another todo, 0 makes it top-level;
+// leaving it out keeps the current parent on update.
 type CreateTodoRequest struct {
-	Text      string `json:"text"`
-	UserID    int    `json:"user_id"`
-	Completed bool   `json:"completed"`
+	Text       string       `json:"text"`
+	Completed  bool         `json:"completed"`
+	ListID     *int         `json:"list_id"`
+	ParentID   *int         `json:"parent_id"`
+	DueAt      NullableTime `json:"due_at"`
+	RemindAt   NullableTime `json:"remind_at"`
+	Recurrence *string      `json:"recurrence"`
+	Priority   *string      `json:"priority"`
+	LabelIDs   *[]int       `json:"label_ids"`
+}
+
+// MoveTodoRequest is the body of PATCH /todos/{id}/move. ListID 0 (or null) takes the todo out of its list.
+type MoveTodoRequest struct {
+	ListID int `json:"list_id"`
+}
+
+// NullableTime is an optional timestamp that tells a missing field (Set is
+// false) apart from an explicit null (Set is true, Time is nil)
+type NullableTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON is only called when the field is present, null included
+func (n *NullableTime) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	n.Time = nil
+	if string(data) == "null" {
+		return nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	n.Time = &t
+	return nil
 }
@@ -0,0 +1,18 @@
+package dto
+
+// CreateUserRequest is the body of POST /users. Role defaults to member.
+type CreateUserRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// UpdateUserRequest is the body of PUT /users/{id}.
+// An empty password or role keeps the current value.
+type UpdateUserRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+// ViewRequest is the body of POST /me/views and PUT /views/{vid}. Filter,
+// Sort and GroupBy take what ?filter= and ?sort= of GET /todos take, and a
+// field to group by. WorkspaceID defaults to the default workspace on create
+// and cannot be changed; Shared defaults to false on create and is left
+// unchanged on update when omitted.
+type ViewRequest struct {
+	WorkspaceID int    `json:"workspace_id"`
+	Name        string `json:"name"`
+	Filter      string `json:"filter"`
+	Sort        string `json:"sort"`
+	GroupBy     string `json:"group_by"`
+	Shared      *bool  `json:"shared"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+// WebhookRequest is the body of POST /webhooks and PUT /webhooks/{whid}.
+// Events lists the event types to deliver. An empty Secret is generated on
+// create and left unchanged on update; Active defaults to true on create and
+// is left unchanged on update when omitted.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// CreateWorkspaceRequest is the body of POST /workspaces
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// MembershipRequest is the body of PUT /workspaces/{wid}/members/{uid}
+type MembershipRequest struct {
+	Role string `json:"role"`
+}
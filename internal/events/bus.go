@@ -0,0 +1,295 @@
+// Package events broadcasts changes to todos, and who is looking at them, to
+// live subscribers such as the Server-Sent Events stream and WebSocket channel.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// Type says what happened
+type Type string
+
+const (
+	TodoCreated Type = "todo.created"
+	TodoUpdated Type = "todo.updated"
+	TodoToggled Type = "todo.toggled" // its completed status changed
+	TodoDeleted Type = "todo.deleted"
+
+	PresenceJoined Type = "presence.joined" // a user started watching the workspace
+	PresenceLeft   Type = "presence.left"   // their last connection to it closed
+	TodoEditing    Type = "todo.editing"    // a user took the soft lock on a todo
+	TodoReleased   Type = "todo.released"   // the lock was released or expired
+)
+
+// Event is one change in a workspace. Todo events carry Todo, the todo after
+// the change or as it was before it was deleted; presence events carry
+// Presence and lock events Lock. Todos are published as stored: the fields
+// computed for responses (Progress, Blocked and CommentCount) are left out
+// of the JSON of an event rather than sent unset.
+type Event struct {
+	ID          string       `json:"id"`
+	Type        Type         `json:"type"`
+	WorkspaceID int          `json:"workspace_id"`
+	Todo        *models.Todo `json:"todo,omitempty"`
+	Presence    *Presence    `json:"presence,omitempty"`
+	Lock        *Lock        `json:"lock,omitempty"`
+	At          time.Time    `json:"at"`
+	before      *models.Todo // the todo before an update, so filters see todos leaving them
+}
+
+// eventTodo is the JSON of the todo of an event. Its fields shadow the
+// computed fields of the todo and are always nil, so they are left out.
+type eventTodo struct {
+	*models.Todo
+	Progress     *struct{} `json:"progress,omitempty"`
+	Blocked      *struct{} `json:"blocked,omitempty"`
+	CommentCount *struct{} `json:"comment_count,omitempty"`
+}
+
+// MarshalJSON encodes the event with its todo as an eventTodo
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event // without the method, so the encoding does not recurse
+	out := struct {
+		event
+		Todo *eventTodo `json:"todo,omitempty"`
+	}{event: event(e)}
+	if e.Todo != nil {
+		out.Todo = &eventTodo{Todo: e.Todo}
+	}
+	return json.Marshal(out)
+}
+
+// Presence is a user watching a workspace live
+type Presence struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// Lock says that a user has been editing a todo since Since. Locks are
+// advisory: they inform other clients but never block a write.
+type Lock struct {
+	TodoID int       `json:"todo_id"`
+	UserID int       `json:"user_id"`
+	Name   string    `json:"name"`
+	Since  time.Time `json:"since"`
+}
+
+// Filter narrows a subscription. A todo event matches when the todo matches
+// before or after the change, so subscribers also learn about todos that left;
+// other events only need to be in the workspace.
+type Filter struct {
+	// WorkspaceID keeps the events of one workspace (0 = every workspace,
+	// only for internal consumers that authorize on their own)
+	WorkspaceID int
+	// UserID keeps only the todos of one owner (0 = any owner)
+	UserID int
+	// ListID keeps only the todos of one list; a pointer to 0 keeps the todos in no list (nil = any)
+	ListID *int
+	// AssigneeID keeps only the todos assigned to one user; a pointer to 0
+	// keeps the unassigned todos (nil = any)
+	AssigneeID *int
+}
+
+// Matches reports whether e passes the filter
+func (f Filter) Matches(e Event) bool {
+	if f.WorkspaceID != 0 && e.WorkspaceID != f.WorkspaceID {
+		return false
+	}
+	if e.Todo == nil {
+		return true
+	}
+	return f.matchesTodo(*e.Todo) || (e.before != nil && f.matchesTodo(*e.before))
+}
+
+func (f Filter) matchesTodo(todo models.Todo) bool {
+	if f.UserID != 0 && todo.UserID != f.UserID {
+		return false
+	}
+	if f.ListID != nil && todo.ListID != *f.ListID {
+		return false
+	}
+	if f.AssigneeID != nil {
+		if *f.AssigneeID == 0 {
+			return len(todo.AssigneeIDs) == 0
+		}
+		for _, id := range todo.AssigneeIDs {
+			if id == *f.AssigneeID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ErrClosed is returned when subscribing to a bus that was closed on shutdown
+var ErrClosed = errors.New("event bus is closed")
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped
+const subscriberBuffer = 64
+
+// Bus fans events out to subscribers and keeps the latest ones for replay.
+// Event IDs are "<epoch>-<sequence>", where the epoch changes on every start,
+// so IDs handed out by an earlier run are recognized as unknown.
+type Bus struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	buffer      []Event // ring of the last len(buffer) events
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBus creates a bus that can replay the last replaySize events
+func NewBus(replaySize int) *Bus {
+	if replaySize < 1 {
+		replaySize = 1
+	}
+	return &Bus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:      make([]Event, replaySize),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events matching its filter on C. C is closed
+// when the subscriber fell too far behind, was cancelled or the bus closed.
+type Subscription struct {
+	C <-chan Event
+	// Since is the ID of the last event published before the subscription
+	// started, so a subscriber that has not received any event yet can
+	// still catch up with Replay
+	Since  string
+	c      chan Event
+	filter Filter
+	bus    *Bus
+}
+
+// Cancel stops the subscription
+func (s *Subscription) Cancel() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
+
+// Publish assigns the next ID to e, records it for replay and delivers it
+func (b *Bus) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.ID = b.id(b.seq)
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	b.buffer[b.seq%uint64(len(b.buffer))] = e
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+			// A subscriber this far behind reconnects and catches up from the replay buffer
+			b.drop(sub)
+		}
+	}
+	return e
+}
+
+// Subscribe starts delivering the events matching filter. With a non-empty
+// lastEventID the matching events published after it are returned for replay
+// first; resync is true when that is impossible because lastEventID is
+// unknown or older than the replay buffer, and the subscriber has to reload
+// instead.
+func (b *Bus) Subscribe(filter Filter, lastEventID string) (sub *Subscription, replay []Event, resync bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, false, ErrClosed
+	}
+
+	replay = make([]Event, 0)
+	if lastEventID != "" {
+		replay, resync = b.replay(filter, lastEventID)
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: c, Since: b.id(b.seq), c: c, filter: filter, bus: b}
+	b.subscribers[sub] = struct{}{}
+	return sub, replay, resync, nil
+}
+
+// Replay returns the events matching filter published after lastEventID, like
+// Subscribe does, without subscribing. It also works after Close, so a
+// subscriber ended by the shutdown can still pick up what was published
+// since, e.g. by requests that were still finishing.
+func (b *Bus) Replay(filter Filter, lastEventID string) (replay []Event, resync bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.replay(filter, lastEventID)
+}
+
+// replay returns the events matching filter after lastEventID from the replay
+// buffer, or resync when they are not all there anymore (lock must be held)
+func (b *Bus) replay(filter Filter, lastEventID string) ([]Event, bool) {
+	after, ok := b.parseID(lastEventID)
+	oldest := uint64(1)
+	if b.seq > uint64(len(b.buffer)) {
+		oldest = b.seq - uint64(len(b.buffer)) + 1
+	}
+	if !ok || after > b.seq || after+1 < oldest {
+		return []Event{}, true
+	}
+
+	replay := make([]Event, 0)
+	for seq := after + 1; seq <= b.seq; seq++ {
+		if e := b.buffer[seq%uint64(len(b.buffer))]; filter.Matches(e) {
+			replay = append(replay, e)
+		}
+	}
+	return replay, false
+}
+
+// Close ends every subscription; publishing afterwards only fills the replay buffer
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub)
+	}
+}
+
+// drop removes a subscriber and closes its channel (lock must be held)
+func (b *Bus) drop(sub *Subscription) {
+	if _, exists := b.subscribers[sub]; exists {
+		delete(b.subscribers, sub)
+		close(sub.c)
+	}
+}
+
+// id returns the ID of the event with sequence number seq
+func (b *Bus) id(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// parseID returns the sequence number of an event ID of this run
+func (b *Bus) parseID(id string) (uint64, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
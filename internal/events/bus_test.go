@@ -0,0 +1,290 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"test_mekari/internal/models"
+)
+
+// publishTodos publishes a todo.updated event for each of ids in workspace 1
+func publishTodos(bus *Bus, ids ...int) []Event {
+	published := make([]Event, len(ids))
+	for i, id := range ids {
+		published[i] = bus.Publish(Event{Type: TodoUpdated, WorkspaceID: 1, Todo: &models.Todo{ID: id, WorkspaceID: 1}})
+	}
+	return published
+}
+
+// todoIDs returns the IDs of the todos of events
+func todoIDs(events []Event) []int {
+	ids := make([]int, len(events))
+	for i, e := range events {
+		ids[i] = e.Todo.ID
+	}
+	return ids
+}
+
+func TestPublishIDs(t *testing.T) {
+	bus := NewBus(4)
+	published := publishTodos(bus, 1, 2)
+	for i, e := range published {
+		epoch, seq, found := strings.Cut(e.ID, "-")
+		if !found || epoch != bus.epoch || seq != fmt.Sprint(i+1) {
+			t.Errorf("event %d has ID %q, want %s-%d", i, e.ID, bus.epoch, i+1)
+		}
+		if e.At.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+
+	for _, row := range []struct {
+		id  string
+		seq uint64
+		ok  bool
+	}{
+		{bus.epoch + "-2", 2, true},
+		{bus.epoch + "-0", 0, true},
+		{bus.epoch + "-18446744073709551615", 18446744073709551615, true},
+		{"other-2", 0, false},
+		{bus.epoch, 0, false},
+		{bus.epoch + "-", 0, false},
+		{bus.epoch + "--2", 0, false},
+		{bus.epoch + "-2x", 0, false},
+		{"", 0, false},
+	} {
+		if seq, ok := bus.parseID(row.id); seq != row.seq || ok != row.ok {
+			t.Errorf("parseID(%q) = %d, %v; want %d, %v", row.id, seq, ok, row.seq, row.ok)
+		}
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	// Ten events through a ring of four keeps the last four, 7 to 10
+	bus := NewBus(4)
+	published := publishTodos(bus, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	idOf := func(seq int) string { return published[seq-1].ID }
+
+	for _, row := range []struct {
+		name        string
+		lastEventID string
+		replay      []int
+		resync      bool
+	}{
+		{"no last event", "", []int{}, false},
+		{"latest event", idOf(10), []int{}, false},
+		{"within the ring", idOf(8), []int{9, 10}, false},
+		{"oldest kept event", idOf(7), []int{8, 9, 10}, false},
+		{"just before the ring", idOf(6), []int{7, 8, 9, 10}, false},
+		{"older than the ring", idOf(5), []int{}, true},
+		{"from an earlier run", "0-8", []int{}, true},
+		{"garbage", "nonsense", []int{}, true},
+		{"from the future", bus.epoch + "-11", []int{}, true},
+	} {
+		t.Run(row.name, func(t *testing.T) {
+			sub, replay, resync, err := bus.Subscribe(Filter{WorkspaceID: 1}, row.lastEventID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Cancel()
+			if got := todoIDs(replay); fmt.Sprint(got) != fmt.Sprint(row.replay) || resync != row.resync {
+				t.Errorf("replay %v, resync %v; want %v, %v", got, resync, row.replay, row.resync)
+			}
+		})
+	}
+}
+
+func TestSubscribeReplayBeforeWrap(t *testing.T) {
+	bus := NewBus(4)
+	first := publishTodos(bus, 1)[0]
+	publishTodos(bus, 2, 3)
+
+	_, replay, resync, err := bus.Subscribe(Filter{}, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := todoIDs(replay); fmt.Sprint(got) != "[2 3]" || resync {
+		t.Errorf("replay %v, resync %v; want [2 3], false", got, resync)
+	}
+	// Event 0 does not exist, but replaying everything after it is still possible
+	if _, replay, resync, _ = bus.Subscribe(Filter{}, bus.epoch+"-0"); fmt.Sprint(todoIDs(replay)) != "[1 2 3]" || resync {
+		t.Errorf("replay after 0: %v, resync %v; want [1 2 3], false", todoIDs(replay), resync)
+	}
+}
+
+func TestSubscribeReplayFiltered(t *testing.T) {
+	bus := NewBus(8)
+	first := bus.Publish(Event{Type: PresenceJoined, WorkspaceID: 1, Presence: &Presence{UserID: 1}})
+	listID := 2
+	bus.Publish(Event{Type: TodoCreated, WorkspaceID: 1, Todo: &models.Todo{ID: 1, WorkspaceID: 1, ListID: 2}})
+	bus.Publish(Event{Type: TodoCreated, WorkspaceID: 1, Todo: &models.Todo{ID: 2, WorkspaceID: 1, ListID: 3}})
+	bus.Publish(Event{Type: TodoCreated, WorkspaceID: 2, Todo: &models.Todo{ID: 3, WorkspaceID: 2, ListID: 2}})
+	bus.Publish(Event{Type: PresenceLeft, WorkspaceID: 1, Presence: &Presence{UserID: 1}})
+
+	_, replay, _, err := bus.Subscribe(Filter{WorkspaceID: 1, ListID: &listID}, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay) != 2 || replay[0].Type != TodoCreated || replay[0].Todo.ID != 1 || replay[1].Type != PresenceLeft {
+		t.Errorf("replay = %+v, want todo 1 and the presence event of workspace 1", replay)
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	zero, two, five := 0, 2, 5
+	todo := func(userID, listID int, assignees ...int) *models.Todo {
+		return &models.Todo{WorkspaceID: 1, UserID: userID, ListID: listID, AssigneeIDs: assignees}
+	}
+
+	for _, row := range []struct {
+		name   string
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		{"presence passes todo filters", Filter{WorkspaceID: 1, UserID: 9, ListID: &two, AssigneeID: &five},
+			Event{Type: PresenceJoined, WorkspaceID: 1, Presence: &Presence{UserID: 1}}, true},
+		{"lock passes todo filters", Filter{WorkspaceID: 1, ListID: &two},
+			Event{Type: TodoEditing, WorkspaceID: 1, Lock: &Lock{TodoID: 1}}, true},
+		{"presence of another workspace", Filter{WorkspaceID: 1},
+			Event{Type: PresenceJoined, WorkspaceID: 2}, false},
+		{"every workspace", Filter{},
+			Event{Type: TodoCreated, WorkspaceID: 2, Todo: todo(1, 0)}, true},
+		{"todo of another workspace", Filter{WorkspaceID: 1},
+			Event{Type: TodoCreated, WorkspaceID: 2, Todo: todo(1, 0)}, false},
+		{"owner", Filter{UserID: 1}, Event{Todo: todo(1, 0)}, true},
+		{"other owner", Filter{UserID: 2}, Event{Todo: todo(1, 0)}, false},
+		{"list", Filter{ListID: &two}, Event{Todo: todo(1, 2)}, true},
+		{"other list", Filter{ListID: &two}, Event{Todo: todo(1, 3)}, false},
+		{"no list", Filter{ListID: &zero}, Event{Todo: todo(1, 0)}, true},
+		{"no list but in one", Filter{ListID: &zero}, Event{Todo: todo(1, 2)}, false},
+		{"assignee", Filter{AssigneeID: &five}, Event{Todo: todo(1, 0, 4, 5)}, true},
+		{"other assignee", Filter{AssigneeID: &five}, Event{Todo: todo(1, 0, 4)}, false},
+		{"unassigned", Filter{AssigneeID: &zero}, Event{Todo: todo(1, 0)}, true},
+		{"unassigned but assigned", Filter{AssigneeID: &zero}, Event{Todo: todo(1, 0, 4)}, false},
+		{"moved out of the list", Filter{ListID: &two}, Event{Todo: todo(1, 3), before: todo(1, 2)}, true},
+		{"moved between other lists", Filter{ListID: &two}, Event{Todo: todo(1, 3), before: todo(1, 4)}, false},
+	} {
+		if got := row.filter.Matches(row.event); got != row.want {
+			t.Errorf("%s: Matches = %v, want %v", row.name, got, row.want)
+		}
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	bus := NewBus(128)
+	slow, _, _, err := bus.Subscribe(Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _, err := bus.Subscribe(Filter{WorkspaceID: 2}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Cancel()
+
+	// A full buffer is fine, one event more drops the subscriber
+	for i := 1; i <= subscriberBuffer; i++ {
+		publishTodos(bus, i)
+	}
+	if len(bus.subscribers) != 2 {
+		t.Fatalf("%d subscribers after %d events, want both", len(bus.subscribers), subscriberBuffer)
+	}
+	last := publishTodos(bus, subscriberBuffer+1)[0]
+	if _, subscribed := bus.subscribers[slow]; subscribed {
+		t.Fatalf("subscriber %d events behind is still subscribed", subscriberBuffer+1)
+	}
+	if _, subscribed := bus.subscribers[other]; !subscribed {
+		t.Errorf("subscriber the events did not match was dropped")
+	}
+
+	// It receives what was buffered, then sees its channel closed
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("received %d events before the close, want %d", received, subscriberBuffer)
+	}
+	slow.Cancel()
+
+	// and catches up from the replay buffer when it resubscribes
+	_, replay, resync, err := bus.Subscribe(Filter{}, fmt.Sprintf("%s-%d", bus.epoch, subscriberBuffer))
+	if err != nil || resync || len(replay) != 1 || replay[0].ID != last.ID {
+		t.Errorf("resubscribe: %d events, resync %v, err %v; want the last event", len(replay), resync, err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	bus := NewBus(4)
+	sub, _, _, err := bus.Subscribe(Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Close()
+	if _, open := <-sub.C; open {
+		t.Error("subscription still open after Close")
+	}
+	sub.Cancel()
+	if _, _, _, err := bus.Subscribe(Filter{}, ""); err != ErrClosed {
+		t.Errorf("subscribe after Close: err = %v, want ErrClosed", err)
+	}
+	// Publishing goes on, into the replay buffer only, where the subscriber
+	// still finds it
+	if e := publishTodos(bus, 1)[0]; e.ID != bus.epoch+"-1" {
+		t.Errorf("published %q after Close, want the first ID", e.ID)
+	}
+	if replay, resync := bus.Replay(Filter{}, sub.Since); fmt.Sprint(todoIDs(replay)) != "[1]" || resync {
+		t.Errorf("replay after Close: %v, resync %v; want [1], false", todoIDs(replay), resync)
+	}
+}
+
+func TestSubscriptionSince(t *testing.T) {
+	bus := NewBus(4)
+	sub, _, _, err := bus.Subscribe(Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Cancel()
+	if sub.Since != bus.epoch+"-0" {
+		t.Errorf("Since = %q before any event, want %s-0", sub.Since, bus.epoch)
+	}
+
+	publishTodos(bus, 1, 2)
+	later, _, _, err := bus.Subscribe(Filter{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer later.Cancel()
+	publishTodos(bus, 3)
+	if replay, _ := bus.Replay(Filter{}, later.Since); fmt.Sprint(todoIDs(replay)) != "[3]" {
+		t.Errorf("replay since the later subscription = %v, want [3]", todoIDs(replay))
+	}
+	if replay, _ := bus.Replay(Filter{}, sub.Since); fmt.Sprint(todoIDs(replay)) != "[1 2 3]" {
+		t.Errorf("replay since the first subscription = %v, want [1 2 3]", todoIDs(replay))
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	progress := 50
+	e := Event{ID: "a-1", Type: TodoUpdated, WorkspaceID: 1, Todo: &models.Todo{ID: 2, Text: "Write tests", Progress: &progress, Blocked: true, CommentCount: 3}}
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	todo, _ := decoded["todo"].(map[string]any)
+	if decoded["id"] != "a-1" || decoded["type"] != "todo.updated" || todo["text"] != "Write tests" {
+		t.Errorf("event JSON %s lacks its fields", data)
+	}
+	for _, computed := range []string{"progress", "blocked", "comment_count"} {
+		if _, present := todo[computed]; present {
+			t.Errorf("event JSON has the computed %s: %s", computed, data)
+		}
+	}
+}
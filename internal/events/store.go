@@ -0,0 +1,46 @@
+package events
+
+import (
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// PublishingStore is a repository.Store that publishes an event on bus for
+// every todo a mutation creates, changes or deletes, whichever backend it
+// wraps and however indirect the change (a deleted list, a cascading user
+// delete, ...). The events come from the changes the backend reports as it
+// commits, so they come out in commit order; the store itself adds nothing
+// to reads and writes.
+type PublishingStore struct {
+	repository.Store
+	bus *Bus
+}
+
+// NewPublishingStore wraps store so its todo changes are published on bus
+func NewPublishingStore(store repository.Store, bus *Bus) *PublishingStore {
+	s := &PublishingStore{Store: store, bus: bus}
+	store.ObserveTodos(s.publishChanges)
+	return s
+}
+
+// publishChanges publishes todo.created and todo.deleted for the todos that
+// appeared and disappeared, todo.toggled for the ones whose completed status
+// changed and todo.updated for the others
+func (s *PublishingStore) publishChanges(changes []repository.TodoChange) {
+	for _, change := range changes {
+		switch {
+		case change.Before == nil:
+			s.publish(TodoCreated, *change.After, nil)
+		case change.After == nil:
+			s.publish(TodoDeleted, *change.Before, nil)
+		case change.Before.Completed != change.After.Completed:
+			s.publish(TodoToggled, *change.After, change.Before)
+		default:
+			s.publish(TodoUpdated, *change.After, change.Before)
+		}
+	}
+}
+
+func (s *PublishingStore) publish(eventType Type, todo models.Todo, before *models.Todo) {
+	s.bus.Publish(Event{Type: eventType, WorkspaceID: todo.WorkspaceID, Todo: &todo, before: before})
+}
@@ -0,0 +1,178 @@
+// Package expr parses the filter expressions of GET /todos, such as
+//
+//	completed:false AND (assignee:2 OR label:urgent) AND created_at>2026-01-01
+//
+// A condition is a field, an operator and a value. Conditions combine with
+// AND, OR and NOT (in order of precedence: NOT, AND, OR, keywords in any
+// case) and parentheses; conditions side by side are ANDed as well. Values
+// holding spaces or parentheses are quoted with double quotes, with \" and
+// \\ as escapes.
+//
+// Every field compares with ":", "=" and "!=". Numbers, priorities and
+// timestamps also compare with "<", "<=", ">" and ">=". For text ":" means
+// contains and "=" equals, both ignoring case. Timestamps are RFC 3339, or
+// dates (UTC days) where "=" means on that day and ">" after it. assignee,
+// list_id, parent_id and label compare with an ID (a name for labels), or
+// with none; due_at compares with none as well.
+//
+// Parse checks fields, operators and values, and every error it returns is
+// an *Error with the position it applies to.
+package expr
+
+import (
+	"fmt"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// Limits on the expressions Parse accepts
+const (
+	MaxLength = 4096 // bytes
+	MaxDepth  = 32   // nested parentheses and NOTs
+)
+
+// Node is a node of a parsed expression: *And, *Or, *Not or *Cond
+type Node interface {
+	node()
+}
+
+// And matches the todos both X and Y match
+type And struct {
+	X, Y Node
+}
+
+// Or matches the todos X or Y match
+type Or struct {
+	X, Y Node
+}
+
+// Not matches the todos X does not match
+type Not struct {
+	X Node
+}
+
+// Cond matches the todos whose Field compares to the value with Op. The value
+// is parsed into the member that fits the field; "!=" is parsed into a Not,
+// and a date into conditions on the instants the day starts and ends.
+type Cond struct {
+	Field Field
+	Op    Op
+	Value string // as written, unquoted
+
+	Int      int             // id, user_id, assignee, list_id, parent_id; label once resolved
+	Bool     bool            // completed
+	Text     string          // text, and the name of a label
+	Priority models.Priority // priority
+	Time     time.Time       // created_at, updated_at, due_at
+	None     bool            // assignee, list_id, parent_id, label or due_at was none (or 0)
+
+	valuePos int // byte offset of the value
+}
+
+// Field is a field of a todo a condition tests
+type Field string
+
+const (
+	FieldID        Field = "id"
+	FieldText      Field = "text"
+	FieldCompleted Field = "completed"
+	FieldPriority  Field = "priority"
+	FieldUserID    Field = "user_id"  // the owner
+	FieldAssignee  Field = "assignee" // one of the assignees
+	FieldListID    Field = "list_id"
+	FieldParentID  Field = "parent_id"
+	FieldLabel     Field = "label" // one of the labels, by name
+	FieldDueAt     Field = "due_at"
+	FieldCreatedAt Field = "created_at"
+	FieldUpdatedAt Field = "updated_at"
+)
+
+// Op is the operator of a condition
+type Op string
+
+const (
+	Eq       Op = "="
+	Contains Op = ":" // text only, on other fields ":" is Eq
+	Lt       Op = "<"
+	Le       Op = "<="
+	Gt       Op = ">"
+	Ge       Op = ">="
+)
+
+// kind is the type of the values of a field
+type kind int
+
+const (
+	kindNumber kind = iota
+	kindRef         // an ID or none
+	kindBool
+	kindText
+	kindPriority
+	kindTime
+)
+
+var fields = map[Field]kind{
+	FieldID:        kindNumber,
+	FieldText:      kindText,
+	FieldCompleted: kindBool,
+	FieldPriority:  kindPriority,
+	FieldUserID:    kindNumber,
+	FieldAssignee:  kindRef,
+	FieldListID:    kindRef,
+	FieldParentID:  kindRef,
+	FieldLabel:     kindRef,
+	FieldDueAt:     kindTime,
+	FieldCreatedAt: kindTime,
+	FieldUpdatedAt: kindTime,
+}
+
+// fieldNames lists the fields in error messages
+const fieldNames = "id, text, completed, priority, user_id, assignee, list_id, parent_id, label, due_at, created_at and updated_at"
+
+// aliases are other names of fields, the sort names of GET /todos among them
+var aliases = map[string]Field{
+	"due": FieldDueAt,
+}
+
+// Error is an invalid expression, with the position it applies to
+type Error struct {
+	Position int    `json:"position"` // 1-based, in characters
+	Message  string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+// ValueError returns an *Error at the value of c, for checks Parse cannot
+// make itself, such as whether a label exists
+func (c *Cond) ValueError(src string, format string, args ...any) error {
+	return errorAt(src, c.valuePos, format, args...)
+}
+
+// Walk calls fn for every condition of n, stopping at the first error
+func Walk(n Node, fn func(*Cond) error) error {
+	switch n := n.(type) {
+	case *And:
+		if err := Walk(n.X, fn); err != nil {
+			return err
+		}
+		return Walk(n.Y, fn)
+	case *Or:
+		if err := Walk(n.X, fn); err != nil {
+			return err
+		}
+		return Walk(n.Y, fn)
+	case *Not:
+		return Walk(n.X, fn)
+	case *Cond:
+		return fn(n)
+	}
+	return nil
+}
+
+func (*And) node()  {}
+func (*Or) node()   {}
+func (*Not) node()  {}
+func (*Cond) node() {}
@@ -0,0 +1,192 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// format writes a parsed expression back out with every AND and OR in
+// parentheses and every value in the member it was parsed into
+func format(n Node) string {
+	switch n := n.(type) {
+	case nil:
+		return "<nil>"
+	case *And:
+		return "(" + format(n.X) + " AND " + format(n.Y) + ")"
+	case *Or:
+		return "(" + format(n.X) + " OR " + format(n.Y) + ")"
+	case *Not:
+		return "NOT " + format(n.X)
+	case *Cond:
+		var value string
+		switch fields[n.Field] {
+		case kindNumber:
+			value = fmt.Sprint(n.Int)
+		case kindRef:
+			switch {
+			case n.None:
+				value = "none"
+			case n.Field == FieldLabel:
+				value = fmt.Sprintf("%q", n.Text)
+			default:
+				value = fmt.Sprint(n.Int)
+			}
+		case kindBool:
+			value = fmt.Sprint(n.Bool)
+		case kindText:
+			value = fmt.Sprintf("%q", n.Text)
+		case kindPriority:
+			value = string(n.Priority)
+		case kindTime:
+			value = n.Time.Format(time.RFC3339)
+			if n.None {
+				value = "none"
+			}
+		}
+		return string(n.Field) + string(n.Op) + value
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+func TestParse(t *testing.T) {
+	table := []struct {
+		src  string
+		want string
+	}{
+		{"", "<nil>"},
+		{"  \t", "<nil>"},
+		{"completed:false", "completed=false"},
+		{"COMPLETED:TRUE", "completed=true"},
+		{"id>=3", "id>=3"},
+		{"user_id < 10", "user_id<10"},
+
+		// Precedence: NOT, then AND, then OR; side by side is AND
+		{"completed:false AND (assignee:2 OR label:urgent)", `(completed=false AND (assignee=2 OR label="urgent"))`},
+		{"id:1 OR id:2 AND id:3", "(id=1 OR (id=2 AND id=3))"},
+		{"id:1 AND id:2 OR id:3", "((id=1 AND id=2) OR id=3)"},
+		{"NOT completed:true OR id<3", "(NOT completed=true OR id<3)"},
+		{"not not id:1", "NOT NOT id=1"},
+		{"completed:true priority>=high", "(completed=true AND priority>=high)"},
+		{"id:1 or (id:2 and id:3) id:4", "(id=1 OR ((id=2 AND id=3) AND id=4))"},
+		{"((id:1))", "id=1"},
+
+		// Operators
+		{"priority!=low", "NOT priority=low"},
+		{"text:bank", `text:"bank"`},
+		{"text=bank", `text="bank"`},
+		{`text:"call the bank"`, `text:"call the bank"`},
+		{`text:"say \"hi\" \\ bye"`, `text:"say \"hi\" \\ bye"`},
+		{`text:"(AND)"`, `text:"(AND)"`},
+		{"label:Bug", `label="Bug"`},
+		{`label:"none"`, `label="none"`},
+		{"label:none", "label=none"},
+		{"assignee:NONE", "assignee=none"},
+		{"list_id:0", "list_id=none"},
+		{"parent_id:7", "parent_id=7"},
+
+		// Timestamps, and dates as the instants their UTC day starts and ends
+		{"due:none", "due_at=none"},
+		{"due_at!=none", "NOT due_at=none"},
+		{"due_at<2026-01-01T10:00:00+07:00", "due_at<2026-01-01T10:00:00+07:00"},
+		{"created_at=2026-01-01", "(created_at>=2026-01-01T00:00:00Z AND created_at<2026-01-02T00:00:00Z)"},
+		{"created_at!=2026-01-01", "NOT (created_at>=2026-01-01T00:00:00Z AND created_at<2026-01-02T00:00:00Z)"},
+		{"created_at<2026-01-01", "created_at<2026-01-01T00:00:00Z"},
+		{"created_at<=2026-01-01", "created_at<2026-01-02T00:00:00Z"},
+		{"updated_at>2026-01-01", "updated_at>=2026-01-02T00:00:00Z"},
+		{"updated_at>=2026-01-01", "updated_at>=2026-01-01T00:00:00Z"},
+	}
+	for _, row := range table {
+		n, err := Parse(row.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", row.src, err)
+			continue
+		}
+		if got := format(n); got != row.want {
+			t.Errorf("Parse(%q) = %s, want %s", row.src, got, row.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	table := []struct {
+		src      string
+		position int
+		message  string
+	}{
+		{"completed:nope", 11, `completed expects true or false, found "nope"`},
+		{"colour:red", 1, `unknown field "colour"`},
+		{"completed", 10, "expected an operator such as : or > after completed"},
+		{"completed:", 11, "expected a value after completed:"},
+		{"text>a", 5, "operator > does not apply to text"},
+		{"label<=bug", 6, "operator <= does not apply to label"},
+		{"(id:1", 6, "expected ) to close the ( at position 1"},
+		{"id:1 AND (id:2 OR (id:3)", 25, "expected ) to close the ( at position 10"},
+		{"id:1)", 5, "unexpected ), no ( to close"},
+		{"id:1 AND", 9, "found the end of the filter"},
+		{"AND id:1", 1, "found AND"},
+		{"id:1 OR or id:2", 9, "found OR"},
+		{"#", 1, `found '#'`},
+		{`text:"open`, 6, `quoted value is missing its closing "`},
+		{"id:-1", 4, `id expects a number, found "-1"`},
+		{"assignee:me", 10, `assignee expects an ID or none, found "me"`},
+		{"priority:huge", 10, "priority expects none, low, medium, high or urgent"},
+		{"due_at<none", 8, "none only compares with :, = and !="},
+		{"created_at:none", 12, "created_at expects an RFC 3339 timestamp or a date"},
+		{"due:2026-13-01", 5, "due_at expects an RFC 3339 timestamp"},
+
+		// Positions count characters, not bytes
+		{"text:é AND x:1", 12, `unknown field "x"`},
+		{`text:"日本" )`, 11, "unexpected )"},
+
+		// Limits
+		{strings.Repeat("(", MaxDepth+1) + "id:1" + strings.Repeat(")", MaxDepth+1), MaxDepth + 1, "nests deeper than 32 levels"},
+		{strings.Repeat("NOT ", MaxDepth+1) + "id:1", 4*MaxDepth + 1, "nests deeper than 32 levels"},
+		{"id:1" + strings.Repeat(" ", MaxLength), MaxLength + 1, "longer than 4096 bytes"},
+	}
+	for _, row := range table {
+		n, err := Parse(row.src)
+		var parseErr *Error
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%.40q) = %s, %v, want an *Error", row.src, format(n), err)
+			continue
+		}
+		if parseErr.Position != row.position || !strings.Contains(parseErr.Message, row.message) {
+			t.Errorf("Parse(%.40q) error = %d: %s, want %d: ...%s...", row.src, parseErr.Position, parseErr.Message, row.position, row.message)
+		}
+	}
+
+	// Nesting up to the limit is fine
+	deepest := strings.Repeat("(", MaxDepth) + "id:1" + strings.Repeat(")", MaxDepth)
+	if _, err := Parse(deepest); err != nil {
+		t.Errorf("Parse of %d nested parentheses: %v", MaxDepth, err)
+	}
+}
+
+func TestValueError(t *testing.T) {
+	src := "label:bug OR label:\"über\""
+	n, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var positions []int
+	err = Walk(n, func(c *Cond) error {
+		var valueErr *Error
+		errors.As(c.ValueError(src, "unknown label %q", c.Text), &valueErr)
+		positions = append(positions, valueErr.Position)
+		return nil
+	})
+	if err != nil || fmt.Sprint(positions) != "[7 20]" {
+		t.Errorf("value positions = %v (err %v), want [7 20]", positions, err)
+	}
+
+	// Walk stops at the first error
+	stop := errors.New("stop")
+	visited := 0
+	err = Walk(n, func(*Cond) error { visited++; return stop })
+	if err != stop || visited != 1 {
+		t.Errorf("Walk returning an error: err = %v after %d conditions, want stop after 1", err, visited)
+	}
+}
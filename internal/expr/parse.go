@@ -0,0 +1,362 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"test_mekari/internal/models"
+)
+
+// dateLayout is the form of a date value, the other timestamps are RFC 3339
+const dateLayout = "2006-01-02"
+
+// Parse parses an expression. An empty expression is nil, it matches every todo.
+func Parse(src string) (Node, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	if len(src) > MaxLength {
+		return nil, errorAt(src, MaxLength, "filter is longer than %d bytes", MaxLength)
+	}
+
+	p := &parser{src: src}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	// or stops early only at a ")" that closes nothing
+	if p.space(); p.pos < len(p.src) {
+		return nil, p.errorf(p.pos, "unexpected ), no ( to close")
+	}
+	return n, nil
+}
+
+// parser is a recursive descent parser over the bytes of an expression
+type parser struct {
+	src   string
+	pos   int // byte offset of the next character
+	depth int
+}
+
+func (p *parser) or() (Node, error) {
+	x, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		y, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		x = &Or{X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) and() (Node, error) {
+	x, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.space()
+		if p.pos == len(p.src) || p.src[p.pos] == ')' || p.peekKeyword("OR") {
+			return x, nil
+		}
+		// AND is optional, conditions side by side are ANDed as well
+		p.keyword("AND")
+		y, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		x = &And{X: x, Y: y}
+	}
+}
+
+func (p *parser) not() (Node, error) {
+	p.space()
+	start := p.pos
+	if !p.keyword("NOT") {
+		return p.primary()
+	}
+
+	if err := p.enter(start); err != nil {
+		return nil, err
+	}
+	x, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	p.depth--
+	return &Not{X: x}, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	p.space()
+	start := p.pos
+	if p.pos == len(p.src) || p.src[p.pos] != '(' {
+		return p.cond()
+	}
+
+	p.pos++
+	if err := p.enter(start); err != nil {
+		return nil, err
+	}
+	x, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.space(); p.pos == len(p.src) || p.src[p.pos] != ')' {
+		return nil, p.errorf(p.pos, "expected ) to close the ( at position %d", column(p.src, start))
+	}
+	p.pos++
+	p.depth--
+	return x, nil
+}
+
+func (p *parser) cond() (Node, error) {
+	p.space()
+	start := p.pos
+	name := p.ident()
+	switch {
+	case name == "" && p.pos == len(p.src):
+		return nil, p.errorf(start, "expected a condition such as completed:false, found the end of the filter")
+	case name == "":
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return nil, p.errorf(start, "expected a condition such as completed:false, found %q", r)
+	case isKeyword(name):
+		return nil, p.errorf(start, "expected a condition such as completed:false, found %s", strings.ToUpper(name))
+	}
+
+	field, ok := aliases[strings.ToLower(name)]
+	if !ok {
+		field = Field(strings.ToLower(name))
+	}
+	kind, ok := fields[field]
+	if !ok {
+		return nil, p.errorf(start, "unknown field %q, expected one of %s", name, fieldNames)
+	}
+
+	p.space()
+	opPos := p.pos
+	op := p.operator()
+	switch {
+	case op == "":
+		return nil, p.errorf(opPos, "expected an operator such as : or > after %s", name)
+	case op != ":" && op != "=" && op != "!=" && kind != kindNumber && kind != kindPriority && kind != kindTime:
+		return nil, p.errorf(opPos, "operator %s does not apply to %s, it compares with :, = and !=", op, field)
+	}
+
+	p.space()
+	valuePos := p.pos
+	value, quoted, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if value == "" && !quoted {
+		return nil, p.errorf(valuePos, "expected a value after %s%s", name, op)
+	}
+
+	c := &Cond{Field: field, Op: Op(op), Value: value, valuePos: valuePos}
+	switch {
+	case op == "!=":
+		c.Op = Eq
+	case op == ":" && kind != kindText:
+		c.Op = Eq
+	}
+	n, err := p.typed(c, kind, quoted)
+	if err != nil {
+		return nil, err
+	}
+	if op == "!=" {
+		return &Not{X: n}, nil
+	}
+	return n, nil
+}
+
+// typed parses the value of c for a field of kind
+func (p *parser) typed(c *Cond, kind kind, quoted bool) (Node, error) {
+	none := !quoted && strings.EqualFold(c.Value, "none")
+
+	switch kind {
+	case kindNumber:
+		n, err := strconv.Atoi(c.Value)
+		if err != nil || n < 0 {
+			return nil, p.errorf(c.valuePos, "%s expects a number, found %q", c.Field, c.Value)
+		}
+		c.Int = n
+
+	case kindRef:
+		if c.Field == FieldLabel {
+			c.Text, c.None = c.Value, none
+			break
+		}
+		n, err := strconv.Atoi(c.Value)
+		if none {
+			n, err = 0, nil
+		}
+		if err != nil || n < 0 {
+			return nil, p.errorf(c.valuePos, "%s expects an ID or none, found %q", c.Field, c.Value)
+		}
+		c.Int, c.None = n, n == 0
+
+	case kindBool:
+		switch strings.ToLower(c.Value) {
+		case "true":
+			c.Bool = true
+		case "false":
+		default:
+			return nil, p.errorf(c.valuePos, "%s expects true or false, found %q", c.Field, c.Value)
+		}
+
+	case kindText:
+		c.Text = c.Value
+
+	case kindPriority:
+		c.Priority = models.Priority(strings.ToLower(c.Value))
+		if !c.Priority.Valid() {
+			return nil, p.errorf(c.valuePos, "%s expects none, low, medium, high or urgent, found %q", c.Field, c.Value)
+		}
+
+	case kindTime:
+		if none && c.Field == FieldDueAt {
+			if c.Op != Eq {
+				return nil, p.errorf(c.valuePos, "none only compares with :, = and !=")
+			}
+			c.None = true
+			break
+		}
+		if day, err := time.Parse(dateLayout, c.Value); err == nil {
+			return dayCond(c, day), nil
+		}
+		t, err := time.Parse(time.RFC3339, c.Value)
+		if err != nil {
+			return nil, p.errorf(c.valuePos, "%s expects an RFC 3339 timestamp or a date such as 2026-01-31, found %q", c.Field, c.Value)
+		}
+		c.Time = t
+	}
+	return c, nil
+}
+
+// dayCond turns a condition on a day into conditions on the instants it starts and ends
+func dayCond(c *Cond, day time.Time) Node {
+	start, end := *c, *c
+	start.Time, end.Time = day, day.AddDate(0, 0, 1)
+	switch c.Op {
+	case Lt:
+		return &start // before the day starts
+	case Le:
+		end.Op = Lt // before the day ends
+		return &end
+	case Gt:
+		end.Op = Ge // once the day ended
+		return &end
+	case Ge:
+		return &start // once the day started
+	}
+	start.Op, end.Op = Ge, Lt
+	return &And{X: &start, Y: &end}
+}
+
+// enter descends into a parenthesis or NOT at start
+func (p *parser) enter(start int) error {
+	if p.depth++; p.depth > MaxDepth {
+		return p.errorf(start, "filter nests deeper than %d levels", MaxDepth)
+	}
+	return nil
+}
+
+// space skips white space
+func (p *parser) space() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// ident reads a field name or keyword
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && isIdent(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// peekKeyword reports whether the keyword (in any case) comes next
+func (p *parser) peekKeyword(keyword string) bool {
+	end := p.pos + len(keyword)
+	return end <= len(p.src) && strings.EqualFold(p.src[p.pos:end], keyword) &&
+		(end == len(p.src) || !isIdent(p.src[end]))
+}
+
+// keyword reads the keyword when it comes next
+func (p *parser) keyword(keyword string) bool {
+	p.space()
+	if !p.peekKeyword(keyword) {
+		return false
+	}
+	p.pos += len(keyword)
+	return true
+}
+
+// operator reads an operator, the longest one that matches
+func (p *parser) operator() string {
+	for _, op := range []string{"!=", "<=", ">=", ":", "=", "<", ">"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+// value reads a bare value, up to white space or a parenthesis, or a quoted one
+func (p *parser) value() (value string, quoted bool, err error) {
+	start := p.pos
+	if p.pos == len(p.src) || p.src[p.pos] != '"' {
+		for p.pos < len(p.src) && strings.IndexByte(" \t\r\n()", p.src[p.pos]) < 0 {
+			p.pos++
+		}
+		return p.src[start:p.pos], false, nil
+	}
+
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '"':
+			p.pos++
+			return b.String(), true, nil
+		case c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '"' || p.src[p.pos+1] == '\\'):
+			p.pos++
+			b.WriteByte(p.src[p.pos])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", true, p.errorf(start, "quoted value is missing its closing \"")
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return errorAt(p.src, pos, format, args...)
+}
+
+// errorAt returns an *Error at the byte offset pos of src
+func errorAt(src string, pos int, format string, args ...any) error {
+	return &Error{Position: column(src, pos), Message: fmt.Sprintf(format, args...)}
+}
+
+// column returns the 1-based position in characters of the byte offset pos of src
+func column(src string, pos int) int {
+	return utf8.RuneCountInString(src[:pos]) + 1
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func isKeyword(name string) bool {
+	return strings.EqualFold(name, "AND") || strings.EqualFold(name, "OR") || strings.EqualFold(name, "NOT")
+}
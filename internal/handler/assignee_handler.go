@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// AssignTodo handles POST /todos/{id}/assignees and POST /workspaces/{wid}/todos/{id}/assignees
+func (h *TodoHandler) AssignTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.AssigneeRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	todo, err := h.service.AssignTodo(middleware.CurrentUser(r.Context()), workspaceID, id, req)
+	if err != nil {
+		writeAssigneeError(w, err, "Failed to assign todo")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, todo, nil, nil)
+}
+
+// UnassignTodo handles DELETE /todos/{id}/assignees/{uid} and DELETE /workspaces/{wid}/todos/{id}/assignees/{uid}
+func (h *TodoHandler) UnassignTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["uid"])
+	if err != nil {
+		msg := "Invalid user ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return
+	}
+
+	todo, err := h.service.UnassignTodo(middleware.CurrentUser(r.Context()), workspaceID, id, userID)
+	if err != nil {
+		writeAssigneeError(w, err, "Failed to unassign todo")
+		return
+	}
+
+	msg := "Assignee removed successfully"
+	helpers.Success(w, helpers.Deleted, todo, &msg, nil)
+}
+
+// writeAssigneeError maps assignee errors of the todo service to their HTTP responses
+func writeAssigneeError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrTodoNotFound, repository.ErrUserNotFound,
+		repository.ErrAssigneeNotFound, service.ErrNotAssigned:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidAssigneeID, service.ErrInvalidAssignee:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead is what a multipart upload may carry besides the file itself
+const multipartOverhead = 1 << 20
+
+var errFilePartMissing = errors.New(`multipart form field "file" is required`)
+
+// AttachmentHandler handles HTTP requests for the files attached to todos
+type AttachmentHandler struct {
+	service *service.AttachmentService
+}
+
+// NewAttachmentHandler creates a new instance of AttachmentHandler
+func NewAttachmentHandler(service *service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{
+		service: service,
+	}
+}
+
+// GetAttachments handles GET /todos/{id}/attachments and GET /workspaces/{wid}/todos/{id}/attachments
+func (h *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.service.GetAttachments(middleware.CurrentUser(r.Context()), workspaceID, todoID)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to retrieve attachments")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, attachments, nil, nil)
+}
+
+// UploadAttachment handles POST /todos/{id}/attachments and POST /workspaces/{wid}/todos/{id}/attachments.
+// The file is sent as the "file" field of a multipart/form-data body and streamed to storage.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.service.MaxSize()+multipartOverhead)
+	defer r.Body.Close()
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		helpers.ErrorValidator(w, err.Error(), nil)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			helpers.ErrorValidator(w, errFilePartMissing.Error(), nil)
+			return
+		}
+		if err != nil {
+			writeAttachmentError(w, err, "Failed to upload attachment")
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := h.service.Upload(middleware.CurrentUser(r.Context()), workspaceID, todoID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			writeAttachmentError(w, err, "Failed to upload attachment")
+			return
+		}
+
+		helpers.Success(w, helpers.Uploaded, attachment, nil, nil)
+		return
+	}
+}
+
+// GetAttachment handles GET /todos/{id}/attachments/{aid} and GET /workspaces/{wid}/todos/{id}/attachments/{aid}.
+// It returns the metadata including download_url, where the content is served.
+func (h *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := attachmentIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.service.GetAttachment(middleware.CurrentUser(r.Context()), workspaceID, todoID, attachmentID)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to retrieve attachment")
+		return
+	}
+
+	helpers.Success(w, helpers.Downloaded, attachment, nil, nil)
+}
+
+// DownloadAttachment handles GET /todos/{id}/attachments/{aid}/download and
+// GET /workspaces/{wid}/todos/{id}/attachments/{aid}/download. It serves the raw
+// content, with support for range and conditional requests.
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := attachmentIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.service.OpenAttachment(middleware.CurrentUser(r.Context()), workspaceID, todoID, attachmentID)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to download attachment")
+		return
+	}
+	defer content.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.BlobKey+`"`)
+	http.ServeContent(w, r, attachment.FileName, attachment.CreatedAt, content)
+}
+
+// DeleteAttachment handles DELETE /todos/{id}/attachments/{aid} and DELETE /workspaces/{wid}/todos/{id}/attachments/{aid}
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, ok := attachmentIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAttachment(middleware.CurrentUser(r.Context()), workspaceID, todoID, attachmentID); err != nil {
+		writeAttachmentError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	msg := "Attachment deleted successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// StartUpload handles POST /todos/{id}/attachments/uploads and POST /workspaces/{wid}/todos/{id}/attachments/uploads
+func (h *AttachmentHandler) StartUpload(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UploadRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	upload, err := h.service.StartUpload(middleware.CurrentUser(r.Context()), workspaceID, todoID, req)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to start upload")
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	helpers.Success(w, helpers.OngoingUpload, upload, nil, nil)
+}
+
+// GetUpload handles GET /todos/{id}/attachments/uploads/{upid} and GET /workspaces/{wid}/todos/{id}/attachments/uploads/{upid}
+func (h *AttachmentHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	upload, err := h.service.GetUpload(middleware.CurrentUser(r.Context()), workspaceID, todoID, mux.Vars(r)["upid"])
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to retrieve upload")
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	helpers.Success(w, helpers.OngoingUpload, upload, nil, nil)
+}
+
+// AppendUpload handles PATCH /todos/{id}/attachments/uploads/{upid} and
+// PATCH /workspaces/{wid}/todos/{id}/attachments/uploads/{upid}. The body is the
+// next chunk of the file, the Upload-Offset header says where it starts.
+func (h *AttachmentHandler) AppendUpload(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		msg := "Upload-Offset header must be the number of bytes already received"
+		helpers.ErrorBadRequest(w, r.Header.Get("Upload-Offset"), &msg)
+		return
+	}
+	defer r.Body.Close()
+
+	upload, attachment, err := h.service.AppendUpload(middleware.CurrentUser(r.Context()), workspaceID, todoID, mux.Vars(r)["upid"], offset, r.Body)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to upload chunk")
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if attachment == nil {
+		helpers.Success(w, helpers.OngoingUpload, upload, nil, nil)
+		return
+	}
+	helpers.Success(w, helpers.Uploaded, attachment, nil, nil)
+}
+
+// CancelUpload handles DELETE /todos/{id}/attachments/uploads/{upid} and DELETE /workspaces/{wid}/todos/{id}/attachments/uploads/{upid}
+func (h *AttachmentHandler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelUpload(middleware.CurrentUser(r.Context()), workspaceID, todoID, mux.Vars(r)["upid"]); err != nil {
+		writeAttachmentError(w, err, "Failed to cancel upload")
+		return
+	}
+
+	msg := "Upload cancelled successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// attachmentIDParam parses the {aid} URL parameter, writing a 400 when it is not a number
+func attachmentIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["aid"])
+	if err != nil {
+		msg := "Invalid attachment ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeAttachmentError maps attachment service errors to their HTTP responses
+func writeAttachmentError(w http.ResponseWriter, err error, failureMsg string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		err = service.ErrFileTooLarge
+	}
+
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrTodoNotFound, repository.ErrAttachmentNotFound, service.ErrUploadNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrUploadOffset, service.ErrUploadBusy:
+		helpers.ErrorConflict(w, err.Error(), nil)
+	case service.ErrFileTypeNotAllowed, service.ErrEmptyFile, service.ErrFileTooLarge:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
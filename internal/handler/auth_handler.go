@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/service"
+)
+
+// AuthHandler handles HTTP requests for authentication
+type AuthHandler struct {
+	service *service.AuthService
+}
+
+// NewAuthHandler creates a new instance of AuthHandler
+func NewAuthHandler(service *service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		service: service,
+	}
+}
+
+// Login handles POST /auth/login
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoginRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		// Parse JSON error to human-readable message
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	result, err := h.service.Login(req)
+	if err != nil {
+		if err == service.ErrInvalidCredentials {
+			helpers.ErrorAuthentication(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrUserDeactivated {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		msg := "Failed to log in"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    result.SessionID,
+		Path:     "/",
+		Expires:  result.SessionExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	response := dto.LoginResponse{
+		Token:     result.Token,
+		TokenType: "Bearer",
+		ExpiresAt: result.TokenExpiresAt,
+		User:      result.User,
+	}
+	msg := "Logged in successfully"
+	helpers.Success(w, helpers.Created, response, &msg, nil)
+}
+
+// Logout handles POST /auth/logout
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
+		h.service.Logout(cookie.Value)
+	}
+
+	// Expire the cookie on the client
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	msg := "Logged out successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// Me handles GET /me
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	helpers.Success(w, helpers.Get, middleware.CurrentUser(r.Context()), nil, nil)
+}
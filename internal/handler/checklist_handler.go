@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// AddChecklistItem handles POST /todos/{id}/checklist and POST /workspaces/{wid}/todos/{id}/checklist
+func (h *TodoHandler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ChecklistItemRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	todo, err := h.service.AddChecklistItem(middleware.CurrentUser(r.Context()), workspaceID, id, req)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to add checklist item")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, todo, nil, nil)
+}
+
+// UpdateChecklistItem handles PATCH /todos/{id}/checklist/{cid} and PATCH /workspaces/{wid}/todos/{id}/checklist/{cid}
+func (h *TodoHandler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	itemID, ok := checklistItemIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ChecklistItemRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	todo, err := h.service.UpdateChecklistItem(middleware.CurrentUser(r.Context()), workspaceID, id, itemID, req)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to update checklist item")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, todo, nil, nil)
+}
+
+// DeleteChecklistItem handles DELETE /todos/{id}/checklist/{cid} and DELETE /workspaces/{wid}/todos/{id}/checklist/{cid}
+func (h *TodoHandler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	itemID, ok := checklistItemIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	todo, err := h.service.DeleteChecklistItem(middleware.CurrentUser(r.Context()), workspaceID, id, itemID)
+	if err != nil {
+		writeChecklistError(w, err, "Failed to delete checklist item")
+		return
+	}
+
+	msg := "Checklist item deleted successfully"
+	helpers.Success(w, helpers.Deleted, todo, &msg, nil)
+}
+
+// todoIDParam parses the {id} URL parameter, writing a 400 when it is not a number
+func todoIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		msg := "Invalid todo ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// checklistItemIDParam parses the {cid} URL parameter, writing a 400 when it is not a number
+func checklistItemIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["cid"])
+	if err != nil {
+		msg := "Invalid checklist item ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeChecklistError maps checklist errors of the todo service to their HTTP responses
+func writeChecklistError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrTodoNotFound, service.ErrChecklistItemNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidChecklistText:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
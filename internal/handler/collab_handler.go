@@ -0,0 +1,390 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"test_mekari/internal/collab"
+	"test_mekari/internal/events"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// presenceTimeout is how long a client may stay silent before it is
+	// disconnected; clients send a heartbeat well within it
+	presenceTimeout = 60 * time.Second
+	// pingInterval keeps idle connections from being closed by proxies
+	pingInterval = 25 * time.Second
+	// writeTimeout is how long one message may take to reach a client
+	writeTimeout = 10 * time.Second
+	// maxClientMessage is the largest message accepted from a client
+	maxClientMessage = 4096
+	// replyBuffer is how many replies may wait for a client before it is dropped
+	replyBuffer = 16
+)
+
+// upgrader only accepts connections from the page's own origin (or clients
+// that send none), so other sites cannot ride on the session cookie
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     sameOrigin,
+}
+
+// sameOrigin reports whether the Origin header of r, if any, names the host
+// r was sent to. Browsers always send it with a WebSocket handshake; other
+// clients may leave it out.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// CollabHandler serves the WebSocket channel that shows who is watching a
+// workspace and editing its todos, next to its live todo changes
+type CollabHandler struct {
+	service *service.CollabService
+}
+
+// NewCollabHandler creates a new instance of CollabHandler
+func NewCollabHandler(service *service.CollabService) *CollabHandler {
+	return &CollabHandler{
+		service: service,
+	}
+}
+
+// collabRequest is a message from a client. Type is one of subscribe,
+// heartbeat, editing and stop_editing.
+type collabRequest struct {
+	Type        string `json:"type"`
+	TodoID      int    `json:"todo_id"`
+	LastEventID string `json:"last_event_id"`
+	UserID      int    `json:"user_id"`
+	ListID      *int   `json:"list_id"`
+	Assignee    *int   `json:"assignee"`
+}
+
+// subscribeRequest is a subscribe handed from the reader to the writer
+type subscribeRequest struct {
+	filter      events.Filter
+	lastEventID string
+}
+
+// subscribedReply answers a subscribe with who is present and editing
+type subscribedReply struct {
+	Type     string            `json:"type"`
+	Resync   bool              `json:"resync"`
+	Presence []events.Presence `json:"presence"`
+	Locks    []events.Lock     `json:"locks"`
+}
+
+// lockReply answers editing and stop_editing
+type lockReply struct {
+	Type   string       `json:"type"`
+	TodoID int          `json:"todo_id"`
+	Lock   *events.Lock `json:"lock,omitempty"`
+}
+
+// errorReply reports a request that failed; the connection stays open
+type errorReply struct {
+	Type    string       `json:"type"`
+	Request string       `json:"request"`
+	TodoID  int          `json:"todo_id,omitempty"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Lock    *events.Lock `json:"lock,omitempty"` // the lock held by someone else
+}
+
+// Connect handles GET /ws and GET /workspaces/{wid}/ws, upgrading to a
+// WebSocket. Connecting makes the user present in the workspace until the
+// connection closes; see the README for the message protocol.
+func (h *CollabHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already answered with an HTTP error
+		return
+	}
+	defer ws.Close()
+
+	user := middleware.CurrentUser(r.Context())
+	// Browsers never see the status of a handshake, failures are reported
+	// as close codes 4000 + the HTTP status instead
+	if err := h.service.Join(user, workspaceID); err != nil {
+		code, status := collabErrorCode(err)
+		closeConn(ws, 4000+status, code)
+		return
+	}
+	defer h.service.Leave(user, workspaceID)
+
+	c := &collabConn{
+		ws:          ws,
+		service:     h.service,
+		user:        user,
+		workspaceID: workspaceID,
+		replies:     make(chan any, replyBuffer),
+		subscribe:   make(chan subscribeRequest),
+		done:        make(chan struct{}),
+	}
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		c.writeLoop()
+	}()
+	c.readLoop(writerDone)
+	close(c.done)
+	<-writerDone
+}
+
+// collabConn is one client connection. The handler goroutine reads requests
+// and a second one writes replies and events, so a slow client never holds up
+// anyone else: its replies and events queue per connection, and it is
+// disconnected when it stops reading altogether.
+type collabConn struct {
+	ws          *websocket.Conn
+	service     *service.CollabService
+	user        *models.User
+	workspaceID int
+
+	replies   chan any              // replies to requests, in order
+	subscribe chan subscribeRequest // handled by the writer, which owns the subscription
+	done      chan struct{}         // closed when the reader stops
+}
+
+// readLoop handles requests until the client disconnects, stays silent for
+// too long, or the writer stops
+func (c *collabConn) readLoop(writerDone <-chan struct{}) {
+	c.ws.SetReadLimit(maxClientMessage)
+	for {
+		c.ws.SetReadDeadline(time.Now().Add(presenceTimeout))
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req collabRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			if !c.reply(errorReply{Type: "error", Code: "invalid_message", Message: err.Error()}) {
+				return
+			}
+			continue
+		}
+
+		switch req.Type {
+		case "subscribe":
+			filter := events.Filter{WorkspaceID: c.workspaceID, UserID: req.UserID, ListID: req.ListID, AssigneeID: req.Assignee}
+			select {
+			case c.subscribe <- subscribeRequest{filter: filter, lastEventID: req.LastEventID}:
+			case <-writerDone:
+				return
+			}
+		case "heartbeat":
+			c.service.Heartbeat(c.user, c.workspaceID)
+		case "editing":
+			lock, err := c.service.StartEditing(c.user, c.workspaceID, req.TodoID)
+			if err != nil {
+				if !c.replyError(req, err, lock) {
+					return
+				}
+				continue
+			}
+			if !c.reply(lockReply{Type: "editing", TodoID: req.TodoID, Lock: lock}) {
+				return
+			}
+		case "stop_editing":
+			if err := c.service.StopEditing(c.user, c.workspaceID, req.TodoID); err != nil {
+				if !c.replyError(req, err, nil) {
+					return
+				}
+				continue
+			}
+			if !c.reply(lockReply{Type: "stopped_editing", TodoID: req.TodoID}) {
+				return
+			}
+		default:
+			if !c.reply(errorReply{Type: "error", Request: req.Type, Code: "unknown_type", Message: "unknown message type"}) {
+				return
+			}
+		}
+	}
+}
+
+// reply queues a message for the writer. It reports false when the client
+// has stopped reading, and the connection should end.
+func (c *collabConn) reply(msg any) bool {
+	select {
+	case c.replies <- msg:
+		return true
+	default:
+		closeConn(c.ws, websocket.ClosePolicyViolation, "too many unread replies")
+		return false
+	}
+}
+
+// replyError queues the error reply for a failed request
+func (c *collabConn) replyError(req collabRequest, err error, lock *events.Lock) bool {
+	code, _ := collabErrorCode(err)
+	reply := errorReply{Type: "error", Request: req.Type, TodoID: req.TodoID, Code: code, Message: err.Error()}
+	if errors.Is(err, collab.ErrLocked) {
+		reply.Lock = lock
+	}
+	return c.reply(reply)
+}
+
+// writeLoop sends replies, events and pings until the reader stops, a write
+// fails or the server shuts down
+func (c *collabConn) writeLoop() {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	var (
+		sub         *events.Subscription
+		filter      events.Filter
+		lastEventID string
+	)
+	defer func() {
+		if sub != nil {
+			sub.Cancel()
+		}
+		// Unblocks the reader when the writer stopped first
+		c.ws.Close()
+	}()
+
+	// subscribe replaces the current subscription, replaying what the client
+	// missed since lastEventID
+	subscribe := func(notify bool) bool {
+		if sub != nil {
+			sub.Cancel()
+			sub = nil
+		}
+		next, replay, resync, presence, locks, err := c.service.Subscribe(c.user, filter, lastEventID)
+		if err != nil {
+			if errors.Is(err, events.ErrClosed) {
+				closeConn(c.ws, websocket.CloseGoingAway, "server shutting down")
+				return false
+			}
+			code, _ := collabErrorCode(err)
+			return c.write(errorReply{Type: "error", Request: "subscribe", Code: code, Message: err.Error()})
+		}
+		sub = next
+
+		if notify || resync {
+			if !c.write(subscribedReply{Type: "subscribed", Resync: resync, Presence: presence, Locks: locks}) {
+				return false
+			}
+		}
+		for _, e := range replay {
+			if !c.write(e) {
+				return false
+			}
+			lastEventID = e.ID
+		}
+		return true
+	}
+
+	for {
+		var subC <-chan events.Event
+		if sub != nil {
+			subC = sub.C
+		}
+
+		select {
+		case <-c.done:
+			return
+		case <-c.service.Done():
+			closeConn(c.ws, websocket.CloseGoingAway, "server shutting down")
+			return
+		case msg := <-c.replies:
+			if !c.write(msg) {
+				return
+			}
+		case req := <-c.subscribe:
+			filter, lastEventID = req.filter, req.lastEventID
+			if !subscribe(true) {
+				return
+			}
+		case e, open := <-subC:
+			if !open {
+				// Dropped for falling behind: catch up from the replay buffer,
+				// or tell the client to resync when it no longer has the events
+				sub = nil
+				if !subscribe(false) {
+					return
+				}
+				continue
+			}
+			if !c.allowed() || !c.write(e) {
+				return
+			}
+			lastEventID = e.ID
+		case <-ping.C:
+			if !c.allowed() {
+				return
+			}
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// allowed checks the user may still view the workspace, as they may have
+// been removed from it since connecting. When they may not, the connection
+// is closed like a refused one.
+func (c *collabConn) allowed() bool {
+	if err := c.service.CheckAccess(c.user, c.workspaceID); err != nil {
+		code, status := collabErrorCode(err)
+		closeConn(c.ws, 4000+status, code)
+		return false
+	}
+	return true
+}
+
+// write sends one message, reporting false when the client is gone or too slow
+func (c *collabConn) write(msg any) bool {
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.ws.WriteJSON(msg) == nil
+}
+
+// closeConn starts the closing handshake with a close code and reason
+func closeConn(ws *websocket.Conn, code int, reason string) {
+	ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
+
+// collabErrorCode maps collaboration errors to the code sent to clients and
+// the matching HTTP status
+func collabErrorCode(err error) (string, int) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrTodoNotFound:
+		return "not_found", http.StatusNotFound
+	case service.ErrUnauthenticated:
+		return "unauthenticated", http.StatusUnauthorized
+	case service.ErrUnauthorized:
+		return "forbidden", http.StatusForbidden
+	case collab.ErrLocked:
+		return "locked", http.StatusConflict
+	case collab.ErrLockNotHeld:
+		return "not_locked", http.StatusConflict
+	default:
+		return "server_error", http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// CommentHandler handles HTTP requests for the comments on todos
+type CommentHandler struct {
+	service *service.CommentService
+}
+
+// NewCommentHandler creates a new instance of CommentHandler
+func NewCommentHandler(service *service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		service: service,
+	}
+}
+
+// GetComments handles GET /todos/{id}/comments and GET /workspaces/{wid}/todos/{id}/comments
+func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := h.service.GetComments(middleware.CurrentUser(r.Context()), workspaceID, todoID)
+	if err != nil {
+		writeCommentError(w, err, "Failed to retrieve comments")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, comments, nil, nil)
+}
+
+// CreateComment handles POST /todos/{id}/comments and POST /workspaces/{wid}/todos/{id}/comments
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CommentRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	comment, err := h.service.CreateComment(middleware.CurrentUser(r.Context()), workspaceID, todoID, req)
+	if err != nil {
+		writeCommentError(w, err, "Failed to create comment")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, comment, nil, nil)
+}
+
+// UpdateComment handles PUT /todos/{id}/comments/{cmid} and PUT /workspaces/{wid}/todos/{id}/comments/{cmid}
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	commentID, ok := commentIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CommentRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	comment, err := h.service.UpdateComment(middleware.CurrentUser(r.Context()), workspaceID, todoID, commentID, req)
+	if err != nil {
+		writeCommentError(w, err, "Failed to update comment")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, comment, nil, nil)
+}
+
+// DeleteComment handles DELETE /todos/{id}/comments/{cmid} and DELETE /workspaces/{wid}/todos/{id}/comments/{cmid}
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	todoID, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	commentID, ok := commentIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteComment(middleware.CurrentUser(r.Context()), workspaceID, todoID, commentID); err != nil {
+		writeCommentError(w, err, "Failed to delete comment")
+		return
+	}
+
+	msg := "Comment deleted successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// commentIDParam parses the {cmid} URL parameter, writing a 400 when it is not a number
+func commentIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["cmid"])
+	if err != nil {
+		msg := "Invalid comment ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeCommentError maps comment service errors to their HTTP responses
+func writeCommentError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrTodoNotFound, repository.ErrCommentNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidCommentBody, repository.ErrReplyNotFound:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// AddDependency handles POST /todos/{id}/dependencies and POST /workspaces/{wid}/todos/{id}/dependencies
+func (h *TodoHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.DependencyRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	todo, err := h.service.AddDependency(middleware.CurrentUser(r.Context()), workspaceID, id, req)
+	if err != nil {
+		writeDependencyError(w, err, "Failed to add dependency")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, todo, nil, nil)
+}
+
+// RemoveDependency handles DELETE /todos/{id}/dependencies/{bid} and DELETE /workspaces/{wid}/todos/{id}/dependencies/{bid}
+func (h *TodoHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	id, ok := todoIDParam(w, r)
+	if !ok {
+		return
+	}
+	blockerID, err := strconv.Atoi(mux.Vars(r)["bid"])
+	if err != nil {
+		msg := "Invalid blocker ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return
+	}
+
+	todo, err := h.service.RemoveDependency(middleware.CurrentUser(r.Context()), workspaceID, id, blockerID)
+	if err != nil {
+		writeDependencyError(w, err, "Failed to remove dependency")
+		return
+	}
+
+	msg := "Dependency removed successfully"
+	helpers.Success(w, helpers.Deleted, todo, &msg, nil)
+}
+
+// GetPlan handles GET /todos/plan and GET /workspaces/{wid}/todos/plan.
+// ?user_id= plans the todos of one owner only.
+func (h *TodoHandler) GetPlan(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	userID := 0
+	if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+		id, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			msg := "Invalid user_id parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		userID = id
+	}
+
+	plan, err := h.service.GetPlan(middleware.CurrentUser(r.Context()), workspaceID, userID)
+	if err != nil {
+		writeDependencyError(w, err, "Failed to plan todos")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, plan, nil, nil)
+}
+
+// writeDependencyError maps dependency errors of the todo service to their HTTP responses
+func writeDependencyError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrTodoNotFound, repository.ErrBlockerNotFound,
+		repository.ErrUserNotFound, service.ErrDependencyNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidBlockerID, service.ErrInvalidUserID:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	case repository.ErrDependencyCycle:
+		helpers.ErrorConflict(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
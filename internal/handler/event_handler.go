@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"test_mekari/internal/events"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+)
+
+// heartbeatInterval keeps idle streams from being closed by proxies
+const heartbeatInterval = 25 * time.Second
+
+// EventHandler streams live todo changes as Server-Sent Events
+type EventHandler struct {
+	service *service.EventService
+}
+
+// NewEventHandler creates a new instance of EventHandler
+func NewEventHandler(service *service.EventService) *EventHandler {
+	return &EventHandler{
+		service: service,
+	}
+}
+
+// Stream handles GET /events and GET /workspaces/{wid}/events. Optional
+// ?user_id=, ?list_id= and ?assignee= narrow the stream like they narrow GET /todos.
+// A client reconnecting with Last-Event-ID (or ?last_event_id=) first receives
+// what it missed, or a resync event when that is no longer available. The
+// stream ends once the user may no longer view the workspace.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	filter := events.Filter{WorkspaceID: workspaceID}
+	params := r.URL.Query()
+
+	if userIDStr := params.Get("user_id"); userIDStr != "" {
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
+			msg := "Invalid user_id parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		filter.UserID = userID
+	}
+	if listIDStr := params.Get("list_id"); listIDStr != "" {
+		listID, err := strconv.Atoi(listIDStr)
+		if err != nil {
+			msg := "Invalid list_id parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		filter.ListID = &listID
+	}
+	if assigneeStr := params.Get("assignee"); assigneeStr != "" {
+		assigneeID, err := strconv.Atoi(assigneeStr)
+		if err != nil {
+			msg := "Invalid assignee parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		filter.AssigneeID = &assigneeID
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		msg := "Streaming is not supported"
+		helpers.ErrorServer(w, "response writer cannot flush", &msg)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = params.Get("last_event_id")
+	}
+	user := middleware.CurrentUser(r.Context())
+	sub, replay, resync, err := h.service.Subscribe(user, filter, lastEventID)
+	if err != nil {
+		writeEventError(w, err, "Failed to subscribe to events")
+		return
+	}
+	defer sub.Cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Clients reconnect after 3s when the stream drops
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if resync {
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, e := range replay {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, open := <-sub.C:
+			if !open {
+				// Dropped for falling behind or shutting down; the client reconnects
+				return
+			}
+			// A user who can no longer view the workspace gets nothing more; their
+			// reconnect is refused
+			if h.service.CheckAccess(user, workspaceID) != nil {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			// Idle streams are checked too, so they do not outlive the access
+			if h.service.CheckAccess(user, workspaceID) != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes one event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// writeEventError maps event service errors to their HTTP responses
+func writeEventError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
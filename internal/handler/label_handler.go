@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// LabelHandler handles HTTP requests for the labels of a workspace
+type LabelHandler struct {
+	service *service.LabelService
+}
+
+// NewLabelHandler creates a new instance of LabelHandler
+func NewLabelHandler(service *service.LabelService) *LabelHandler {
+	return &LabelHandler{
+		service: service,
+	}
+}
+
+// GetLabels handles GET /labels and GET /workspaces/{wid}/labels
+func (h *LabelHandler) GetLabels(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	labels, err := h.service.GetLabels(middleware.CurrentUser(r.Context()), workspaceID)
+	if err != nil {
+		writeLabelError(w, err, "Failed to retrieve labels")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, labels, nil, nil)
+}
+
+// GetLabel handles GET /labels/{lbid} and GET /workspaces/{wid}/labels/{lbid}
+func (h *LabelHandler) GetLabel(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	labelID, ok := labelIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	label, err := h.service.GetLabel(middleware.CurrentUser(r.Context()), workspaceID, labelID)
+	if err != nil {
+		writeLabelError(w, err, "Failed to retrieve label")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, label, nil, nil)
+}
+
+// CreateLabel handles POST /labels and POST /workspaces/{wid}/labels
+func (h *LabelHandler) CreateLabel(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.LabelRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	label, err := h.service.CreateLabel(middleware.CurrentUser(r.Context()), workspaceID, req)
+	if err != nil {
+		writeLabelError(w, err, "Failed to create label")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, label, nil, nil)
+}
+
+// UpdateLabel handles PUT /labels/{lbid} and PUT /workspaces/{wid}/labels/{lbid}
+func (h *LabelHandler) UpdateLabel(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	labelID, ok := labelIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.LabelRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	label, err := h.service.UpdateLabel(middleware.CurrentUser(r.Context()), workspaceID, labelID, req)
+	if err != nil {
+		writeLabelError(w, err, "Failed to update label")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, label, nil, nil)
+}
+
+// DeleteLabel handles DELETE /labels/{lbid} and DELETE /workspaces/{wid}/labels/{lbid}
+func (h *LabelHandler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	labelID, ok := labelIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteLabel(middleware.CurrentUser(r.Context()), workspaceID, labelID); err != nil {
+		writeLabelError(w, err, "Failed to delete label")
+		return
+	}
+
+	msg := "Label deleted successfully, it was removed from its todos"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// labelIDParam parses the {lbid} URL parameter, writing a 400 when it is not a number
+func labelIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["lbid"])
+	if err != nil {
+		msg := "Invalid label ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeLabelError maps label service errors to their HTTP responses
+func writeLabelError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrLabelNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidLabelName, service.ErrInvalidLabelColor:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	case service.ErrLabelNameTaken:
+		helpers.ErrorConflict(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ListHandler handles HTTP requests for the todo lists of a workspace
+type ListHandler struct {
+	service *service.ListService
+}
+
+// NewListHandler creates a new instance of ListHandler
+func NewListHandler(service *service.ListService) *ListHandler {
+	return &ListHandler{
+		service: service,
+	}
+}
+
+// GetLists handles GET /lists and GET /workspaces/{wid}/lists
+func (h *ListHandler) GetLists(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	includeArchived, ok := boolQueryParam(w, r, "include_archived")
+	if !ok {
+		return
+	}
+
+	lists, err := h.service.GetLists(middleware.CurrentUser(r.Context()), workspaceID, includeArchived)
+	if err != nil {
+		writeListError(w, err, "Failed to retrieve lists")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, lists, nil, nil)
+}
+
+// GetList handles GET /lists/{lid} and GET /workspaces/{wid}/lists/{lid}
+func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	listID, ok := listIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.GetList(middleware.CurrentUser(r.Context()), workspaceID, listID)
+	if err != nil {
+		writeListError(w, err, "Failed to retrieve list")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, list, nil, nil)
+}
+
+// CreateList handles POST /lists and POST /workspaces/{wid}/lists
+func (h *ListHandler) CreateList(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ListRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	list, err := h.service.CreateList(middleware.CurrentUser(r.Context()), workspaceID, req)
+	if err != nil {
+		writeListError(w, err, "Failed to create list")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, list, nil, nil)
+}
+
+// RenameList handles PUT /lists/{lid} and PUT /workspaces/{wid}/lists/{lid}
+func (h *ListHandler) RenameList(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	listID, ok := listIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ListRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	list, err := h.service.RenameList(middleware.CurrentUser(r.Context()), workspaceID, listID, req)
+	if err != nil {
+		writeListError(w, err, "Failed to update list")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, list, nil, nil)
+}
+
+// ArchiveList handles POST /lists/{lid}/archive and POST /workspaces/{wid}/lists/{lid}/archive
+func (h *ListHandler) ArchiveList(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true, "List archived successfully")
+}
+
+// UnarchiveList handles POST /lists/{lid}/unarchive and POST /workspaces/{wid}/lists/{lid}/unarchive
+func (h *ListHandler) UnarchiveList(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false, "List unarchived successfully")
+}
+
+func (h *ListHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool, msg string) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	listID, ok := listIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.SetArchived(middleware.CurrentUser(r.Context()), workspaceID, listID, archived)
+	if err != nil {
+		writeListError(w, err, "Failed to update list")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, list, &msg, nil)
+}
+
+// DeleteList handles DELETE /lists/{lid} and DELETE /workspaces/{wid}/lists/{lid}
+func (h *ListHandler) DeleteList(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	listID, ok := listIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteList(middleware.CurrentUser(r.Context()), workspaceID, listID); err != nil {
+		writeListError(w, err, "Failed to delete list")
+		return
+	}
+
+	msg := "List deleted successfully, its todos were kept without a list"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// listIDParam parses the {lid} URL parameter, writing a 400 when it is not a number
+func listIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["lid"])
+	if err != nil {
+		msg := "Invalid list ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// boolQueryParam parses an optional boolean query parameter (false when absent),
+// writing a 400 when it is not a boolean
+func boolQueryParam(w http.ResponseWriter, r *http.Request, name string) (bool, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, true
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		msg := "Invalid " + name + " parameter"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return false, false
+	}
+	return value, true
+}
+
+// writeListError maps list service errors to their HTTP responses
+func writeListError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrListNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidListName:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// NotificationHandler handles HTTP requests for the notification inbox of the authenticated user
+type NotificationHandler struct {
+	service *service.NotificationService
+}
+
+// NewNotificationHandler creates a new instance of NotificationHandler
+func NewNotificationHandler(service *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		service: service,
+	}
+}
+
+// GetNotifications handles GET /me/notifications
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	unreadOnly, ok := boolQueryParam(w, r, "unread")
+	if !ok {
+		return
+	}
+
+	inbox, err := h.service.GetInbox(middleware.CurrentUser(r.Context()), unreadOnly)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to retrieve notifications")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, inbox, nil, nil)
+}
+
+// MarkRead handles POST /me/notifications/{nid}/read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["nid"])
+	if err != nil {
+		msg := "Invalid notification ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return
+	}
+
+	notification, err := h.service.MarkRead(middleware.CurrentUser(r.Context()), id)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to mark notification as read")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, notification, nil, nil)
+}
+
+// MarkAllRead handles POST /me/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	marked, err := h.service.MarkAllRead(middleware.CurrentUser(r.Context()))
+	if err != nil {
+		writeNotificationError(w, err, "Failed to mark notifications as read")
+		return
+	}
+
+	msg := "All notifications marked as read"
+	helpers.Success(w, helpers.Updated, map[string]int{"marked": marked, "unread_count": 0}, &msg, nil)
+}
+
+// writeNotificationError maps notification service errors to their HTTP responses
+func writeNotificationError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrNotificationNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/models"
+	"test_mekari/internal/service"
+)
+
+// Page size of GET /todos, see pageParams
+const (
+	defaultTodoLimit = 50
+	maxTodoLimit     = 200
+)
+
+// todoFields holds the JSON names of the fields of a todo, for ?fields=
+var todoFields = jsonFields(reflect.TypeOf(models.Todo{}))
+
+// todoPageParams parses ?sort=, ?limit= and ?cursor= into query, writing a 400
+// when one of them is invalid
+func todoPageParams(w http.ResponseWriter, r *http.Request, query *service.TodoQuery) bool {
+	var err error
+	if query.Sort, query.Desc, err = service.ParseTodoSort(r.URL.Query().Get("sort")); err != nil {
+		msg := "Invalid sort parameter"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return false
+	}
+	return pageParams(w, r, query)
+}
+
+// pageParams parses ?limit= and ?cursor= into query, writing a 400 for an
+// invalid limit. A cursor without a limit continues with pages of
+// defaultTodoLimit; without either every todo is returned.
+func pageParams(w http.ResponseWriter, r *http.Request, query *service.TodoQuery) bool {
+	params := r.URL.Query()
+
+	var err error
+	query.Cursor = params.Get("cursor")
+	if query.Cursor != "" {
+		query.Limit = defaultTodoLimit
+	}
+	if limitStr := params.Get("limit"); limitStr != "" {
+		query.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || query.Limit < 1 || query.Limit > maxTodoLimit {
+			msg := "Invalid limit parameter, expected a number from 1 to 200"
+			helpers.ErrorBadRequest(w, "limit must be between 1 and 200", &msg)
+			return false
+		}
+	}
+	return true
+}
+
+// fieldsParam parses ?fields=, the sparse fieldset of the todos in a response,
+// writing a 400 for an unknown field. The id is always included, nil means
+// every field.
+func fieldsParam(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	names := listQueryParam(r, "fields")
+	if len(names) == 0 {
+		return nil, true
+	}
+
+	fields := []string{"id"}
+	for _, name := range names {
+		if !todoFields[name] {
+			msg := "Invalid fields parameter, unknown field " + strconv.Quote(name)
+			helpers.ErrorBadRequest(w, "fields must be a comma separated list of todo fields", &msg)
+			return nil, false
+		}
+		if name != "id" {
+			fields = append(fields, name)
+		}
+	}
+	return fields, true
+}
+
+// selectFields returns todos with only the given fields, or todos themselves
+// when fields is nil
+func selectFields(todos []models.Todo, fields []string) (interface{}, error) {
+	if fields == nil {
+		return todos, nil
+	}
+
+	selected := make([]map[string]json.RawMessage, 0, len(todos))
+	for _, todo := range todos {
+		data, err := json.Marshal(todo)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		some := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			some[field] = all[field]
+		}
+		selected = append(selected, some)
+	}
+	return selected, nil
+}
+
+// setLinkHeader sets the RFC 8288 Link header of a page: the first page, and
+// the next one unless this is the last
+func setLinkHeader(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	link := func(cursor, rel string) string {
+		u := *r.URL
+		query := u.Query()
+		query.Del("cursor")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		u.RawQuery = query.Encode()
+		return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+	}
+
+	links := []string{link("", "first")}
+	if nextCursor != "" {
+		links = append(links, link(nextCursor, "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// jsonFields returns the JSON names of the exported fields of a struct type
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && t.Field(i).IsExported() {
+			fields[name] = true
+		}
+	}
+	return fields
+}
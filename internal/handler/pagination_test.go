@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"test_mekari/internal/service"
+)
+
+func TestPageParams(t *testing.T) {
+	for _, row := range []struct {
+		query  string
+		ok     bool
+		limit  int
+		cursor string
+	}{
+		{"", true, 0, ""},
+		{"?cursor=abc", true, defaultTodoLimit, "abc"},
+		{"?cursor=abc&limit=10", true, 10, "abc"},
+		{"?limit=10", true, 10, ""},
+		{"?limit=200", true, 200, ""},
+		{"?limit=201", false, 0, ""},
+		{"?limit=0", false, 0, ""},
+		{"?limit=ten", false, 0, ""},
+		{"?cursor=abc&limit=-1", false, 0, ""},
+	} {
+		w := httptest.NewRecorder()
+		var query service.TodoQuery
+		ok := pageParams(w, httptest.NewRequest(http.MethodGet, "/todos"+row.query, nil), &query)
+		if ok != row.ok {
+			t.Errorf("%q: ok = %v, want %v", row.query, ok, row.ok)
+			continue
+		}
+		if !ok {
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%q: status %d, want 400", row.query, w.Code)
+			}
+			continue
+		}
+		if query.Limit != row.limit || query.Cursor != row.cursor {
+			t.Errorf("%q: limit %d and cursor %q, want %d and %q", row.query, query.Limit, query.Cursor, row.limit, row.cursor)
+		}
+	}
+}
+
+func TestSetLinkHeader(t *testing.T) {
+	for _, row := range []struct {
+		name       string
+		target     string
+		nextCursor string
+		want       string
+	}{
+		{"last page", "/todos?limit=2&sort=due",
+			"", `</todos?limit=2&sort=due>; rel="first"`},
+		{"first page", "/todos?limit=2&sort=due",
+			"next-1", `</todos?limit=2&sort=due>; rel="first", </todos?cursor=next-1&limit=2&sort=due>; rel="next"`},
+		{"later page", "/workspaces/3/todos?cursor=next-1&limit=2",
+			"next-2", `</workspaces/3/todos?limit=2>; rel="first", </workspaces/3/todos?cursor=next-2&limit=2>; rel="next"`},
+		{"escaped values", "/todos?filter=text:%22a+b%22&cursor=x",
+			"a_b-c", `</todos?filter=text%3A%22a+b%22>; rel="first", </todos?cursor=a_b-c&filter=text%3A%22a+b%22>; rel="next"`},
+	} {
+		w := httptest.NewRecorder()
+		setLinkHeader(w, httptest.NewRequest(http.MethodGet, row.target, nil), row.nextCursor)
+		if got := w.Header().Get("Link"); got != row.want {
+			t.Errorf("%s: Link = %s, want %s", row.name, got, row.want)
+		}
+	}
+}
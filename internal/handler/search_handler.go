@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+)
+
+// Number of hits of GET /search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchHandler handles HTTP requests for full-text search
+type SearchHandler struct {
+	service *service.SearchService
+}
+
+// NewSearchHandler creates a new instance of SearchHandler
+func NewSearchHandler(service *service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		service: service,
+	}
+}
+
+// Search handles GET /search?q= and GET /workspaces/{wid}/search?q=, with an optional ?limit=
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			msg := "Invalid limit parameter, expected a number from 1 to 100"
+			helpers.ErrorBadRequest(w, "limit must be between 1 and 100", &msg)
+			return
+		}
+	}
+
+	hits, err := h.service.Search(middleware.CurrentUser(r.Context()), workspaceID, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		writeSearchError(w, err)
+		return
+	}
+
+	helpers.Success(w, helpers.Searched, hits, nil, nil)
+}
+
+// writeSearchError maps search service errors to their HTTP responses
+func writeSearchError(w http.ResponseWriter, err error) {
+	switch err {
+	case repository.ErrWorkspaceNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrEmptyQuery:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		msg := "Failed to search"
+		helpers.ErrorServer(w, err.Error(), &msg)
+	}
+}
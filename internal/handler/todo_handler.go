@@ -2,12 +2,19 @@ package handler
 
 import (
 	"test_mekari/internal/dto"
+	"test_mekari/internal/expr"
 	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/models"
+	"test_mekari/internal/recurrence"
 	"test_mekari/internal/repository"
 	"test_mekari/internal/service"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -24,56 +31,166 @@ func NewTodoHandler(service *service.TodoService) *TodoHandler {
 	}
 }
 
-// GetTodos handles GET /todos
+// GetTodos handles GET /todos and GET /workspaces/{wid}/todos.
+// Optional filters: ?user_id= (created by), ?assignee= (assigned to, 0 for unassigned todos),
+// ?list_id= (0 for todos in no list), ?parent_id= (0 for
+// top-level todos), ?include_archived=true, ?overdue=true, ?due_before= / ?due_after= (RFC 3339), ?priority=high,urgent and
+// ?label=bug,ui with ?label_match=any (default) or all, and ?filter= with an expression such as
+// completed:false AND (assignee:2 OR label:urgent) AND created_at>2026-01-01 (see package expr).
+// Pages: ?sort=created_at|updated_at|due|priority|text[:asc|:desc], ?limit= (default 50, at most 200)
+// and ?cursor= from meta.next_cursor or the Link header; ?fields=text,completed selects fields.
 func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
-	// Check for user_id query parameter
-	userIDStr := r.URL.Query().Get("user_id")
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	params := r.URL.Query()
 
-	var todos interface{}
-	var err error
+	var query service.TodoQuery
 
-	if userIDStr != "" {
-		// Filter by user ID
-		userID, parseErr := strconv.Atoi(userIDStr)
-		if parseErr != nil {
+	// Check for user_id query parameter
+	if userIDStr := params.Get("user_id"); userIDStr != "" {
+		userID, err := strconv.Atoi(userIDStr)
+		if err != nil {
 			msg := "Invalid user_id parameter"
-			helpers.ErrorBadRequest(w, parseErr.Error(), &msg)
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
 			return
 		}
+		query.UserID = userID
+	}
 
-		todos, err = h.service.GetTodosByUser(userID)
+	// Check for assignee query parameter
+	if assigneeStr := params.Get("assignee"); assigneeStr != "" {
+		assigneeID, err := strconv.Atoi(assigneeStr)
 		if err != nil {
-			msg := "Failed to retrieve todos"
-			helpers.ErrorServer(w, err.Error(), &msg)
+			msg := "Invalid assignee parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
 			return
 		}
-	} else {
-		// Get all todos
-		todos, err = h.service.GetAllTodos()
+		query.AssigneeID = &assigneeID
+	}
+
+	// Check for list_id query parameter
+	if listIDStr := params.Get("list_id"); listIDStr != "" {
+		listID, err := strconv.Atoi(listIDStr)
 		if err != nil {
-			msg := "Failed to retrieve todos"
-			helpers.ErrorServer(w, err.Error(), &msg)
+			msg := "Invalid list_id parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
 			return
 		}
+		query.ListID = &listID
 	}
 
-	helpers.Success(w, helpers.Get, todos, nil, nil)
-}
+	// Check for parent_id query parameter
+	if parentIDStr := params.Get("parent_id"); parentIDStr != "" {
+		parentID, err := strconv.Atoi(parentIDStr)
+		if err != nil {
+			msg := "Invalid parent_id parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		query.ParentID = &parentID
+	}
 
-// GetUsers handles GET /users
-func (h *TodoHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.service.GetAllUsers()
+	query.IncludeArchived, ok = boolQueryParam(w, r, "include_archived")
+	if !ok {
+		return
+	}
+
+	// Check for due date query parameters
+	if query.DueBefore, ok = timeQueryParam(w, r, "due_before"); !ok {
+		return
+	}
+	if query.DueAfter, ok = timeQueryParam(w, r, "due_after"); !ok {
+		return
+	}
+	overdue, ok := boolQueryParam(w, r, "overdue")
+	if !ok {
+		return
+	}
+	if overdue {
+		now := time.Now()
+		query.OverdueAt = &now
+	}
+
+	// Check for priority and label query parameters, both take comma separated values
+	for _, raw := range listQueryParam(r, "priority") {
+		priority := models.Priority(strings.ToLower(raw))
+		if !priority.Valid() {
+			msg := "Invalid priority parameter"
+			helpers.ErrorBadRequest(w, service.ErrInvalidPriority.Error(), &msg)
+			return
+		}
+		query.Priorities = append(query.Priorities, priority)
+	}
+	query.Labels = listQueryParam(r, "label")
+	switch params.Get("label_match") {
+	case "", "any":
+	case "all":
+		query.AllLabels = true
+	default:
+		msg := "Invalid label_match parameter, expected any or all"
+		helpers.ErrorBadRequest(w, "label_match must be any or all", &msg)
+		return
+	}
+
+	// The filter expression is parsed by the service, it resolves label names
+	query.Filter = params.Get("filter")
+
+	// Check for sort, limit, cursor and fields query parameters
+	if !todoPageParams(w, r, &query) {
+		return
+	}
+	fields, ok := fieldsParam(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.GetTodos(middleware.CurrentUser(r.Context()), workspaceID, query)
 	if err != nil {
-		msg := "Failed to retrieve users"
+		if err == repository.ErrWorkspaceNotFound || err == repository.ErrUserNotFound || err == repository.ErrListNotFound || err == repository.ErrLabelNotFound {
+			helpers.ErrorNotFound(w, err.Error(), nil)
+			return
+		}
+		var filterErr *expr.Error
+		if errors.As(err, &filterErr) {
+			msg := "Invalid filter parameter"
+			helpers.ErrorValidator(w, filterErr, &msg)
+			return
+		}
+		if err == service.ErrInvalidSort || err == service.ErrInvalidCursor {
+			msg := "Invalid sort or cursor parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		msg := "Failed to retrieve todos"
 		helpers.ErrorServer(w, err.Error(), &msg)
 		return
 	}
 
-	helpers.Success(w, helpers.Get, users, nil, nil)
+	data, err := selectFields(page.Todos, fields)
+	if err != nil {
+		msg := "Failed to retrieve todos"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+	setLinkHeader(w, r, page.NextCursor)
+	meta := helpers.Pagination{
+		Limit:      query.Limit,
+		Count:      len(page.Todos),
+		HasMore:    page.NextCursor != "",
+		NextCursor: page.NextCursor,
+	}
+	helpers.SuccessWithMeta(w, helpers.Get, data, meta, nil, nil)
 }
 
-// CreateTodo handles POST /todos
+// CreateTodo handles POST /todos and POST /workspaces/{wid}/todos
 func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
 	var req dto.CreateTodoRequest
 
 	// Decode request body
@@ -86,15 +203,26 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Create todo through service
-	todo, err := h.service.CreateTodo(req)
+	// Create todo through service, owned by the authenticated user
+	todo, err := h.service.CreateTodo(middleware.CurrentUser(r.Context()), workspaceID, req)
 	if err != nil {
-		// Check for specific error types
-		if err == service.ErrUserNotFound {
+		if err == repository.ErrWorkspaceNotFound || err == repository.ErrListNotFound || err == repository.ErrLabelNotFound ||
+			err == repository.ErrParentNotFound {
 			helpers.ErrorNotFound(w, err.Error(), nil)
 			return
 		}
-		if err == service.ErrInvalidTodoText || err == service.ErrInvalidUserID {
+		// Check for specific error types
+		if err == service.ErrUnauthenticated {
+			helpers.ErrorAuthentication(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrUnauthorized {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrInvalidTodoText || err == service.ErrListArchived || err == service.ErrReminderAfterDue ||
+			err == service.ErrRecurrenceNeedsDue || err == service.ErrInvalidPriority ||
+			err == service.ErrSubtaskTooDeep || errors.Is(err, recurrence.ErrInvalidRule) {
 			helpers.ErrorValidator(w, err.Error(), nil)
 			return
 		}
@@ -106,8 +234,14 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	helpers.Success(w, helpers.Created, todo, nil, nil)
 }
 
-// DeleteTodo handles DELETE /todos/{id}
+// DeleteTodo handles DELETE /todos/{id} and DELETE /workspaces/{wid}/todos/{id}.
+// ?on_subtasks=block|promote|cascade says what happens to the todo's subtasks (default block).
 func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
 	// Get ID from URL parameters
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -120,11 +254,23 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete todo through service
-	if err := h.service.DeleteTodo(id); err != nil {
-		if err == repository.ErrTodoNotFound {
+	if err := h.service.DeleteTodo(middleware.CurrentUser(r.Context()), workspaceID, id, r.URL.Query().Get("on_subtasks")); err != nil {
+		if err == repository.ErrTodoNotFound || err == repository.ErrWorkspaceNotFound {
 			helpers.ErrorNotFound(w, err.Error(), nil)
 			return
 		}
+		if err == service.ErrUnauthorized {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrTodoHasSubtasks {
+			helpers.ErrorConflict(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrInvalidSubtaskDisposition {
+			helpers.ErrorValidator(w, err.Error(), nil)
+			return
+		}
 		msg := "Failed to delete todo"
 		helpers.ErrorServer(w, err.Error(), &msg)
 		return
@@ -134,8 +280,15 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
 }
 
-// ToggleTodo handles PATCH /todos/{id}/toggle
+// ToggleTodo handles PATCH /todos/{id}/toggle and PATCH /workspaces/{wid}/todos/{id}/toggle.
+// ?complete_subtasks=true also completes the open subtasks when the todo gets completed,
+// ?force=true completes it even while todos it depends on are open.
 func (h *TodoHandler) ToggleTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
 	// Get ID from URL parameters
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -147,13 +300,32 @@ func (h *TodoHandler) ToggleTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	completeSubtasks, ok := boolQueryParam(w, r, "complete_subtasks")
+	if !ok {
+		return
+	}
+
+	force, ok := boolQueryParam(w, r, "force")
+	if !ok {
+		return
+	}
+
 	// Toggle todo through service
-	todo, err := h.service.ToggleTodo(id)
+	opts := service.ToggleOptions{CompleteSubtasks: completeSubtasks, Force: force}
+	todo, err := h.service.ToggleTodo(middleware.CurrentUser(r.Context()), workspaceID, id, opts)
 	if err != nil {
-		if err == repository.ErrTodoNotFound {
+		if err == repository.ErrTodoNotFound || err == repository.ErrWorkspaceNotFound {
 			helpers.ErrorNotFound(w, err.Error(), nil)
 			return
 		}
+		if err == service.ErrUnauthorized {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrTodoBlocked {
+			helpers.ErrorConflict(w, err.Error(), nil)
+			return
+		}
 		msg := "Failed to toggle todo"
 		helpers.ErrorServer(w, err.Error(), &msg)
 		return
@@ -163,8 +335,13 @@ func (h *TodoHandler) ToggleTodo(w http.ResponseWriter, r *http.Request) {
 	helpers.Success(w, helpers.Updated, todo, &msg, nil)
 }
 
-// UpdateTodo handles PUT /todos/{id}
+// UpdateTodo handles PUT /todos/{id} and PUT /workspaces/{wid}/todos/{id}
 func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
 	// Get ID from URL parameters
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -189,16 +366,27 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// Update todo through service
-	todo, err := h.service.UpdateTodo(id, req)
+	todo, err := h.service.UpdateTodo(middleware.CurrentUser(r.Context()), workspaceID, id, req)
 	if err != nil {
-		if err == repository.ErrTodoNotFound {
+		if err == repository.ErrTodoNotFound || err == repository.ErrWorkspaceNotFound || err == repository.ErrListNotFound ||
+			err == repository.ErrLabelNotFound || err == repository.ErrParentNotFound {
 			helpers.ErrorNotFound(w, err.Error(), nil)
 			return
 		}
-		if err == service.ErrInvalidTodoText || err == service.ErrInvalidUserID {
+		if err == service.ErrUnauthorized {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrInvalidTodoText || err == service.ErrListArchived || err == service.ErrReminderAfterDue ||
+			err == service.ErrRecurrenceNeedsDue || err == service.ErrInvalidPriority ||
+			err == service.ErrSubtaskTooDeep || errors.Is(err, recurrence.ErrInvalidRule) {
 			helpers.ErrorValidator(w, err.Error(), nil)
 			return
 		}
+		if err == service.ErrTodoBlocked || err == repository.ErrSubtaskCycle {
+			helpers.ErrorConflict(w, err.Error(), nil)
+			return
+		}
 		msg := "Failed to update todo"
 		helpers.ErrorServer(w, err.Error(), &msg)
 		return
@@ -206,3 +394,188 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 
 	helpers.Success(w, helpers.Updated, todo, nil, nil)
 }
+
+// MoveTodo handles PATCH /todos/{id}/move and PATCH /workspaces/{wid}/todos/{id}/move
+func (h *TodoHandler) MoveTodo(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Get ID from URL parameters
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		msg := "Invalid todo ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return
+	}
+
+	var req dto.MoveTodoRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	todo, err := h.service.MoveTodo(middleware.CurrentUser(r.Context()), workspaceID, id, req)
+	if err != nil {
+		if err == repository.ErrTodoNotFound || err == repository.ErrWorkspaceNotFound || err == repository.ErrListNotFound {
+			helpers.ErrorNotFound(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrUnauthorized {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrListArchived {
+			helpers.ErrorValidator(w, err.Error(), nil)
+			return
+		}
+		msg := "Failed to move todo"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+
+	msg := "Todo moved successfully"
+	helpers.Success(w, helpers.Updated, todo, &msg, nil)
+}
+
+// StopRecurrence handles DELETE /todos/{id}/recurrence and DELETE /workspaces/{wid}/todos/{id}/recurrence
+func (h *TodoHandler) StopRecurrence(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Get ID from URL parameters
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		msg := "Invalid todo ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return
+	}
+
+	todo, err := h.service.StopRecurrence(middleware.CurrentUser(r.Context()), workspaceID, id)
+	if err != nil {
+		if err == repository.ErrTodoNotFound || err == repository.ErrWorkspaceNotFound {
+			helpers.ErrorNotFound(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrUnauthorized {
+			helpers.ErrorForbidden(w, err.Error(), nil)
+			return
+		}
+		msg := "Failed to stop recurrence"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+
+	msg := "Recurrence stopped, no further occurrences will be created"
+	helpers.Success(w, helpers.Updated, todo, &msg, nil)
+}
+
+// GetOccurrences handles GET /todos/{id}/occurrences and GET /workspaces/{wid}/todos/{id}/occurrences.
+// ?count= sets how many upcoming occurrences to preview (default 5, at most 100).
+func (h *TodoHandler) GetOccurrences(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	// Get ID from URL parameters
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		msg := "Invalid todo ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return
+	}
+
+	count := 5
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 || count > 100 {
+			msg := "Invalid count parameter, expected a number from 1 to 100"
+			helpers.ErrorBadRequest(w, "count must be between 1 and 100", &msg)
+			return
+		}
+	}
+
+	occurrences, err := h.service.PreviewOccurrences(middleware.CurrentUser(r.Context()), workspaceID, id, count)
+	if err != nil {
+		if err == repository.ErrTodoNotFound || err == repository.ErrWorkspaceNotFound {
+			helpers.ErrorNotFound(w, err.Error(), nil)
+			return
+		}
+		if err == service.ErrNotRecurring {
+			helpers.ErrorValidator(w, err.Error(), nil)
+			return
+		}
+		msg := "Failed to preview occurrences"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+
+	helpers.Success(w, helpers.Get, occurrences, nil, nil)
+}
+
+// timeQueryParam parses an optional RFC 3339 query parameter (nil when absent),
+// writing a 400 when it is not a timestamp
+func timeQueryParam(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, true
+	}
+
+	// An unescaped "+07:00" offset arrives as " 07:00"
+	t, err := time.Parse(time.RFC3339, strings.Replace(raw, " ", "+", 1))
+	if err != nil {
+		msg := "Invalid " + name + " parameter, expected an RFC 3339 timestamp"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return nil, false
+	}
+	return &t, true
+}
+
+// listQueryParam returns the values of a query parameter that may be repeated
+// and/or hold comma separated values, skipping empty ones
+func listQueryParam(r *http.Request, name string) []string {
+	var values []string
+	for _, raw := range r.URL.Query()[name] {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// workspaceIDParam returns the {wid} URL parameter, or the default workspace
+// for the legacy /todos routes, writing a 400 when it is not a number
+func workspaceIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw, scoped := mux.Vars(r)["wid"]
+	if !scoped {
+		return repository.DefaultWorkspaceID, true
+	}
+
+	workspaceID, err := strconv.Atoi(raw)
+	if err != nil {
+		msg := "Invalid workspace ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return workspaceID, true
+}
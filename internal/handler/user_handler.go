@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// UserHandler handles HTTP requests for user accounts
+type UserHandler struct {
+	service *service.UserService
+}
+
+// NewUserHandler creates a new instance of UserHandler
+func NewUserHandler(service *service.UserService) *UserHandler {
+	return &UserHandler{
+		service: service,
+	}
+}
+
+// GetUsers handles GET /users
+func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.service.GetAllUsers()
+	if err != nil {
+		msg := "Failed to retrieve users"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+
+	helpers.Success(w, helpers.Get, users, nil, nil)
+}
+
+// GetUser handles GET /users/{id}
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetUserByID(id)
+	if err != nil {
+		writeUserError(w, err, "Failed to retrieve user")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, user, nil, nil)
+}
+
+// CreateUser handles POST /users
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateUserRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.service.CreateUser(middleware.CurrentUser(r.Context()), req)
+	if err != nil {
+		writeUserError(w, err, "Failed to create user")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, user, nil, nil)
+}
+
+// UpdateUser handles PUT /users/{id}
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateUserRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.service.UpdateUser(middleware.CurrentUser(r.Context()), id, req)
+	if err != nil {
+		writeUserError(w, err, "Failed to update user")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, user, nil, nil)
+}
+
+// DeactivateUser handles POST /users/{id}/deactivate
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setDeactivated(w, r, true, "User deactivated successfully")
+}
+
+// ActivateUser handles POST /users/{id}/activate
+func (h *UserHandler) ActivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setDeactivated(w, r, false, "User activated successfully")
+}
+
+func (h *UserHandler) setDeactivated(w http.ResponseWriter, r *http.Request, deactivated bool, successMsg string) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.SetDeactivated(middleware.CurrentUser(r.Context()), id, deactivated)
+	if err != nil {
+		writeUserError(w, err, "Failed to update user")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, user, &successMsg, nil)
+}
+
+// DeleteUser handles DELETE /users/{id}?on_todos=block|reassign|cascade&reassign_to={id}
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	reassignTo := 0
+	if raw := query.Get("reassign_to"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			msg := "Invalid reassign_to parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+			return
+		}
+		reassignTo = parsed
+	}
+
+	err := h.service.DeleteUser(middleware.CurrentUser(r.Context()), id, query.Get("on_todos"), reassignTo)
+	if err != nil {
+		writeUserError(w, err, "Failed to delete user")
+		return
+	}
+
+	msg := "User deleted successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// userIDParam parses the {id} URL parameter, writing a 400 when it is not a number
+func userIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		msg := "Invalid user ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeUserError maps user service errors to their HTTP responses
+func writeUserError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrUserNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized, service.ErrCannotModifySelf:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrEmailTaken, service.ErrUserHasTodos:
+		helpers.ErrorConflict(w, err.Error(), nil)
+	case service.ErrInvalidUserID, service.ErrInvalidName, service.ErrInvalidEmail, service.ErrWeakPassword,
+		service.ErrInvalidRole, service.ErrInvalidDisposition, service.ErrInvalidReassignUser:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
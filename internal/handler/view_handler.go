@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/expr"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// ViewHandler handles HTTP requests for saved views
+type ViewHandler struct {
+	service *service.ViewService
+}
+
+// NewViewHandler creates a new instance of ViewHandler
+func NewViewHandler(service *service.ViewService) *ViewHandler {
+	return &ViewHandler{
+		service: service,
+	}
+}
+
+// viewMeta is the meta of GET /views/{vid}/todos: the page, the view and
+// the groups of the todos of the page
+type viewMeta struct {
+	helpers.Pagination
+	View   *models.View        `json:"view"`
+	Groups []service.TodoGroup `json:"groups,omitempty"`
+}
+
+// GetViews handles GET /me/views (optional: ?workspace_id=)
+func (h *ViewHandler) GetViews(w http.ResponseWriter, r *http.Request) {
+	workspaceID := 0
+	if raw := r.URL.Query().Get("workspace_id"); raw != "" {
+		var err error
+		if workspaceID, err = strconv.Atoi(raw); err != nil || workspaceID < 1 {
+			msg := "Invalid workspace_id parameter"
+			helpers.ErrorBadRequest(w, "workspace_id must be a workspace ID", &msg)
+			return
+		}
+	}
+
+	views, err := h.service.GetViews(middleware.CurrentUser(r.Context()), workspaceID)
+	if err != nil {
+		writeViewError(w, err, "Failed to retrieve views")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, views, nil, nil)
+}
+
+// GetView handles GET /views/{vid}
+func (h *ViewHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	id, ok := viewIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	view, err := h.service.GetView(middleware.CurrentUser(r.Context()), id)
+	if err != nil {
+		writeViewError(w, err, "Failed to retrieve view")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, view, nil, nil)
+}
+
+// CreateView handles POST /me/views
+func (h *ViewHandler) CreateView(w http.ResponseWriter, r *http.Request) {
+	var req dto.ViewRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	view, err := h.service.CreateView(middleware.CurrentUser(r.Context()), req)
+	if err != nil {
+		writeViewError(w, err, "Failed to create view")
+		return
+	}
+
+	msg := "View saved successfully"
+	helpers.Success(w, helpers.Created, view, &msg, nil)
+}
+
+// UpdateView handles PUT /views/{vid}
+func (h *ViewHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	id, ok := viewIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ViewRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	view, err := h.service.UpdateView(middleware.CurrentUser(r.Context()), id, req)
+	if err != nil {
+		writeViewError(w, err, "Failed to update view")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, view, nil, nil)
+}
+
+// DeleteView handles DELETE /views/{vid}
+func (h *ViewHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	id, ok := viewIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteView(middleware.CurrentUser(r.Context()), id); err != nil {
+		writeViewError(w, err, "Failed to delete view")
+		return
+	}
+
+	helpers.Success(w, helpers.Deleted, nil, nil, nil)
+}
+
+// GetViewTodos handles GET /views/{vid}/todos: the todos of the view, a page at
+// a time (optional: ?limit=, ?cursor=, ?fields=)
+func (h *ViewHandler) GetViewTodos(w http.ResponseWriter, r *http.Request) {
+	id, ok := viewIDParam(w, r)
+	if !ok {
+		return
+	}
+	var query service.TodoQuery
+	if !pageParams(w, r, &query) {
+		return
+	}
+	fields, ok := fieldsParam(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.GetViewTodos(middleware.CurrentUser(r.Context()), id, query)
+	if err != nil {
+		var filterErr *expr.Error
+		switch {
+		case errors.As(err, &filterErr):
+			// The filter was valid when saved, a label it names is gone since
+			msg := "The filter of the view is no longer valid, update the view"
+			helpers.ErrorValidator(w, filterErr, &msg)
+		case err == service.ErrInvalidCursor:
+			msg := "Invalid cursor parameter"
+			helpers.ErrorBadRequest(w, err.Error(), &msg)
+		default:
+			writeViewError(w, err, "Failed to retrieve the todos of the view")
+		}
+		return
+	}
+
+	data, err := selectFields(page.Todos, fields)
+	if err != nil {
+		msg := "Failed to retrieve the todos of the view"
+		helpers.ErrorServer(w, err.Error(), &msg)
+		return
+	}
+	setLinkHeader(w, r, page.NextCursor)
+	meta := viewMeta{
+		Pagination: helpers.Pagination{
+			Limit:      query.Limit,
+			Count:      len(page.Todos),
+			HasMore:    page.NextCursor != "",
+			NextCursor: page.NextCursor,
+		},
+		View:   page.View,
+		Groups: page.Groups,
+	}
+	helpers.SuccessWithMeta(w, helpers.Get, data, meta, nil, nil)
+}
+
+// viewIDParam parses the {vid} URL parameter, writing a 400 when it is not a number
+func viewIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["vid"])
+	if err != nil {
+		msg := "Invalid view ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeViewError maps view service errors to their HTTP responses
+func writeViewError(w http.ResponseWriter, err error, failureMsg string) {
+	var filterErr *expr.Error
+	if errors.As(err, &filterErr) {
+		msg := "Invalid filter"
+		helpers.ErrorValidator(w, filterErr, &msg)
+		return
+	}
+
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrViewNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidViewName, service.ErrInvalidSort, service.ErrInvalidGroupBy:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+	"test_mekari/internal/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+// Page size of GET /webhooks/{whid}/deliveries
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// WebhookHandler handles HTTP requests for the webhooks of a workspace
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+// NewWebhookHandler creates a new instance of WebhookHandler
+func NewWebhookHandler(service *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+// GetWebhooks handles GET /webhooks and GET /workspaces/{wid}/webhooks
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.service.GetWebhooks(middleware.CurrentUser(r.Context()), workspaceID)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to retrieve webhooks")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, webhooks, nil, nil)
+}
+
+// GetWebhook handles GET /webhooks/{whid} and GET /workspaces/{wid}/webhooks/{whid}
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(middleware.CurrentUser(r.Context()), workspaceID, webhookID)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to retrieve webhook")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, webhook, nil, nil)
+}
+
+// CreateWebhook handles POST /webhooks and POST /workspaces/{wid}/webhooks
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.WebhookRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	webhook, err := h.service.CreateWebhook(middleware.CurrentUser(r.Context()), workspaceID, req)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to create webhook")
+		return
+	}
+
+	msg := "Webhook created successfully, store its secret now: it is not shown again"
+	helpers.Success(w, helpers.Created, webhook, &msg, nil)
+}
+
+// UpdateWebhook handles PUT /webhooks/{whid} and PUT /workspaces/{wid}/webhooks/{whid}
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.WebhookRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	webhook, err := h.service.UpdateWebhook(middleware.CurrentUser(r.Context()), workspaceID, webhookID, req)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to update webhook")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, webhook, nil, nil)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{whid} and DELETE /workspaces/{wid}/webhooks/{whid}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(middleware.CurrentUser(r.Context()), workspaceID, webhookID); err != nil {
+		writeWebhookError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	msg := "Webhook deleted successfully, together with its delivery log"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// GetDeliveries handles GET /webhooks/{whid}/deliveries and its workspace-scoped variant
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			msg := "Invalid limit parameter, expected a number from 1 to 200"
+			helpers.ErrorBadRequest(w, "limit must be between 1 and 200", &msg)
+			return
+		}
+	}
+
+	deliveries, err := h.service.GetDeliveries(middleware.CurrentUser(r.Context()), workspaceID, webhookID, limit)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to retrieve deliveries")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, deliveries, nil, nil)
+}
+
+// GetDelivery handles GET /webhooks/{whid}/deliveries/{dlid} and its workspace-scoped variant
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, ok := deliveryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(middleware.CurrentUser(r.Context()), workspaceID, webhookID, deliveryID)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to retrieve delivery")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, delivery, nil, nil)
+}
+
+// Redeliver handles POST /webhooks/{whid}/deliveries/{dlid}/redeliver and its workspace-scoped variant
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	webhookID, ok := webhookIDParam(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, ok := deliveryIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Redeliver(middleware.CurrentUser(r.Context()), workspaceID, webhookID, deliveryID)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to redeliver")
+		return
+	}
+
+	msg := "Delivery queued again"
+	helpers.Success(w, helpers.Created, delivery, &msg, nil)
+}
+
+// webhookIDParam parses the {whid} URL parameter, writing a 400 when it is not a number
+func webhookIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["whid"])
+	if err != nil {
+		msg := "Invalid webhook ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// deliveryIDParam parses the {dlid} URL parameter, writing a 400 when it is not a number
+func deliveryIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["dlid"])
+	if err != nil {
+		msg := "Invalid delivery ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeWebhookError maps webhook service errors to their HTTP responses
+func writeWebhookError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrWebhookNotFound, repository.ErrDeliveryNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidWebhookURL, service.ErrUnknownWebhookHost, webhook.ErrForbiddenAddress,
+		service.ErrInvalidWebhookEvents, service.ErrInvalidWebhookSecret:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	case service.ErrDeliveryPending:
+		helpers.ErrorConflict(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/middleware"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// WorkspaceHandler handles HTTP requests for workspaces and their members
+type WorkspaceHandler struct {
+	service *service.WorkspaceService
+}
+
+// NewWorkspaceHandler creates a new instance of WorkspaceHandler
+func NewWorkspaceHandler(service *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		service: service,
+	}
+}
+
+// GetWorkspaces handles GET /workspaces
+func (h *WorkspaceHandler) GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := h.service.ListWorkspaces(middleware.CurrentUser(r.Context()))
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to retrieve workspaces")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, workspaces, nil, nil)
+}
+
+// GetWorkspace handles GET /workspaces/{wid}
+func (h *WorkspaceHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	workspace, err := h.service.GetWorkspace(middleware.CurrentUser(r.Context()), workspaceID)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to retrieve workspace")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, workspace, nil, nil)
+}
+
+// CreateWorkspace handles POST /workspaces
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateWorkspaceRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	workspace, err := h.service.CreateWorkspace(middleware.CurrentUser(r.Context()), req)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to create workspace")
+		return
+	}
+
+	helpers.Success(w, helpers.Created, workspace, nil, nil)
+}
+
+// DeleteWorkspace handles DELETE /workspaces/{wid}
+func (h *WorkspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWorkspace(middleware.CurrentUser(r.Context()), workspaceID); err != nil {
+		writeWorkspaceError(w, err, "Failed to delete workspace")
+		return
+	}
+
+	msg := "Workspace deleted successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// GetMembers handles GET /workspaces/{wid}/members
+func (h *WorkspaceHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := h.service.GetMembers(middleware.CurrentUser(r.Context()), workspaceID)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to retrieve members")
+		return
+	}
+
+	helpers.Success(w, helpers.Get, members, nil, nil)
+}
+
+// SaveMember handles PUT /workspaces/{wid}/members/{uid}
+func (h *WorkspaceHandler) SaveMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	memberID, ok := memberIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.MembershipRequest
+
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		humanMsg := helpers.ParseJSONError(err)
+		helpers.ErrorValidator(w, humanMsg, nil)
+		return
+	}
+	defer r.Body.Close()
+
+	membership, err := h.service.SaveMember(middleware.CurrentUser(r.Context()), workspaceID, memberID, req)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to save member")
+		return
+	}
+
+	helpers.Success(w, helpers.Updated, membership, nil, nil)
+}
+
+// RemoveMember handles DELETE /workspaces/{wid}/members/{uid}
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	workspaceID, ok := workspaceIDParam(w, r)
+	if !ok {
+		return
+	}
+	memberID, ok := memberIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveMember(middleware.CurrentUser(r.Context()), workspaceID, memberID); err != nil {
+		writeWorkspaceError(w, err, "Failed to remove member")
+		return
+	}
+
+	msg := "Member removed successfully"
+	helpers.Success(w, helpers.Deleted, nil, &msg, nil)
+}
+
+// memberIDParam parses the {uid} URL parameter, writing a 400 when it is not a number
+func memberIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["uid"])
+	if err != nil {
+		msg := "Invalid user ID"
+		helpers.ErrorBadRequest(w, err.Error(), &msg)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeWorkspaceError maps workspace service errors to their HTTP responses
+func writeWorkspaceError(w http.ResponseWriter, err error, failureMsg string) {
+	switch err {
+	case repository.ErrWorkspaceNotFound, repository.ErrMembershipNotFound, repository.ErrUserNotFound:
+		helpers.ErrorNotFound(w, err.Error(), nil)
+	case service.ErrUnauthenticated:
+		helpers.ErrorAuthentication(w, err.Error(), nil)
+	case service.ErrUnauthorized, service.ErrCannotModifyOwnMember, service.ErrDefaultWorkspaceLocked:
+		helpers.ErrorForbidden(w, err.Error(), nil)
+	case service.ErrInvalidWorkspaceName, service.ErrInvalidRole:
+		helpers.ErrorValidator(w, err.Error(), nil)
+	default:
+		helpers.ErrorServer(w, err.Error(), &failureMsg)
+	}
+}
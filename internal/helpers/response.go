@@ -13,9 +13,18 @@ type SuccessResponse struct {
 	ResponseStatus string      `json:"response_status"`
 	Message        string      `json:"message"`
 	Data           interface{} `json:"data,omitempty"`
+	Meta           interface{} `json:"meta,omitempty"`
 	Redirect       *string     `json:"redirect,omitempty"`
 }
 
+// Pagination is the meta of a response holding one page of a list
+type Pagination struct {
+	Limit      int    `json:"limit,omitempty"`
+	Count      int    `json:"count"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
 // ErrorResponse represents an error API response
 type ErrorResponse struct {
 	ResponseCode   int         `json:"response_code"`
@@ -55,6 +64,11 @@ var responseFormats = map[ResponseType]struct {
 
 // Success returns a success JSON response
 func Success(w http.ResponseWriter, responseType ResponseType, data interface{}, message *string, redirect *string) {
+	SuccessWithMeta(w, responseType, data, nil, message, redirect)
+}
+
+// SuccessWithMeta returns a success JSON response with meta next to the data, e.g. its Pagination
+func SuccessWithMeta(w http.ResponseWriter, responseType ResponseType, data interface{}, meta interface{}, message *string, redirect *string) {
 	format, exists := responseFormats[responseType]
 	if !exists {
 		format = struct {
@@ -74,6 +88,7 @@ func Success(w http.ResponseWriter, responseType ResponseType, data interface{},
 		ResponseStatus: "successfully-" + string(responseType),
 		Message:        finalMessage,
 		Data:           data,
+		Meta:           meta,
 		Redirect:       redirect,
 	}
 
@@ -131,6 +146,40 @@ func ErrorAuthentication(w http.ResponseWriter, errors interface{}, message *str
 	writeJSON(w, http.StatusUnauthorized, response)
 }
 
+// ErrorForbidden returns an authorization error JSON response
+func ErrorForbidden(w http.ResponseWriter, errors interface{}, message *string) {
+	finalMessage := "Error! You are not allowed to perform this action!"
+	if message != nil {
+		finalMessage = *message
+	}
+
+	response := ErrorResponse{
+		ResponseCode:   http.StatusForbidden,
+		ResponseStatus: "failed-authorization",
+		Message:        finalMessage,
+		Errors:         errors,
+	}
+
+	writeJSON(w, http.StatusForbidden, response)
+}
+
+// ErrorConflict returns a conflict error JSON response
+func ErrorConflict(w http.ResponseWriter, errors interface{}, message *string) {
+	finalMessage := "Error! The request conflicts with the current state!"
+	if message != nil {
+		finalMessage = *message
+	}
+
+	response := ErrorResponse{
+		ResponseCode:   http.StatusConflict,
+		ResponseStatus: "failed-conflict",
+		Message:        finalMessage,
+		Errors:         errors,
+	}
+
+	writeJSON(w, http.StatusConflict, response)
+}
+
 // ErrorServer returns a server error JSON response
 func ErrorServer(w http.ResponseWriter, errors interface{}, message *string) {
 	// Log the error
@@ -200,6 +249,11 @@ func ParseJSONError(err error) string {
 		return "Invalid data type for one or more fields"
 	}
 
+	// Timestamp fields (due_at, remind_at)
+	if strings.Contains(errMsg, "parsing time") {
+		return "Date fields must be RFC 3339 timestamps, e.g. 2025-01-31T17:00:00+07:00"
+	}
+
 	// Missing field errors
 	if strings.Contains(errMsg, "missing") {
 		return "Required field is missing"
@@ -0,0 +1,81 @@
+// Package mention resolves @mentions in free text (todo texts, comments) to users.
+//
+// A mention is "@" followed by a handle, e.g. "@jane", "@janesmith" or
+// "@jane@example.com". It must start the text or follow a character that
+// cannot be part of a handle, so "me@example.com" mentions nobody. Trailing
+// dots, as in "thanks @jane.", are not part of the handle.
+package mention
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"test_mekari/internal/models"
+)
+
+// pattern captures the handle of every mention; the optional second "@" part lets whole emails through
+var pattern = regexp.MustCompile(`(?:^|[^\w@.+-])@(\w[\w.+-]*(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// Handles returns the handles mentioned in text, lower-cased, in order of
+// first appearance and without duplicates
+func Handles(text string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], "."))
+		if !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// Resolve returns the sorted IDs of the users among candidates that text
+// mentions. A handle is matched, ignoring case, against in turn: the whole
+// email, the part of the email before the "@", the name without spaces and
+// the first word of the name. The first of these that any candidate matches
+// decides; when it matches more than one candidate the mention is ambiguous
+// and resolves to nobody. Unknown handles are ignored.
+func Resolve(text string, candidates []models.User) []int {
+	handles := Handles(text)
+	if len(handles) == 0 {
+		return []int{}
+	}
+
+	keys := []func(models.User) string{
+		func(user models.User) string { return user.Email },
+		func(user models.User) string { return strings.SplitN(user.Email, "@", 2)[0] },
+		func(user models.User) string { return strings.Join(strings.Fields(user.Name), "") },
+		func(user models.User) string {
+			if fields := strings.Fields(user.Name); len(fields) > 0 {
+				return fields[0]
+			}
+			return ""
+		},
+	}
+
+	ids := make([]int, 0, len(handles))
+	seen := make(map[int]bool)
+	for _, handle := range handles {
+		for _, key := range keys {
+			var matched []int
+			for _, user := range candidates {
+				if strings.EqualFold(key(user), handle) {
+					matched = append(matched, user.ID)
+				}
+			}
+			if len(matched) == 0 {
+				continue
+			}
+			if len(matched) == 1 && !seen[matched[0]] {
+				seen[matched[0]] = true
+				ids = append(ids, matched[0])
+			}
+			break
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,79 @@
+package mention
+
+import (
+	"reflect"
+	"testing"
+
+	"test_mekari/internal/models"
+)
+
+func TestHandles(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"no mention", "buy milk", nil},
+		{"at the start", "@jane buy milk", []string{"jane"}},
+		{"after a space", "ask @jane", []string{"jane"}},
+		{"after punctuation", "(@jane) and,@john", []string{"jane", "john"}},
+		{"trailing dot", "thanks @jane.", []string{"jane"}},
+		{"trailing dots", "thanks @jane...", []string{"jane"}},
+		{"trailing comma and colon", "@jane, @john: done", []string{"jane", "john"}},
+		{"dot inside the handle", "ask @jane.smith", []string{"jane.smith"}},
+		{"whole email", "ask @jane@example.com", []string{"jane@example.com"}},
+		{"email at the end of a sentence", "mail @jane@example.com.", []string{"jane@example.com"}},
+		{"plus and dash", "ask @jane+todo-list", []string{"jane+todo-list"}},
+		{"plain email mentions nobody", "mail me@example.com", nil},
+		{"lower-cased", "@Jane", []string{"jane"}},
+		{"duplicates once, in order", "@john @jane @JOHN", []string{"john", "jane"}},
+		{"lone at sign", "meet @ 5", nil},
+		{"double at sign", "@@jane", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Handles(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Handles(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	users := []models.User{
+		{ID: 1, Name: "Jane Smith", Email: "jane@example.com"},
+		{ID: 2, Name: "John Doe", Email: "jdoe@example.com"},
+		{ID: 3, Name: "Jane Doe", Email: "jane@other.org"},
+		{ID: 4, Name: "Mary Ann Lee", Email: "mal@example.com"},
+		{ID: 5, Name: "Bob", Email: "john@example.com"},
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{"no mention", "buy milk", []int{}},
+		{"unknown handle", "@nobody", []int{}},
+		{"whole email", "@jane@example.com", []int{1}},
+		{"whole email ignores case", "@JANE@Other.org", []int{3}},
+		{"local part", "@jdoe", []int{2}},
+		{"name without spaces", "@maryannlee", []int{4}},
+		{"first name", "@mary", []int{4}},
+		{"name without spaces ignores case", "@JaneSmith", []int{1}},
+		{"ambiguous local part resolves to nobody", "@jane", []int{}},
+		{"local part wins over a first name", "@john", []int{5}},
+		{"first name of a single-word name", "@bob", []int{5}},
+		{"trailing dot", "thanks @jdoe.", []int{2}},
+		{"sorted and without duplicates", "@maryannlee @jdoe @mary @JDOE", []int{2, 4}},
+		{"ambiguous and unique together", "@jane @jdoe", []int{2}},
+		{"plain email mentions nobody", "mail jdoe@example.com", []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(tt.text, users); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"test_mekari/internal/helpers"
+	"test_mekari/internal/models"
+	"test_mekari/internal/service"
+)
+
+// SessionCookieName is the cookie that carries the server-side session ID
+const SessionCookieName = "session_id"
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// Authenticate requires a valid "Authorization: Bearer <token>" header or
+// session cookie and stores the authenticated user on the request context
+func Authenticate(authService *service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := authenticateRequest(authService, r)
+			if err != nil {
+				helpers.ErrorAuthentication(w, err.Error(), nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticateRequest prefers the bearer token and falls back to the session cookie
+func authenticateRequest(authService *service.AuthService, r *http.Request) (*models.User, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return nil, service.ErrUnauthenticated
+		}
+		return authService.AuthenticateToken(strings.TrimSpace(token))
+	}
+
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		return authService.AuthenticateSession(cookie.Value)
+	}
+
+	return nil, service.ErrUnauthenticated
+}
+
+// CurrentUser returns the user stored by Authenticate, or nil on public routes
+func CurrentUser(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey).(*models.User)
+	return user
+}
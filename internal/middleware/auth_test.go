@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"test_mekari/internal/auth"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/service"
+)
+
+func TestAuthenticate(t *testing.T) {
+	store, err := repository.NewTodoRepository(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.CreateUser(&models.User{Name: "Other", Email: "other@example.com", Role: "member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	signer := auth.NewTokenSigner(secret, time.Hour)
+	sessions := auth.NewSessionStore(time.Hour)
+	authService, err := service.NewAuthService(store, signer, sessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(signer *auth.TokenSigner, userID int) string {
+		t.Helper()
+		token, _, err := signer.Issue(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	session := func(sessions *auth.SessionStore, userID int) string {
+		t.Helper()
+		id, _, err := sessions.Create(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	ownerToken := token(signer, 1)
+	otherSession := session(sessions, other.ID)
+	expiredToken := token(auth.NewTokenSigner(secret, -time.Second), 1)
+	expiredSession := session(auth.NewSessionStore(-time.Second), 1)
+	loggedOut := session(sessions, 1)
+	sessions.Delete(loggedOut)
+
+	// The handler behind the middleware answers with the user it was given
+	handler := Authenticate(authService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, CurrentUser(r.Context()).ID)
+	}))
+
+	for _, row := range []struct {
+		name          string
+		authorization string
+		cookie        string
+		status        int
+		userID        string
+	}{
+		{"bearer token", "Bearer " + ownerToken, "", http.StatusOK, "1"},
+		{"lowercase scheme", "bearer " + ownerToken, "", http.StatusOK, "1"},
+		{"session cookie", "", otherSession, http.StatusOK, fmt.Sprint(other.ID)},
+		{"bearer wins over cookie", "Bearer " + ownerToken, otherSession, http.StatusOK, "1"},
+		{"invalid bearer does not fall back to the cookie", "Bearer nonsense", otherSession, http.StatusUnauthorized, ""},
+		{"other scheme", "Basic " + ownerToken, "", http.StatusUnauthorized, ""},
+		{"scheme only", "Bearer", "", http.StatusUnauthorized, ""},
+		{"nothing", "", "", http.StatusUnauthorized, ""},
+		{"invalid token", "Bearer " + ownerToken + "x", "", http.StatusUnauthorized, ""},
+		{"expired token", "Bearer " + expiredToken, "", http.StatusUnauthorized, ""},
+		{"unknown session", "", "nonsense", http.StatusUnauthorized, ""},
+		{"expired session", "", expiredSession, http.StatusUnauthorized, ""},
+		{"deleted session", "", loggedOut, http.StatusUnauthorized, ""},
+		{"token of an unknown user", "Bearer " + token(signer, 999), "", http.StatusUnauthorized, ""},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if row.authorization != "" {
+			r.Header.Set("Authorization", row.authorization)
+		}
+		if row.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: row.cookie})
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != row.status {
+			t.Errorf("%s: status %d, want %d", row.name, w.Code, row.status)
+			continue
+		}
+		if row.status == http.StatusOK && w.Body.String() != row.userID {
+			t.Errorf("%s: authenticated user %s, want %s", row.name, w.Body.String(), row.userID)
+		}
+	}
+}
@@ -10,7 +10,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Upload-Offset")
+		w.Header().Set("Access-Control-Expose-Headers", "Upload-Offset, Content-Disposition")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		// Handle preflight requests
@@ -0,0 +1,344 @@
+// Package migrations evolves the SQL schema with ordered, checksummed migration files.
+//
+// Migrations live in sql/ as NNNN_name.up.sql / NNNN_name.down.sql pairs and are
+// embedded into the binary. Applied migrations are tracked in schema_migrations
+// together with their checksum, so an edited migration is detected instead of
+// silently diverging from databases that already ran it.
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrMissingMigration = errors.New("applied migration is missing from this build")
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New creates a Migrator over the migrations embedded in the binary
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads and orders every migration pair under sql/ in fsys
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		content, err := fs.ReadFile(fsys, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up + "\x00" + m.Down))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedRow is a migration recorded in schema_migrations
+type appliedRow struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// tableExists reports whether schema_migrations has been created
+func (m *Migrator) tableExists() (bool, error) {
+	var count int
+	err := m.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&count)
+	return count > 0, err
+}
+
+// legacyVersion returns the schema version recorded in PRAGMA user_version by
+// databases created before the migration runner existed
+func (m *Migrator) legacyVersion() (int, error) {
+	var version int
+	err := m.db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// ensureTable creates the tracking table, baselining legacy databases so
+// their initial migrations are not applied twice
+func (m *Migrator) ensureTable() error {
+	exists, err := m.tableExists()
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = m.db.Exec(`CREATE TABLE schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	legacyVersion, err := m.legacyVersion()
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if migration.Version > legacyVersion {
+			break
+		}
+		if err := m.record(m.db, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applied returns the tracked migrations, verifying each against this build.
+// It never writes, so dry runs and status checks leave the database untouched.
+func (m *Migrator) applied() (map[int]appliedRow, error) {
+	applied := make(map[int]appliedRow)
+
+	exists, err := m.tableExists()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		legacyVersion, err := m.legacyVersion()
+		if err != nil {
+			return nil, err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version <= legacyVersion {
+				applied[migration.Version] = appliedRow{checksum: migration.Checksum}
+			}
+		}
+		return applied, nil
+	}
+
+	rows, err := m.db.Query("SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for rows.Next() {
+		var version int
+		var row appliedRow
+		var appliedAt string
+		if err := rows.Scan(&version, &row.checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		row.appliedAt, _ = time.Parse(time.RFC3339, appliedAt)
+
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: version %d", ErrMissingMigration, version)
+		}
+		if migration.Checksum != row.checksum {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+		applied[version] = row
+	}
+	return applied, rows.Err()
+}
+
+// Status lists every known migration in order with its applied state
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		row, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: row.appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet, in order
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration, each in its own transaction.
+// With dryRun the migrations that would run are returned without touching the database.
+func (m *Migrator) Up(dryRun bool) ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil || dryRun {
+		return pending, err
+	}
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		err := m.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration.Up); err != nil {
+				return err
+			}
+			return m.record(tx, migration)
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("apply %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+// With dryRun the migrations that would be rolled back are returned without touching the database.
+func (m *Migrator) Down(steps int, dryRun bool) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]Migration, 0, steps)
+	for i := len(m.migrations) - 1; i >= 0 && len(targets) < steps; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			targets = append(targets, m.migrations[i])
+		}
+	}
+	if dryRun {
+		return targets, nil
+	}
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	for i, migration := range targets {
+		err := m.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+			return err
+		})
+		if err != nil {
+			return targets[:i], fmt.Errorf("roll back %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return targets, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// record marks migration as applied
+func (m *Migrator) record(db execer, migration Migration) error {
+	_, err := db.Exec(
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		migration.Version, migration.Name, migration.Checksum, time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// inTx runs fn in a transaction, rolling back if it fails
+func (m *Migrator) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,350 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+// openDB opens an empty SQLite database with foreign keys on, as the server does
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Temporary tables live in one connection, as they do in production
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// files returns a migration directory holding the given name -> SQL files
+func files(sqlFiles map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range sqlFiles {
+		fsys["sql/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+// twoMigrations is a schema of two migrations, notes and then tags
+var twoMigrations = map[string]string{
+	"0002_add_tags.up.sql":    "CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);",
+	"0002_add_tags.down.sql":  "DROP TABLE tags;",
+	"0001_add_notes.up.sql":   "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL);",
+	"0001_add_notes.down.sql": "DROP TABLE notes;",
+}
+
+// migrator loads sqlFiles into a Migrator of db
+func migrator(t *testing.T, db *sql.DB, sqlFiles map[string]string) *Migrator {
+	t.Helper()
+	migrations, err := Load(files(sqlFiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// versions returns the versions of migrations, as in "[1 2]"
+func versions(migrations []Migration) string {
+	list := make([]int, len(migrations))
+	for i, m := range migrations {
+		list[i] = m.Version
+	}
+	return fmt.Sprint(list)
+}
+
+// tables returns the user tables of db in name order, as in "[notes tags]"
+func tables(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return fmt.Sprint(names)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(files(twoMigrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions(migrations) != "[1 2]" || migrations[0].Name != "add_notes" || !strings.Contains(migrations[1].Down, "DROP TABLE tags") {
+		t.Errorf("Load = %+v, want add_notes then add_tags", migrations)
+	}
+
+	// The checksum covers both directions
+	edited := map[string]string{}
+	for name, content := range twoMigrations {
+		edited[name] = content
+	}
+	edited["0001_add_notes.down.sql"] = "DROP TABLE IF EXISTS notes;"
+	changed, err := Load(files(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed[0].Checksum == migrations[0].Checksum || changed[1].Checksum != migrations[1].Checksum {
+		t.Error("editing a down file must change the checksum of its migration only")
+	}
+
+	for _, row := range []struct {
+		files map[string]string
+		err   string
+	}{
+		{map[string]string{"init.sql": ""}, `invalid migration file name "init.sql"`},
+		{map[string]string{"0001_Add_Notes.up.sql": ""}, "invalid migration file name"},
+		{map[string]string{"0001_add_notes.sideways.sql": ""}, "invalid migration file name"},
+		{map[string]string{"0001_add_notes.up.sql": "SELECT 1;"}, "0001_add_notes needs both an up and a down file"},
+		{map[string]string{"0001_add_notes.down.sql": "SELECT 1;"}, "needs both an up and a down file"},
+		{map[string]string{"0001_add_notes.up.sql": "SELECT 1;", "0001_add_tags.down.sql": "SELECT 1;"}, "migration 1 has two names"},
+	} {
+		if _, err := Load(files(row.files)); err == nil || !strings.Contains(err.Error(), row.err) {
+			t.Errorf("Load(%v) error = %v, want ...%s...", row.files, err, row.err)
+		}
+	}
+	if _, err := Load(fstest.MapFS{}); err == nil {
+		t.Error("Load without a sql directory succeeded")
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	db := openDB(t)
+	m := migrator(t, db, twoMigrations)
+
+	// A dry run reports without writing, not even the tracking table
+	planned, err := m.Up(true)
+	if err != nil || versions(planned) != "[1 2]" {
+		t.Fatalf("Up(dry run) = %s, %v, want [1 2]", versions(planned), err)
+	}
+	if got := tables(t, db); got != "[]" {
+		t.Fatalf("tables after a dry run = %s, want none", got)
+	}
+
+	applied, err := m.Up(false)
+	if err != nil || versions(applied) != "[1 2]" {
+		t.Fatalf("Up = %s, %v, want [1 2]", versions(applied), err)
+	}
+	if got := tables(t, db); got != "[notes schema_migrations tags]" {
+		t.Errorf("tables after Up = %s", got)
+	}
+	if again, err := m.Up(false); err != nil || len(again) != 0 {
+		t.Errorf("second Up = %s, %v, want nothing to apply", versions(again), err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("status of %d = %+v, want applied with a time", status.Version, status)
+		}
+	}
+
+	planned, err = m.Down(5, true)
+	if err != nil || versions(planned) != "[2 1]" {
+		t.Fatalf("Down(5, dry run) = %s, %v, want [2 1]", versions(planned), err)
+	}
+	if got := tables(t, db); got != "[notes schema_migrations tags]" {
+		t.Fatalf("tables after a dry run = %s, want them untouched", got)
+	}
+
+	rolledBack, err := m.Down(1, false)
+	if err != nil || versions(rolledBack) != "[2]" {
+		t.Fatalf("Down(1) = %s, %v, want [2]", versions(rolledBack), err)
+	}
+	pending, err := m.Pending()
+	if err != nil || versions(pending) != "[2]" {
+		t.Errorf("Pending after Down(1) = %s, %v, want [2]", versions(pending), err)
+	}
+	if got := tables(t, db); got != "[notes schema_migrations]" {
+		t.Errorf("tables after Down(1) = %s", got)
+	}
+}
+
+// TestFailedMigration checks a migration that fails leaves neither its
+// changes nor its record behind, and stops the ones after it
+func TestFailedMigration(t *testing.T) {
+	db := openDB(t)
+	m := migrator(t, db, map[string]string{
+		"0001_add_notes.up.sql":   twoMigrations["0001_add_notes.up.sql"],
+		"0001_add_notes.down.sql": twoMigrations["0001_add_notes.down.sql"],
+		"0002_broken.up.sql":      "CREATE TABLE half (id INTEGER); INSERT INTO nowhere VALUES (1);",
+		"0002_broken.down.sql":    "DROP TABLE half;",
+		"0003_add_tags.up.sql":    twoMigrations["0002_add_tags.up.sql"],
+		"0003_add_tags.down.sql":  twoMigrations["0002_add_tags.down.sql"],
+	})
+	applied, err := m.Up(false)
+	if err == nil || !strings.Contains(err.Error(), "apply 0002_broken") {
+		t.Fatalf("Up error = %v, want 0002_broken to fail", err)
+	}
+	if versions(applied) != "[1]" {
+		t.Errorf("Up applied %s, want [1]", versions(applied))
+	}
+	if got := tables(t, db); got != "[notes schema_migrations]" {
+		t.Errorf("tables after a failed Up = %s", got)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db := openDB(t)
+	if _, err := migrator(t, db, twoMigrations).Up(false); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := map[string]string{}
+	for name, content := range twoMigrations {
+		edited[name] = content
+	}
+	edited["0002_add_tags.up.sql"] = "CREATE TABLE tags (id INTEGER PRIMARY KEY, label TEXT NOT NULL);"
+	m := migrator(t, db, edited)
+	if _, err := m.Up(false); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "0002_add_tags") {
+		t.Errorf("Up after an edit = %v, want ErrChecksumMismatch for 0002_add_tags", err)
+	}
+	if _, err := m.Status(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Status after an edit = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := m.Down(1, false); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Down after an edit = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestMissingMigration(t *testing.T) {
+	db := openDB(t)
+	if _, err := migrator(t, db, twoMigrations).Up(false); err != nil {
+		t.Fatal(err)
+	}
+
+	older := map[string]string{
+		"0001_add_notes.up.sql":   twoMigrations["0001_add_notes.up.sql"],
+		"0001_add_notes.down.sql": twoMigrations["0001_add_notes.down.sql"],
+	}
+	if _, err := migrator(t, db, older).Pending(); !errors.Is(err, ErrMissingMigration) || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("Pending of an older build = %v, want ErrMissingMigration for version 2", err)
+	}
+}
+
+// TestLegacyBaseline checks a database versioned with PRAGMA user_version
+// before the runner existed only gets the migrations after that version
+func TestLegacyBaseline(t *testing.T) {
+	db := openDB(t)
+	if _, err := db.Exec(twoMigrations["0001_add_notes.up.sql"] + "PRAGMA user_version = 1;"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO notes (body) VALUES ('kept')"); err != nil {
+		t.Fatal(err)
+	}
+	m := migrator(t, db, twoMigrations)
+
+	// Reading the state writes nothing
+	pending, err := m.Pending()
+	if err != nil || versions(pending) != "[2]" {
+		t.Fatalf("Pending = %s, %v, want [2]", versions(pending), err)
+	}
+	if got := tables(t, db); got != "[notes]" {
+		t.Fatalf("tables after Pending = %s, want no tracking table yet", got)
+	}
+
+	applied, err := m.Up(false)
+	if err != nil || versions(applied) != "[2]" {
+		t.Fatalf("Up = %s, %v, want [2]", versions(applied), err)
+	}
+	var recorded int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&recorded); err != nil || recorded != 2 {
+		t.Errorf("schema_migrations holds %d rows (%v), want the baseline and the applied one", recorded, err)
+	}
+	var body string
+	if err := db.QueryRow("SELECT body FROM notes").Scan(&body); err != nil || body != "kept" {
+		t.Errorf("note after Up = %q, %v, want it kept", body, err)
+	}
+}
+
+// TestEmbeddedRoundTrip runs every migration of the build up, down and up
+// again. Rolling back to before 0010 rebuilds todos and todo_labels, which
+// must keep their rows.
+func TestEmbeddedRoundTrip(t *testing.T) {
+	db := openDB(t)
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := len(m.migrations)
+	if _, err := m.Up(false); err != nil {
+		t.Fatal(err)
+	}
+
+	const now = "2026-03-10T12:00:00Z"
+	for _, stmt := range []string{
+		"INSERT INTO users (id, name, email, created_at) VALUES (1, 'Ann', 'ann@example.com', '" + now + "')",
+		"INSERT INTO labels (id, workspace_id, name, color, created_at, updated_at) VALUES (1, 1, 'bug', '#d73a4a', '" + now + "', '" + now + "')",
+		"INSERT INTO todos (id, workspace_id, text, user_id, created_by, created_at, updated_at) VALUES (1, 1, 'parent', 1, 'Ann', '" + now + "', '" + now + "')",
+		"INSERT INTO todos (id, workspace_id, text, user_id, created_by, created_at, updated_at, parent_id) VALUES (2, 1, 'subtask', 1, 'Ann', '" + now + "', '" + now + "', 1)",
+		"INSERT INTO todo_labels (todo_id, label_id) VALUES (1, 1), (2, 1)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	kept := func(when string) {
+		t.Helper()
+		var todos, labelled int
+		if err := db.QueryRow("SELECT COUNT(*) FROM todos").Scan(&todos); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.QueryRow("SELECT COUNT(*) FROM todo_labels").Scan(&labelled); err != nil {
+			t.Fatal(err)
+		}
+		if todos != 2 || labelled != 2 {
+			t.Errorf("%s: %d todos and %d todo labels, want 2 and 2", when, todos, labelled)
+		}
+	}
+
+	rolledBack, err := m.Down(all-9, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := rolledBack[len(rolledBack)-1]; last.Version != 10 {
+		t.Fatalf("Down stopped at %04d_%s, want 0010", last.Version, last.Name)
+	}
+	kept("down to 0009")
+
+	if _, err := m.Up(false); err != nil {
+		t.Fatal(err)
+	}
+	kept("up again")
+
+	// The todo IDs carry on after the rebuilt table's
+	if _, err := db.Exec("INSERT INTO todos (workspace_id, text, user_id, created_by, created_at, updated_at) VALUES (1, 'next', 1, 'Ann', '" + now + "', '" + now + "')"); err != nil {
+		t.Fatal(err)
+	}
+	var next int
+	if err := db.QueryRow("SELECT MAX(id) FROM todos").Scan(&next); err != nil || next != 3 {
+		t.Errorf("next todo ID = %d (%v), want 3", next, err)
+	}
+
+	// All the way down leaves only the tracking table, and up builds it all again
+	if _, err := m.Down(all, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := tables(t, db); got != "[schema_migrations]" {
+		t.Errorf("tables after rolling everything back = %s", got)
+	}
+	if applied, err := m.Up(false); err != nil || len(applied) != all {
+		t.Errorf("Up from scratch applied %d of %d: %v", len(applied), all, err)
+	}
+}
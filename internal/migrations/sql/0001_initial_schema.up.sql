@@ -0,0 +1,20 @@
+-- AUTOINCREMENT keeps the ID sequence in sqlite_sequence,
+-- so IDs of deleted rows are never reused (same as the in-memory nextID).
+CREATE TABLE users (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT    NOT NULL,
+    email      TEXT    NOT NULL,
+    created_at TEXT    NOT NULL
+);
+
+CREATE TABLE todos (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    text       TEXT    NOT NULL,
+    completed  INTEGER NOT NULL DEFAULT 0,
+    user_id    INTEGER NOT NULL REFERENCES users(id),
+    created_by TEXT    NOT NULL,
+    created_at TEXT    NOT NULL,
+    updated_at TEXT    NOT NULL
+);
+
+CREATE INDEX idx_todos_user_id ON todos(user_id);
@@ -0,0 +1,4 @@
+-- argon2id PHC string; empty means the user cannot log in with a password yet
+ALTER TABLE users ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_users_email ON users(email);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'member';
+
+-- The first seeded user administers existing databases
+UPDATE users SET role = 'admin' WHERE id = 1 AND email = 'john@example.com';
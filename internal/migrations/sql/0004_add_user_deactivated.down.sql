@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_email;
+CREATE INDEX idx_users_email ON users(email);
+
+ALTER TABLE users DROP COLUMN deactivated;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN deactivated INTEGER NOT NULL DEFAULT 0;
+
+-- Emails are unique ignoring case. This fails on databases that already hold
+-- duplicates; resolve them by hand before migrating.
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users(lower(email));
@@ -0,0 +1,25 @@
+-- Todos of every workspace are kept, only the workspace column goes away
+CREATE TABLE todos_old (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    text       TEXT    NOT NULL,
+    completed  INTEGER NOT NULL DEFAULT 0,
+    user_id    INTEGER NOT NULL REFERENCES users(id),
+    created_by TEXT    NOT NULL,
+    created_at TEXT    NOT NULL,
+    updated_at TEXT    NOT NULL
+);
+
+INSERT INTO todos_old (id, text, completed, user_id, created_by, created_at, updated_at)
+SELECT id, text, completed, user_id, created_by, created_at, updated_at FROM todos;
+
+DELETE FROM sqlite_sequence WHERE name = 'todos_old';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'todos_old', seq FROM sqlite_sequence WHERE name = 'todos';
+
+DROP TABLE todos;
+ALTER TABLE todos_old RENAME TO todos;
+
+CREATE INDEX idx_todos_user_id ON todos(user_id);
+
+DROP INDEX IF EXISTS idx_workspace_members_user_id;
+DROP TABLE workspace_members;
+DROP TABLE workspaces;
@@ -0,0 +1,45 @@
+CREATE TABLE workspaces (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT    NOT NULL,
+    created_at TEXT    NOT NULL
+);
+
+CREATE TABLE workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role         TEXT    NOT NULL DEFAULT 'member',
+    created_at   TEXT    NOT NULL,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+
+-- Everything that exists today moves into the default workspace
+INSERT INTO workspaces (id, name, created_at) VALUES (1, 'Default', strftime('%Y-%m-%dT%H:%M:%fZ', 'now'));
+INSERT INTO workspace_members (workspace_id, user_id, role, created_at)
+SELECT 1, id, role, created_at FROM users;
+
+-- SQLite cannot add a REFERENCES column with ALTER TABLE, so todos is rebuilt
+CREATE TABLE todos_new (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id),
+    text         TEXT    NOT NULL,
+    completed    INTEGER NOT NULL DEFAULT 0,
+    user_id      INTEGER NOT NULL REFERENCES users(id),
+    created_by   TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+INSERT INTO todos_new (id, workspace_id, text, completed, user_id, created_by, created_at, updated_at)
+SELECT id, 1, text, completed, user_id, created_by, created_at, updated_at FROM todos;
+
+-- Carry the ID sequence over, so IDs of deleted todos are still never reused
+DELETE FROM sqlite_sequence WHERE name = 'todos_new';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'todos_new', seq FROM sqlite_sequence WHERE name = 'todos';
+
+DROP TABLE todos;
+ALTER TABLE todos_new RENAME TO todos;
+
+CREATE INDEX idx_todos_workspace_user ON todos(workspace_id, user_id);
+CREATE INDEX idx_todos_user_id ON todos(user_id);
@@ -0,0 +1,26 @@
+-- SQLite cannot drop a column used by a foreign key, so todos is rebuilt without list_id
+CREATE TABLE todos_old (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id),
+    text         TEXT    NOT NULL,
+    completed    INTEGER NOT NULL DEFAULT 0,
+    user_id      INTEGER NOT NULL REFERENCES users(id),
+    created_by   TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+INSERT INTO todos_old (id, workspace_id, text, completed, user_id, created_by, created_at, updated_at)
+SELECT id, workspace_id, text, completed, user_id, created_by, created_at, updated_at FROM todos;
+
+DELETE FROM sqlite_sequence WHERE name = 'todos_old';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'todos_old', seq FROM sqlite_sequence WHERE name = 'todos';
+
+DROP TABLE todos;
+ALTER TABLE todos_old RENAME TO todos;
+
+CREATE INDEX idx_todos_workspace_user ON todos(workspace_id, user_id);
+CREATE INDEX idx_todos_user_id ON todos(user_id);
+
+DROP INDEX IF EXISTS idx_lists_workspace_id;
+DROP TABLE lists;
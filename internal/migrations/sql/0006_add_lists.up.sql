@@ -0,0 +1,15 @@
+CREATE TABLE lists (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    archived     INTEGER NOT NULL DEFAULT 0,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+CREATE INDEX idx_lists_workspace_id ON lists(workspace_id);
+
+-- A nullable REFERENCES column can be added in place; NULL means the todo is in no list
+ALTER TABLE todos ADD COLUMN list_id INTEGER REFERENCES lists(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_todos_workspace_list ON todos(workspace_id, list_id);
@@ -0,0 +1,4 @@
+-- RFC 3339 text with the offset the client sent; NULL when not set
+ALTER TABLE todos ADD COLUMN due_at TEXT;
+ALTER TABLE todos ADD COLUMN remind_at TEXT;
+ALTER TABLE todos ADD COLUMN reminded_at TEXT;
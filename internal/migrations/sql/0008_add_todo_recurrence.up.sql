@@ -0,0 +1,4 @@
+-- Canonical RRULE of a recurring todo, '' when it does not repeat
+ALTER TABLE todos ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';
+-- 1-based number of the todo within its series, 0 when never recurring
+ALTER TABLE todos ADD COLUMN occurrence INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP TABLE todo_labels;
+DROP TABLE labels;
+DROP INDEX idx_todos_workspace_priority;
+ALTER TABLE todos DROP COLUMN priority;
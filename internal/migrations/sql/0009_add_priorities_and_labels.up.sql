@@ -0,0 +1,25 @@
+-- none, low, medium, high or urgent
+ALTER TABLE todos ADD COLUMN priority TEXT NOT NULL DEFAULT 'none';
+
+CREATE INDEX idx_todos_workspace_priority ON todos(workspace_id, priority);
+
+CREATE TABLE labels (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    color        TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+-- Label names are unique per workspace, ignoring case
+CREATE UNIQUE INDEX idx_labels_workspace_name ON labels(workspace_id, lower(name));
+
+-- Deleting a todo or a label removes its rows here in the same statement
+CREATE TABLE todo_labels (
+    todo_id  INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, label_id)
+);
+
+CREATE INDEX idx_todo_labels_label_id ON todo_labels(label_id);
@@ -0,0 +1,41 @@
+-- SQLite cannot drop a column used by a foreign key, so todos is rebuilt
+-- without parent_id and checklist. Dropping todos cascades into todo_labels,
+-- so its rows are kept aside meanwhile.
+CREATE TEMP TABLE todo_labels_backup AS SELECT todo_id, label_id FROM todo_labels;
+
+CREATE TABLE todos_old (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id),
+    text         TEXT    NOT NULL,
+    completed    INTEGER NOT NULL DEFAULT 0,
+    user_id      INTEGER NOT NULL REFERENCES users(id),
+    created_by   TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL,
+    list_id      INTEGER REFERENCES lists(id) ON DELETE SET NULL,
+    due_at       TEXT,
+    remind_at    TEXT,
+    reminded_at  TEXT,
+    recurrence   TEXT    NOT NULL DEFAULT '',
+    occurrence   INTEGER NOT NULL DEFAULT 0,
+    priority     TEXT    NOT NULL DEFAULT 'none'
+);
+
+INSERT INTO todos_old (id, workspace_id, text, completed, user_id, created_by, created_at, updated_at,
+                       list_id, due_at, remind_at, reminded_at, recurrence, occurrence, priority)
+SELECT id, workspace_id, text, completed, user_id, created_by, created_at, updated_at,
+       list_id, due_at, remind_at, reminded_at, recurrence, occurrence, priority FROM todos;
+
+DELETE FROM sqlite_sequence WHERE name = 'todos_old';
+INSERT INTO sqlite_sequence (name, seq) SELECT 'todos_old', seq FROM sqlite_sequence WHERE name = 'todos';
+
+DROP TABLE todos;
+ALTER TABLE todos_old RENAME TO todos;
+
+CREATE INDEX idx_todos_workspace_user ON todos(workspace_id, user_id);
+CREATE INDEX idx_todos_user_id ON todos(user_id);
+CREATE INDEX idx_todos_workspace_list ON todos(workspace_id, list_id);
+CREATE INDEX idx_todos_workspace_priority ON todos(workspace_id, priority);
+
+INSERT INTO todo_labels (todo_id, label_id) SELECT todo_id, label_id FROM todo_labels_backup;
+DROP TABLE todo_labels_backup;
@@ -0,0 +1,8 @@
+-- NULL for a top-level todo. Subtasks whose parent is removed by a bulk
+-- delete (a user's or a workspace's todos) become top-level.
+ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_todos_parent_id ON todos(parent_id);
+
+-- JSON array of {"id", "text", "done"} items
+ALTER TABLE todos ADD COLUMN checklist TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,9 @@
+-- todo_id cannot start before blocker_id is done. Deleting either todo
+-- removes the edge in the same statement.
+CREATE TABLE todo_dependencies (
+    todo_id    INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    blocker_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, blocker_id)
+);
+
+CREATE INDEX idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);
@@ -0,0 +1,9 @@
+-- The users doing the work on a todo, apart from its owner (todos.user_id).
+-- Deleting the todo or the user removes the assignment.
+CREATE TABLE todo_assignees (
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE INDEX idx_todo_assignees_user_id ON todo_assignees(user_id);
@@ -0,0 +1,26 @@
+-- Comments on todos, optionally replying to another comment on the same todo.
+-- Deleting the todo deletes its comments; deleting a comment keeps its replies
+-- and deleting a user keeps their comments, both without the reference.
+CREATE TABLE comments (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    todo_id      INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    reply_to_id  INTEGER REFERENCES comments(id) ON DELETE SET NULL,
+    author_id    INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    body         TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    edited_at    TEXT
+);
+
+CREATE INDEX idx_comments_todo_id ON comments(todo_id);
+CREATE INDEX idx_comments_reply_to_id ON comments(reply_to_id);
+CREATE INDEX idx_comments_author_id ON comments(author_id);
+
+-- The users a comment @mentions
+CREATE TABLE comment_mentions (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX idx_comment_mentions_user_id ON comment_mentions(user_id);
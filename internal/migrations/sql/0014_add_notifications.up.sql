@@ -0,0 +1,19 @@
+-- The inbox of every user. Notifications about a deleted todo, and those of a
+-- deleted recipient, go away; those of a deleted comment or actor stay.
+CREATE TABLE notifications (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    todo_id      INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    comment_id   INTEGER REFERENCES comments(id) ON DELETE SET NULL,
+    actor_id     INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    type         TEXT    NOT NULL,
+    message      TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    read_at      TEXT
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, read_at);
+CREATE INDEX idx_notifications_todo_id ON notifications(todo_id);
+CREATE INDEX idx_notifications_comment_id ON notifications(comment_id);
+CREATE INDEX idx_notifications_actor_id ON notifications(actor_id);
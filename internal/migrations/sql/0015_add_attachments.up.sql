@@ -0,0 +1,17 @@
+-- Files attached to todos. Only the metadata lives here, the content is in
+-- blob storage under blob_key, which several attachments may share.
+CREATE TABLE attachments (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    todo_id      INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    uploader_id  INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    file_name    TEXT    NOT NULL,
+    content_type TEXT    NOT NULL,
+    size         INTEGER NOT NULL,
+    blob_key     TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL
+);
+
+CREATE INDEX idx_attachments_todo_id ON attachments(todo_id);
+CREATE INDEX idx_attachments_uploader_id ON attachments(uploader_id);
+CREATE INDEX idx_attachments_blob_key ON attachments(blob_key);
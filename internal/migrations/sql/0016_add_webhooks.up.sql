@@ -0,0 +1,35 @@
+-- Outgoing webhooks of a workspace. events is a JSON array of event types.
+CREATE TABLE webhooks (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    url          TEXT    NOT NULL,
+    events       TEXT    NOT NULL DEFAULT '[]',
+    secret       TEXT    NOT NULL,
+    active       INTEGER NOT NULL DEFAULT 1,
+    created_by   INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+CREATE INDEX idx_webhooks_workspace_id ON webhooks(workspace_id);
+CREATE INDEX idx_webhooks_created_by ON webhooks(created_by);
+
+-- The delivery queue. attempts is a JSON array of every POST made so far;
+-- next_attempt_at is set while the delivery is pending.
+CREATE TABLE webhook_deliveries (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id      INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    workspace_id    INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    event_id        TEXT    NOT NULL,
+    event_type      TEXT    NOT NULL,
+    payload         TEXT    NOT NULL,
+    status          TEXT    NOT NULL,
+    attempts        TEXT    NOT NULL DEFAULT '[]',
+    next_attempt_at TEXT,
+    created_at      TEXT    NOT NULL,
+    completed_at    TEXT
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX idx_webhook_deliveries_workspace_id ON webhook_deliveries(workspace_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
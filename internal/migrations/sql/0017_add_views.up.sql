@@ -0,0 +1,18 @@
+-- Saved views: a filter expression, sort order and grouping of the todos of a
+-- workspace. A view belongs to the user who saved it; shared ones are visible
+-- to every member of the workspace.
+CREATE TABLE views (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         TEXT    NOT NULL,
+    filter       TEXT    NOT NULL DEFAULT '',
+    sort         TEXT    NOT NULL DEFAULT '',
+    group_by     TEXT    NOT NULL DEFAULT '',
+    shared       INTEGER NOT NULL DEFAULT 0,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL
+);
+
+CREATE INDEX idx_views_workspace_id ON views(workspace_id);
+CREATE INDEX idx_views_user_id ON views(user_id);
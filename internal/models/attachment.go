@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Attachment is a file uploaded to a todo. The content lives in blob storage
+// under BlobKey, the SHA-256 of the content, so identical uploads share it.
+type Attachment struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	TodoID      int       `json:"todo_id"`
+	UploaderID  int       `json:"uploader_id"` // 0 once the uploader's account is deleted
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"` // sniffed from the content, never taken from the client
+	Size        int64     `json:"size"`
+	BlobKey     string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+	DownloadURL string    `json:"download_url"` // computed on read, never stored
+}
+
+// OwnerID returns the uploader; uploaders own their attachments
+func (a Attachment) OwnerID() int {
+	return a.UploaderID
+}
+
+// Upload is a resumable upload in progress. Chunks are appended at Offset
+// until it reaches Size, then the upload turns into an Attachment.
+type Upload struct {
+	ID          string    `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	TodoID      int       `json:"todo_id"`
+	UserID      int       `json:"user_id"`
+	FileName    string    `json:"file_name"`
+	Size        int64     `json:"size"`   // total size announced when the upload started
+	Offset      int64     `json:"offset"` // bytes received so far
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"` // the upload is discarded if not finished by then
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Comment is a message in the discussion of a todo. Replies point at the
+// comment they answer, so the discussion can be shown as threads.
+type Comment struct {
+	ID          int        `json:"id"`
+	WorkspaceID int        `json:"workspace_id"`
+	TodoID      int        `json:"todo_id"`
+	ReplyToID   int        `json:"reply_to_id"` // comment of the same todo this one answers, 0 for none
+	AuthorID    int        `json:"author_id"`   // 0 once the author's account is deleted
+	Body        string     `json:"body"`
+	Mentions    []int      `json:"mentions"` // sorted IDs of the users @mentioned in Body, never nil
+	CreatedAt   time.Time  `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at"` // nil until the body is edited
+}
+
+// OwnerID returns the author; authors own their comments
+func (c Comment) OwnerID() int {
+	return c.AuthorID
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Label tags the todos of a workspace by area (bug, frontend, ...); a todo can carry many labels
+type Label struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	Name        string    `json:"name"`  // unique within the workspace, ignoring case
+	Color       string    `json:"color"` // "#rrggbb"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// List groups the todos of a workspace into a project; a todo is in at most one list
+type List struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	Name        string    `json:"name"`
+	Archived    bool      `json:"archived"` // todos of archived lists are hidden from GET /todos
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
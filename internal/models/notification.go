@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// NotificationType says why a user was notified
+type NotificationType string
+
+const (
+	// NotificationMention: the actor @mentioned the user in a todo text or comment
+	NotificationMention NotificationType = "mention"
+	// NotificationAssigned: the actor assigned a todo to the user
+	NotificationAssigned NotificationType = "assigned"
+	// NotificationCompleted: the actor completed a todo the user owns
+	NotificationCompleted NotificationType = "completed"
+)
+
+// Notification is an entry in the inbox of one user, about something another
+// user did to a todo
+type Notification struct {
+	ID          int              `json:"id"`
+	UserID      int              `json:"user_id"` // recipient
+	WorkspaceID int              `json:"workspace_id"`
+	TodoID      int              `json:"todo_id"`
+	CommentID   int              `json:"comment_id"` // comment a mention is in, 0 for none
+	ActorID     int              `json:"actor_id"`   // 0 once the actor's account is deleted
+	Type        NotificationType `json:"type"`
+	Message     string           `json:"message"` // human-readable summary, e.g. `Jane Smith mentioned you in "Ship it"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ReadAt      *time.Time       `json:"read_at"` // nil while unread
+}
+
+// Inbox is the notifications of a user together with how many are unread
+type Inbox struct {
+	UnreadCount   int            `json:"unread_count"`
+	Notifications []Notification `json:"notifications"`
+}
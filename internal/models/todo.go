@@ -2,13 +2,89 @@ package models
 
 import "time"
 
+// Priority ranks todos for triage
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Valid reports whether p is one of the known priorities
+func (p Priority) Valid() bool {
+	return p.Rank() >= 0
+}
+
+// Rank orders priorities from none (0) to urgent (4), -1 for an unknown priority
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityNone:
+		return 0
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	case PriorityUrgent:
+		return 4
+	}
+	return -1
+}
+
 // Todo represents a todo item
 type Todo struct {
-	ID        int       `json:"id"`
-	Text      string    `json:"text"`
-	Completed bool      `json:"completed"`
-	UserID    int       `json:"user_id"`
-	CreatedBy string    `json:"created_by"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int             `json:"id"`
+	WorkspaceID  int             `json:"workspace_id"`
+	ListID       int             `json:"list_id"`   // 0 when the todo is in no list
+	ParentID     int             `json:"parent_id"` // 0 for a top-level todo, else the todo it is a subtask of
+	Text         string          `json:"text"`
+	Completed    bool            `json:"completed"`
+	Priority     Priority        `json:"priority"`
+	LabelIDs     []int           `json:"label_ids"`     // sorted IDs of labels of the same workspace, never nil
+	Checklist    []ChecklistItem `json:"checklist"`     // never nil
+	Progress     *int            `json:"progress"`      // percent done, computed for responses and never stored; nil without subtasks or checklist
+	BlockedBy    []int           `json:"blocked_by"`    // sorted IDs of todos of the same workspace this one depends on, never nil
+	Blocked      bool            `json:"blocked"`       // computed for responses and never stored: one of BlockedBy is still open
+	AssigneeIDs  []int           `json:"assignee_ids"`  // sorted IDs of the users doing the work, apart from the owner; never nil
+	CommentCount int             `json:"comment_count"` // computed for responses and never stored
+	UserID       int             `json:"user_id"`
+	CreatedBy    string          `json:"created_by"`
+	DueAt        *time.Time      `json:"due_at"`      // optional, keeps the timezone offset it was given in
+	RemindAt     *time.Time      `json:"remind_at"`   // optional, a reminder fires once this passes
+	RemindedAt   *time.Time      `json:"reminded_at"` // set once the reminder fired, cleared when remind_at changes
+	Recurrence   string          `json:"recurrence"`  // RRULE of the series, moves on to the next occurrence on completion
+	Occurrence   int             `json:"occurrence"`  // 1-based number within its series, 0 when never recurring
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// ChecklistItem is a step of a todo that, unlike a subtask, has no owner,
+// dates or labels of its own
+type ChecklistItem struct {
+	ID   int    `json:"id"` // unique within the todo
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// Stage is a step of a work plan: its todos can be started once the todos of
+// all earlier stages are done, the todos of stage 1 right away
+type Stage struct {
+	Stage int    `json:"stage"`
+	Todos []Todo `json:"todos"`
+}
+
+// Occurrence is an upcoming occurrence of a recurring todo
+type Occurrence struct {
+	Occurrence int        `json:"occurrence"`
+	DueAt      time.Time  `json:"due_at"`
+	RemindAt   *time.Time `json:"remind_at"`
+}
+
+// OwnerID returns the ID of the user who owns the todo
+func (t Todo) OwnerID() int {
+	return t.UserID
 }
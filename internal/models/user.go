@@ -4,8 +4,16 @@ import "time"
 
 // User represents a user in the system
 type User struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`        // admin, member or viewer (see internal/policy)
+	PasswordHash string    `json:"-"`           // argon2id PHC string, never sent to clients
+	Deactivated  bool      `json:"deactivated"` // deactivated users cannot log in
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OwnerID returns the user's own ID; users own their account
+func (u User) OwnerID() int {
+	return u.ID
 }
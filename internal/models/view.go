@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// View is a saved filter of the todos of a workspace: a filter expression, a
+// sort order and a grouping, as GET /todos takes them. A view belongs to the
+// user who saved it; shared views are visible to the whole workspace.
+type View struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	UserID      int       `json:"user_id"` // the owner
+	Name        string    `json:"name"`
+	Filter      string    `json:"filter"`   // a filter expression, "" for every todo
+	Sort        string    `json:"sort"`     // as ?sort= of GET /todos, "" for creation order
+	GroupBy     GroupBy   `json:"group_by"` // "" for no grouping
+	Shared      bool      `json:"shared"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OwnerID returns the user who saved the view
+func (v View) OwnerID() int {
+	return v.UserID
+}
+
+// GroupBy is the field the todos of a view are grouped by
+type GroupBy string
+
+const (
+	GroupByNone      GroupBy = ""
+	GroupByCompleted GroupBy = "completed"
+	GroupByPriority  GroupBy = "priority"
+	GroupByOwner     GroupBy = "user_id"
+	GroupByAssignee  GroupBy = "assignee" // a todo is in the group of each of its assignees
+	GroupByList      GroupBy = "list_id"
+	GroupByLabel     GroupBy = "label" // a todo is in the group of each of its labels
+)
+
+// Valid reports whether g is a known grouping
+func (g GroupBy) Valid() bool {
+	switch g {
+	case GroupByNone, GroupByCompleted, GroupByPriority, GroupByOwner, GroupByAssignee, GroupByList, GroupByLabel:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a URL the todo events of a workspace are POSTed to. Payloads are
+// signed with Secret, which is only returned when it is set.
+type Webhook struct {
+	ID          int       `json:"id"`
+	WorkspaceID int       `json:"workspace_id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"` // the event types delivered
+	Secret      string    `json:"secret,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedBy   int       `json:"created_by"` // 0 once the creator's account is deleted
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"   // waiting for its next attempt
+	DeliverySucceeded = "succeeded" // the receiver answered 2xx
+	DeliveryDead      = "dead"      // every attempt failed, it is not retried anymore
+)
+
+// WebhookDelivery is one event queued for a webhook, with every attempt made
+// to deliver it
+type WebhookDelivery struct {
+	ID            int               `json:"id"`
+	WebhookID     int               `json:"webhook_id"`
+	WorkspaceID   int               `json:"workspace_id"`
+	EventID       string            `json:"event_id"`
+	EventType     string            `json:"event_type"`
+	Payload       json.RawMessage   `json:"payload"` // the exact body sent on every attempt
+	Status        string            `json:"status"`
+	Attempts      []DeliveryAttempt `json:"attempts"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"` // while pending
+	CreatedAt     time.Time         `json:"created_at"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"` // when it succeeded or went dead
+}
+
+// DeliveryAttempt is one POST of a delivery. StatusCode is 0 when no
+// response was received, Error says why.
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
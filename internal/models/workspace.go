@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Workspace is an isolated board; todos always belong to exactly one workspace
+type Workspace struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership gives a user a role inside one workspace
+type Membership struct {
+	WorkspaceID int       `json:"workspace_id"`
+	UserID      int       `json:"user_id"`
+	Role        string    `json:"role"` // admin, member or viewer within the workspace
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,185 @@
+// Package policy declares who may do what to todos, lists, labels, comments, user accounts and workspaces.
+//
+// All authorization rules live in the rules table below, so they can be read
+// (and unit-tested) in one place without going through HTTP handlers.
+package policy
+
+import "test_mekari/internal/models"
+
+// Role is a capability level. Admin, member and viewer are assigned to users
+// (globally, and per workspace through their membership);
+// owner is derived for the user who owns the resource being acted on
+// (the creator of a todo, the author of a comment, or the account holder of a user).
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+	RoleOwner  Role = "owner"
+)
+
+// Action is an operation that can be authorized
+type Action string
+
+const (
+	ActionViewTodo   Action = "todo:view"
+	ActionCreateTodo Action = "todo:create"
+	ActionUpdateTodo Action = "todo:update"
+	ActionToggleTodo Action = "todo:toggle"
+	ActionDeleteTodo Action = "todo:delete"
+	ActionMoveTodo   Action = "todo:move"
+	ActionAssignTodo Action = "todo:assign"
+
+	ActionCreateList Action = "list:create"
+	ActionUpdateList Action = "list:update"
+	ActionDeleteList Action = "list:delete"
+
+	ActionCreateLabel Action = "label:create"
+	ActionUpdateLabel Action = "label:update"
+	ActionDeleteLabel Action = "label:delete"
+
+	ActionCreateComment Action = "comment:create"
+	ActionUpdateComment Action = "comment:update"
+	ActionDeleteComment Action = "comment:delete"
+
+	ActionCreateAttachment Action = "attachment:create"
+	ActionDeleteAttachment Action = "attachment:delete"
+
+	ActionCreateUser Action = "user:create"
+	ActionUpdateUser Action = "user:update"
+	ActionManageUser Action = "user:manage"
+	ActionDeleteUser Action = "user:delete"
+
+	ActionCreateWorkspace Action = "workspace:create"
+	ActionViewWorkspace   Action = "workspace:view"
+	ActionManageWorkspace Action = "workspace:manage"
+
+	ActionManageWebhook Action = "webhook:manage"
+
+	ActionUpdateView Action = "view:update"
+	ActionDeleteView Action = "view:delete"
+)
+
+// rules maps every action to the roles allowed to perform it
+var rules = map[Action][]Role{
+	ActionViewTodo:   {RoleViewer, RoleMember, RoleOwner, RoleAdmin},
+	ActionCreateTodo: {RoleMember, RoleAdmin},
+	// Only the creator or an admin may change the text or delete a todo
+	ActionUpdateTodo: {RoleOwner, RoleAdmin},
+	ActionDeleteTodo: {RoleOwner, RoleAdmin},
+	// Any teammate may tick a todo off, viewers may not
+	ActionToggleTodo: {RoleMember, RoleOwner, RoleAdmin},
+	// Moving a todo between lists is triage, which any teammate may do
+	ActionMoveTodo: {RoleMember, RoleOwner, RoleAdmin},
+	// So is handing a todo to a teammate, or taking it on
+	ActionAssignTodo: {RoleMember, RoleOwner, RoleAdmin},
+
+	// Lists are shared by the workspace: members create, rename and archive them,
+	// only admins delete them (viewing lists follows ActionViewTodo)
+	ActionCreateList: {RoleMember, RoleAdmin},
+	ActionUpdateList: {RoleMember, RoleAdmin},
+	ActionDeleteList: {RoleAdmin},
+
+	// Labels follow lists: any teammate may add or edit one, deleting a label
+	// strips it from every todo and is reserved for admins
+	ActionCreateLabel: {RoleMember, RoleAdmin},
+	ActionUpdateLabel: {RoleMember, RoleAdmin},
+	ActionDeleteLabel: {RoleAdmin},
+
+	// Anyone who may work on todos may discuss them (reading comments follows
+	// ActionViewTodo); only the author edits a comment, admins may also remove it
+	ActionCreateComment: {RoleMember, RoleAdmin},
+	ActionUpdateComment: {RoleOwner},
+	ActionDeleteComment: {RoleOwner, RoleAdmin},
+
+	// Members attach files to todos (downloading follows ActionViewTodo); the
+	// uploader or an admin removes them
+	ActionCreateAttachment: {RoleMember, RoleAdmin},
+	ActionDeleteAttachment: {RoleOwner, RoleAdmin},
+
+	ActionCreateUser: {RoleAdmin},
+	// Users may edit their own name, email and password
+	ActionUpdateUser: {RoleOwner, RoleAdmin},
+	// Changing a role or (de)activating an account is reserved for admins
+	ActionManageUser: {RoleAdmin},
+	ActionDeleteUser: {RoleAdmin},
+
+	// Checked against the global role, anyone but a viewer may start a workspace
+	ActionCreateWorkspace: {RoleMember, RoleAdmin},
+	// Checked against the workspace role
+	ActionViewWorkspace: {RoleViewer, RoleMember, RoleAdmin},
+	// Renaming, deleting and managing members
+	ActionManageWorkspace: {RoleAdmin},
+
+	// Webhooks send every todo of the workspace to a third party, and their
+	// deliveries hold those todos, so only admins see and manage them
+	ActionManageWebhook: {RoleAdmin},
+	// Anyone who may see the todos may save views of them (running a shared
+	// view follows ActionViewTodo); only the owner changes or shares a view,
+	// admins may also remove a shared one
+	ActionUpdateView: {RoleOwner},
+	ActionDeleteView: {RoleOwner, RoleAdmin},
+}
+
+// Resource is the target of an action. Its owner holds RoleOwner for it.
+type Resource interface {
+	OwnerID() int
+}
+
+// Actor is the user an action is authorized for
+type Actor struct {
+	UserID int
+	Role   Role
+}
+
+// NewActor builds an actor from a user; users without a role are members
+func NewActor(user *models.User) Actor {
+	role := Role(user.Role)
+	if !IsAssignable(role) {
+		role = RoleMember
+	}
+	return Actor{UserID: user.ID, Role: role}
+}
+
+// NewMemberActor builds an actor inside a workspace from the user's membership.
+// Global admins act as admins of every workspace, members or not; a nil
+// membership falls back to the global role.
+func NewMemberActor(user *models.User, membership *models.Membership) Actor {
+	actor := NewActor(user)
+	if actor.Role == RoleAdmin || membership == nil {
+		return actor
+	}
+	role := Role(membership.Role)
+	if !IsAssignable(role) {
+		role = RoleMember
+	}
+	return Actor{UserID: user.ID, Role: role}
+}
+
+// IsAssignable reports whether role can be stored on a user (owner is derived)
+func IsAssignable(role Role) bool {
+	return role == RoleAdmin || role == RoleMember || role == RoleViewer
+}
+
+// RolesFor returns every role actor holds with respect to resource (nil for actions without a target)
+func RolesFor(actor Actor, resource Resource) []Role {
+	roles := []Role{actor.Role}
+	if resource != nil && resource.OwnerID() == actor.UserID {
+		roles = append(roles, RoleOwner)
+	}
+	return roles
+}
+
+// Can reports whether actor may perform action on resource
+func Can(actor Actor, action Action, resource Resource) bool {
+	allowed := rules[action]
+	for _, held := range RolesFor(actor, resource) {
+		for _, role := range allowed {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
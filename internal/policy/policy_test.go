@@ -0,0 +1,164 @@
+package policy
+
+import (
+	"testing"
+
+	"test_mekari/internal/models"
+)
+
+// resource is a Resource owned by a user
+type resource int
+
+func (r resource) OwnerID() int { return int(r) }
+
+// TestCan checks every action for every role, with and without owning the
+// resource. Each row reads: viewer, member, admin acting on someone else's
+// resource, then viewer, member, admin acting on their own; "y" allows.
+func TestCan(t *testing.T) {
+	table := []struct {
+		action  Action
+		allowed string
+	}{
+		{ActionViewTodo, "yyyyyy"},
+		{ActionCreateTodo, ".yy.yy"},
+		{ActionUpdateTodo, "..yyyy"},
+		{ActionToggleTodo, ".yyyyy"},
+		{ActionDeleteTodo, "..yyyy"},
+		{ActionMoveTodo, ".yyyyy"},
+		{ActionAssignTodo, ".yyyyy"},
+
+		{ActionCreateList, ".yy.yy"},
+		{ActionUpdateList, ".yy.yy"},
+		{ActionDeleteList, "..y..y"},
+
+		{ActionCreateLabel, ".yy.yy"},
+		{ActionUpdateLabel, ".yy.yy"},
+		{ActionDeleteLabel, "..y..y"},
+
+		{ActionCreateComment, ".yy.yy"},
+		{ActionUpdateComment, "...yyy"},
+		{ActionDeleteComment, "..yyyy"},
+
+		{ActionCreateAttachment, ".yy.yy"},
+		{ActionDeleteAttachment, "..yyyy"},
+
+		{ActionCreateUser, "..y..y"},
+		{ActionUpdateUser, "..yyyy"},
+		{ActionManageUser, "..y..y"},
+		{ActionDeleteUser, "..y..y"},
+
+		{ActionCreateWorkspace, ".yy.yy"},
+		{ActionViewWorkspace, "yyyyyy"},
+		{ActionManageWorkspace, "..y..y"},
+
+		{ActionManageWebhook, "..y..y"},
+
+		{ActionUpdateView, "...yyy"},
+		{ActionDeleteView, "..yyyy"},
+	}
+
+	const actorID, otherID = 7, 8
+	columns := []struct {
+		role  Role
+		owner int
+	}{
+		{RoleViewer, otherID}, {RoleMember, otherID}, {RoleAdmin, otherID},
+		{RoleViewer, actorID}, {RoleMember, actorID}, {RoleAdmin, actorID},
+	}
+
+	tested := make(map[Action]bool)
+	for _, row := range table {
+		tested[row.action] = true
+		for i, column := range columns {
+			actor := Actor{UserID: actorID, Role: column.role}
+			want := row.allowed[i] == 'y'
+			if got := Can(actor, row.action, resource(column.owner)); got != want {
+				t.Errorf("Can(%s, %s, owned=%v) = %v, want %v", column.role, row.action, column.owner == actorID, got, want)
+			}
+		}
+	}
+	for action := range rules {
+		if !tested[action] {
+			t.Errorf("action %s has a rule but no test row", action)
+		}
+	}
+}
+
+// TestCanWithoutResource checks that nobody holds the owner role when an
+// action has no target
+func TestCanWithoutResource(t *testing.T) {
+	for _, role := range []Role{RoleViewer, RoleMember} {
+		if Can(Actor{UserID: 1, Role: role}, ActionUpdateUser, nil) {
+			t.Errorf("%s may update a user without owning it", role)
+		}
+	}
+	if !Can(Actor{UserID: 1, Role: RoleAdmin}, ActionUpdateUser, nil) {
+		t.Error("admin may not update a user")
+	}
+	if Can(Actor{UserID: 1, Role: RoleMember}, Action("todo:unknown"), nil) {
+		t.Error("an action without a rule is allowed")
+	}
+}
+
+func TestNewActor(t *testing.T) {
+	tests := []struct {
+		role string
+		want Role
+	}{
+		{"admin", RoleAdmin},
+		{"member", RoleMember},
+		{"viewer", RoleViewer},
+		{"", RoleMember},
+		// owner is derived, it cannot be stored on a user
+		{"owner", RoleMember},
+		{"superuser", RoleMember},
+	}
+	for _, tt := range tests {
+		actor := NewActor(&models.User{ID: 3, Role: tt.role})
+		if actor.Role != tt.want || actor.UserID != 3 {
+			t.Errorf("NewActor(role %q) = %+v, want role %s", tt.role, actor, tt.want)
+		}
+	}
+}
+
+func TestNewMemberActor(t *testing.T) {
+	tests := []struct {
+		name       string
+		globalRole string
+		membership *models.Membership
+		want       Role
+	}{
+		{"workspace role applies", "member", &models.Membership{Role: "viewer"}, RoleViewer},
+		{"workspace admin", "member", &models.Membership{Role: "admin"}, RoleAdmin},
+		{"global viewer made workspace member", "viewer", &models.Membership{Role: "member"}, RoleMember},
+		{"global admins stay admins", "admin", &models.Membership{Role: "viewer"}, RoleAdmin},
+		{"global admins without membership", "admin", nil, RoleAdmin},
+		{"no membership falls back to the global role", "viewer", nil, RoleViewer},
+		{"unknown workspace role", "viewer", &models.Membership{Role: "owner"}, RoleMember},
+	}
+	for _, tt := range tests {
+		actor := NewMemberActor(&models.User{ID: 4, Role: tt.globalRole}, tt.membership)
+		if actor.Role != tt.want {
+			t.Errorf("%s: role = %s, want %s", tt.name, actor.Role, tt.want)
+		}
+	}
+}
+
+// TestWorkspaceAdminOverridesOwnership checks an admin of a workspace may act
+// on todos and comments of others where a member may only act on their own
+func TestWorkspaceAdminOverridesOwnership(t *testing.T) {
+	member := NewMemberActor(&models.User{ID: 5, Role: "member"}, &models.Membership{Role: "member"})
+	admin := NewMemberActor(&models.User{ID: 6, Role: "member"}, &models.Membership{Role: "admin"})
+	todo := &models.Todo{UserID: 9}
+	own := &models.Todo{UserID: 5}
+
+	if Can(member, ActionDeleteTodo, todo) {
+		t.Error("member may delete a todo of another user")
+	}
+	if !Can(member, ActionDeleteTodo, own) {
+		t.Error("member may not delete their own todo")
+	}
+	if !Can(admin, ActionDeleteTodo, todo) {
+		t.Error("workspace admin may not delete a todo of another user")
+	}
+}
@@ -0,0 +1,305 @@
+// Package recurrence implements the subset of iCalendar RRULEs (RFC 5545)
+// recurring todos support: FREQ=DAILY|WEEKLY|MONTHLY|YEARLY with INTERVAL,
+// BYDAY (weekly only), and UNTIL or COUNT.
+//
+// A rule is anchored on the due date of the first occurrence. Occurrences
+// keep its wall clock time and timezone offset; monthly and yearly rules skip
+// periods that lack the day (e.g. the 31st), as RFC 5545 does.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is wrapped by every error Parse returns
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Frequency is the FREQ part of a rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxSkips bounds the search for a period that has the anchor's day,
+// a yearly rule on February 29th needs up to 8 years
+const maxSkips = 100
+
+// untilLayout and untilDateLayout are the UNTIL forms Parse accepts
+const (
+	untilLayout     = "20060102T150405Z"
+	untilDateLayout = "20060102"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     Frequency
+	Interval int            // at least 1
+	ByDay    []time.Weekday // weekly only, sorted Monday first; empty repeats the anchor's weekday
+	Until    *time.Time     // last allowed occurrence, inclusive
+	Count    int            // total number of occurrences, 0 for no limit
+
+	// untilDate is set when UNTIL was a date, it then covers that whole day
+	// in the timezone of the occurrences
+	untilDate bool
+}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10".
+// Names and values are case-insensitive and a leading "RRULE:" is accepted.
+func Parse(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return Rule{}, invalid("rule is empty")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return Rule{}, invalid("%q is not NAME=VALUE", part)
+		}
+		if seen[name] {
+			return Rule{}, invalid("%s is given twice", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			switch freq := Frequency(value); freq {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = freq
+			default:
+				return Rule{}, invalid("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return Rule{}, invalid("INTERVAL must be a positive number")
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			days, err := parseByDay(value)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.ByDay = days
+		case "UNTIL":
+			if t, err := time.Parse(untilLayout, value); err == nil {
+				rule.Until = &t
+			} else if t, err := time.Parse(untilDateLayout, value); err == nil {
+				rule.Until = &t
+				rule.untilDate = true
+			} else {
+				return Rule{}, invalid("UNTIL must look like 20251231T170000Z or 20251231")
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return Rule{}, invalid("COUNT must be a positive number")
+			}
+			rule.Count = count
+		default:
+			return Rule{}, invalid("%s is not supported", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, invalid("FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return Rule{}, invalid("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if rule.Until != nil && rule.Count > 0 {
+		return Rule{}, invalid("UNTIL and COUNT cannot be combined")
+	}
+	return rule, nil
+}
+
+// parseByDay parses a BYDAY list such as "MO,WE,FR"
+func parseByDay(value string) ([]time.Weekday, error) {
+	var set [7]bool
+	for _, code := range strings.Split(value, ",") {
+		day, ok := weekdays[strings.TrimSpace(code)]
+		if !ok {
+			return nil, invalid("BYDAY takes MO, TU, WE, TH, FR, SA or SU, not %q", code)
+		}
+		set[day] = true
+	}
+
+	// Monday first, the default week start of RFC 5545
+	var days []time.Weekday
+	for i := 1; i <= 7; i++ {
+		if day := time.Weekday(i % 7); set[day] {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// String formats the rule in canonical form, Parse(r.String()) gives r back
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = strings.ToUpper(day.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		if r.untilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format(untilDateLayout))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+		}
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence after prev, which is occurrence number index
+// (1 for the anchor). ok is false once the series is over.
+func (r Rule) Next(prev time.Time, index int) (next time.Time, ok bool) {
+	if r.Count > 0 && index >= r.Count {
+		return time.Time{}, false
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Freq {
+	case Daily:
+		next = addDays(prev, interval)
+	case Weekly:
+		next = r.nextWeekly(prev, interval)
+	case Monthly:
+		next, ok = nextWithDay(prev, func(step int) time.Time {
+			return date(prev, prev.Year(), prev.Month()+time.Month(step*interval), prev.Day())
+		})
+		if !ok {
+			return time.Time{}, false
+		}
+	case Yearly:
+		next, ok = nextWithDay(prev, func(step int) time.Time {
+			return date(prev, prev.Year()+step*interval, prev.Month(), prev.Day())
+		})
+		if !ok {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if r.after(next) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// Occurrences returns up to n occurrences following prev (occurrence number index)
+func (r Rule) Occurrences(prev time.Time, index, n int) []time.Time {
+	var occurrences []time.Time
+	for len(occurrences) < n {
+		next, ok := r.Next(prev, index)
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+		prev, index = next, index+1
+	}
+	return occurrences
+}
+
+// nextWeekly returns the next BYDAY day after prev: later in the same week,
+// else the first one of the week interval weeks on
+func (r Rule) nextWeekly(prev time.Time, interval int) time.Time {
+	if len(r.ByDay) == 0 {
+		return addDays(prev, 7*interval)
+	}
+
+	offset := mondayOffset(prev.Weekday())
+	for _, day := range r.ByDay {
+		if d := mondayOffset(day); d > offset {
+			return addDays(prev, d-offset)
+		}
+	}
+	return addDays(prev, 7*interval+mondayOffset(r.ByDay[0])-offset)
+}
+
+// after reports whether t lies beyond UNTIL
+func (r Rule) after(t time.Time) bool {
+	if r.Until == nil {
+		return false
+	}
+	if r.untilDate {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.After(*r.Until)
+	}
+	return t.After(*r.Until)
+}
+
+// nextWithDay returns the first candidate after prev that kept prev's day of
+// the month, skipping periods where it does not exist
+func nextWithDay(prev time.Time, candidate func(step int) time.Time) (time.Time, bool) {
+	for step := 1; step <= maxSkips; step++ {
+		if next := candidate(step); next.Day() == prev.Day() {
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// date builds a time on the given day with the wall clock and location of
+// like. time.Date normalizes a missing day into the next month. A wall clock
+// skipped by a daylight saving change is moved on by the length of the gap
+// (02:30 becomes 03:30), as RFC 5545 does; time.Date may pick the earlier
+// side instead.
+func date(like time.Time, year int, month time.Month, day int) time.Time {
+	t := time.Date(year, month, day, like.Hour(), like.Minute(), like.Second(), like.Nanosecond(), like.Location())
+	want := time.Date(year, month, day, like.Hour(), like.Minute(), like.Second(), like.Nanosecond(), time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if gap := want.Sub(got); gap > 0 {
+		return t.Add(gap)
+	}
+	return t
+}
+
+// addDays returns the time days calendar days after t, at the same wall clock
+func addDays(t time.Time, days int) time.Time {
+	return date(t, t.Year(), t.Month(), t.Day()+days)
+}
+
+// mondayOffset numbers the days of a week starting on Monday
+func mondayOffset(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,160 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(loc *time.Location, year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, loc)
+	}
+	utc := func(year int, month time.Month, day int) time.Time {
+		return at(time.UTC, year, month, day, 9, 0)
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		prev time.Time
+		want []time.Time
+	}{
+		// month-end rollover
+		{"monthly on the 31st skips short months", "FREQ=MONTHLY",
+			utc(2025, time.January, 31),
+			[]time.Time{utc(2025, time.March, 31), utc(2025, time.May, 31), utc(2025, time.July, 31), utc(2025, time.August, 31)}},
+		{"monthly on the 30th skips February", "FREQ=MONTHLY",
+			utc(2025, time.January, 30),
+			[]time.Time{utc(2025, time.March, 30), utc(2025, time.April, 30)}},
+		{"monthly across the year end", "FREQ=MONTHLY",
+			utc(2025, time.December, 31),
+			[]time.Time{utc(2026, time.January, 31), utc(2026, time.March, 31)}},
+		{"every other month on the 31st", "FREQ=MONTHLY;INTERVAL=2",
+			utc(2025, time.August, 31),
+			[]time.Time{utc(2025, time.October, 31), utc(2025, time.December, 31), utc(2026, time.August, 31)}},
+		{"daily across the month end", "FREQ=DAILY",
+			utc(2025, time.April, 30),
+			[]time.Time{utc(2025, time.May, 1)}},
+		{"weekly across the year end", "FREQ=WEEKLY;BYDAY=MO,TH",
+			utc(2025, time.December, 29),
+			[]time.Time{utc(2026, time.January, 1), utc(2026, time.January, 5)}},
+
+		// leap days
+		{"yearly on a leap day waits for the next leap year", "FREQ=YEARLY",
+			utc(2024, time.February, 29),
+			[]time.Time{utc(2028, time.February, 29), utc(2032, time.February, 29)}},
+		{"yearly on a leap day skips 2100", "FREQ=YEARLY",
+			utc(2096, time.February, 29),
+			[]time.Time{utc(2104, time.February, 29)}},
+		{"every third year on a leap day", "FREQ=YEARLY;INTERVAL=3",
+			utc(2024, time.February, 29),
+			[]time.Time{utc(2036, time.February, 29)}},
+		{"monthly on the 29th includes the leap day", "FREQ=MONTHLY",
+			utc(2024, time.January, 29),
+			[]time.Time{utc(2024, time.February, 29), utc(2024, time.March, 29)}},
+		{"monthly on the 29th skips February of common years", "FREQ=MONTHLY",
+			utc(2025, time.January, 29),
+			[]time.Time{utc(2025, time.March, 29)}},
+		{"daily through a leap day", "FREQ=DAILY",
+			utc(2024, time.February, 28),
+			[]time.Time{utc(2024, time.February, 29), utc(2024, time.March, 1)}},
+
+		// daylight saving time keeps the wall clock, not the elapsed hours
+		{"daily across spring forward", "FREQ=DAILY",
+			at(newYork, 2025, time.March, 8, 9, 0),
+			[]time.Time{at(newYork, 2025, time.March, 9, 9, 0), at(newYork, 2025, time.March, 10, 9, 0)}},
+		{"daily across fall back", "FREQ=DAILY",
+			at(newYork, 2025, time.November, 1, 9, 0),
+			[]time.Time{at(newYork, 2025, time.November, 2, 9, 0), at(newYork, 2025, time.November, 3, 9, 0)}},
+		{"weekly across spring forward", "FREQ=WEEKLY",
+			at(newYork, 2025, time.March, 3, 18, 30),
+			[]time.Time{at(newYork, 2025, time.March, 10, 18, 30)}},
+		{"monthly across fall back", "FREQ=MONTHLY",
+			at(newYork, 2025, time.October, 15, 8, 0),
+			[]time.Time{at(newYork, 2025, time.November, 15, 8, 0)}},
+		{"a time skipped by spring forward moves an hour on", "FREQ=DAILY",
+			at(newYork, 2025, time.March, 8, 2, 30),
+			[]time.Time{at(newYork, 2025, time.March, 9, 3, 30)}},
+
+		// end of the series
+		{"count stops the series", "FREQ=DAILY;COUNT=3",
+			utc(2025, time.June, 1),
+			[]time.Time{utc(2025, time.June, 2), utc(2025, time.June, 3)}},
+		{"until is inclusive", "FREQ=DAILY;UNTIL=20250603T090000Z",
+			utc(2025, time.June, 1),
+			[]time.Time{utc(2025, time.June, 2), utc(2025, time.June, 3)}},
+		{"until date covers the whole day", "FREQ=DAILY;UNTIL=20250603",
+			at(time.UTC, 2025, time.June, 1, 23, 0),
+			[]time.Time{at(time.UTC, 2025, time.June, 2, 23, 0), at(time.UTC, 2025, time.June, 3, 23, 0)}},
+		{"until date in the timezone of the occurrences", "FREQ=DAILY;UNTIL=20251102",
+			at(newYork, 2025, time.October, 31, 22, 0),
+			[]time.Time{at(newYork, 2025, time.November, 1, 22, 0), at(newYork, 2025, time.November, 2, 22, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the anchor is occurrence 1
+			got := rule.Occurrences(tt.prev, 1, len(tt.want)+1)
+			if len(got) > len(tt.want) && (rule.Count > 0 || rule.Until != nil) {
+				t.Fatalf("got %d occurrences, want %d: %v", len(got), len(tt.want), got)
+			}
+			if len(got) < len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if !got[i].Equal(want) || got[i].Location() != want.Location() {
+					t.Errorf("occurrence %d = %v, want %v", i+2, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		invalid bool
+	}{
+		{rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rule: "rrule:freq=weekly;byday=th,mo;interval=2", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{rule: "FREQ=WEEKLY;BYDAY=SU,SA", want: "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{rule: "FREQ=MONTHLY;UNTIL=20251231", want: "FREQ=MONTHLY;UNTIL=20251231"},
+		{rule: "FREQ=YEARLY;COUNT=5", want: "FREQ=YEARLY;COUNT=5"},
+		{rule: "", invalid: true},
+		{rule: "INTERVAL=2", invalid: true},
+		{rule: "FREQ=HOURLY", invalid: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", invalid: true},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", invalid: true},
+		{rule: "FREQ=MONTHLY;BYDAY=MO", invalid: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", invalid: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20251231", invalid: true},
+		{rule: "FREQ=DAILY;UNTIL=2025-12-31", invalid: true},
+		{rule: "FREQ=DAILY;BYMONTH=1", invalid: true},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.rule, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package reminder fires the reminders of todos whose remind_at has passed.
+//
+// A Scheduler polls the store in the background and hands every due reminder
+// to a Notifier, which decides how it is delivered (log, email, push, ...).
+package reminder
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// Event is one fired reminder
+type Event struct {
+	Todo    models.Todo
+	FiredAt time.Time
+}
+
+// Notifier delivers reminder events. A returned error leaves the reminder
+// pending, so it is retried on the next poll.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// LogNotifier is the default Notifier, it only writes reminders to the log
+type LogNotifier struct{}
+
+// Notify logs the reminder
+func (LogNotifier) Notify(ctx context.Context, event Event) error {
+	todo := event.Todo
+	due := "no due date"
+	if todo.DueAt != nil {
+		due = "due " + todo.DueAt.Format(time.RFC3339)
+	}
+	log.Printf("⏰ Reminder: todo %d %q of user %d in workspace %d (%s)", todo.ID, todo.Text, todo.UserID, todo.WorkspaceID, due)
+	return nil
+}
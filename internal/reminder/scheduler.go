@@ -0,0 +1,75 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"test_mekari/internal/repository"
+)
+
+// Scheduler polls a ReminderStore and fires every reminder that came due
+type Scheduler struct {
+	store    repository.ReminderStore
+	notifier Notifier
+	interval time.Duration
+	// now is the clock reminders come due by
+	now func() time.Time
+}
+
+// NewScheduler creates a scheduler that checks for due reminders every interval
+func NewScheduler(store repository.ReminderStore, notifier Notifier, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		notifier: notifier,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run fires due reminders until ctx is cancelled. Reminders that came due while
+// the server was down fire on the first poll, right after start.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Tick(ctx); err != nil {
+			log.Printf("⚠️  Reminders: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick fires every reminder that is due now. A reminder is marked as fired only
+// after the notifier accepted it, so delivery is at least once.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	now := s.now()
+	todos, err := s.store.DueReminders(now)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for _, todo := range todos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.notifier.Notify(ctx, Event{Todo: todo, FiredAt: now}); err != nil {
+			failed = errors.Join(failed, err)
+			continue
+		}
+		// The todo may have been deleted meanwhile, there is nothing left to remind of then
+		err := s.store.MarkReminded(todo.ID, *todo.RemindAt, now)
+		if err != nil && !errors.Is(err, repository.ErrTodoNotFound) {
+			failed = errors.Join(failed, err)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,101 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// recorder is a Notifier keeping the IDs of the todos it was told about, and
+// failing while fail is set
+type recorder struct {
+	fired []int
+	fail  error
+}
+
+func (r *recorder) Notify(ctx context.Context, event Event) error {
+	if r.fail != nil {
+		return r.fail
+	}
+	r.fired = append(r.fired, event.Todo.ID)
+	return nil
+}
+
+func TestSchedulerTick(t *testing.T) {
+	store, err := repository.NewTodoRepository(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	now := start
+	notifier := &recorder{}
+	scheduler := NewScheduler(store, notifier, time.Minute)
+	scheduler.now = func() time.Time { return now }
+
+	remindAt := func(text string, at time.Time) *models.Todo {
+		t.Helper()
+		todo, err := store.Create(&models.Todo{WorkspaceID: repository.DefaultWorkspaceID, Text: text, UserID: 1, RemindAt: &at, CreatedAt: start, UpdatedAt: start})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	// tick advances the clock to at and returns what fired since the last tick
+	tick := func(at time.Time) string {
+		t.Helper()
+		now = at
+		notifier.fired = nil
+		if err := scheduler.Tick(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(notifier.fired)
+	}
+
+	due := remindAt("due at ten", start.Add(time.Hour))
+	completed := remindAt("completed first", start.Add(time.Hour))
+	deleted := remindAt("deleted first", start.Add(time.Hour))
+	later := remindAt("due at noon", start.Add(3*time.Hour))
+
+	if got := tick(start.Add(59 * time.Minute)); got != "[]" {
+		t.Errorf("before any reminder is due, fired %s", got)
+	}
+
+	completed.Completed = true
+	if _, err := store.Update(completed); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(repository.DefaultWorkspaceID, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the open todo fires, exactly once
+	if got, want := tick(start.Add(time.Hour)), fmt.Sprint([]int{due.ID}); got != want {
+		t.Errorf("at ten fired %s, want %s", got, want)
+	}
+	if got := tick(start.Add(2 * time.Hour)); got != "[]" {
+		t.Errorf("an hour later fired %s again, want nothing", got)
+	}
+	stored, err := store.FindByID(repository.DefaultWorkspaceID, due.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RemindedAt == nil || !stored.RemindedAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("reminded_at = %v, want the time it fired", stored.RemindedAt)
+	}
+
+	// A failed notification leaves the reminder for the next tick
+	notifier.fail = errors.New("mail server down")
+	now = start.Add(3 * time.Hour)
+	if err := scheduler.Tick(context.Background()); err == nil {
+		t.Error("Tick with a failing notifier returned no error")
+	}
+	notifier.fail = nil
+	if got, want := tick(start.Add(3*time.Hour+time.Minute)), fmt.Sprint([]int{later.ID}); got != want {
+		t.Errorf("after the failure fired %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"test_mekari/internal/models"
+)
+
+// GetAttachments returns the attachments of a todo ordered by ID
+func (r *TodoRepository) GetAttachments(workspaceID, todoID int) ([]models.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.todos.inWorkspace(workspaceID, todoID) == nil {
+		return nil, ErrTodoNotFound
+	}
+	return r.sortedAttachments(func(a models.Attachment) bool { return a.TodoID == todoID }), nil
+}
+
+// GetAttachment retrieves an attachment by ID within a workspace
+func (r *TodoRepository) GetAttachment(workspaceID, id int) (*models.Attachment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if attachment, exists := r.attachments[id]; exists && attachment.WorkspaceID == workspaceID {
+		return &attachment, nil
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+// CreateAttachment stores the metadata of a new attachment on a.TodoID
+func (r *TodoRepository) CreateAttachment(a *models.Attachment) (*models.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.todos.inWorkspace(a.WorkspaceID, a.TodoID) == nil {
+		return nil, ErrTodoNotFound
+	}
+	if _, exists := r.users[a.UploaderID]; !exists {
+		return nil, ErrUserNotFound
+	}
+
+	a.ID = r.nextAttachmentID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityAttachment, ID: a.ID, Attachment: a}); err != nil {
+		return nil, err
+	}
+
+	attachmentCopy := *a
+	return &attachmentCopy, nil
+}
+
+// DeleteAttachment removes an attachment; its blob is left for the sweep
+func (r *TodoRepository) DeleteAttachment(workspaceID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attachment, exists := r.attachments[id]; !exists || attachment.WorkspaceID != workspaceID {
+		return ErrAttachmentNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityAttachment, ID: id})
+}
+
+// BlobKeys returns the set of blob keys referenced by any attachment
+func (r *TodoRepository) BlobKeys() (map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make(map[string]bool, len(r.attachments))
+	for _, attachment := range r.attachments {
+		keys[attachment.BlobKey] = true
+	}
+	return keys, nil
+}
+
+// applyAttachment applies an attachment record (lock must be held)
+func (r *TodoRepository) applyAttachment(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.attachments[rec.ID] = *rec.Attachment
+		if rec.ID >= r.nextAttachmentID {
+			r.nextAttachmentID = rec.ID + 1
+		}
+	case opDelete:
+		if _, exists := r.attachments[rec.ID]; !exists {
+			return ErrAttachmentNotFound
+		}
+		delete(r.attachments, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// sortedAttachments returns the attachments matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedAttachments(keep func(models.Attachment) bool) []models.Attachment {
+	attachments := make([]models.Attachment, 0)
+	for _, attachment := range r.attachments {
+		if keep(attachment) {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].ID < attachments[j].ID })
+	return attachments
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"test_mekari/internal/models"
+)
+
+// GetComments returns the comments on a todo ordered by ID
+func (r *TodoRepository) GetComments(workspaceID, todoID int) ([]models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.todos.inWorkspace(workspaceID, todoID) == nil {
+		return nil, ErrTodoNotFound
+	}
+	return r.sortedComments(func(comment models.Comment) bool { return comment.TodoID == todoID }), nil
+}
+
+// GetComment retrieves a comment by ID within a workspace
+func (r *TodoRepository) GetComment(workspaceID, id int) (*models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if comment, exists := r.comments[id]; exists && comment.WorkspaceID == workspaceID {
+		return &comment, nil
+	}
+	return nil, ErrCommentNotFound
+}
+
+// CreateComment stores a new comment on comment.TodoID
+func (r *TodoRepository) CreateComment(comment *models.Comment) (*models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.todos.inWorkspace(comment.WorkspaceID, comment.TodoID) == nil {
+		return nil, ErrTodoNotFound
+	}
+	if comment.ReplyToID != 0 {
+		if parent, exists := r.comments[comment.ReplyToID]; !exists || parent.TodoID != comment.TodoID || parent.WorkspaceID != comment.WorkspaceID {
+			return nil, ErrReplyNotFound
+		}
+	}
+	for _, userID := range append([]int{comment.AuthorID}, comment.Mentions...) {
+		if _, exists := r.users[userID]; !exists {
+			return nil, ErrUserNotFound
+		}
+	}
+	comment.Mentions = normalizeIDs(comment.Mentions)
+
+	comment.ID = r.nextCommentID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityComment, ID: comment.ID, Comment: comment}); err != nil {
+		return nil, err
+	}
+
+	commentCopy := *comment
+	return &commentCopy, nil
+}
+
+// UpdateComment changes the body, mentions and edit time of a comment
+func (r *TodoRepository) UpdateComment(comment *models.Comment) (*models.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.comments[comment.ID]
+	if !exists || existing.WorkspaceID != comment.WorkspaceID {
+		return nil, ErrCommentNotFound
+	}
+	for _, userID := range comment.Mentions {
+		if _, exists := r.users[userID]; !exists {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	updated := existing
+	updated.Body = comment.Body
+	updated.Mentions = normalizeIDs(comment.Mentions)
+	updated.EditedAt = comment.EditedAt
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityComment, ID: updated.ID, Comment: &updated}); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteComment removes a comment; its replies stay without a comment to reply to
+func (r *TodoRepository) DeleteComment(workspaceID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if comment, exists := r.comments[id]; !exists || comment.WorkspaceID != workspaceID {
+		return ErrCommentNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityComment, ID: id})
+}
+
+// CountComments returns the number of comments on each of todoIDs within a workspace
+func (r *TodoRepository) CountComments(workspaceID int, todoIDs []int) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for _, id := range todoIDs {
+		if count := r.commentCounts[id]; count > 0 && r.todos.inWorkspace(workspaceID, id) != nil {
+			counts[id] = count
+		}
+	}
+	return counts, nil
+}
+
+// GetAllComments returns the comments on every todo of a workspace ordered by ID
+func (r *TodoRepository) GetAllComments(workspaceID int) ([]models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedComments(func(comment models.Comment) bool { return comment.WorkspaceID == workspaceID }), nil
+}
+
+// applyComment applies a comment record (lock must be held)
+func (r *TodoRepository) applyComment(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		comment := *rec.Comment
+		comment.Mentions = normalizeIDs(comment.Mentions)
+		r.putComment(comment)
+		if rec.ID >= r.nextCommentID {
+			r.nextCommentID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.comments[rec.ID]; !exists {
+			return ErrCommentNotFound
+		}
+		comment := *rec.Comment
+		comment.Mentions = normalizeIDs(comment.Mentions)
+		r.comments[rec.ID] = comment
+	case opDelete:
+		if _, exists := r.comments[rec.ID]; !exists {
+			return ErrCommentNotFound
+		}
+		for id, reply := range r.comments {
+			if reply.ReplyToID == rec.ID {
+				reply.ReplyToID = 0
+				r.comments[id] = reply
+			}
+		}
+		r.deleteComment(rec.ID)
+		// Notifications about the comment stay, without it
+		for id, n := range r.notifications {
+			if n.CommentID == rec.ID {
+				n.CommentID = 0
+				r.notifications[id] = n
+			}
+		}
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// putComment stores a new comment and counts it on its todo (lock must be held)
+func (r *TodoRepository) putComment(comment models.Comment) {
+	if _, exists := r.comments[comment.ID]; !exists {
+		r.commentCounts[comment.TodoID]++
+	}
+	r.comments[comment.ID] = comment
+}
+
+// deleteComment removes a comment and its count on its todo (lock must be held)
+func (r *TodoRepository) deleteComment(id int) {
+	comment, exists := r.comments[id]
+	if !exists {
+		return
+	}
+	delete(r.comments, id)
+	if r.commentCounts[comment.TodoID]--; r.commentCounts[comment.TodoID] <= 0 {
+		delete(r.commentCounts, comment.TodoID)
+	}
+}
+
+// sortedComments returns the comments matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedComments(keep func(models.Comment) bool) []models.Comment {
+	comments := make([]models.Comment, 0)
+	for _, comment := range r.comments {
+		if keep(comment) {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments
+}
@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+const (
+	journalFileName  = "journal.log"
+	snapshotFileName = "snapshot.json"
+
+	// Every frame is [4 byte payload length][4 byte CRC32 of payload][payload]
+	frameHeaderSize = 8
+	// Upper bound for a single record, anything larger is treated as corruption
+	maxRecordSize = 16 << 20
+)
+
+// ErrJournalBroken is returned for every append after a failed one whose torn
+// bytes could not be removed from the journal again
+var ErrJournalBroken = errors.New("journal refuses writes after a failed append")
+
+// Journal operations
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+	// opSave stores every todo of Todos, creating the ones that do not exist yet
+	opSave = "save"
+	// opReadAll marks every unread notification of user ID read at ReadAt
+	opReadAll = "read_all"
+	// opPrune deletes the deliveries completed before Before
+	opPrune = "prune"
+)
+
+// Journal entities
+const (
+	entityTodo      = "todo"
+	entityUser      = "user"
+	entityWorkspace = "workspace"
+	entityMember    = "member"
+	entityList      = "list"
+	entityLabel     = "label"
+	entityComment   = "comment"
+
+	entityNotification = "notification"
+	entityAttachment   = "attachment"
+	entityWebhook      = "webhook"
+	entityDelivery     = "delivery"
+	entityView         = "view"
+)
+
+// journalRecord is one mutation appended to the write-ahead journal
+type journalRecord struct {
+	Seq         uint64             `json:"seq"`
+	Op          string             `json:"op"`
+	Entity      string             `json:"entity"`
+	ID          int                `json:"id"`
+	Todo        *models.Todo       `json:"todo,omitempty"`
+	Todos       []models.Todo      `json:"todos,omitempty"`
+	Subtasks    bool               `json:"subtasks,omitempty"` // a todo delete also removes its subtasks
+	User        *storedUser        `json:"user,omitempty"`
+	Disposition *TodoDisposition   `json:"disposition,omitempty"`
+	Workspace   *models.Workspace  `json:"workspace,omitempty"`
+	Membership  *models.Membership `json:"membership,omitempty"`
+	List        *models.List       `json:"list,omitempty"`
+	Label       *models.Label      `json:"label,omitempty"`
+	Comment     *models.Comment    `json:"comment,omitempty"`
+
+	Notification *models.Notification    `json:"notification,omitempty"`
+	ReadAt       *time.Time              `json:"read_at,omitempty"`
+	Attachment   *models.Attachment      `json:"attachment,omitempty"`
+	Webhook      *models.Webhook         `json:"webhook,omitempty"`
+	Delivery     *models.WebhookDelivery `json:"delivery,omitempty"`
+	Before       *time.Time              `json:"before,omitempty"`
+	View         *models.View            `json:"view,omitempty"`
+}
+
+// snapshot is the compacted state of the repository up to (and including) Seq
+type snapshot struct {
+	Seq        uint64        `json:"seq"`
+	NextID     int           `json:"next_id"`
+	Todos      []models.Todo `json:"todos"`
+	NextUserID int           `json:"next_user_id,omitempty"`
+	Users      []storedUser  `json:"users,omitempty"`
+
+	NextWorkspaceID int                 `json:"next_workspace_id,omitempty"`
+	Workspaces      []models.Workspace  `json:"workspaces,omitempty"`
+	Members         []models.Membership `json:"members,omitempty"`
+
+	NextListID int           `json:"next_list_id,omitempty"`
+	Lists      []models.List `json:"lists,omitempty"`
+
+	NextLabelID int            `json:"next_label_id,omitempty"`
+	Labels      []models.Label `json:"labels,omitempty"`
+
+	NextCommentID int              `json:"next_comment_id,omitempty"`
+	Comments      []models.Comment `json:"comments,omitempty"`
+
+	NextNotificationID int                   `json:"next_notification_id,omitempty"`
+	Notifications      []models.Notification `json:"notifications,omitempty"`
+
+	NextAttachmentID int                 `json:"next_attachment_id,omitempty"`
+	Attachments      []models.Attachment `json:"attachments,omitempty"`
+
+	NextWebhookID  int                      `json:"next_webhook_id,omitempty"`
+	Webhooks       []models.Webhook         `json:"webhooks,omitempty"`
+	NextDeliveryID int                      `json:"next_delivery_id,omitempty"`
+	Deliveries     []models.WebhookDelivery `json:"deliveries,omitempty"`
+
+	NextViewID int           `json:"next_view_id,omitempty"`
+	Views      []models.View `json:"views,omitempty"`
+}
+
+// storedUser is the on-disk form of a user. models.User hides PasswordHash
+// from JSON so it is never sent to clients, but the journal must keep it.
+type storedUser struct {
+	models.User
+	PasswordHash string `json:"password_hash"`
+}
+
+func newStoredUser(user models.User) storedUser {
+	return storedUser{User: user, PasswordHash: user.PasswordHash}
+}
+
+func (s storedUser) toUser() models.User {
+	user := s.User
+	user.PasswordHash = s.PasswordHash
+	return user
+}
+
+// Journal is an append-only write-ahead log plus periodic snapshots stored in one directory.
+// It is not safe for concurrent use; TodoRepository serializes access with its mutex.
+type Journal struct {
+	dir           string
+	file          *os.File
+	seq           uint64
+	snapshotEvery int
+	sinceSnapshot int
+	// broken is why a failed append could not be rolled back, nil while the
+	// journal is intact
+	broken error
+}
+
+// OpenJournal opens (or creates) the journal stored in dir.
+// A snapshot is written every snapshotEvery records; 0 disables periodic snapshots.
+func OpenJournal(dir string, snapshotEvery int) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+
+	return &Journal{
+		dir:           dir,
+		file:          file,
+		snapshotEvery: snapshotEvery,
+	}, nil
+}
+
+// load reads the latest snapshot and every journal record written after it.
+// A torn or corrupt tail (e.g. a crash in the middle of a write) is truncated away.
+func (j *Journal) load() (*snapshot, []journalRecord, error) {
+	snap := &snapshot{NextID: 1}
+	data, err := os.ReadFile(filepath.Join(j.dir, snapshotFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, snap); err != nil {
+			return nil, nil, fmt.Errorf("decode snapshot: %w", err)
+		}
+	}
+	j.seq = snap.Seq
+
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	records := make([]journalRecord, 0)
+	reader := bufio.NewReader(j.file)
+	var offset int64
+	for {
+		rec, size, err := readFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("⚠️  Journal: truncating torn record at offset %d: %v", offset, err)
+			if err := j.file.Truncate(offset); err != nil {
+				return nil, nil, fmt.Errorf("truncate journal: %w", err)
+			}
+			break
+		}
+		offset += size
+
+		// Records already folded into the snapshot are skipped
+		if rec.Seq <= snap.Seq {
+			continue
+		}
+		records = append(records, rec)
+		j.seq = rec.Seq
+		j.sinceSnapshot++
+	}
+
+	if _, err := j.file.Seek(offset, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	return snap, records, nil
+}
+
+// readFrame decodes one frame and returns its record and size on disk.
+// It returns io.EOF only on a clean frame boundary.
+func readFrame(reader *bufio.Reader) (journalRecord, int64, error) {
+	var rec journalRecord
+
+	header := make([]byte, frameHeaderSize)
+	n, err := io.ReadFull(reader, header)
+	if err == io.EOF {
+		return rec, 0, io.EOF
+	}
+	if err != nil {
+		return rec, 0, fmt.Errorf("short header (%d bytes)", n)
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if length == 0 || length > maxRecordSize {
+		return rec, 0, fmt.Errorf("invalid record length %d", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return rec, 0, errors.New("short payload")
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return rec, 0, errors.New("checksum mismatch")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, 0, fmt.Errorf("decode record: %w", err)
+	}
+	return rec, int64(frameHeaderSize) + int64(length), nil
+}
+
+// append writes rec to the journal and fsyncs it before returning. A failed
+// write or sync is cut off the file again, so no torn frame is left for a
+// replay to stop at before later records; if that fails too, the journal is
+// broken and refuses every later append.
+func (j *Journal) append(rec journalRecord) error {
+	if j.broken != nil {
+		return fmt.Errorf("%w: %v", ErrJournalBroken, j.broken)
+	}
+	offset, err := j.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("append journal: %w", err)
+	}
+	rec.Seq = j.seq + 1
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	copy(frame[frameHeaderSize:], payload)
+
+	if _, err := j.file.Write(frame); err != nil {
+		return j.rollback(offset, fmt.Errorf("append journal: %w", err))
+	}
+	if err := j.file.Sync(); err != nil {
+		return j.rollback(offset, fmt.Errorf("sync journal: %w", err))
+	}
+
+	j.seq = rec.Seq
+	j.sinceSnapshot++
+	return nil
+}
+
+// rollback cuts the journal back to offset, where the append that failed with
+// err started, and returns err. When the cut fails the journal is broken.
+func (j *Journal) rollback(offset int64, err error) error {
+	cutErr := j.file.Truncate(offset)
+	if cutErr == nil {
+		_, cutErr = j.file.Seek(offset, io.SeekStart)
+	}
+	if cutErr != nil {
+		j.broken = fmt.Errorf("%v, then removing it failed: %v", err, cutErr)
+		log.Printf("❌ Journal: refusing further writes: %v", j.broken)
+		return fmt.Errorf("%w: %v", ErrJournalBroken, j.broken)
+	}
+	return err
+}
+
+// snapshotDue reports whether enough records were appended since the last snapshot
+func (j *Journal) snapshotDue() bool {
+	return j.snapshotEvery > 0 && j.sinceSnapshot >= j.snapshotEvery
+}
+
+// writeSnapshot atomically replaces the snapshot with snap and compacts the journal
+func (j *Journal) writeSnapshot(snap snapshot) error {
+	snap.Seq = j.seq
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename, so a crash never leaves a half-written snapshot
+	tmpPath := filepath.Join(j.dir, snapshotFileName+".tmp")
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(j.dir, snapshotFileName)); err != nil {
+		return fmt.Errorf("install snapshot: %w", err)
+	}
+
+	// Records up to snap.Seq are now in the snapshot. If we crash before the
+	// truncate, load() skips them by sequence number.
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("compact journal: %w", err)
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.sinceSnapshot = 0
+	return nil
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openTestJournal opens and loads the journal in dir
+func openTestJournal(t *testing.T, dir string) (*Journal, []journalRecord) {
+	t.Helper()
+	journal, err := OpenJournal(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, records, err := journal.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return journal, records
+}
+
+// TestJournalRollback checks the torn bytes of a failed append are cut off
+// again, so a record appended after it survives a replay
+func TestJournalRollback(t *testing.T) {
+	dir := t.TempDir()
+	journal, _ := openTestJournal(t, dir)
+	if err := journal.append(journalRecord{Op: opDelete, Entity: entityTodo, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Half a frame made it to the file before the write failed
+	offset, err := journal.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := journal.file.Write([]byte{0, 0, 0, 42, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("disk full")
+	if err := journal.rollback(offset, failed); err != failed {
+		t.Fatalf("rollback = %v, want the append's error", err)
+	}
+
+	if err := journal.append(journalRecord{Op: opDelete, Entity: entityTodo, ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	journal, records := openTestJournal(t, dir)
+	defer journal.Close()
+	if len(records) != 2 || records[1].ID != 2 {
+		t.Errorf("replayed %d records, want both appends", len(records))
+	}
+}
+
+// TestJournalBroken checks a journal whose failed append cannot be cut off
+// refuses every later append
+func TestJournalBroken(t *testing.T) {
+	dir := t.TempDir()
+	journal, _ := openTestJournal(t, dir)
+	defer journal.Close()
+	if err := journal.append(journalRecord{Op: opDelete, Entity: entityTodo, ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A read-only descriptor fails the write and the truncate alike
+	writable := journal.file
+	readOnly, err := os.Open(filepath.Join(dir, journalFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Close()
+	journal.file = readOnly
+	if err := journal.append(journalRecord{Op: opDelete, Entity: entityTodo, ID: 2}); !errors.Is(err, ErrJournalBroken) {
+		t.Fatalf("failed append = %v, want ErrJournalBroken", err)
+	}
+
+	journal.file = writable
+	if err := journal.append(journalRecord{Op: opDelete, Entity: entityTodo, ID: 3}); !errors.Is(err, ErrJournalBroken) {
+		t.Errorf("append after a broken one = %v, want ErrJournalBroken", err)
+	}
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"test_mekari/internal/models"
+)
+
+// GetLabels returns the labels of a workspace ordered by ID
+func (r *TodoRepository) GetLabels(workspaceID int) ([]models.Label, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedLabels(func(label models.Label) bool { return label.WorkspaceID == workspaceID }), nil
+}
+
+// GetLabel retrieves a label by ID within a workspace
+func (r *TodoRepository) GetLabel(workspaceID, id int) (*models.Label, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if label, exists := r.labels[id]; exists && label.WorkspaceID == workspaceID {
+		return &label, nil
+	}
+	return nil, ErrLabelNotFound
+}
+
+// CreateLabel stores a new label in label.WorkspaceID
+func (r *TodoRepository) CreateLabel(label *models.Label) (*models.Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[label.WorkspaceID]; !exists {
+		return nil, ErrWorkspaceNotFound
+	}
+	if r.labelNameTaken(*label) {
+		return nil, ErrLabelNameTaken
+	}
+
+	label.ID = r.nextLabelID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityLabel, ID: label.ID, Label: label}); err != nil {
+		return nil, err
+	}
+
+	labelCopy := *label
+	return &labelCopy, nil
+}
+
+// UpdateLabel replaces an existing label
+func (r *TodoRepository) UpdateLabel(label *models.Label) (*models.Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.labels[label.ID]; !exists || existing.WorkspaceID != label.WorkspaceID {
+		return nil, ErrLabelNotFound
+	}
+	if r.labelNameTaken(*label) {
+		return nil, ErrLabelNameTaken
+	}
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityLabel, ID: label.ID, Label: label}); err != nil {
+		return nil, err
+	}
+
+	labelCopy := *label
+	return &labelCopy, nil
+}
+
+// DeleteLabel removes a label and detaches it from its todos in the same journal record
+func (r *TodoRepository) DeleteLabel(workspaceID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if label, exists := r.labels[id]; !exists || label.WorkspaceID != workspaceID {
+		return ErrLabelNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityLabel, ID: id})
+}
+
+// applyLabel applies a label record (lock must be held)
+func (r *TodoRepository) applyLabel(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.labels[rec.ID] = *rec.Label
+		if rec.ID >= r.nextLabelID {
+			r.nextLabelID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.labels[rec.ID]; !exists {
+			return ErrLabelNotFound
+		}
+		r.labels[rec.ID] = *rec.Label
+	case opDelete:
+		if _, exists := r.labels[rec.ID]; !exists {
+			return ErrLabelNotFound
+		}
+		for _, id := range r.todos.ids(r.labels[rec.ID].WorkspaceID) {
+			if todo := *r.todos.get(id); containsID(todo.LabelIDs, rec.ID) {
+				todo.LabelIDs = withoutID(todo.LabelIDs, rec.ID)
+				r.todos.put(todo)
+			}
+		}
+		delete(r.labels, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// labelsInWorkspace reports whether every id is a label of workspaceID (lock must be held)
+func (r *TodoRepository) labelsInWorkspace(workspaceID int, ids []int) bool {
+	for _, id := range ids {
+		if label, exists := r.labels[id]; !exists || label.WorkspaceID != workspaceID {
+			return false
+		}
+	}
+	return true
+}
+
+// labelNameTaken reports whether another label of the workspace has the same name (lock must be held)
+func (r *TodoRepository) labelNameTaken(label models.Label) bool {
+	for _, other := range r.labels {
+		if other.ID != label.ID && other.WorkspaceID == label.WorkspaceID && strings.EqualFold(other.Name, label.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedLabels returns the labels matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedLabels(keep func(models.Label) bool) []models.Label {
+	labels := make([]models.Label, 0)
+	for _, label := range r.labels {
+		if keep(label) {
+			labels = append(labels, label)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+	return labels
+}
+
+// withoutID returns ids without id. ID slices are shared with copies
+// handed out to callers, so they are replaced, never modified in place.
+func withoutID(ids []int, id int) []int {
+	kept := make([]int, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	if len(kept) == len(ids) {
+		return ids
+	}
+	return kept
+}
+
+// normalizeIDs returns a sorted, de-duplicated copy of ids, so the caller's
+// slice is never shared with the repository
+func normalizeIDs(ids []int) []int {
+	normalized := append(make([]int, 0, len(ids)), ids...)
+	sort.Ints(normalized)
+	// Sorted, every duplicate follows the ID it repeats
+	n := 0
+	for i, id := range normalized {
+		if i == 0 || id != normalized[n-1] {
+			normalized[n] = id
+			n++
+		}
+	}
+	return normalized[:n]
+}
+
+func containsID(ids []int, id int) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
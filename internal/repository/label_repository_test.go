@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeIDs(t *testing.T) {
+	for _, row := range []struct {
+		ids  []int
+		want string
+	}{
+		{nil, "[]"},
+		{[]int{}, "[]"},
+		{[]int{3}, "[3]"},
+		{[]int{3, 1, 2}, "[1 2 3]"},
+		{[]int{2, 2, 2}, "[2]"},
+		{[]int{5, 1, 5, 3, 1, 5}, "[1 3 5]"},
+	} {
+		given := fmt.Sprint(row.ids)
+		got := normalizeIDs(row.ids)
+		if got == nil || fmt.Sprint(got) != row.want {
+			t.Errorf("normalizeIDs(%v) = %#v, want %s", row.ids, got, row.want)
+		}
+		if fmt.Sprint(row.ids) != given {
+			t.Errorf("normalizeIDs changed its argument to %v", row.ids)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"test_mekari/internal/models"
+)
+
+// GetLists returns the lists of a workspace ordered by ID
+func (r *TodoRepository) GetLists(workspaceID int) ([]models.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedLists(func(list models.List) bool { return list.WorkspaceID == workspaceID }), nil
+}
+
+// GetList retrieves a list by ID within a workspace
+func (r *TodoRepository) GetList(workspaceID, id int) (*models.List, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if list, exists := r.lists[id]; exists && list.WorkspaceID == workspaceID {
+		return &list, nil
+	}
+	return nil, ErrListNotFound
+}
+
+// CreateList stores a new list in list.WorkspaceID
+func (r *TodoRepository) CreateList(list *models.List) (*models.List, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[list.WorkspaceID]; !exists {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	list.ID = r.nextListID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityList, ID: list.ID, List: list}); err != nil {
+		return nil, err
+	}
+
+	listCopy := *list
+	return &listCopy, nil
+}
+
+// UpdateList replaces an existing list
+func (r *TodoRepository) UpdateList(list *models.List) (*models.List, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.lists[list.ID]; !exists || existing.WorkspaceID != list.WorkspaceID {
+		return nil, ErrListNotFound
+	}
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityList, ID: list.ID, List: list}); err != nil {
+		return nil, err
+	}
+
+	listCopy := *list
+	return &listCopy, nil
+}
+
+// DeleteList removes a list; its todos are kept without a list
+func (r *TodoRepository) DeleteList(workspaceID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if list, exists := r.lists[id]; !exists || list.WorkspaceID != workspaceID {
+		return ErrListNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityList, ID: id})
+}
+
+// applyList applies a list record (lock must be held)
+func (r *TodoRepository) applyList(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.lists[rec.ID] = *rec.List
+		if rec.ID >= r.nextListID {
+			r.nextListID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.lists[rec.ID]; !exists {
+			return ErrListNotFound
+		}
+		r.lists[rec.ID] = *rec.List
+	case opDelete:
+		if _, exists := r.lists[rec.ID]; !exists {
+			return ErrListNotFound
+		}
+		for _, id := range r.todos.ids(r.lists[rec.ID].WorkspaceID) {
+			if todo := *r.todos.get(id); todo.ListID == rec.ID {
+				todo.ListID = 0
+				r.todos.put(todo)
+			}
+		}
+		delete(r.lists, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// listInWorkspace reports whether id is a list of workspaceID; 0 (no list) always is (lock must be held)
+func (r *TodoRepository) listInWorkspace(workspaceID, id int) bool {
+	if id == 0 {
+		return true
+	}
+	list, exists := r.lists[id]
+	return exists && list.WorkspaceID == workspaceID
+}
+
+// sortedLists returns the lists matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedLists(keep func(models.List) bool) []models.List {
+	lists := make([]models.List, 0)
+	for _, list := range r.lists {
+		if keep(list) {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID < lists[j].ID })
+	return lists
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// GetNotifications returns the notifications of a user, newest first
+func (r *TodoRepository) GetNotifications(userID int, unreadOnly bool) ([]models.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notifications := make([]models.Notification, 0)
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, n)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+	return notifications, nil
+}
+
+// CreateNotification stores a new notification in the inbox of n.UserID
+func (r *TodoRepository) CreateNotification(n *models.Notification) (*models.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, userID := range []int{n.UserID, n.ActorID} {
+		if _, exists := r.users[userID]; !exists {
+			return nil, ErrUserNotFound
+		}
+	}
+	if r.todos.inWorkspace(n.WorkspaceID, n.TodoID) == nil {
+		return nil, ErrTodoNotFound
+	}
+	if n.CommentID != 0 {
+		if comment, exists := r.comments[n.CommentID]; !exists || comment.TodoID != n.TodoID || comment.WorkspaceID != n.WorkspaceID {
+			return nil, ErrCommentNotFound
+		}
+	}
+
+	n.ID = r.nextNotificationID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityNotification, ID: n.ID, Notification: n}); err != nil {
+		return nil, err
+	}
+
+	notificationCopy := *n
+	return &notificationCopy, nil
+}
+
+// MarkNotificationRead sets ReadAt of a notification of userID, unless it is read already
+func (r *TodoRepository) MarkNotificationRead(userID, id int, at time.Time) (*models.Notification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, exists := r.notifications[id]
+	if !exists || n.UserID != userID {
+		return nil, ErrNotificationNotFound
+	}
+	if n.ReadAt != nil {
+		return &n, nil
+	}
+
+	n.ReadAt = &at
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityNotification, ID: id, Notification: &n}); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user read in one record
+func (r *TodoRepository) MarkAllNotificationsRead(userID int, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unread := r.countUnread(userID)
+	if unread == 0 {
+		return 0, nil
+	}
+	if err := r.commit(journalRecord{Op: opReadAll, Entity: entityNotification, ID: userID, ReadAt: &at}); err != nil {
+		return 0, err
+	}
+	return unread, nil
+}
+
+// CountUnreadNotifications returns the number of unread notifications of a user
+func (r *TodoRepository) CountUnreadNotifications(userID int) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.countUnread(userID), nil
+}
+
+// countUnread counts the unread notifications of a user (lock must be held)
+func (r *TodoRepository) countUnread(userID int) int {
+	count := 0
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// applyNotification applies a notification record (lock must be held)
+func (r *TodoRepository) applyNotification(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.notifications[rec.ID] = *rec.Notification
+		if rec.ID >= r.nextNotificationID {
+			r.nextNotificationID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.notifications[rec.ID]; !exists {
+			return ErrNotificationNotFound
+		}
+		r.notifications[rec.ID] = *rec.Notification
+	case opReadAll:
+		for id, n := range r.notifications {
+			if n.UserID == rec.ID && n.ReadAt == nil {
+				n.ReadAt = rec.ReadAt
+				r.notifications[id] = n
+			}
+		}
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// sortedNotifications returns every notification ordered by ID (lock must be held)
+func (r *TodoRepository) sortedNotifications() []models.Notification {
+	notifications := make([]models.Notification, 0, len(r.notifications))
+	for _, n := range r.notifications {
+		notifications = append(notifications, n)
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID < notifications[j].ID })
+	return notifications
+}
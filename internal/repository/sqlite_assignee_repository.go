@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"database/sql"
+
+	"test_mekari/internal/models"
+)
+
+// setTodoAssignees replaces the assignees of a todo, returning
+// ErrAssigneeNotFound unless every assignee is a user
+func setTodoAssignees(tx *sql.Tx, todo *models.Todo) error {
+	for _, id := range todo.AssigneeIDs {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", id).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrAssigneeNotFound
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM todo_assignees WHERE todo_id = ?", todo.ID); err != nil {
+		return err
+	}
+	for _, id := range todo.AssigneeIDs {
+		if _, err := tx.Exec("INSERT INTO todo_assignees (todo_id, user_id) VALUES (?, ?)", todo.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTodoAssignees fills in AssigneeIDs of todos
+func loadTodoAssignees(db querier, todos []models.Todo) error {
+	return loadTodoLinks(db, todos, "SELECT todo_id, user_id FROM todo_assignees", func(todo *models.Todo) *[]int { return &todo.AssigneeIDs })
+}
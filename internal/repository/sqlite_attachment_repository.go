@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"test_mekari/internal/models"
+)
+
+const attachmentColumns = "id, workspace_id, todo_id, uploader_id, file_name, content_type, size, blob_key, created_at"
+
+// GetAttachments returns the attachments of a todo ordered by ID
+func (r *SQLiteRepository) GetAttachments(workspaceID, todoID int) ([]models.Attachment, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", workspaceID, todoID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrTodoNotFound
+	}
+
+	rows, err := r.db.Query("SELECT "+attachmentColumns+" FROM attachments WHERE workspace_id = ? AND todo_id = ? ORDER BY id", workspaceID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make([]models.Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachment retrieves an attachment by ID within a workspace
+func (r *SQLiteRepository) GetAttachment(workspaceID, id int) (*models.Attachment, error) {
+	attachment, err := scanAttachment(r.db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE workspace_id = ? AND id = ?", workspaceID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// CreateAttachment stores the metadata of a new attachment on a.TodoID
+func (r *SQLiteRepository) CreateAttachment(a *models.Attachment) (*models.Attachment, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", a.WorkspaceID, a.TodoID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrTodoNotFound
+		}
+		if err := usersExist(tx, a.UploaderID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO attachments (workspace_id, todo_id, uploader_id, file_name, content_type, size, blob_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			a.WorkspaceID, a.TodoID, a.UploaderID, a.FileName, a.ContentType, a.Size, a.BlobKey, formatTime(a.CreatedAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		a.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attachmentCopy := *a
+	return &attachmentCopy, nil
+}
+
+// DeleteAttachment removes an attachment; its blob is left for the sweep
+func (r *SQLiteRepository) DeleteAttachment(workspaceID, id int) error {
+	result, err := r.db.Exec("DELETE FROM attachments WHERE workspace_id = ? AND id = ?", workspaceID, id)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// BlobKeys returns the set of blob keys referenced by any attachment
+func (r *SQLiteRepository) BlobKeys() (map[string]bool, error) {
+	rows, err := r.db.Query("SELECT DISTINCT blob_key FROM attachments")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys[key] = true
+	}
+	return keys, rows.Err()
+}
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	var a models.Attachment
+	var uploaderID sql.NullInt64
+	var createdAt string
+	if err := row.Scan(&a.ID, &a.WorkspaceID, &a.TodoID, &uploaderID, &a.FileName, &a.ContentType, &a.Size, &a.BlobKey, &createdAt); err != nil {
+		return nil, err
+	}
+	a.UploaderID = int(uploaderID.Int64)
+	a.CreatedAt = parseTime(createdAt)
+	return &a, nil
+}
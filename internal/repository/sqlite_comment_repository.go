@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"test_mekari/internal/models"
+)
+
+const commentColumns = "id, workspace_id, todo_id, reply_to_id, author_id, body, created_at, edited_at"
+
+// GetComments returns the comments on a todo ordered by ID
+func (r *SQLiteRepository) GetComments(workspaceID, todoID int) ([]models.Comment, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", workspaceID, todoID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrTodoNotFound
+	}
+
+	return r.queryComments("SELECT "+commentColumns+" FROM comments WHERE workspace_id = ? AND todo_id = ? ORDER BY id", workspaceID, todoID)
+}
+
+// GetAllComments returns the comments on every todo of a workspace ordered by ID
+func (r *SQLiteRepository) GetAllComments(workspaceID int) ([]models.Comment, error) {
+	return r.queryComments("SELECT "+commentColumns+" FROM comments WHERE workspace_id = ? ORDER BY id", workspaceID)
+}
+
+// queryComments returns the comments a query selects, with their mentions
+func (r *SQLiteRepository) queryComments(query string, args ...any) ([]models.Comment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]models.Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadCommentMentions(comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetComment retrieves a comment by ID within a workspace
+func (r *SQLiteRepository) GetComment(workspaceID, id int) (*models.Comment, error) {
+	comment, err := scanComment(r.db.QueryRow("SELECT "+commentColumns+" FROM comments WHERE workspace_id = ? AND id = ?", workspaceID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comments := []models.Comment{*comment}
+	if err := r.loadCommentMentions(comments); err != nil {
+		return nil, err
+	}
+	return &comments[0], nil
+}
+
+// CreateComment stores a new comment on comment.TodoID together with its mentions
+func (r *SQLiteRepository) CreateComment(comment *models.Comment) (*models.Comment, error) {
+	comment.Mentions = normalizeIDs(comment.Mentions)
+	err := r.inTx(func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", comment.WorkspaceID, comment.TodoID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrTodoNotFound
+		}
+		if comment.ReplyToID != 0 {
+			if err := tx.QueryRow("SELECT COUNT(*) FROM comments WHERE todo_id = ? AND id = ?", comment.TodoID, comment.ReplyToID).Scan(&count); err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrReplyNotFound
+			}
+		}
+		if err := usersExist(tx, comment.AuthorID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO comments (workspace_id, todo_id, reply_to_id, author_id, body, created_at, edited_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			comment.WorkspaceID, comment.TodoID, nullableID(comment.ReplyToID), comment.AuthorID, comment.Body,
+			formatTime(comment.CreatedAt), nullableTime(comment.EditedAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		comment.ID = int(id)
+		return setCommentMentions(tx, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commentCopy := *comment
+	return &commentCopy, nil
+}
+
+// UpdateComment changes the body, mentions and edit time of a comment
+func (r *SQLiteRepository) UpdateComment(comment *models.Comment) (*models.Comment, error) {
+	comment.Mentions = normalizeIDs(comment.Mentions)
+	err := r.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"UPDATE comments SET body = ?, edited_at = ? WHERE workspace_id = ? AND id = ?",
+			comment.Body, nullableTime(comment.EditedAt), comment.WorkspaceID, comment.ID,
+		)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return ErrCommentNotFound
+		}
+		return setCommentMentions(tx, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetComment(comment.WorkspaceID, comment.ID)
+}
+
+// DeleteComment removes a comment; ON DELETE SET NULL detaches its replies in the same statement
+func (r *SQLiteRepository) DeleteComment(workspaceID, id int) error {
+	result, err := r.db.Exec("DELETE FROM comments WHERE workspace_id = ? AND id = ?", workspaceID, id)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// CountComments returns the number of comments on each of todoIDs within a
+// workspace. Todo IDs are bound in batches of linkBatchSize.
+func (r *SQLiteRepository) CountComments(workspaceID int, todoIDs []int) (map[int]int, error) {
+	counts := make(map[int]int)
+	for start := 0; start < len(todoIDs); start += linkBatchSize {
+		batch := todoIDs[start:min(start+linkBatchSize, len(todoIDs))]
+		args := make([]any, 0, len(batch)+1)
+		args = append(args, workspaceID)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+
+		rows, err := r.db.Query("SELECT todo_id, COUNT(*) FROM comments WHERE workspace_id = ? AND todo_id IN ("+placeholders(len(batch))+") GROUP BY todo_id", args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var todoID, count int
+			if err := rows.Scan(&todoID, &count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts[todoID] = count
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// setCommentMentions replaces the mentions of a comment, returning
+// ErrUserNotFound unless every mentioned user exists
+func setCommentMentions(tx *sql.Tx, comment *models.Comment) error {
+	if err := usersExist(tx, comment.Mentions...); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM comment_mentions WHERE comment_id = ?", comment.ID); err != nil {
+		return err
+	}
+	for _, id := range comment.Mentions {
+		if _, err := tx.Exec("INSERT INTO comment_mentions (comment_id, user_id) VALUES (?, ?)", comment.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usersExist returns ErrUserNotFound unless every one of ids is a user
+func usersExist(tx *sql.Tx, ids ...int) error {
+	for _, id := range ids {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", id).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrUserNotFound
+		}
+	}
+	return nil
+}
+
+// loadCommentMentions fills in Mentions of comments
+func (r *SQLiteRepository) loadCommentMentions(comments []models.Comment) error {
+	index := make(map[int]int, len(comments))
+	for i := range comments {
+		comments[i].Mentions = []int{}
+		index[comments[i].ID] = i
+	}
+
+	for start := 0; start < len(comments); start += linkBatchSize {
+		batch := comments[start:min(start+linkBatchSize, len(comments))]
+		args := make([]any, len(batch))
+		for i, comment := range batch {
+			args[i] = comment.ID
+		}
+
+		rows, err := r.db.Query("SELECT comment_id, user_id FROM comment_mentions WHERE comment_id IN ("+placeholders(len(batch))+") ORDER BY 2", args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var commentID, userID int
+			if err := rows.Scan(&commentID, &userID); err != nil {
+				rows.Close()
+				return err
+			}
+			comment := &comments[index[commentID]]
+			comment.Mentions = append(comment.Mentions, userID)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanComment reads one comment in commentColumns order
+func scanComment(row rowScanner) (*models.Comment, error) {
+	var comment models.Comment
+	var replyToID, authorID sql.NullInt64
+	var createdAt string
+	var editedAt sql.NullString
+	if err := row.Scan(&comment.ID, &comment.WorkspaceID, &comment.TodoID, &replyToID, &authorID, &comment.Body, &createdAt, &editedAt); err != nil {
+		return nil, err
+	}
+	comment.ReplyToID = int(replyToID.Int64)
+	comment.AuthorID = int(authorID.Int64)
+	comment.CreatedAt = parseTime(createdAt)
+	comment.EditedAt = parseNullableTime(editedAt)
+	return &comment, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+
+	"test_mekari/internal/models"
+)
+
+// linkBatchSize bounds the number of todo IDs bound to one query when loading links
+const linkBatchSize = 500
+
+// setTodoBlockers replaces the dependencies of a todo, returning
+// ErrBlockerNotFound unless every blocker is a todo of the todo's workspace
+func setTodoBlockers(tx *sql.Tx, todo *models.Todo) error {
+	for _, id := range todo.BlockedBy {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", todo.WorkspaceID, id).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrBlockerNotFound
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM todo_dependencies WHERE todo_id = ?", todo.ID); err != nil {
+		return err
+	}
+	for _, id := range todo.BlockedBy {
+		if _, err := tx.Exec("INSERT INTO todo_dependencies (todo_id, blocker_id) VALUES (?, ?)", todo.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDependencyCycle returns ErrDependencyCycle when one of todo.BlockedBy
+// is todo itself or waits for it, directly or through other todos
+func checkDependencyCycle(tx *sql.Tx, todo *models.Todo) error {
+	if len(todo.BlockedBy) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(todo.BlockedBy)+1)
+	for _, id := range todo.BlockedBy {
+		args = append(args, id)
+	}
+	args = append(args, todo.ID)
+
+	var count int
+	err := tx.QueryRow(
+		`WITH RECURSIVE waits(id) AS (
+			SELECT id FROM todos WHERE id IN (`+placeholders(len(todo.BlockedBy))+`)
+			UNION SELECT todo_dependencies.blocker_id FROM todo_dependencies JOIN waits ON todo_dependencies.todo_id = waits.id
+		)
+		SELECT COUNT(*) FROM waits WHERE id = ?`,
+		args...,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDependencyCycle
+	}
+	return nil
+}
+
+// loadTodoIDs fills in LabelIDs, BlockedBy and AssigneeIDs of todos
+func loadTodoIDs(db querier, todos []models.Todo) error {
+	if err := loadTodoLabels(db, todos); err != nil {
+		return err
+	}
+	if err := loadTodoBlockers(db, todos); err != nil {
+		return err
+	}
+	return loadTodoAssignees(db, todos)
+}
+
+// loadTodoBlockers fills in BlockedBy of todos
+func loadTodoBlockers(db querier, todos []models.Todo) error {
+	return loadTodoLinks(db, todos, "SELECT todo_id, blocker_id FROM todo_dependencies", func(todo *models.Todo) *[]int { return &todo.BlockedBy })
+}
+
+// loadTodoLinks fills in one ID list of todos, e.g. their labels, from query
+// selecting (todo ID, linked ID) rows of a link table. The list of every todo
+// is reset first and comes out sorted. Todo IDs are bound in batches of linkBatchSize.
+func loadTodoLinks(db querier, todos []models.Todo, query string, list func(*models.Todo) *[]int) error {
+	index := make(map[int]int, len(todos))
+	for i := range todos {
+		*list(&todos[i]) = []int{}
+		index[todos[i].ID] = i
+	}
+
+	for start := 0; start < len(todos); start += linkBatchSize {
+		batch := todos[start:min(start+linkBatchSize, len(todos))]
+		args := make([]any, len(batch))
+		for i, todo := range batch {
+			args[i] = todo.ID
+		}
+
+		rows, err := db.Query(query+" WHERE todo_id IN ("+placeholders(len(batch))+") ORDER BY 2", args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var todoID, linkedID int
+			if err := rows.Scan(&todoID, &linkedID); err != nil {
+				rows.Close()
+				return err
+			}
+			ids := list(&todos[index[todoID]])
+			*ids = append(*ids, linkedID)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
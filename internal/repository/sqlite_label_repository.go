@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"test_mekari/internal/models"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const labelColumns = "id, workspace_id, name, color, created_at, updated_at"
+
+// GetLabels returns the labels of a workspace ordered by ID
+func (r *SQLiteRepository) GetLabels(workspaceID int) ([]models.Label, error) {
+	rows, err := r.db.Query("SELECT "+labelColumns+" FROM labels WHERE workspace_id = ? ORDER BY id", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := make([]models.Label, 0)
+	for rows.Next() {
+		label, err := scanLabel(rows)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, *label)
+	}
+	return labels, rows.Err()
+}
+
+// GetLabel retrieves a label by ID within a workspace
+func (r *SQLiteRepository) GetLabel(workspaceID, id int) (*models.Label, error) {
+	label, err := scanLabel(r.db.QueryRow("SELECT "+labelColumns+" FROM labels WHERE workspace_id = ? AND id = ?", workspaceID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLabelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// CreateLabel stores a new label in label.WorkspaceID;
+// the unique name index enforces ErrLabelNameTaken
+func (r *SQLiteRepository) CreateLabel(label *models.Label) (*models.Label, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		if err := workspaceExists(tx, label.WorkspaceID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO labels (workspace_id, name, color, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			label.WorkspaceID, label.Name, label.Color, formatTime(label.CreatedAt), formatTime(label.UpdatedAt),
+		)
+		if err != nil {
+			return mapLabelError(err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		label.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	labelCopy := *label
+	return &labelCopy, nil
+}
+
+// UpdateLabel replaces an existing label
+func (r *SQLiteRepository) UpdateLabel(label *models.Label) (*models.Label, error) {
+	result, err := r.db.Exec(
+		"UPDATE labels SET name = ?, color = ?, created_at = ?, updated_at = ? WHERE workspace_id = ? AND id = ?",
+		label.Name, label.Color, formatTime(label.CreatedAt), formatTime(label.UpdatedAt), label.WorkspaceID, label.ID,
+	)
+	if err != nil {
+		return nil, mapLabelError(err)
+	}
+	if err := expectAffected(result); err != nil {
+		return nil, ErrLabelNotFound
+	}
+
+	labelCopy := *label
+	return &labelCopy, nil
+}
+
+// DeleteLabel removes a label; ON DELETE CASCADE detaches it from its todos in the same statement
+func (r *SQLiteRepository) DeleteLabel(workspaceID, id int) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		if err := tx.trackQuery("SELECT todo_id FROM todo_labels WHERE label_id = ?", id); err != nil {
+			return err
+		}
+		result, err := tx.Exec("DELETE FROM labels WHERE workspace_id = ? AND id = ?", workspaceID, id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return ErrLabelNotFound
+		}
+		return nil
+	})
+}
+
+// setTodoLabels replaces the labels of a todo, returning ErrLabelNotFound
+// unless every label belongs to the todo's workspace
+func setTodoLabels(tx *sql.Tx, todo *models.Todo) error {
+	for _, id := range todo.LabelIDs {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM labels WHERE workspace_id = ? AND id = ?", todo.WorkspaceID, id).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrLabelNotFound
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM todo_labels WHERE todo_id = ?", todo.ID); err != nil {
+		return err
+	}
+	for _, id := range todo.LabelIDs {
+		if _, err := tx.Exec("INSERT INTO todo_labels (todo_id, label_id) VALUES (?, ?)", todo.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTodoLabels fills in LabelIDs of todos
+func loadTodoLabels(db querier, todos []models.Todo) error {
+	return loadTodoLinks(db, todos, "SELECT todo_id, label_id FROM todo_labels", func(todo *models.Todo) *[]int { return &todo.LabelIDs })
+}
+
+// scanLabel reads one label in labelColumns order
+func scanLabel(row rowScanner) (*models.Label, error) {
+	var label models.Label
+	var createdAt, updatedAt string
+	if err := row.Scan(&label.ID, &label.WorkspaceID, &label.Name, &label.Color, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	label.CreatedAt = parseTime(createdAt)
+	label.UpdatedAt = parseTime(updatedAt)
+	return &label, nil
+}
+
+// placeholders returns n comma separated "?" for an IN (...) list
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// mapLabelError turns a unique name violation into ErrLabelNameTaken
+func mapLabelError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrLabelNameTaken
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"test_mekari/internal/models"
+)
+
+const listColumns = "id, workspace_id, name, archived, created_at, updated_at"
+
+// GetLists returns the lists of a workspace ordered by ID
+func (r *SQLiteRepository) GetLists(workspaceID int) ([]models.List, error) {
+	rows, err := r.db.Query("SELECT "+listColumns+" FROM lists WHERE workspace_id = ? ORDER BY id", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make([]models.List, 0)
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, *list)
+	}
+	return lists, rows.Err()
+}
+
+// GetList retrieves a list by ID within a workspace
+func (r *SQLiteRepository) GetList(workspaceID, id int) (*models.List, error) {
+	list, err := scanList(r.db.QueryRow("SELECT "+listColumns+" FROM lists WHERE workspace_id = ? AND id = ?", workspaceID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CreateList stores a new list in list.WorkspaceID
+func (r *SQLiteRepository) CreateList(list *models.List) (*models.List, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		if err := workspaceExists(tx, list.WorkspaceID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO lists (workspace_id, name, archived, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			list.WorkspaceID, list.Name, list.Archived, formatTime(list.CreatedAt), formatTime(list.UpdatedAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		list.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listCopy := *list
+	return &listCopy, nil
+}
+
+// UpdateList replaces an existing list
+func (r *SQLiteRepository) UpdateList(list *models.List) (*models.List, error) {
+	result, err := r.db.Exec(
+		"UPDATE lists SET name = ?, archived = ?, created_at = ?, updated_at = ? WHERE workspace_id = ? AND id = ?",
+		list.Name, list.Archived, formatTime(list.CreatedAt), formatTime(list.UpdatedAt), list.WorkspaceID, list.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result); err != nil {
+		return nil, ErrListNotFound
+	}
+
+	listCopy := *list
+	return &listCopy, nil
+}
+
+// DeleteList removes a list; its todos are kept without a list (ON DELETE SET NULL)
+func (r *SQLiteRepository) DeleteList(workspaceID, id int) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		if err := tx.trackQuery("SELECT id FROM todos WHERE list_id = ?", id); err != nil {
+			return err
+		}
+		result, err := tx.Exec("DELETE FROM lists WHERE workspace_id = ? AND id = ?", workspaceID, id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return ErrListNotFound
+		}
+		return nil
+	})
+}
+
+// listExists returns ErrListNotFound unless id is a list of workspaceID; 0 (no list) always exists
+func listExists(db queryRower, workspaceID, id int) error {
+	if id == 0 {
+		return nil
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM lists WHERE workspace_id = ? AND id = ?", workspaceID, id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrListNotFound
+	}
+	return nil
+}
+
+// scanList reads one list in listColumns order
+func scanList(row rowScanner) (*models.List, error) {
+	var list models.List
+	var createdAt, updatedAt string
+	if err := row.Scan(&list.ID, &list.WorkspaceID, &list.Name, &list.Archived, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	list.CreatedAt = parseTime(createdAt)
+	list.UpdatedAt = parseTime(updatedAt)
+	return &list, nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+const notificationColumns = "id, user_id, workspace_id, todo_id, comment_id, actor_id, type, message, created_at, read_at"
+
+// GetNotifications returns the notifications of a user, newest first
+func (r *SQLiteRepository) GetNotifications(userID int, unreadOnly bool) ([]models.Notification, error) {
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = ?"
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	rows, err := r.db.Query(query+" ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *n)
+	}
+	return notifications, rows.Err()
+}
+
+// CreateNotification stores a new notification in the inbox of n.UserID
+func (r *SQLiteRepository) CreateNotification(n *models.Notification) (*models.Notification, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		if err := usersExist(tx, n.UserID, n.ActorID); err != nil {
+			return err
+		}
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", n.WorkspaceID, n.TodoID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrTodoNotFound
+		}
+		if n.CommentID != 0 {
+			if err := tx.QueryRow("SELECT COUNT(*) FROM comments WHERE todo_id = ? AND id = ?", n.TodoID, n.CommentID).Scan(&count); err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrCommentNotFound
+			}
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO notifications (user_id, workspace_id, todo_id, comment_id, actor_id, type, message, created_at, read_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			n.UserID, n.WorkspaceID, n.TodoID, nullableID(n.CommentID), n.ActorID, n.Type, n.Message,
+			formatTime(n.CreatedAt), nullableTime(n.ReadAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		n.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notificationCopy := *n
+	return &notificationCopy, nil
+}
+
+// MarkNotificationRead sets read_at of a notification of userID, unless it is read already
+func (r *SQLiteRepository) MarkNotificationRead(userID, id int, at time.Time) (*models.Notification, error) {
+	var n *models.Notification
+	err := r.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE notifications SET read_at = ? WHERE user_id = ? AND id = ? AND read_at IS NULL", formatTime(at), userID, id); err != nil {
+			return err
+		}
+		var err error
+		n, err = scanNotification(tx.QueryRow("SELECT "+notificationColumns+" FROM notifications WHERE user_id = ? AND id = ?", userID, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotificationNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user read in one statement
+func (r *SQLiteRepository) MarkAllNotificationsRead(userID int, at time.Time) (int, error) {
+	result, err := r.db.Exec("UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL", formatTime(at), userID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
+
+// CountUnreadNotifications returns the number of unread notifications of a user
+func (r *SQLiteRepository) CountUnreadNotifications(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&count)
+	return count, err
+}
+
+// scanNotification reads one notification in notificationColumns order
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var n models.Notification
+	var commentID, actorID sql.NullInt64
+	var createdAt string
+	var readAt sql.NullString
+	if err := row.Scan(&n.ID, &n.UserID, &n.WorkspaceID, &n.TodoID, &commentID, &actorID, &n.Type, &n.Message, &createdAt, &readAt); err != nil {
+		return nil, err
+	}
+	n.CommentID = int(commentID.Int64)
+	n.ActorID = int(actorID.Int64)
+	n.CreatedAt = parseTime(createdAt)
+	n.ReadAt = parseNullableTime(readAt)
+	return &n, nil
+}
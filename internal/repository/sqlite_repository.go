@@ -0,0 +1,861 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/models"
+
+	_ "modernc.org/sqlite" // pure Go SQLite driver, registers "sqlite"
+)
+
+// SQLiteRepository is the durable Store backend.
+// The schema it relies on lives in internal/migrations/sql.
+type SQLiteRepository struct {
+	db *sql.DB
+
+	// mu serializes the transactions that change todos, so observers are
+	// told about them in commit order
+	mu        sync.Mutex
+	observers []func([]TodoChange)
+}
+
+// OpenSQLite opens (or creates) the SQLite database file at path.
+// The schema is managed by the migrations package and must be applied
+// before the database is handed to NewSQLiteRepository.
+func OpenSQLite(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create database directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	// SQLite allows a single writer, serialize access through one connection
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// NewSQLiteRepository wraps a migrated database and seeds users into an empty one
+func NewSQLiteRepository(db *sql.DB) (*SQLiteRepository, error) {
+	repo := &SQLiteRepository{db: db}
+	if err := repo.seedUsers(); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// Close releases the underlying database handle
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// seedUsers inserts the SeedUsers data when the users table is empty
+func (r *SQLiteRepository) seedUsers() error {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		// Seed users created before passwords existed get the seed password,
+		// otherwise nobody could log in after upgrading
+		for _, user := range SeedUsers() {
+			_, err := r.db.Exec("UPDATE users SET password_hash = ? WHERE id = ? AND email = ? AND password_hash = ''",
+				user.PasswordHash, user.ID, user.Email)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Seeded users carry explicit IDs, so insertion order does not matter
+	return r.inTx(func(tx *sql.Tx) error {
+		for _, user := range SeedUsers() {
+			_, err := tx.Exec(
+				"INSERT INTO users (id, name, email, role, password_hash, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+				user.ID, user.Name, user.Email, user.Role, user.PasswordHash, formatTime(user.CreatedAt),
+			)
+			if err != nil {
+				return fmt.Errorf("seed users: %w", err)
+			}
+			if err := joinDefaultWorkspace(tx, user); err != nil {
+				return fmt.Errorf("seed users: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+const todoColumns = "id, workspace_id, list_id, parent_id, text, completed, priority, checklist, user_id, created_by, due_at, remind_at, reminded_at, recurrence, occurrence, created_at, updated_at"
+
+// FindAll returns the todos of a workspace that match filter
+func (r *SQLiteRepository) FindAll(workspaceID int, filter TodoFilter) ([]models.Todo, error) {
+	query := "SELECT " + todoColumns + " FROM todos WHERE workspace_id = ?"
+	args := []any{workspaceID}
+
+	if filter.UserID != 0 {
+		query += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	switch {
+	case filter.ListID != nil && *filter.ListID == 0:
+		query += " AND list_id IS NULL"
+	case filter.ListID != nil:
+		query += " AND list_id = ?"
+		args = append(args, *filter.ListID)
+	case !filter.IncludeArchived:
+		query += " AND (list_id IS NULL OR list_id NOT IN (SELECT id FROM lists WHERE archived = 1))"
+	}
+	switch {
+	case filter.ParentID != nil && *filter.ParentID == 0:
+		query += " AND parent_id IS NULL"
+	case filter.ParentID != nil:
+		query += " AND parent_id = ?"
+		args = append(args, *filter.ParentID)
+	}
+	switch {
+	case filter.AssigneeID != nil && *filter.AssigneeID == 0:
+		query += " AND NOT EXISTS (SELECT 1 FROM todo_assignees WHERE todo_id = todos.id)"
+	case filter.AssigneeID != nil:
+		query += " AND EXISTS (SELECT 1 FROM todo_assignees WHERE todo_id = todos.id AND user_id = ?)"
+		args = append(args, *filter.AssigneeID)
+	}
+	// Timestamps carry their own offsets, julianday compares them as instants
+	if filter.DueAfter != nil {
+		query += " AND julianday(due_at) > julianday(?)"
+		args = append(args, formatTime(*filter.DueAfter))
+	}
+	if filter.DueBefore != nil {
+		query += " AND julianday(due_at) < julianday(?)"
+		args = append(args, formatTime(*filter.DueBefore))
+	}
+	if filter.OverdueAt != nil {
+		query += " AND completed = 0 AND julianday(due_at) < julianday(?)"
+		args = append(args, formatTime(*filter.OverdueAt))
+	}
+	if len(filter.Priorities) > 0 {
+		query += " AND priority IN (" + placeholders(len(filter.Priorities)) + ")"
+		for _, priority := range filter.Priorities {
+			args = append(args, priority)
+		}
+	}
+	if len(filter.LabelIDs) > 0 {
+		// Any label: at least one match; all labels: as many matches as labels asked for
+		labels := "SELECT COUNT(*) FROM todo_labels WHERE todo_id = todos.id AND label_id IN (" + placeholders(len(filter.LabelIDs)) + ")"
+		if filter.AllLabels {
+			query += " AND (" + labels + ") = ?"
+		} else {
+			query += " AND (" + labels + ") > 0"
+		}
+		for _, id := range filter.LabelIDs {
+			args = append(args, id)
+		}
+		if filter.AllLabels {
+			args = append(args, len(normalizeIDs(filter.LabelIDs)))
+		}
+	}
+	if filter.Where != nil {
+		where, whereArgs := whereSQL(filter.Where)
+		query += " AND " + where
+		args = append(args, whereArgs...)
+	}
+
+	after, afterArgs, orderBy := todoOrder(filter)
+	query += after + " ORDER BY " + orderBy
+	args = append(args, afterArgs...)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return r.queryTodos(query, args...)
+}
+
+// whereSQL translates a filter expression into an SQL condition on todos that
+// is never NULL, so NOT keeps working, evaluating like matchWhere
+func whereSQL(n expr.Node) (string, []any) {
+	switch n := n.(type) {
+	case *expr.And:
+		x, xArgs := whereSQL(n.X)
+		y, yArgs := whereSQL(n.Y)
+		return "(" + x + " AND " + y + ")", append(xArgs, yArgs...)
+	case *expr.Or:
+		x, xArgs := whereSQL(n.X)
+		y, yArgs := whereSQL(n.Y)
+		return "(" + x + " OR " + y + ")", append(xArgs, yArgs...)
+	case *expr.Not:
+		x, args := whereSQL(n.X)
+		return "NOT " + x, args
+	case *expr.Cond:
+		return condSQL(n)
+	}
+	return "1", nil
+}
+
+// condSQL translates a single condition for whereSQL
+func condSQL(c *expr.Cond) (string, []any) {
+	op := " " + string(c.Op) + " "
+	switch c.Field {
+	case expr.FieldID:
+		return "(id" + op + "?)", []any{c.Int}
+	case expr.FieldUserID:
+		return "(user_id" + op + "?)", []any{c.Int}
+	case expr.FieldListID:
+		return "(IFNULL(list_id, 0) = ?)", []any{c.Int}
+	case expr.FieldParentID:
+		return "(IFNULL(parent_id, 0) = ?)", []any{c.Int}
+	case expr.FieldAssignee:
+		if c.None {
+			return "NOT EXISTS (SELECT 1 FROM todo_assignees WHERE todo_id = todos.id)", nil
+		}
+		return "EXISTS (SELECT 1 FROM todo_assignees WHERE todo_id = todos.id AND user_id = ?)", []any{c.Int}
+	case expr.FieldLabel:
+		if c.None {
+			return "NOT EXISTS (SELECT 1 FROM todo_labels WHERE todo_id = todos.id)", nil
+		}
+		return "EXISTS (SELECT 1 FROM todo_labels WHERE todo_id = todos.id AND label_id = ?)", []any{c.Int}
+	case expr.FieldCompleted:
+		return "(completed = ?)", []any{c.Bool}
+	case expr.FieldText:
+		// lower folds ASCII letters only, like foldASCII
+		if c.Op == expr.Contains {
+			return "(instr(lower(text), ?) > 0)", []any{foldASCIIString(c.Text)}
+		}
+		return "(text = ? COLLATE NOCASE)", []any{c.Text}
+	case expr.FieldPriority:
+		return "(" + priorityRank + op + "?)", []any{c.Priority.Rank()}
+	case expr.FieldCreatedAt:
+		return "(julianday(created_at)" + op + "julianday(?))", []any{formatTime(c.Time)}
+	case expr.FieldUpdatedAt:
+		return "(julianday(updated_at)" + op + "julianday(?))", []any{formatTime(c.Time)}
+	case expr.FieldDueAt:
+		if c.None {
+			return "(due_at IS NULL)", nil
+		}
+		return "(due_at IS NOT NULL AND julianday(due_at)" + op + "julianday(?))", []any{formatTime(c.Time)}
+	}
+	return "0", nil
+}
+
+// priorityRank computes models.Priority.Rank in SQL
+const priorityRank = "CASE priority WHEN 'none' THEN 0 WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 WHEN 'urgent' THEN 4 ELSE -1 END"
+
+// todoOrder returns the ORDER BY clause for filter.Sort, and the condition
+// keeping the todos after filter.After with its arguments, like compareTodos
+func todoOrder(filter TodoFilter) (after string, args []any, orderBy string) {
+	dir, op := "ASC", ">"
+	if filter.Desc {
+		dir, op = "DESC", "<"
+	}
+
+	// column is the sorted expression, param the placeholder for a value of it.
+	// Timestamps carry their own offsets, julianday compares them as instants.
+	column, param := "", "?"
+	switch filter.Sort {
+	case SortByCreatedAt:
+		column, param = "julianday(created_at)", "julianday(?)"
+	case SortByUpdatedAt:
+		column, param = "julianday(updated_at)", "julianday(?)"
+	case SortByDue:
+		column, param = "julianday(due_at)", "julianday(?)"
+	case SortByPriority:
+		column = priorityRank
+	case SortByText:
+		column = "text COLLATE NOCASE"
+	}
+
+	orderBy = "id " + dir
+	if column != "" {
+		orderBy = column + " " + dir + ", " + orderBy
+	}
+	if filter.Sort == SortByDue {
+		// Todos without a due date last, in both directions
+		orderBy = "due_at IS NULL, " + orderBy
+	}
+
+	if filter.After == nil {
+		return "", nil, orderBy
+	}
+	id := filter.After.ID
+	switch {
+	case column == "":
+		return " AND id " + op + " ?", []any{id}, orderBy
+	case filter.Sort == SortByDue && filter.After.DueAt == nil:
+		return " AND due_at IS NULL AND id " + op + " ?", []any{id}, orderBy
+	}
+
+	value := sortValue(filter.After, filter.Sort)
+	after = column + " " + op + " " + param + " OR (" + column + " = " + param + " AND id " + op + " ?)"
+	if filter.Sort == SortByDue {
+		after = "due_at IS NULL OR " + after
+	}
+	return " AND (" + after + ")", []any{value, value, id}, orderBy
+}
+
+// sortValue returns the field of todo that sort orders by as an SQL argument
+func sortValue(todo *models.Todo, sort TodoSort) any {
+	switch sort {
+	case SortByCreatedAt:
+		return formatTime(todo.CreatedAt)
+	case SortByUpdatedAt:
+		return formatTime(todo.UpdatedAt)
+	case SortByDue:
+		return formatTime(*todo.DueAt)
+	case SortByPriority:
+		return todo.Priority.Rank()
+	case SortByText:
+		return todo.Text
+	}
+	return todo.ID
+}
+
+// FindByID finds a todo by its ID within a workspace
+func (r *SQLiteRepository) FindByID(workspaceID, id int) (*models.Todo, error) {
+	row := r.db.QueryRow("SELECT "+todoColumns+" FROM todos WHERE workspace_id = ? AND id = ?", workspaceID, id)
+	todo, err := scanTodo(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTodoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	todos := []models.Todo{*todo}
+	if err := loadTodoIDs(r.db, todos); err != nil {
+		return nil, err
+	}
+	return &todos[0], nil
+}
+
+// Create creates a new todo in todo.WorkspaceID
+func (r *SQLiteRepository) Create(todo *models.Todo) (*models.Todo, error) {
+	if err := r.inTodoTx(func(tx *todoTx) error { return createTodo(tx, todo) }); err != nil {
+		return nil, err
+	}
+
+	// Return a copy
+	todoCopy := *todo
+	return &todoCopy, nil
+}
+
+// Update updates an existing todo
+func (r *SQLiteRepository) Update(todo *models.Todo) (*models.Todo, error) {
+	if err := r.inTodoTx(func(tx *todoTx) error { return updateTodo(tx, todo) }); err != nil {
+		return nil, err
+	}
+
+	todoCopy := *todo
+	return &todoCopy, nil
+}
+
+// SaveTodos updates and creates todos in one transaction
+func (r *SQLiteRepository) SaveTodos(updates, creates []*models.Todo) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		for _, todo := range updates {
+			if err := updateTodo(tx, todo); err != nil {
+				return err
+			}
+		}
+		for _, todo := range creates {
+			if err := createTodo(tx, todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createTodo inserts todo and sets its ID
+func createTodo(tx *todoTx, todo *models.Todo) error {
+	if err := workspaceExists(tx, todo.WorkspaceID); err != nil {
+		return err
+	}
+	if err := listExists(tx, todo.WorkspaceID, todo.ListID); err != nil {
+		return err
+	}
+	if err := parentExists(tx, todo.WorkspaceID, todo.ParentID); err != nil {
+		return err
+	}
+	normalizeTodo(todo)
+	checklist, err := encodeChecklist(todo.Checklist)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO todos (workspace_id, list_id, parent_id, text, completed, priority, checklist, user_id, created_by, due_at, remind_at, reminded_at, recurrence, occurrence, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		todo.WorkspaceID, nullableID(todo.ListID), nullableID(todo.ParentID), todo.Text, todo.Completed, todo.Priority, checklist, todo.UserID, todo.CreatedBy,
+		nullableTime(todo.DueAt), nullableTime(todo.RemindAt), nullableTime(todo.RemindedAt), todo.Recurrence, todo.Occurrence,
+		formatTime(todo.CreatedAt), formatTime(todo.UpdatedAt),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	todo.ID = int(id)
+	tx.created(todo.ID)
+	if err := setTodoLabels(tx.Tx, todo); err != nil {
+		return err
+	}
+	if err := setTodoBlockers(tx.Tx, todo); err != nil {
+		return err
+	}
+	return setTodoAssignees(tx.Tx, todo)
+}
+
+// updateTodo stores todo over the todo with its ID
+func updateTodo(tx *todoTx, todo *models.Todo) error {
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", todo.WorkspaceID, todo.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTodoNotFound
+	}
+	if err := tx.track(todo.ID); err != nil {
+		return err
+	}
+	if err := listExists(tx, todo.WorkspaceID, todo.ListID); err != nil {
+		return err
+	}
+	if err := parentExists(tx, todo.WorkspaceID, todo.ParentID); err != nil {
+		return err
+	}
+	if err := checkSubtaskCycle(tx, todo); err != nil {
+		return err
+	}
+	normalizeTodo(todo)
+	checklist, err := encodeChecklist(todo.Checklist)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE todos SET list_id = ?, parent_id = ?, text = ?, completed = ?, priority = ?, checklist = ?, user_id = ?, created_by = ?, due_at = ?, remind_at = ?, reminded_at = ?, recurrence = ?, occurrence = ?, created_at = ?, updated_at = ? WHERE workspace_id = ? AND id = ?",
+		nullableID(todo.ListID), nullableID(todo.ParentID), todo.Text, todo.Completed, todo.Priority, checklist, todo.UserID, todo.CreatedBy,
+		nullableTime(todo.DueAt), nullableTime(todo.RemindAt), nullableTime(todo.RemindedAt), todo.Recurrence, todo.Occurrence,
+		formatTime(todo.CreatedAt), formatTime(todo.UpdatedAt),
+		todo.WorkspaceID, todo.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setTodoLabels(tx.Tx, todo); err != nil {
+		return err
+	}
+	if err := checkDependencyCycle(tx.Tx, todo); err != nil {
+		return err
+	}
+	if err := setTodoBlockers(tx.Tx, todo); err != nil {
+		return err
+	}
+	return setTodoAssignees(tx.Tx, todo)
+}
+
+// Delete deletes a todo by its ID within a workspace, moving its subtasks up to its parent
+func (r *SQLiteRepository) Delete(workspaceID, id int) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		var parentID sql.NullInt64
+		err := tx.QueryRow("SELECT parent_id FROM todos WHERE workspace_id = ? AND id = ?", workspaceID, id).Scan(&parentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTodoNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// The todo, its subtasks and the todos waiting for it
+		err = tx.trackQuery(
+			"SELECT id FROM todos WHERE id = ? OR parent_id = ? UNION SELECT todo_id FROM todo_dependencies WHERE blocker_id = ?",
+			id, id, id,
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE todos SET parent_id = ? WHERE parent_id = ?", parentID, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM todos WHERE id = ?", id)
+		return err
+	})
+}
+
+// todoTree selects the IDs of a todo of a workspace and of all of its subtasks
+const todoTree = `WITH RECURSIVE tree(id) AS (
+	SELECT id FROM todos WHERE workspace_id = ? AND id = ?
+	UNION SELECT todos.id FROM todos JOIN tree ON todos.parent_id = tree.id
+)`
+
+// DeleteTree deletes a todo and all of its subtasks in one statement
+func (r *SQLiteRepository) DeleteTree(workspaceID, id int) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		// The tree and the todos waiting for one of its todos
+		err := tx.trackQuery(
+			todoTree+" SELECT id FROM tree UNION SELECT todo_id FROM todo_dependencies WHERE blocker_id IN (SELECT id FROM tree)",
+			workspaceID, id,
+		)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(todoTree+" DELETE FROM todos WHERE id IN (SELECT id FROM tree)", workspaceID, id)
+		if err != nil {
+			return err
+		}
+		return expectAffected(result)
+	})
+}
+
+// DueReminders returns the open todos whose reminder is due and has not fired yet
+func (r *SQLiteRepository) DueReminders(now time.Time) ([]models.Todo, error) {
+	return r.queryTodos(
+		"SELECT "+todoColumns+" FROM todos WHERE completed = 0 AND reminded_at IS NULL AND julianday(remind_at) <= julianday(?) ORDER BY julianday(remind_at), id",
+		formatTime(now),
+	)
+}
+
+// MarkReminded records that the reminder of a todo fired, unless it was rescheduled meanwhile
+func (r *SQLiteRepository) MarkReminded(id int, remindAt, at time.Time) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		var current sql.NullString
+		err := tx.QueryRow("SELECT remind_at FROM todos WHERE id = ?", id).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTodoNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if scheduled := parseNullableTime(current); scheduled == nil || !scheduled.Equal(remindAt) {
+			return nil
+		}
+		if err := tx.track(id); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE todos SET reminded_at = ? WHERE id = ?", formatTime(at), id)
+		return err
+	})
+}
+
+// inTx runs fn in a transaction, rolling back if it fails
+func (r *SQLiteRepository) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ObserveTodos has fn called with the todos every later transaction run by
+// inTodoTx changed
+func (r *SQLiteRepository) ObserveTodos(fn func([]TodoChange)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observers = append(r.observers, fn)
+}
+
+// inTodoTx runs fn in a transaction like inTx and, once it committed, tells
+// the observers about the todos fn tracked. Every mutation that may change a
+// todo runs here.
+func (r *SQLiteRepository) inTodoTx(fn func(tx *todoTx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changes []TodoChange
+	err := r.inTx(func(sqlTx *sql.Tx) error {
+		tx := &todoTx{Tx: sqlTx}
+		if len(r.observers) > 0 {
+			tx.before = make(map[int]*models.Todo)
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		var err error
+		changes, err = tx.changes()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	notify(r.observers, changes)
+	return nil
+}
+
+// todoTx is a transaction that tracks the todos it may change. A todo is
+// tracked before the change, so its state from before can be reported.
+type todoTx struct {
+	*sql.Tx
+	// before holds the tracked todos as they were, nil for the ones that did
+	// not exist yet; the map itself is nil when nobody observes
+	before map[int]*models.Todo
+}
+
+// track records the todos with ids as they are now, unless tracked already
+func (tx *todoTx) track(ids ...int) error {
+	if tx.before == nil {
+		return nil
+	}
+	var untracked []int
+	for _, id := range ids {
+		if _, tracked := tx.before[id]; !tracked {
+			tx.before[id] = nil
+			untracked = append(untracked, id)
+		}
+	}
+	todos, err := todosByID(tx.Tx, untracked)
+	if err != nil {
+		return err
+	}
+	for i := range todos {
+		tx.before[todos[i].ID] = &todos[i]
+	}
+	return nil
+}
+
+// trackQuery tracks the todos whose IDs query selects
+func (tx *todoTx) trackQuery(query string, args ...any) error {
+	if tx.before == nil {
+		return nil
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	return tx.track(ids...)
+}
+
+// created tracks a todo the transaction just inserted
+func (tx *todoTx) created(id int) {
+	if tx.before != nil {
+		tx.before[id] = nil
+	}
+}
+
+// changes compares the tracked todos with what became of them
+func (tx *todoTx) changes() ([]TodoChange, error) {
+	if len(tx.before) == 0 {
+		return nil, nil
+	}
+	set := make(changeSet, len(tx.before))
+	ids := make([]int, 0, len(tx.before))
+	for id, before := range tx.before {
+		set.record(id, before, nil)
+		ids = append(ids, id)
+	}
+	after, err := todosByID(tx.Tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range after {
+		set.record(after[i].ID, nil, &after[i])
+	}
+	return set.list(), nil
+}
+
+// todosByID loads the todos with ids, in batches of linkBatchSize
+func todosByID(db querier, ids []int) ([]models.Todo, error) {
+	todos := make([]models.Todo, 0, len(ids))
+	for start := 0; start < len(ids); start += linkBatchSize {
+		batch := ids[start:min(start+linkBatchSize, len(ids))]
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		found, err := selectTodos(db, "SELECT "+todoColumns+" FROM todos WHERE id IN ("+placeholders(len(batch))+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, found...)
+	}
+	return todos, nil
+}
+
+// queryTodos runs a SELECT over todoColumns and scans every row
+func (r *SQLiteRepository) queryTodos(query string, args ...any) ([]models.Todo, error) {
+	return selectTodos(r.db, query, args...)
+}
+
+// selectTodos runs a SELECT over todoColumns on db and scans every row
+func selectTodos(db querier, query string, args ...any) ([]models.Todo, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := make([]models.Todo, 0)
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, *todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTodoIDs(db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTodo reads one todo in todoColumns order
+func scanTodo(row rowScanner) (*models.Todo, error) {
+	var todo models.Todo
+	var listID, parentID sql.NullInt64
+	var checklist string
+	var dueAt, remindAt, remindedAt sql.NullString
+	var createdAt, updatedAt string
+	err := row.Scan(&todo.ID, &todo.WorkspaceID, &listID, &parentID, &todo.Text, &todo.Completed, &todo.Priority, &checklist, &todo.UserID, &todo.CreatedBy,
+		&dueAt, &remindAt, &remindedAt, &todo.Recurrence, &todo.Occurrence, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(checklist), &todo.Checklist); err != nil {
+		return nil, fmt.Errorf("todo %d: decode checklist: %w", todo.ID, err)
+	}
+	if todo.Checklist == nil {
+		todo.Checklist = []models.ChecklistItem{}
+	}
+	todo.ListID = int(listID.Int64)
+	todo.ParentID = int(parentID.Int64)
+	todo.DueAt = parseNullableTime(dueAt)
+	todo.RemindAt = parseNullableTime(remindAt)
+	todo.RemindedAt = parseNullableTime(remindedAt)
+	todo.CreatedAt = parseTime(createdAt)
+	todo.UpdatedAt = parseTime(updatedAt)
+	return &todo, nil
+}
+
+// parentExists returns ErrParentNotFound unless id is a todo of workspaceID; 0 (top-level) always exists
+func parentExists(db queryRower, workspaceID, id int) error {
+	if id == 0 {
+		return nil
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM todos WHERE workspace_id = ? AND id = ?", workspaceID, id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrParentNotFound
+	}
+	return nil
+}
+
+// checkSubtaskCycle returns ErrSubtaskCycle when todo.ParentID is todo itself
+// or one of its subtasks, walking up from the new parent
+func checkSubtaskCycle(db queryRower, todo *models.Todo) error {
+	if todo.ParentID == 0 {
+		return nil
+	}
+	var count int
+	err := db.QueryRow(
+		`WITH RECURSIVE up(id) AS (
+			SELECT ?
+			UNION SELECT todos.parent_id FROM todos JOIN up ON todos.id = up.id WHERE todos.parent_id IS NOT NULL
+		)
+		SELECT COUNT(*) FROM up WHERE id = ?`,
+		todo.ParentID, todo.ID,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSubtaskCycle
+	}
+	return nil
+}
+
+// encodeChecklist stores a checklist as a JSON array
+func encodeChecklist(items []models.ChecklistItem) (string, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// expectAffected maps "no row matched" to ErrTodoNotFound
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTodoNotFound
+	}
+	return nil
+}
+
+// nullableID stores the "none" ID 0 as NULL, as foreign keys require
+func nullableID(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// Timestamps are stored as RFC 3339 text so they round-trip with their zone
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// nullableTime stores a missing optional timestamp as NULL
+func nullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseNullableTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t := parseTime(s.String)
+	return &t
+}
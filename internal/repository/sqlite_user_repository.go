@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"test_mekari/internal/models"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const userColumns = "id, name, email, role, password_hash, deactivated, created_at"
+
+// GetUserByID retrieves a user by ID
+func (r *SQLiteRepository) GetUserByID(userID int) (*models.User, error) {
+	return r.queryUser("SELECT "+userColumns+" FROM users WHERE id = ?", userID)
+}
+
+// GetUserByEmail retrieves a user by email, ignoring case
+func (r *SQLiteRepository) GetUserByEmail(email string) (*models.User, error) {
+	return r.queryUser("SELECT "+userColumns+" FROM users WHERE lower(email) = lower(?)", email)
+}
+
+// GetAllUsers returns all users ordered by ID
+func (r *SQLiteRepository) GetAllUsers() ([]models.User, error) {
+	rows, err := r.db.Query("SELECT " + userColumns + " FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// CreateUser inserts a new user and adds them to the default workspace;
+// the unique email index enforces ErrEmailTaken
+func (r *SQLiteRepository) CreateUser(user *models.User) (*models.User, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"INSERT INTO users (name, email, role, password_hash, deactivated, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			user.Name, user.Email, user.Role, user.PasswordHash, user.Deactivated, formatTime(user.CreatedAt),
+		)
+		if err != nil {
+			return mapUserError(err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		user.ID = int(id)
+		return joinDefaultWorkspace(tx, *user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userCopy := *user
+	return &userCopy, nil
+}
+
+// UpdateUser replaces a user and renames CreatedBy on every todo they own
+func (r *SQLiteRepository) UpdateUser(user *models.User) (*models.User, error) {
+	err := r.inTodoTx(func(tx *todoTx) error {
+		result, err := tx.Exec(
+			"UPDATE users SET name = ?, email = ?, role = ?, password_hash = ?, deactivated = ? WHERE id = ?",
+			user.Name, user.Email, user.Role, user.PasswordHash, user.Deactivated, user.ID,
+		)
+		if err != nil {
+			return mapUserError(err)
+		}
+		if err := expectAffected(result); err != nil {
+			return ErrUserNotFound
+		}
+
+		// Keep the denormalized creator name in sync with a rename
+		if err := tx.trackQuery("SELECT id FROM todos WHERE user_id = ?", user.ID); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE todos SET created_by = ? WHERE user_id = ?", user.Name, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userCopy := *user
+	return &userCopy, nil
+}
+
+// DeleteUser removes a user and disposes of their todos in the same transaction
+func (r *SQLiteRepository) DeleteUser(id int, disposition TodoDisposition) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		if _, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		// Their todos with the subtasks and the todos waiting for them, and
+		// the todos assigned to them
+		err := tx.trackQuery(
+			`WITH owned(id) AS (SELECT id FROM todos WHERE user_id = ?)
+			SELECT id FROM owned
+			UNION SELECT id FROM todos WHERE parent_id IN (SELECT id FROM owned)
+			UNION SELECT todo_id FROM todo_dependencies WHERE blocker_id IN (SELECT id FROM owned)
+			UNION SELECT todo_id FROM todo_assignees WHERE user_id = ?`,
+			id, id,
+		)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case disposition.ReassignTo != 0:
+			target, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", disposition.ReassignTo))
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && target.ID == id) {
+				return ErrUserNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE todos SET user_id = ?, created_by = ? WHERE user_id = ?", target.ID, target.Name, id); err != nil {
+				return err
+			}
+		case disposition.Cascade:
+			if _, err := tx.Exec("DELETE FROM todos WHERE user_id = ?", id); err != nil {
+				return err
+			}
+		default:
+			var owned int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM todos WHERE user_id = ?", id).Scan(&owned); err != nil {
+				return err
+			}
+			if owned > 0 {
+				return ErrUserHasTodos
+			}
+		}
+
+		// Workspace memberships, assignments, mentions and notifications go with the user
+		// (ON DELETE CASCADE), their comments and the notifications they caused stay
+		// without them (ON DELETE SET NULL)
+		_, err = tx.Exec("DELETE FROM users WHERE id = ?", id)
+		return err
+	})
+}
+
+// queryUser runs a single-row SELECT over userColumns
+func (r *SQLiteRepository) queryUser(query string, args ...any) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow(query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// scanUser reads one user in userColumns order
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var createdAt string
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.PasswordHash, &user.Deactivated, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	user.CreatedAt = parseTime(createdAt)
+	return &user, nil
+}
+
+// mapUserError turns a unique email violation into ErrEmailTaken
+func mapUserError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrEmailTaken
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"test_mekari/internal/models"
+)
+
+const viewColumns = "id, workspace_id, user_id, name, filter, sort, group_by, shared, created_at, updated_at"
+
+// GetViews returns the views of a workspace userID saved or others shared, ordered by ID
+func (r *SQLiteRepository) GetViews(workspaceID, userID int) ([]models.View, error) {
+	rows, err := r.db.Query("SELECT "+viewColumns+" FROM views WHERE workspace_id = ? AND (user_id = ? OR shared) ORDER BY id", workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]models.View, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, rows.Err()
+}
+
+// GetView retrieves a view by ID
+func (r *SQLiteRepository) GetView(id int) (*models.View, error) {
+	view, err := scanView(r.db.QueryRow("SELECT "+viewColumns+" FROM views WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrViewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// CreateView stores a new view in v.WorkspaceID
+func (r *SQLiteRepository) CreateView(v *models.View) (*models.View, error) {
+	view := *v
+	err := r.inTx(func(tx *sql.Tx) error {
+		if err := workspaceExists(tx, v.WorkspaceID); err != nil {
+			return err
+		}
+		if err := usersExist(tx, v.UserID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO views (workspace_id, user_id, name, filter, sort, group_by, shared, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			v.WorkspaceID, v.UserID, v.Name, v.Filter, v.Sort, string(v.GroupBy), v.Shared, formatTime(v.CreatedAt), formatTime(v.UpdatedAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		view.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetView(view.ID)
+}
+
+// UpdateView changes the name, filter, sort, grouping and sharing of a view
+func (r *SQLiteRepository) UpdateView(v *models.View) (*models.View, error) {
+	result, err := r.db.Exec(
+		"UPDATE views SET name = ?, filter = ?, sort = ?, group_by = ?, shared = ?, updated_at = ? WHERE id = ?",
+		v.Name, v.Filter, v.Sort, string(v.GroupBy), v.Shared, formatTime(v.UpdatedAt), v.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result); err != nil {
+		return nil, ErrViewNotFound
+	}
+	return r.GetView(v.ID)
+}
+
+// DeleteView removes a view
+func (r *SQLiteRepository) DeleteView(id int) error {
+	result, err := r.db.Exec("DELETE FROM views WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return ErrViewNotFound
+	}
+	return nil
+}
+
+func scanView(row rowScanner) (*models.View, error) {
+	var v models.View
+	var groupBy, createdAt, updatedAt string
+	if err := row.Scan(&v.ID, &v.WorkspaceID, &v.UserID, &v.Name, &v.Filter, &v.Sort, &groupBy, &v.Shared, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	v.GroupBy = models.GroupBy(groupBy)
+	v.CreatedAt = parseTime(createdAt)
+	v.UpdatedAt = parseTime(updatedAt)
+	return &v, nil
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+const (
+	webhookColumns  = "id, workspace_id, url, events, secret, active, created_by, created_at, updated_at"
+	deliveryColumns = "id, webhook_id, workspace_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, completed_at"
+)
+
+// GetWebhooks returns the webhooks of a workspace ordered by ID
+func (r *SQLiteRepository) GetWebhooks(workspaceID int) ([]models.Webhook, error) {
+	rows, err := r.db.Query("SELECT "+webhookColumns+" FROM webhooks WHERE workspace_id = ? ORDER BY id", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook retrieves a webhook by ID within a workspace
+func (r *SQLiteRepository) GetWebhook(workspaceID, id int) (*models.Webhook, error) {
+	webhook, err := scanWebhook(r.db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE workspace_id = ? AND id = ?", workspaceID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// CreateWebhook stores a new webhook in w.WorkspaceID
+func (r *SQLiteRepository) CreateWebhook(w *models.Webhook) (*models.Webhook, error) {
+	events, err := encodeEvents(w.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := *w
+	err = r.inTx(func(tx *sql.Tx) error {
+		if err := workspaceExists(tx, w.WorkspaceID); err != nil {
+			return err
+		}
+		if err := usersExist(tx, w.CreatedBy); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO webhooks (workspace_id, url, events, secret, active, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			w.WorkspaceID, w.URL, events, w.Secret, w.Active, w.CreatedBy, formatTime(w.CreatedAt), formatTime(w.UpdatedAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		webhook.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetWebhook(webhook.WorkspaceID, webhook.ID)
+}
+
+// UpdateWebhook changes the URL, events, secret and active flag of a webhook
+func (r *SQLiteRepository) UpdateWebhook(w *models.Webhook) (*models.Webhook, error) {
+	events, err := encodeEvents(w.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.Exec(
+		"UPDATE webhooks SET url = ?, events = ?, secret = ?, active = ?, updated_at = ? WHERE workspace_id = ? AND id = ?",
+		w.URL, events, w.Secret, w.Active, formatTime(w.UpdatedAt), w.WorkspaceID, w.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return r.GetWebhook(w.WorkspaceID, w.ID)
+}
+
+// DeleteWebhook removes a webhook; its deliveries cascade
+func (r *SQLiteRepository) DeleteWebhook(workspaceID, id int) error {
+	result, err := r.db.Exec("DELETE FROM webhooks WHERE workspace_id = ? AND id = ?", workspaceID, id)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// GetDeliveries returns the latest limit deliveries of a webhook, newest first
+func (r *SQLiteRepository) GetDeliveries(workspaceID, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := r.GetWebhook(workspaceID, webhookID); err != nil {
+		return nil, err
+	}
+	return r.queryDeliveries("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?", webhookID, limit)
+}
+
+// GetDelivery retrieves a delivery of a webhook by ID
+func (r *SQLiteRepository) GetDelivery(workspaceID, webhookID, id int) (*models.WebhookDelivery, error) {
+	d, err := scanDelivery(r.db.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE workspace_id = ? AND webhook_id = ? AND id = ?", workspaceID, webhookID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// CreateDelivery queues a delivery for d.WebhookID
+func (r *SQLiteRepository) CreateDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	attempts, err := encodeAttempts(d.Attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = r.inTx(func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM webhooks WHERE workspace_id = ? AND id = ?", d.WorkspaceID, d.WebhookID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrWebhookNotFound
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO webhook_deliveries (webhook_id, workspace_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, completed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			d.WebhookID, d.WorkspaceID, d.EventID, d.EventType, string(d.Payload), d.Status, attempts,
+			nullableTime(d.NextAttemptAt), formatTime(d.CreatedAt), nullableTime(d.CompletedAt),
+		)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetDelivery(d.WorkspaceID, d.WebhookID, int(id))
+}
+
+// UpdateDelivery records the outcome of an attempt
+func (r *SQLiteRepository) UpdateDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	attempts, err := encodeAttempts(d.Attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, completed_at = ? WHERE id = ?",
+		d.Status, attempts, nullableTime(d.NextAttemptAt), nullableTime(d.CompletedAt), d.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result); err != nil {
+		return nil, ErrDeliveryNotFound
+	}
+	return scanDelivery(r.db.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?", d.ID))
+}
+
+// DueDeliveries returns at most limit pending deliveries due at now, earliest first
+func (r *SQLiteRepository) DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return r.queryDeliveries(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE status = ? AND julianday(next_attempt_at) <= julianday(?) ORDER BY julianday(next_attempt_at), id LIMIT ?",
+		models.DeliveryPending, formatTime(now), limit,
+	)
+}
+
+// PruneDeliveries deletes the deliveries completed before the given time
+func (r *SQLiteRepository) PruneDeliveries(before time.Time) (int, error) {
+	result, err := r.db.Exec(
+		"DELETE FROM webhook_deliveries WHERE status != ? AND julianday(completed_at) < julianday(?)",
+		models.DeliveryPending, formatTime(before),
+	)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := result.RowsAffected()
+	return int(pruned), err
+}
+
+// queryDeliveries runs a query selecting deliveryColumns
+func (r *SQLiteRepository) queryDeliveries(query string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var w models.Webhook
+	var events string
+	var createdBy sql.NullInt64
+	var createdAt, updatedAt string
+	if err := row.Scan(&w.ID, &w.WorkspaceID, &w.URL, &events, &w.Secret, &w.Active, &createdBy, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+		return nil, fmt.Errorf("webhook %d: decode events: %w", w.ID, err)
+	}
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+	w.CreatedBy = int(createdBy.Int64)
+	w.CreatedAt = parseTime(createdAt)
+	w.UpdatedAt = parseTime(updatedAt)
+	return &w, nil
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload, attempts, createdAt string
+	var nextAttemptAt, completedAt sql.NullString
+	err := row.Scan(&d.ID, &d.WebhookID, &d.WorkspaceID, &d.EventID, &d.EventType, &payload, &d.Status, &attempts,
+		&nextAttemptAt, &createdAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(attempts), &d.Attempts); err != nil {
+		return nil, fmt.Errorf("delivery %d: decode attempts: %w", d.ID, err)
+	}
+	if d.Attempts == nil {
+		d.Attempts = []models.DeliveryAttempt{}
+	}
+	d.Payload = json.RawMessage(payload)
+	d.NextAttemptAt = parseNullableTime(nextAttemptAt)
+	d.CreatedAt = parseTime(createdAt)
+	d.CompletedAt = parseNullableTime(completedAt)
+	return &d, nil
+}
+
+// encodeEvents stores the event types of a webhook as a JSON array
+func encodeEvents(events []string) (string, error) {
+	if events == nil {
+		events = []string{}
+	}
+	data, err := json.Marshal(events)
+	return string(data), err
+}
+
+// encodeAttempts stores the attempts of a delivery as a JSON array
+func encodeAttempts(attempts []models.DeliveryAttempt) (string, error) {
+	if attempts == nil {
+		attempts = []models.DeliveryAttempt{}
+	}
+	data, err := json.Marshal(attempts)
+	return string(data), err
+}
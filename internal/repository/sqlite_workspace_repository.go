@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"test_mekari/internal/models"
+)
+
+const (
+	workspaceColumns  = "id, name, created_at"
+	membershipColumns = "workspace_id, user_id, role, created_at"
+)
+
+// GetWorkspace retrieves a workspace by ID
+func (r *SQLiteRepository) GetWorkspace(id int) (*models.Workspace, error) {
+	workspace, err := scanWorkspace(r.db.QueryRow("SELECT "+workspaceColumns+" FROM workspaces WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkspaceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// GetAllWorkspaces returns every workspace ordered by ID
+func (r *SQLiteRepository) GetAllWorkspaces() ([]models.Workspace, error) {
+	return r.queryWorkspaces("SELECT " + workspaceColumns + " FROM workspaces ORDER BY id")
+}
+
+// GetWorkspacesForUser returns the workspaces userID is a member of
+func (r *SQLiteRepository) GetWorkspacesForUser(userID int) ([]models.Workspace, error) {
+	return r.queryWorkspaces(
+		"SELECT w.id, w.name, w.created_at FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id WHERE m.user_id = ? ORDER BY w.id",
+		userID,
+	)
+}
+
+// CreateWorkspace stores a new workspace and its first member in one transaction
+func (r *SQLiteRepository) CreateWorkspace(workspace *models.Workspace, owner models.Membership) (*models.Workspace, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		if _, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", owner.UserID)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		result, err := tx.Exec("INSERT INTO workspaces (name, created_at) VALUES (?, ?)", workspace.Name, formatTime(workspace.CreatedAt))
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		workspace.ID = int(id)
+
+		owner.WorkspaceID = workspace.ID
+		_, err = tx.Exec(
+			"INSERT INTO workspace_members ("+membershipColumns+") VALUES (?, ?, ?, ?)",
+			owner.WorkspaceID, owner.UserID, owner.Role, formatTime(owner.CreatedAt),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceCopy := *workspace
+	return &workspaceCopy, nil
+}
+
+// DeleteWorkspace removes a workspace with its todos, lists and members in one transaction
+func (r *SQLiteRepository) DeleteWorkspace(id int) error {
+	return r.inTodoTx(func(tx *todoTx) error {
+		if err := workspaceExists(tx, id); err != nil {
+			return err
+		}
+		if err := tx.trackQuery("SELECT id FROM todos WHERE workspace_id = ?", id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM todos WHERE workspace_id = ?", id); err != nil {
+			return err
+		}
+		// Lists and members go with the workspace (ON DELETE CASCADE)
+		_, err := tx.Exec("DELETE FROM workspaces WHERE id = ?", id)
+		return err
+	})
+}
+
+// GetMembership returns the membership of userID in workspaceID
+func (r *SQLiteRepository) GetMembership(workspaceID, userID int) (*models.Membership, error) {
+	membership, err := scanMembership(r.db.QueryRow(
+		"SELECT "+membershipColumns+" FROM workspace_members WHERE workspace_id = ? AND user_id = ?",
+		workspaceID, userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMembershipNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// GetMembers returns the members of a workspace ordered by user ID
+func (r *SQLiteRepository) GetMembers(workspaceID int) ([]models.Membership, error) {
+	if err := workspaceExists(r.db, workspaceID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query("SELECT "+membershipColumns+" FROM workspace_members WHERE workspace_id = ? ORDER BY user_id", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]models.Membership, 0)
+	for rows.Next() {
+		membership, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *membership)
+	}
+	return members, rows.Err()
+}
+
+// SaveMembership adds a member or changes their role, keeping the original join time
+func (r *SQLiteRepository) SaveMembership(membership *models.Membership) (*models.Membership, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		if err := workspaceExists(tx, membership.WorkspaceID); err != nil {
+			return err
+		}
+		if _, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", membership.UserID)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		_, err := tx.Exec(
+			"INSERT INTO workspace_members ("+membershipColumns+") VALUES (?, ?, ?, ?) "+
+				"ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = excluded.role",
+			membership.WorkspaceID, membership.UserID, membership.Role, formatTime(membership.CreatedAt),
+		)
+		if err != nil {
+			return err
+		}
+
+		saved, err := scanMembership(tx.QueryRow(
+			"SELECT "+membershipColumns+" FROM workspace_members WHERE workspace_id = ? AND user_id = ?",
+			membership.WorkspaceID, membership.UserID,
+		))
+		if err != nil {
+			return err
+		}
+		*membership = *saved
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	membershipCopy := *membership
+	return &membershipCopy, nil
+}
+
+// RemoveMembership removes userID from workspaceID; their todos stay in the workspace
+func (r *SQLiteRepository) RemoveMembership(workspaceID, userID int) error {
+	result, err := r.db.Exec("DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
+
+// queryWorkspaces runs a SELECT over workspaceColumns and scans every row
+func (r *SQLiteRepository) queryWorkspaces(query string, args ...any) ([]models.Workspace, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := make([]models.Workspace, 0)
+	for rows.Next() {
+		workspace, err := scanWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, *workspace)
+	}
+	return workspaces, rows.Err()
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// workspaceExists returns ErrWorkspaceNotFound unless the workspace exists
+func workspaceExists(db queryRower, id int) error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM workspaces WHERE id = ?", id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrWorkspaceNotFound
+	}
+	return nil
+}
+
+// joinDefaultWorkspace makes user a member of the default workspace, if it exists
+func joinDefaultWorkspace(tx *sql.Tx, user models.User) error {
+	membership := defaultMembership(user)
+	_, err := tx.Exec(
+		"INSERT OR IGNORE INTO workspace_members ("+membershipColumns+") SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM workspaces WHERE id = ?)",
+		membership.WorkspaceID, membership.UserID, membership.Role, formatTime(membership.CreatedAt), membership.WorkspaceID,
+	)
+	return err
+}
+
+// scanWorkspace reads one workspace in workspaceColumns order
+func scanWorkspace(row rowScanner) (*models.Workspace, error) {
+	var workspace models.Workspace
+	var createdAt string
+	if err := row.Scan(&workspace.ID, &workspace.Name, &createdAt); err != nil {
+		return nil, err
+	}
+	workspace.CreatedAt = parseTime(createdAt)
+	return &workspace, nil
+}
+
+// scanMembership reads one membership in membershipColumns order
+func scanMembership(row rowScanner) (*models.Membership, error) {
+	var membership models.Membership
+	var createdAt string
+	if err := row.Scan(&membership.WorkspaceID, &membership.UserID, &membership.Role, &createdAt); err != nil {
+		return nil, err
+	}
+	membership.CreatedAt = parseTime(createdAt)
+	return &membership, nil
+}
@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/models"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email is already in use")
+	ErrUserHasTodos = errors.New("user still owns todos")
+
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrMembershipNotFound = errors.New("user is not a member of this workspace")
+
+	ErrListNotFound = errors.New("list not found")
+
+	ErrLabelNotFound  = errors.New("label not found")
+	ErrLabelNameTaken = errors.New("label name is already in use in this workspace")
+
+	ErrParentNotFound  = errors.New("parent todo not found")
+	ErrBlockerNotFound = errors.New("blocking todo not found")
+	ErrSubtaskCycle    = errors.New("a todo cannot be a subtask of itself or of one of its subtasks")
+	ErrDependencyCycle = errors.New("a todo cannot depend on itself or on a todo that depends on it")
+
+	ErrAssigneeNotFound = errors.New("assignee not found")
+
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrReplyNotFound   = errors.New("comment replied to not found")
+
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	ErrAttachmentNotFound = errors.New("attachment not found")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	ErrViewNotFound = errors.New("view not found")
+)
+
+// DefaultWorkspaceID is the workspace that holds data from before workspaces
+// existed and that every new user joins
+const DefaultWorkspaceID = 1
+
+// Store is a complete storage backend. Every backend implements all store
+// contracts in one type, so multi-entity changes can be applied atomically.
+type Store interface {
+	TodoStore
+	UserStore
+	WorkspaceStore
+	ListStore
+	LabelStore
+	CommentStore
+	NotificationStore
+	AttachmentStore
+	WebhookStore
+	ViewStore
+	ReminderStore
+}
+
+// TodoStore is the persistence contract for todos.
+// Every backend (in-memory, SQLite, ...) must satisfy the same semantics:
+//   - Every read and write is scoped to one workspace; a todo of another
+//     workspace behaves exactly like a todo that does not exist
+//   - FindAll returns the todos matching filter in filter.Sort order, which
+//     is insertion (ID) order by default; timestamps are compared at
+//     millisecond precision
+//   - Create assigns a new, never reused ID and returns ErrWorkspaceNotFound
+//     for an unknown todo.WorkspaceID
+//   - Create and Update return ErrListNotFound when todo.ListID is not a list
+//     of the todo's workspace (0 means no list), ErrLabelNotFound when one
+//     of todo.LabelIDs is not a label of it, ErrParentNotFound when
+//     todo.ParentID is not a todo of it (0 means top-level), and
+//     ErrBlockerNotFound when one of todo.BlockedBy is not a todo of it, and
+//     ErrAssigneeNotFound when one of todo.AssigneeIDs is not a user.
+//   - Update returns ErrSubtaskCycle when todo.ParentID is the todo itself or
+//     one of its subtasks, and ErrDependencyCycle when one of todo.BlockedBy
+//     is the todo itself or waits for it, directly or through other todos;
+//     both are checked atomically with the write
+//   - Depth limits and workspace membership of assignees are left to the caller
+//   - Returned todos have LabelIDs, BlockedBy and AssigneeIDs sorted and never
+//     nil, Checklist never nil, Progress nil, Blocked false and CommentCount 0
+//   - Deleting a todo by any means removes it from the BlockedBy of other todos
+//     and deletes its comments, attachments and the notifications about it
+//   - Deleting a user removes them from the AssigneeIDs of every todo
+//   - FindByID, Update, Delete and DeleteTree return ErrTodoNotFound for unknown IDs
+//   - A todo whose parent is deleted by any other means (DeleteUser,
+//     DeleteWorkspace) becomes top-level
+type TodoStore interface {
+	FindAll(workspaceID int, filter TodoFilter) ([]models.Todo, error)
+	FindByID(workspaceID, id int) (*models.Todo, error)
+	Create(todo *models.Todo) (*models.Todo, error)
+	// Update matches on both todo.ID and todo.WorkspaceID; todos never move between workspaces
+	Update(todo *models.Todo) (*models.Todo, error)
+	// SaveTodos updates every todo of updates as Update does and creates every
+	// todo of creates as Create does, setting their IDs, atomically: either
+	// all of them are stored or none is
+	SaveTodos(updates, creates []*models.Todo) error
+	// Delete removes the todo; its subtasks move up to its parent, atomically
+	Delete(workspaceID, id int) error
+	// DeleteTree removes the todo together with all of its subtasks, atomically
+	DeleteTree(workspaceID, id int) error
+	// ObserveTodos has fn called with the todos every later mutation of any
+	// kind created, changed or deleted, once it committed. Calls come in
+	// commit order, before the mutation returns and while the store holds its
+	// write lock: fn must be quick and must not call the store.
+	ObserveTodos(fn func([]TodoChange))
+}
+
+// TodoChange is a todo a mutation created, changed or deleted: Before is nil
+// for a created todo and After is nil for a deleted one. A mutation reports
+// each todo once, in ID order, and never a todo it left as it was.
+type TodoChange struct {
+	Before *models.Todo
+	After  *models.Todo
+}
+
+// TodoFilter narrows and orders FindAll. The zero value matches every todo
+// that is not in an archived list, in ID order.
+type TodoFilter struct {
+	// UserID keeps only the todos of one owner (0 = any owner)
+	UserID int
+	// ListID keeps only the todos of one list, archived or not; a pointer to 0
+	// keeps the todos that are in no list (nil = any list)
+	ListID *int
+	// ParentID keeps only the subtasks of one todo; a pointer to 0 keeps the
+	// top-level todos (nil = any)
+	ParentID *int
+	// AssigneeID keeps only the todos assigned to one user; a pointer to 0
+	// keeps the unassigned todos (nil = any)
+	AssigneeID *int
+	// IncludeArchived also returns the todos of archived lists
+	IncludeArchived bool
+	// DueAfter and DueBefore keep only the todos due strictly after / before an instant
+	DueAfter  *time.Time
+	DueBefore *time.Time
+	// OverdueAt keeps only the open (not completed) todos that were due before an instant
+	OverdueAt *time.Time
+	// Priorities keeps only the todos with one of these priorities (empty = any)
+	Priorities []models.Priority
+	// LabelIDs keeps only the todos carrying any of these labels, or all of
+	// them with AllLabels (empty = any labels, or none)
+	LabelIDs  []int
+	AllLabels bool
+	// Where keeps only the todos matching a filter expression, with its labels
+	// resolved to IDs (nil = any)
+	Where expr.Node
+
+	// Sort orders the todos by a field, ties broken by ID (empty = by ID);
+	// Desc reverses both, but todos without a due date stay last
+	Sort TodoSort
+	Desc bool
+	// After keeps only the todos that come after it in that order, for keyset
+	// pagination; only its ID and the sorted field are read (nil = from the start)
+	After *models.Todo
+	// Limit keeps only the first todos (0 = all)
+	Limit int
+}
+
+// TodoSort is a field FindAll can order todos by
+type TodoSort string
+
+const (
+	SortByID        TodoSort = ""
+	SortByCreatedAt TodoSort = "created_at"
+	SortByUpdatedAt TodoSort = "updated_at"
+	SortByDue       TodoSort = "due" // todos without a due date come last
+	SortByPriority  TodoSort = "priority"
+	SortByText      TodoSort = "text" // ignoring the case of ASCII letters
+)
+
+// Valid reports whether s is one of the known sort fields
+func (s TodoSort) Valid() bool {
+	switch s {
+	case SortByID, SortByCreatedAt, SortByUpdatedAt, SortByDue, SortByPriority, SortByText:
+		return true
+	}
+	return false
+}
+
+// UserStore is the persistence contract for users.
+// Emails are unique ignoring case; CreateUser and UpdateUser return ErrEmailTaken otherwise.
+type UserStore interface {
+	GetUserByID(userID int) (*models.User, error)
+	// GetUserByEmail matches the email case-insensitively
+	GetUserByEmail(email string) (*models.User, error)
+	GetAllUsers() ([]models.User, error)
+	// CreateUser also makes the user a member of the default workspace with their role
+	CreateUser(user *models.User) (*models.User, error)
+	// UpdateUser also rewrites CreatedBy on the user's todos, atomically
+	UpdateUser(user *models.User) (*models.User, error)
+	// DeleteUser removes the user and applies disposition to their todos, atomically
+	DeleteUser(id int, disposition TodoDisposition) error
+}
+
+// TodoDisposition says what happens to the todos of a deleted user.
+// The zero value blocks the deletion with ErrUserHasTodos while any todo remains.
+type TodoDisposition struct {
+	// Cascade deletes the user's todos
+	Cascade bool `json:"cascade,omitempty"`
+	// ReassignTo moves the user's todos to another user (takes precedence over Cascade)
+	ReassignTo int `json:"reassign_to,omitempty"`
+}
+
+// WorkspaceStore is the persistence contract for workspaces and their members
+type WorkspaceStore interface {
+	GetWorkspace(id int) (*models.Workspace, error)
+	// GetAllWorkspaces returns every workspace ordered by ID
+	GetAllWorkspaces() ([]models.Workspace, error)
+	// GetWorkspacesForUser returns the workspaces userID is a member of, ordered by ID
+	GetWorkspacesForUser(userID int) ([]models.Workspace, error)
+	// CreateWorkspace stores the workspace together with its first member, atomically
+	CreateWorkspace(workspace *models.Workspace, owner models.Membership) (*models.Workspace, error)
+	// DeleteWorkspace removes the workspace with all of its todos, lists, labels and members, atomically
+	DeleteWorkspace(id int) error
+
+	GetMembership(workspaceID, userID int) (*models.Membership, error)
+	// GetMembers returns the members of a workspace ordered by user ID
+	GetMembers(workspaceID int) ([]models.Membership, error)
+	// SaveMembership adds the member, or changes the role of an existing one
+	SaveMembership(membership *models.Membership) (*models.Membership, error)
+	RemoveMembership(workspaceID, userID int) error
+}
+
+// ListStore is the persistence contract for todo lists. Lists are scoped to a
+// workspace like todos; a list of another workspace behaves like a missing one.
+type ListStore interface {
+	// GetLists returns the lists of a workspace, archived ones included, ordered by ID
+	GetLists(workspaceID int) ([]models.List, error)
+	GetList(workspaceID, id int) (*models.List, error)
+	// CreateList returns ErrWorkspaceNotFound for an unknown list.WorkspaceID
+	CreateList(list *models.List) (*models.List, error)
+	// UpdateList matches on both list.ID and list.WorkspaceID
+	UpdateList(list *models.List) (*models.List, error)
+	// DeleteList removes the list; its todos stay in the workspace without a list, atomically
+	DeleteList(workspaceID, id int) error
+}
+
+// LabelStore is the persistence contract for labels. Labels are scoped to a
+// workspace like lists; names are unique per workspace ignoring case, and
+// CreateLabel and UpdateLabel return ErrLabelNameTaken otherwise.
+type LabelStore interface {
+	// GetLabels returns the labels of a workspace ordered by ID
+	GetLabels(workspaceID int) ([]models.Label, error)
+	GetLabel(workspaceID, id int) (*models.Label, error)
+	// CreateLabel returns ErrWorkspaceNotFound for an unknown label.WorkspaceID
+	CreateLabel(label *models.Label) (*models.Label, error)
+	// UpdateLabel matches on both label.ID and label.WorkspaceID
+	UpdateLabel(label *models.Label) (*models.Label, error)
+	// DeleteLabel removes the label and detaches it from every todo, atomically
+	DeleteLabel(workspaceID, id int) error
+}
+
+// CommentStore is the persistence contract for the comments on todos.
+// Comments are scoped to the workspace of their todo like todos are.
+//   - CreateComment returns ErrTodoNotFound unless comment.TodoID is a todo of
+//     comment.WorkspaceID, ErrReplyNotFound unless comment.ReplyToID is a
+//     comment on the same todo (0 means none), and ErrUserNotFound when the
+//     author or one of comment.Mentions is not a user
+//   - Returned comments have Mentions sorted and never nil
+//   - Deleting a comment keeps its replies, they no longer reply to anything
+//   - Deleting a user keeps their comments with AuthorID 0 and removes them
+//     from the Mentions of every comment
+type CommentStore interface {
+	// GetComments returns the comments on a todo ordered by ID, ErrTodoNotFound for an unknown todo
+	GetComments(workspaceID, todoID int) ([]models.Comment, error)
+	GetComment(workspaceID, id int) (*models.Comment, error)
+	CreateComment(comment *models.Comment) (*models.Comment, error)
+	// UpdateComment matches on comment.ID and comment.WorkspaceID and only
+	// changes Body, Mentions and EditedAt
+	UpdateComment(comment *models.Comment) (*models.Comment, error)
+	DeleteComment(workspaceID, id int) error
+	// CountComments returns the number of comments on each of todoIDs within
+	// a workspace, todos without comments (or of other workspaces) left out
+	CountComments(workspaceID int, todoIDs []int) (map[int]int, error)
+	// GetAllComments returns the comments on every todo of a workspace ordered by ID
+	GetAllComments(workspaceID int) ([]models.Comment, error)
+}
+
+// NotificationStore is the persistence contract for the per-user notification
+// inbox. Notifications belong to their recipient, across workspaces.
+//   - CreateNotification returns ErrUserNotFound when the recipient or the
+//     actor is not a user, ErrTodoNotFound unless n.TodoID is a todo of
+//     n.WorkspaceID and ErrCommentNotFound unless n.CommentID is a comment on
+//     it (0 means none)
+//   - Deleting a comment keeps the notifications about it with CommentID 0
+//   - Deleting a user deletes their notifications and keeps the ones they
+//     caused with ActorID 0
+type NotificationStore interface {
+	// GetNotifications returns the notifications of a user, newest first;
+	// only the unread ones with unreadOnly
+	GetNotifications(userID int, unreadOnly bool) ([]models.Notification, error)
+	CreateNotification(n *models.Notification) (*models.Notification, error)
+	// MarkNotificationRead sets ReadAt of a notification of userID, unless it is
+	// read already. Returns ErrNotificationNotFound for notifications of other users.
+	MarkNotificationRead(userID, id int, at time.Time) (*models.Notification, error)
+	// MarkAllNotificationsRead marks every unread notification of a user read
+	// at once, returning how many there were
+	MarkAllNotificationsRead(userID int, at time.Time) (int, error)
+	// CountUnreadNotifications returns the number of unread notifications of a user
+	CountUnreadNotifications(userID int) (int, error)
+}
+
+// AttachmentStore is the persistence contract for the files attached to todos.
+// It only keeps the metadata, the content lives in blob storage under BlobKey.
+//   - CreateAttachment returns ErrTodoNotFound unless a.TodoID is a todo of
+//     a.WorkspaceID and ErrUserNotFound when the uploader is not a user
+//   - Deleting a user keeps their attachments with UploaderID 0
+//   - Deleting attachments never deletes blobs; unreferenced blobs are swept
+//     by the caller, see BlobKeys
+type AttachmentStore interface {
+	// GetAttachments returns the attachments of a todo ordered by ID, ErrTodoNotFound for an unknown todo
+	GetAttachments(workspaceID, todoID int) ([]models.Attachment, error)
+	GetAttachment(workspaceID, id int) (*models.Attachment, error)
+	CreateAttachment(a *models.Attachment) (*models.Attachment, error)
+	DeleteAttachment(workspaceID, id int) error
+	// BlobKeys returns the set of blob keys referenced by any attachment, across all workspaces
+	BlobKeys() (map[string]bool, error)
+}
+
+// WebhookStore is the persistence contract for outgoing webhooks and their
+// durable delivery queue.
+//   - CreateWebhook returns ErrWorkspaceNotFound for an unknown w.WorkspaceID
+//     and ErrUserNotFound when the creator is not a user
+//   - CreateDelivery returns ErrWebhookNotFound unless d.WebhookID is a
+//     webhook of d.WorkspaceID
+//   - Deleting a webhook deletes its deliveries
+//   - Deleting a user keeps the webhooks they created with CreatedBy 0
+//   - Returned webhooks have Events, and deliveries Attempts, never nil
+type WebhookStore interface {
+	// GetWebhooks returns the webhooks of a workspace ordered by ID
+	GetWebhooks(workspaceID int) ([]models.Webhook, error)
+	GetWebhook(workspaceID, id int) (*models.Webhook, error)
+	CreateWebhook(w *models.Webhook) (*models.Webhook, error)
+	// UpdateWebhook matches on w.ID and w.WorkspaceID and only changes URL,
+	// Events, Secret, Active and UpdatedAt
+	UpdateWebhook(w *models.Webhook) (*models.Webhook, error)
+	DeleteWebhook(workspaceID, id int) error
+
+	// GetDeliveries returns the latest limit deliveries of a webhook, newest
+	// first, ErrWebhookNotFound for an unknown webhook
+	GetDeliveries(workspaceID, webhookID, limit int) ([]models.WebhookDelivery, error)
+	GetDelivery(workspaceID, webhookID, id int) (*models.WebhookDelivery, error)
+	CreateDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	// UpdateDelivery matches on d.ID and only changes Status, Attempts,
+	// NextAttemptAt and CompletedAt
+	UpdateDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	// DueDeliveries returns at most limit pending deliveries whose
+	// NextAttemptAt is at or before now, across all workspaces, earliest first
+	DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// PruneDeliveries deletes the deliveries that succeeded or went dead
+	// before the given time, returning how many
+	PruneDeliveries(before time.Time) (int, error)
+}
+
+// ViewStore is the persistence contract for saved views. A view belongs to
+// the user who saved it, within one workspace.
+//   - CreateView returns ErrWorkspaceNotFound for an unknown v.WorkspaceID
+//     and ErrUserNotFound when the owner is not a user
+//   - Deleting a user deletes their views, deleting a workspace its views
+type ViewStore interface {
+	// GetViews returns the views of a workspace userID saved, and the views
+	// shared with the workspace by others, ordered by ID
+	GetViews(workspaceID, userID int) ([]models.View, error)
+	GetView(id int) (*models.View, error)
+	CreateView(v *models.View) (*models.View, error)
+	// UpdateView matches on v.ID and only changes Name, Filter, Sort,
+	// GroupBy, Shared and UpdatedAt
+	UpdateView(v *models.View) (*models.View, error)
+	DeleteView(id int) error
+}
+
+// ReminderStore is used by the reminder scheduler, across all workspaces
+type ReminderStore interface {
+	// DueReminders returns the open todos whose RemindAt is at or before now
+	// and whose reminder has not fired yet, earliest reminder first
+	DueReminders(now time.Time) ([]models.Todo, error)
+	// MarkReminded sets RemindedAt on todo id, unless its RemindAt no longer
+	// equals remindAt (it was rescheduled meanwhile). Returns ErrTodoNotFound for unknown IDs.
+	MarkReminded(id int, remindAt, at time.Time) error
+}
+
+// Compile-time checks that every backend satisfies the store contracts
+var (
+	_ Store = (*TodoRepository)(nil)
+	_ Store = (*SQLiteRepository)(nil)
+)
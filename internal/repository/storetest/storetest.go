@@ -0,0 +1,2738 @@
+// Package storetest implements a backend-agnostic conformance suite for
+// repository.Store implementations.
+//
+// Like testing/fstest, it reports failures as an error instead of depending
+// on *testing.T, so it can be driven from any harness (see TestConformance in
+// internal/repository).
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// Factory returns a fresh, empty (users and default workspace seeded) store for one check
+type Factory func() (repository.Store, error)
+
+// check is a single named conformance rule
+type check struct {
+	name string
+	run  func(store repository.Store) error
+}
+
+// ws is the workspace every check works in unless it is testing isolation
+const ws = repository.DefaultWorkspaceID
+
+var checks = []check{
+	{"create assigns sequential ids", checkCreateAssignsIDs},
+	{"find all keeps insertion order", checkFindAllOrder},
+	{"find by id returns a copy", checkFindByID},
+	{"find all filters by user", checkFindByUser},
+	{"update replaces fields", checkUpdate},
+	{"save todos stores every update and creation or none", checkSaveTodos},
+	{"delete removes and never reuses ids", checkDelete},
+	{"unknown ids return ErrTodoNotFound", checkNotFound},
+	{"seeded users are readable", checkUsers},
+	{"create user enforces unique emails", checkCreateUser},
+	{"update user renames their todos", checkUpdateUser},
+	{"delete user blocks, reassigns or cascades", checkDeleteUser},
+	{"todos are isolated per workspace", checkWorkspaceIsolation},
+	{"workspace members", checkWorkspaceMembers},
+	{"delete workspace removes its todos and members", checkDeleteWorkspace},
+	{"lists are scoped to their workspace", checkListIsolation},
+	{"find all filters by list and hides archived lists", checkListFilter},
+	{"delete list keeps its todos", checkDeleteList},
+	{"find all filters by due date", checkDueFilter},
+	{"due reminders fire once", checkReminders},
+	{"find all sorts and pages after a todo", checkSortPages},
+	{"find all evaluates filter expressions", checkWhere},
+	{"labels are scoped and unique per workspace", checkLabelIsolation},
+	{"find all filters by priority and labels", checkLabelFilter},
+	{"delete label detaches it from todos", checkDeleteLabel},
+	{"subtasks stay in their workspace", checkSubtasks},
+	{"delete promotes subtasks, delete tree removes them", checkDeleteSubtasks},
+	{"checklists are stored with their todo", checkChecklist},
+	{"blockers stay in their workspace and go away with deleted todos", checkBlockers},
+	{"find all filters by assignee, deleted users are unassigned", checkAssignees},
+	{"comments stay with their todo and outlive replies and authors", checkComments},
+	{"notifications belong to their recipient", checkNotifications},
+	{"attachments stay with their todo and outlive their uploader", checkAttachments},
+	{"webhooks queue deliveries that are retried, completed and pruned", checkWebhooks},
+	{"views are private to their owner unless shared", checkViews},
+	{"observers see every todo a mutation changes", checkObserveTodos},
+}
+
+// Run executes every conformance check against a fresh store from newStore
+// and returns all failures joined together, or nil if the backend conforms
+func Run(newStore Factory) error {
+	var failures []string
+	for _, c := range checks {
+		store, err := newStore()
+		if err != nil {
+			return fmt.Errorf("create store: %w", err)
+		}
+		if err := c.run(store); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", c.name, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+func newTodo(text string, userID int) *models.Todo {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &models.Todo{
+		WorkspaceID: ws,
+		Text:        text,
+		Priority:    models.PriorityNone,
+		UserID:      userID,
+		CreatedBy:   fmt.Sprintf("user %d", userID),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+func mustCreate(todos repository.TodoStore, text string, userID int) (*models.Todo, error) {
+	todo, err := todos.Create(newTodo(text, userID))
+	if err != nil {
+		return nil, fmt.Errorf("create %q: %w", text, err)
+	}
+	return todo, nil
+}
+
+func checkCreateAssignsIDs(store repository.Store) error {
+	first, err := mustCreate(store, "first", 1)
+	if err != nil {
+		return err
+	}
+	second, err := mustCreate(store, "second", 1)
+	if err != nil {
+		return err
+	}
+	if first.ID <= 0 {
+		return fmt.Errorf("first id = %d, want > 0", first.ID)
+	}
+	if second.ID != first.ID+1 {
+		return fmt.Errorf("second id = %d, want %d", second.ID, first.ID+1)
+	}
+	return nil
+}
+
+func checkFindAllOrder(store repository.Store) error {
+	texts := []string{"a", "b", "c"}
+	for _, text := range texts {
+		if _, err := mustCreate(store, text, 1); err != nil {
+			return err
+		}
+	}
+
+	all, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(all) != len(texts) {
+		return fmt.Errorf("got %d todos, want %d", len(all), len(texts))
+	}
+	for i, todo := range all {
+		if todo.Text != texts[i] {
+			return fmt.Errorf("todo %d text = %q, want %q", i, todo.Text, texts[i])
+		}
+	}
+	return nil
+}
+
+func checkFindByID(store repository.Store) error {
+	created, err := mustCreate(store, "find me", 2)
+	if err != nil {
+		return err
+	}
+
+	found, err := store.FindByID(ws, created.ID)
+	if err != nil {
+		return err
+	}
+	if found.Text != "find me" || found.UserID != 2 || found.CreatedBy != created.CreatedBy {
+		return fmt.Errorf("got %+v, want %+v", *found, *created)
+	}
+	if !found.CreatedAt.Equal(created.CreatedAt) {
+		return fmt.Errorf("created_at = %v, want %v", found.CreatedAt, created.CreatedAt)
+	}
+
+	// Mutating the returned value must not leak into the store
+	found.Text = "mutated"
+	again, err := store.FindByID(ws, created.ID)
+	if err != nil {
+		return err
+	}
+	if again.Text != "find me" {
+		return errors.New("returned todo aliases stored data")
+	}
+	return nil
+}
+
+func checkFindByUser(store repository.Store) error {
+	for _, userID := range []int{1, 2, 1, 3} {
+		if _, err := mustCreate(store, "todo", userID); err != nil {
+			return err
+		}
+	}
+
+	userTodos, err := store.FindAll(ws, repository.TodoFilter{UserID: 1})
+	if err != nil {
+		return err
+	}
+	if len(userTodos) != 2 {
+		return fmt.Errorf("got %d todos for user 1, want 2", len(userTodos))
+	}
+	for _, todo := range userTodos {
+		if todo.UserID != 1 {
+			return fmt.Errorf("todo %d belongs to user %d", todo.ID, todo.UserID)
+		}
+	}
+
+	none, err := store.FindAll(ws, repository.TodoFilter{UserID: 99})
+	if err != nil {
+		return err
+	}
+	if none == nil || len(none) != 0 {
+		return fmt.Errorf("unknown user: got %v, want empty non-nil slice", none)
+	}
+	return nil
+}
+
+func checkUpdate(store repository.Store) error {
+	created, err := mustCreate(store, "before", 1)
+	if err != nil {
+		return err
+	}
+
+	created.Text = "after"
+	created.Completed = true
+	created.Recurrence = "FREQ=WEEKLY;BYDAY=MO"
+	created.Occurrence = 2
+	created.UpdatedAt = created.UpdatedAt.Add(time.Minute)
+	if _, err := store.Update(created); err != nil {
+		return err
+	}
+
+	found, err := store.FindByID(ws, created.ID)
+	if err != nil {
+		return err
+	}
+	if found.Text != "after" || !found.Completed || found.Recurrence != "FREQ=WEEKLY;BYDAY=MO" || found.Occurrence != 2 {
+		return fmt.Errorf("got %+v after update", *found)
+	}
+	if !found.UpdatedAt.Equal(created.UpdatedAt) {
+		return fmt.Errorf("updated_at = %v, want %v", found.UpdatedAt, created.UpdatedAt)
+	}
+	return nil
+}
+
+func checkSaveTodos(store repository.Store) error {
+	first, err := mustCreate(store, "first", 1)
+	if err != nil {
+		return err
+	}
+	second, err := mustCreate(store, "second", 1)
+	if err != nil {
+		return err
+	}
+
+	first.Completed = true
+	second.Text = "second, renamed"
+	third := newTodo("third", 1)
+	third.ParentID = first.ID
+	if err := store.SaveTodos([]*models.Todo{first, second}, []*models.Todo{third}); err != nil {
+		return err
+	}
+	if third.ID <= second.ID {
+		return fmt.Errorf("created todo got id %d, want one after %d", third.ID, second.ID)
+	}
+	texts := func() (string, error) {
+		all, err := store.FindAll(ws, repository.TodoFilter{})
+		if err != nil {
+			return "", err
+		}
+		parts := make([]string, len(all))
+		for i, todo := range all {
+			parts[i] = fmt.Sprintf("%s/%v/%d", todo.Text, todo.Completed, todo.ParentID)
+		}
+		return strings.Join(parts, ", "), nil
+	}
+	want := fmt.Sprintf("first/true/0, second, renamed/false/0, third/false/%d", first.ID)
+	if got, err := texts(); err != nil || got != want {
+		return fmt.Errorf("after save: %s (err %v), want %s", got, err, want)
+	}
+
+	// A creation or an update that fails stores nothing of the others
+	first.Text = "first, renamed"
+	broken := newTodo("broken", 1)
+	broken.ListID = 9999
+	if err := store.SaveTodos([]*models.Todo{first}, []*models.Todo{newTodo("fourth", 1), broken}); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("save with an unknown list: err = %v, want ErrListNotFound", err)
+	}
+	missing := newTodo("missing", 1)
+	missing.ID = 12345
+	if err := store.SaveTodos([]*models.Todo{first, missing}, []*models.Todo{newTodo("fourth", 1)}); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("save of an unknown todo: err = %v, want ErrTodoNotFound", err)
+	}
+	if got, err := texts(); err != nil || got != want {
+		return fmt.Errorf("after failed saves: %s (err %v), want %s", got, err, want)
+	}
+	return nil
+}
+
+func checkDelete(store repository.Store) error {
+	first, err := mustCreate(store, "first", 1)
+	if err != nil {
+		return err
+	}
+	last, err := mustCreate(store, "last", 1)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Delete(ws, last.ID); err != nil {
+		return err
+	}
+	if _, err := store.FindByID(ws, last.ID); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("find deleted todo: err = %v, want ErrTodoNotFound", err)
+	}
+
+	all, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(all) != 1 || all[0].ID != first.ID {
+		return fmt.Errorf("remaining todos = %v, want only id %d", all, first.ID)
+	}
+
+	next, err := mustCreate(store, "next", 1)
+	if err != nil {
+		return err
+	}
+	if next.ID <= last.ID {
+		return fmt.Errorf("id %d of deleted todo was reused (got %d)", last.ID, next.ID)
+	}
+	return nil
+}
+
+func checkNotFound(store repository.Store) error {
+	if _, err := store.FindByID(ws, 12345); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("FindByID: err = %v, want ErrTodoNotFound", err)
+	}
+	if _, err := store.Update(&models.Todo{ID: 12345, WorkspaceID: ws, Text: "ghost", UserID: 1}); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("Update: err = %v, want ErrTodoNotFound", err)
+	}
+	if err := store.Delete(ws, 12345); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("Delete: err = %v, want ErrTodoNotFound", err)
+	}
+	return nil
+}
+
+func checkUsers(store repository.Store) error {
+	all, err := store.GetAllUsers()
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		return errors.New("no seeded users")
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].ID >= all[i].ID {
+			return errors.New("users are not ordered by id")
+		}
+	}
+
+	user, err := store.GetUserByID(all[0].ID)
+	if err != nil {
+		return err
+	}
+	if user.Email != all[0].Email {
+		return fmt.Errorf("user email = %q, want %q", user.Email, all[0].Email)
+	}
+	if _, err := store.GetUserByID(12345); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+
+	byEmail, err := store.GetUserByEmail(strings.ToUpper(all[0].Email))
+	if err != nil {
+		return fmt.Errorf("GetUserByEmail: %w", err)
+	}
+	if byEmail.ID != all[0].ID || byEmail.PasswordHash == "" {
+		return fmt.Errorf("GetUserByEmail returned %+v", *byEmail)
+	}
+	if _, err := store.GetUserByEmail("nobody@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("unknown email: err = %v, want ErrUserNotFound", err)
+	}
+	return nil
+}
+
+func newUser(name, email string) *models.User {
+	return &models.User{
+		Name:         name,
+		Email:        email,
+		Role:         "member",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func checkCreateUser(store repository.Store) error {
+	before, err := store.GetAllUsers()
+	if err != nil {
+		return err
+	}
+
+	created, err := store.CreateUser(newUser("Alice", "alice@example.com"))
+	if err != nil {
+		return err
+	}
+	if created.ID <= before[len(before)-1].ID {
+		return fmt.Errorf("new user id = %d, want above seeded ids", created.ID)
+	}
+
+	found, err := store.GetUserByID(created.ID)
+	if err != nil {
+		return err
+	}
+	if found.Name != "Alice" || found.PasswordHash != "hash" || found.Deactivated {
+		return fmt.Errorf("got %+v after create", *found)
+	}
+
+	if _, err := store.CreateUser(newUser("Other Alice", "ALICE@example.com")); !errors.Is(err, repository.ErrEmailTaken) {
+		return fmt.Errorf("duplicate email: err = %v, want ErrEmailTaken", err)
+	}
+	return nil
+}
+
+func checkUpdateUser(store repository.Store) error {
+	user, err := store.CreateUser(newUser("Alice", "alice@example.com"))
+	if err != nil {
+		return err
+	}
+	todo, err := mustCreate(store, "mine", user.ID)
+	if err != nil {
+		return err
+	}
+
+	user.Name = "Alice Smith"
+	user.Deactivated = true
+	if _, err := store.UpdateUser(user); err != nil {
+		return err
+	}
+
+	found, err := store.GetUserByID(user.ID)
+	if err != nil {
+		return err
+	}
+	if found.Name != "Alice Smith" || !found.Deactivated {
+		return fmt.Errorf("got %+v after update", *found)
+	}
+	renamed, err := store.FindByID(ws, todo.ID)
+	if err != nil {
+		return err
+	}
+	if renamed.CreatedBy != "Alice Smith" {
+		return fmt.Errorf("todo created_by = %q after rename, want %q", renamed.CreatedBy, "Alice Smith")
+	}
+
+	// Taking another user's email must fail
+	all, err := store.GetAllUsers()
+	if err != nil {
+		return err
+	}
+	user.Email = all[0].Email
+	if _, err := store.UpdateUser(user); !errors.Is(err, repository.ErrEmailTaken) {
+		return fmt.Errorf("duplicate email: err = %v, want ErrEmailTaken", err)
+	}
+	if _, err := store.UpdateUser(&models.User{ID: 12345, Name: "ghost", Email: "ghost@example.com"}); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+	return nil
+}
+
+func checkDeleteUser(store repository.Store) error {
+	target, err := store.CreateUser(newUser("Target", "target@example.com"))
+	if err != nil {
+		return err
+	}
+	var ids []int
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		user, err := store.CreateUser(newUser(email, email))
+		if err != nil {
+			return err
+		}
+		if _, err := mustCreate(store, "owned", user.ID); err != nil {
+			return err
+		}
+		ids = append(ids, user.ID)
+	}
+
+	// Block is the default and leaves everything in place
+	if err := store.DeleteUser(ids[0], repository.TodoDisposition{}); !errors.Is(err, repository.ErrUserHasTodos) {
+		return fmt.Errorf("blocked delete: err = %v, want ErrUserHasTodos", err)
+	}
+	if _, err := store.GetUserByID(ids[0]); err != nil {
+		return fmt.Errorf("blocked delete removed the user: %w", err)
+	}
+	if err := store.DeleteUser(ids[0], repository.TodoDisposition{ReassignTo: 12345}); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("reassign to unknown user: err = %v, want ErrUserNotFound", err)
+	}
+
+	if err := store.DeleteUser(ids[1], repository.TodoDisposition{ReassignTo: target.ID}); err != nil {
+		return fmt.Errorf("reassign delete: %w", err)
+	}
+	moved, err := store.FindAll(ws, repository.TodoFilter{UserID: target.ID})
+	if err != nil {
+		return err
+	}
+	if len(moved) != 1 || moved[0].CreatedBy != "Target" {
+		return fmt.Errorf("reassigned todos = %+v, want one created by Target", moved)
+	}
+
+	if err := store.DeleteUser(ids[2], repository.TodoDisposition{Cascade: true}); err != nil {
+		return fmt.Errorf("cascade delete: %w", err)
+	}
+	left, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(left) != 2 {
+		return fmt.Errorf("got %d todos after cascade, want 2", len(left))
+	}
+	if _, err := store.GetUserByID(ids[2]); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("deleted user: err = %v, want ErrUserNotFound", err)
+	}
+	if err := store.DeleteUser(ids[2], repository.TodoDisposition{}); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("delete twice: err = %v, want ErrUserNotFound", err)
+	}
+	return nil
+}
+
+func newWorkspace(store repository.Store, name string, ownerID int) (*models.Workspace, error) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	workspace, err := store.CreateWorkspace(
+		&models.Workspace{Name: name, CreatedAt: now},
+		models.Membership{UserID: ownerID, Role: "admin", CreatedAt: now},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create workspace %q: %w", name, err)
+	}
+	return workspace, nil
+}
+
+func checkWorkspaceIsolation(store repository.Store) error {
+	other, err := newWorkspace(store, "Other", 1)
+	if err != nil {
+		return err
+	}
+	if other.ID == ws {
+		return fmt.Errorf("new workspace reused the default id %d", ws)
+	}
+
+	mine, err := mustCreate(store, "mine", 1)
+	if err != nil {
+		return err
+	}
+	foreign := newTodo("foreign", 1)
+	foreign.WorkspaceID = other.ID
+	if foreign, err = store.Create(foreign); err != nil {
+		return err
+	}
+
+	all, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(all) != 1 || all[0].ID != mine.ID {
+		return fmt.Errorf("default workspace sees %+v, want only todo %d", all, mine.ID)
+	}
+	byUser, err := store.FindAll(ws, repository.TodoFilter{UserID: 1})
+	if err != nil {
+		return err
+	}
+	if len(byUser) != 1 {
+		return fmt.Errorf("FindAll by user leaked %d todos across workspaces", len(byUser)-1)
+	}
+
+	// A todo of another workspace must look exactly like a missing one
+	if _, err := store.FindByID(ws, foreign.ID); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("FindByID across workspaces: err = %v, want ErrTodoNotFound", err)
+	}
+	hijack := *foreign
+	hijack.WorkspaceID = ws
+	hijack.Text = "hijacked"
+	if _, err := store.Update(&hijack); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("Update across workspaces: err = %v, want ErrTodoNotFound", err)
+	}
+	if err := store.Delete(ws, foreign.ID); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("Delete across workspaces: err = %v, want ErrTodoNotFound", err)
+	}
+	untouched, err := store.FindByID(other.ID, foreign.ID)
+	if err != nil {
+		return err
+	}
+	if untouched.Text != "foreign" {
+		return fmt.Errorf("todo changed through another workspace: %+v", *untouched)
+	}
+
+	ghost := newTodo("ghost", 1)
+	ghost.WorkspaceID = 12345
+	if _, err := store.Create(ghost); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("create in unknown workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	return nil
+}
+
+func checkWorkspaceMembers(store repository.Store) error {
+	workspace, err := newWorkspace(store, "Team", 1)
+	if err != nil {
+		return err
+	}
+
+	owner, err := store.GetMembership(workspace.ID, 1)
+	if err != nil {
+		return fmt.Errorf("owner membership: %w", err)
+	}
+	if owner.Role != "admin" {
+		return fmt.Errorf("owner role = %q, want admin", owner.Role)
+	}
+	if _, err := store.GetMembership(workspace.ID, 2); !errors.Is(err, repository.ErrMembershipNotFound) {
+		return fmt.Errorf("non-member: err = %v, want ErrMembershipNotFound", err)
+	}
+
+	added, err := store.SaveMembership(&models.Membership{WorkspaceID: workspace.ID, UserID: 2, Role: "viewer", CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	changed, err := store.SaveMembership(&models.Membership{WorkspaceID: workspace.ID, UserID: 2, Role: "member", CreatedAt: time.Now().Add(time.Hour).UTC()})
+	if err != nil {
+		return err
+	}
+	if changed.Role != "member" || !changed.CreatedAt.Equal(added.CreatedAt) {
+		return fmt.Errorf("role change = %+v, want member joined at %v", *changed, added.CreatedAt)
+	}
+
+	members, err := store.GetMembers(workspace.ID)
+	if err != nil {
+		return err
+	}
+	if len(members) != 2 || members[0].UserID != 1 || members[1].UserID != 2 {
+		return fmt.Errorf("members = %+v, want users 1 and 2", members)
+	}
+
+	forUser, err := store.GetWorkspacesForUser(2)
+	if err != nil {
+		return err
+	}
+	if len(forUser) != 2 || forUser[0].ID != ws || forUser[1].ID != workspace.ID {
+		return fmt.Errorf("workspaces for user 2 = %+v, want default and %d", forUser, workspace.ID)
+	}
+
+	if err := store.RemoveMembership(workspace.ID, 2); err != nil {
+		return err
+	}
+	if err := store.RemoveMembership(workspace.ID, 2); !errors.Is(err, repository.ErrMembershipNotFound) {
+		return fmt.Errorf("remove twice: err = %v, want ErrMembershipNotFound", err)
+	}
+	if _, err := store.SaveMembership(&models.Membership{WorkspaceID: 12345, UserID: 2, Role: "member"}); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("unknown workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	if _, err := store.SaveMembership(&models.Membership{WorkspaceID: workspace.ID, UserID: 12345, Role: "member"}); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+
+	// New users join the default workspace; deleted users leave every workspace
+	user, err := store.CreateUser(newUser("Newcomer", "newcomer@example.com"))
+	if err != nil {
+		return err
+	}
+	if _, err := store.GetMembership(ws, user.ID); err != nil {
+		return fmt.Errorf("new user in default workspace: %w", err)
+	}
+	if _, err := store.SaveMembership(&models.Membership{WorkspaceID: workspace.ID, UserID: user.ID, Role: "member"}); err != nil {
+		return err
+	}
+	if err := store.DeleteUser(user.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	if _, err := store.GetMembership(workspace.ID, user.ID); !errors.Is(err, repository.ErrMembershipNotFound) {
+		return fmt.Errorf("deleted user membership: err = %v, want ErrMembershipNotFound", err)
+	}
+	return nil
+}
+
+func checkDeleteWorkspace(store repository.Store) error {
+	workspace, err := newWorkspace(store, "Doomed", 1)
+	if err != nil {
+		return err
+	}
+	todo := newTodo("doomed", 1)
+	todo.WorkspaceID = workspace.ID
+	if _, err := store.Create(todo); err != nil {
+		return err
+	}
+	kept, err := mustCreate(store, "kept", 1)
+	if err != nil {
+		return err
+	}
+
+	if err := store.DeleteWorkspace(workspace.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetWorkspace(workspace.ID); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("deleted workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	if _, err := store.GetMembers(workspace.ID); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("members of deleted workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	if _, err := store.GetMembership(workspace.ID, 1); !errors.Is(err, repository.ErrMembershipNotFound) {
+		return fmt.Errorf("membership in deleted workspace: err = %v, want ErrMembershipNotFound", err)
+	}
+	orphans, err := store.FindAll(workspace.ID, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(orphans) != 0 {
+		return fmt.Errorf("%d todos survived their workspace", len(orphans))
+	}
+	if _, err := store.FindByID(ws, kept.ID); err != nil {
+		return fmt.Errorf("todo of another workspace was removed: %w", err)
+	}
+	if err := store.DeleteWorkspace(workspace.ID); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("delete twice: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	return nil
+}
+
+func newList(store repository.Store, workspaceID int, name string) (*models.List, error) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	list, err := store.CreateList(&models.List{WorkspaceID: workspaceID, Name: name, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		return nil, fmt.Errorf("create list %q: %w", name, err)
+	}
+	return list, nil
+}
+
+func checkListIsolation(store repository.Store) error {
+	other, err := newWorkspace(store, "Other", 1)
+	if err != nil {
+		return err
+	}
+	mine, err := newList(store, ws, "Mine")
+	if err != nil {
+		return err
+	}
+	foreign, err := newList(store, other.ID, "Foreign")
+	if err != nil {
+		return err
+	}
+
+	lists, err := store.GetLists(ws)
+	if err != nil {
+		return err
+	}
+	if len(lists) != 1 || lists[0].ID != mine.ID || lists[0].Name != "Mine" {
+		return fmt.Errorf("default workspace lists = %+v, want only list %d", lists, mine.ID)
+	}
+
+	// A list of another workspace must look exactly like a missing one
+	if _, err := store.GetList(ws, foreign.ID); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("GetList across workspaces: err = %v, want ErrListNotFound", err)
+	}
+	hijack := *foreign
+	hijack.WorkspaceID = ws
+	hijack.Name = "hijacked"
+	if _, err := store.UpdateList(&hijack); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("UpdateList across workspaces: err = %v, want ErrListNotFound", err)
+	}
+	if err := store.DeleteList(ws, foreign.ID); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("DeleteList across workspaces: err = %v, want ErrListNotFound", err)
+	}
+
+	todo := newTodo("smuggled", 1)
+	todo.ListID = foreign.ID
+	if _, err := store.Create(todo); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("create into a foreign list: err = %v, want ErrListNotFound", err)
+	}
+	created, err := mustCreate(store, "mine", 1)
+	if err != nil {
+		return err
+	}
+	created.ListID = foreign.ID
+	if _, err := store.Update(created); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("move into a foreign list: err = %v, want ErrListNotFound", err)
+	}
+
+	if _, err := store.CreateList(&models.List{WorkspaceID: 12345, Name: "ghost"}); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("create list in unknown workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+
+	// Lists go with their workspace
+	if err := store.DeleteWorkspace(other.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetList(other.ID, foreign.ID); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("list of deleted workspace: err = %v, want ErrListNotFound", err)
+	}
+	return nil
+}
+
+func checkListFilter(store repository.Store) error {
+	active, err := newList(store, ws, "Active")
+	if err != nil {
+		return err
+	}
+	archived, err := newList(store, ws, "Archived")
+	if err != nil {
+		return err
+	}
+
+	create := func(text string, userID, listID int) error {
+		todo := newTodo(text, userID)
+		todo.ListID = listID
+		_, err := store.Create(todo)
+		return err
+	}
+	for _, err := range []error{
+		create("loose", 1, 0),
+		create("active 1", 1, active.ID),
+		create("active 2", 2, active.ID),
+		create("archived", 1, archived.ID),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	archived.Archived = true
+	if _, err := store.UpdateList(archived); err != nil {
+		return err
+	}
+	found, err := store.GetList(ws, archived.ID)
+	if err != nil {
+		return err
+	}
+	if !found.Archived || found.Name != "Archived" {
+		return fmt.Errorf("list after archive = %+v", *found)
+	}
+
+	texts := func(filter repository.TodoFilter) (string, error) {
+		todos, err := store.FindAll(ws, filter)
+		if err != nil {
+			return "", err
+		}
+		var names []string
+		for _, todo := range todos {
+			names = append(names, todo.Text)
+		}
+		return strings.Join(names, ","), nil
+	}
+	none := 0
+	for _, tc := range []struct {
+		filter repository.TodoFilter
+		want   string
+	}{
+		{repository.TodoFilter{}, "loose,active 1,active 2"},
+		{repository.TodoFilter{IncludeArchived: true}, "loose,active 1,active 2,archived"},
+		{repository.TodoFilter{ListID: &active.ID}, "active 1,active 2"},
+		{repository.TodoFilter{ListID: &active.ID, UserID: 2}, "active 2"},
+		{repository.TodoFilter{ListID: &archived.ID}, "archived"},
+		{repository.TodoFilter{ListID: &none}, "loose"},
+	} {
+		got, err := texts(tc.filter)
+		if err != nil {
+			return err
+		}
+		if got != tc.want {
+			return fmt.Errorf("FindAll(%+v) = %q, want %q", tc.filter, got, tc.want)
+		}
+	}
+	return nil
+}
+
+func checkDeleteList(store repository.Store) error {
+	list, err := newList(store, ws, "Doomed")
+	if err != nil {
+		return err
+	}
+	todo := newTodo("survivor", 1)
+	todo.ListID = list.ID
+	if todo, err = store.Create(todo); err != nil {
+		return err
+	}
+
+	if err := store.DeleteList(ws, list.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetList(ws, list.ID); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("deleted list: err = %v, want ErrListNotFound", err)
+	}
+	survivor, err := store.FindByID(ws, todo.ID)
+	if err != nil {
+		return fmt.Errorf("todo of deleted list: %w", err)
+	}
+	if survivor.ListID != 0 {
+		return fmt.Errorf("todo of deleted list has list_id %d, want 0", survivor.ListID)
+	}
+	if err := store.DeleteList(ws, list.ID); !errors.Is(err, repository.ErrListNotFound) {
+		return fmt.Errorf("delete twice: err = %v, want ErrListNotFound", err)
+	}
+
+	next, err := newList(store, ws, "Next")
+	if err != nil {
+		return err
+	}
+	if next.ID <= list.ID {
+		return fmt.Errorf("id %d of deleted list was reused (got %d)", list.ID, next.ID)
+	}
+	return nil
+}
+
+// at returns a fixed instant in a +07:00 zone, hours after 2025-01-01T00:00:00Z
+func at(hours int) *time.Time {
+	t := time.Date(2025, 1, 1, 7, 0, 0, 0, time.FixedZone("WIB", 7*60*60)).Add(time.Duration(hours) * time.Hour)
+	return &t
+}
+
+func checkSortPages(store repository.Store) error {
+	utc := at(1).UTC()
+	for _, todo := range []struct {
+		text             string
+		priority         models.Priority
+		due              *time.Time
+		created, updated *time.Time
+	}{
+		{"banana", models.PriorityHigh, at(5), at(0), at(9)},
+		{"Apple", models.PriorityNone, nil, &utc, at(3)},
+		{"cherry", models.PriorityUrgent, at(2), at(1), at(3)},
+		{"apple pie", models.PriorityHigh, nil, at(2), at(1)},
+		{"Éclair", models.PriorityLow, at(2), at(0), at(4)},
+	} {
+		t := newTodo(todo.text, 1)
+		t.Priority, t.DueAt, t.CreatedAt, t.UpdatedAt = todo.priority, todo.due, *todo.created, *todo.updated
+		if _, err := store.Create(t); err != nil {
+			return err
+		}
+	}
+
+	ids := func(todos []models.Todo) string {
+		var s []string
+		for _, todo := range todos {
+			s = append(s, fmt.Sprint(todo.ID))
+		}
+		return strings.Join(s, ",")
+	}
+	// page walks FindAll two todos at a time
+	page := func(filter repository.TodoFilter) ([]models.Todo, error) {
+		filter.Limit = 2
+		var all []models.Todo
+		for {
+			todos, err := store.FindAll(ws, filter)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, todos...)
+			if len(todos) < filter.Limit {
+				return all, nil
+			}
+			filter.After = &todos[len(todos)-1]
+		}
+	}
+
+	// Ties are broken by ID, instants are compared rather than their text,
+	// undated todos come last both ways and text ignores ASCII case only
+	for _, tc := range []struct {
+		sort repository.TodoSort
+		asc  string
+		desc string
+	}{
+		{repository.SortByID, "1,2,3,4,5", "5,4,3,2,1"},
+		{repository.SortByCreatedAt, "1,5,2,3,4", "4,3,2,5,1"},
+		{repository.SortByUpdatedAt, "4,2,3,5,1", "1,5,3,2,4"},
+		{repository.SortByDue, "3,5,1,2,4", "1,5,3,4,2"},
+		{repository.SortByPriority, "2,5,1,4,3", "3,4,1,5,2"},
+		{repository.SortByText, "2,4,1,3,5", "5,3,1,4,2"},
+	} {
+		for _, desc := range []bool{false, true} {
+			want := tc.asc
+			if desc {
+				want = tc.desc
+			}
+			filter := repository.TodoFilter{Sort: tc.sort, Desc: desc}
+			todos, err := store.FindAll(ws, filter)
+			if err != nil {
+				return err
+			}
+			if got := ids(todos); got != want {
+				return fmt.Errorf("FindAll(sort %q, desc %v) = %s, want %s", tc.sort, desc, got, want)
+			}
+			if todos, err = page(filter); err != nil {
+				return err
+			}
+			if got := ids(todos); got != want {
+				return fmt.Errorf("pages of sort %q, desc %v = %s, want %s", tc.sort, desc, got, want)
+			}
+		}
+	}
+
+	// Pages stay stable while todos come and go, even the one paged after
+	filter := repository.TodoFilter{Sort: repository.SortByText, Limit: 2}
+	first, err := store.FindAll(ws, filter)
+	if err != nil {
+		return err
+	}
+	if _, err := store.Create(newTodo("aardvark", 1)); err != nil {
+		return err
+	}
+	for _, id := range []int{1, 4} {
+		if err := store.Delete(ws, id); err != nil {
+			return err
+		}
+	}
+	filter.After = &first[len(first)-1]
+	next, err := store.FindAll(ws, filter)
+	if err != nil {
+		return err
+	}
+	if got := ids(next); got != "3,5" {
+		return fmt.Errorf("page after a deleted todo = %s, want 3,5", got)
+	}
+	return nil
+}
+
+func checkWhere(store repository.Store) error {
+	bug, err := newLabel(store, ws, "bug")
+	if err != nil {
+		return err
+	}
+	list, err := newList(store, ws, "errands")
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range []struct {
+		text      string
+		userID    int
+		completed bool
+		priority  models.Priority
+		due       *time.Time
+		created   *time.Time
+		listID    int
+		labelIDs  []int
+		assignees []int
+	}{
+		{"Buy MILK", 1, false, models.PriorityHigh, at(5), at(0), list.ID, []int{bug.ID}, []int{2}},
+		{"call the bank", 2, true, models.PriorityLow, nil, at(24), 0, nil, nil},
+		{"Fix (urgent) bug", 1, false, models.PriorityUrgent, at(2), at(48), 0, []int{bug.ID}, []int{2, 3}},
+		{"Éclair for milkmen", 3, false, models.PriorityNone, nil, at(49), list.ID, nil, nil},
+	} {
+		t := newTodo(todo.text, todo.userID)
+		t.Completed, t.Priority, t.DueAt, t.CreatedAt = todo.completed, todo.priority, todo.due, *todo.created
+		t.ListID, t.LabelIDs, t.AssigneeIDs = todo.listID, todo.labelIDs, todo.assignees
+		if _, err := store.Create(t); err != nil {
+			return err
+		}
+	}
+
+	// Dates are UTC days: at(0) is 2025-01-01 00:00 UTC, at(24) the 2nd, at(48) and at(49) the 3rd
+	for _, tc := range []struct {
+		filter string
+		want   string
+	}{
+		{"completed:false", "1,3,4"},
+		{"completed:false AND (assignee:3 OR label:bug) AND priority>=high", "1,3"},
+		{"NOT label:bug", "2,4"},
+		{"label:none OR assignee:none", "2,4"},
+		{"assignee!=2", "2,4"},
+		{"text:milk", "1,4"},
+		{`text:"(URGENT)"`, "3"},
+		{"text:ÉCLAIR", "4"}, // ASCII letters fold, others must match
+		{`text="call THE bank"`, "2"},
+		{"priority<medium user_id:1", ""},
+		{"priority<=low OR id>3", "2,4"},
+		{"due:none", "2,4"},
+		{"due_at!=none", "1,3"},
+		{"due_at<2025-01-01T02:30:00Z", "3"},
+		{"due_at!=2025-01-01", "2,4"},
+		{"created_at:2025-01-03", "3,4"},
+		{"created_at>2025-01-02", "3,4"},
+		{"created_at<=2025-01-02", "1,2"},
+		{"created_at>=2025-01-03T00:00:00Z created_at<2025-01-03T01:30:00+01:00", "3"},
+		{fmt.Sprintf("list_id:%d AND NOT NOT completed:false", list.ID), "1,4"},
+		{"list_id:0 OR parent_id:none", "1,2,3,4"},
+	} {
+		where, err := expr.Parse(tc.filter)
+		if err != nil {
+			return fmt.Errorf("parse %q: %w", tc.filter, err)
+		}
+		expr.Walk(where, func(c *expr.Cond) error {
+			if c.Field == expr.FieldLabel && !c.None {
+				c.Int = bug.ID
+			}
+			return nil
+		})
+
+		todos, err := store.FindAll(ws, repository.TodoFilter{Where: where})
+		if err != nil {
+			return fmt.Errorf("filter %q: %w", tc.filter, err)
+		}
+		var ids []string
+		for _, todo := range todos {
+			ids = append(ids, fmt.Sprint(todo.ID))
+		}
+		if got := strings.Join(ids, ","); got != tc.want {
+			return fmt.Errorf("FindAll(filter %q) = %q, want %q", tc.filter, got, tc.want)
+		}
+	}
+	return nil
+}
+
+func checkDueFilter(store repository.Store) error {
+	create := func(text string, dueAt *time.Time, completed bool) error {
+		todo := newTodo(text, 1)
+		todo.DueAt = dueAt
+		todo.Completed = completed
+		_, err := store.Create(todo)
+		return err
+	}
+	for _, err := range []error{
+		create("undated", nil, false),
+		create("early", at(1), false),
+		create("early done", at(1), true),
+		create("late", at(48), false),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	found, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if due := found[1].DueAt; due == nil || !due.Equal(*at(1)) {
+		return fmt.Errorf("due_at = %v, want %v", due, at(1))
+	}
+	if _, offset := found[1].DueAt.Zone(); offset != 7*60*60 {
+		return fmt.Errorf("due_at lost its offset: %v", found[1].DueAt)
+	}
+
+	// Instants are compared, not their text: 02:00Z is 09:00+07:00
+	utc := at(2).UTC()
+	texts := func(filter repository.TodoFilter) (string, error) {
+		todos, err := store.FindAll(ws, filter)
+		if err != nil {
+			return "", err
+		}
+		var names []string
+		for _, todo := range todos {
+			names = append(names, todo.Text)
+		}
+		return strings.Join(names, ","), nil
+	}
+	for _, tc := range []struct {
+		filter repository.TodoFilter
+		want   string
+	}{
+		{repository.TodoFilter{DueBefore: &utc}, "early,early done"},
+		{repository.TodoFilter{DueAfter: &utc}, "late"},
+		{repository.TodoFilter{DueAfter: at(0), DueBefore: at(2)}, "early,early done"},
+		{repository.TodoFilter{OverdueAt: at(2)}, "early"},
+		{repository.TodoFilter{OverdueAt: at(100)}, "early,late"},
+	} {
+		got, err := texts(tc.filter)
+		if err != nil {
+			return err
+		}
+		if got != tc.want {
+			return fmt.Errorf("FindAll(%+v) = %q, want %q", tc.filter, got, tc.want)
+		}
+	}
+	return nil
+}
+
+func checkReminders(store repository.Store) error {
+	create := func(text string, remindAt *time.Time, completed bool) (*models.Todo, error) {
+		todo := newTodo(text, 1)
+		todo.RemindAt = remindAt
+		todo.Completed = completed
+		return store.Create(todo)
+	}
+	later, err := create("later", at(5), false)
+	if err != nil {
+		return err
+	}
+	soon, err := create("soon", at(1), false)
+	if err != nil {
+		return err
+	}
+	if _, err := create("done", at(1), true); err != nil {
+		return err
+	}
+	if _, err := create("none", nil, false); err != nil {
+		return err
+	}
+
+	due, err := store.DueReminders(*at(10))
+	if err != nil {
+		return err
+	}
+	if len(due) != 2 || due[0].ID != soon.ID || due[1].ID != later.ID {
+		return fmt.Errorf("due reminders = %+v, want %d then %d", due, soon.ID, later.ID)
+	}
+	if due, err = store.DueReminders(*at(1)); err != nil {
+		return err
+	}
+	if len(due) != 1 || due[0].ID != soon.ID {
+		return fmt.Errorf("due reminders at the reminder time = %+v, want only %d", due, soon.ID)
+	}
+
+	// A stale remind_at (rescheduled meanwhile) must not be marked
+	if err := store.MarkReminded(later.ID, *at(4), *at(10)); err != nil {
+		return err
+	}
+	if err := store.MarkReminded(soon.ID, *at(1), *at(10)); err != nil {
+		return err
+	}
+	if due, err = store.DueReminders(*at(10)); err != nil {
+		return err
+	}
+	if len(due) != 1 || due[0].ID != later.ID {
+		return fmt.Errorf("due reminders after marking = %+v, want only %d", due, later.ID)
+	}
+	reminded, err := store.FindByID(ws, soon.ID)
+	if err != nil {
+		return err
+	}
+	if reminded.RemindedAt == nil || !reminded.RemindedAt.Equal(*at(10)) {
+		return fmt.Errorf("reminded_at = %v, want %v", reminded.RemindedAt, at(10))
+	}
+
+	if err := store.MarkReminded(12345, *at(1), *at(10)); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("MarkReminded unknown todo: err = %v, want ErrTodoNotFound", err)
+	}
+	return nil
+}
+
+// Opener opens a persistent store; calling it again after close must reopen the same data
+type Opener func() (store repository.Store, close func() error, err error)
+
+func newLabel(store repository.Store, workspaceID int, name string) (*models.Label, error) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	label, err := store.CreateLabel(&models.Label{WorkspaceID: workspaceID, Name: name, Color: "#d73a4a", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		return nil, fmt.Errorf("create label %q: %w", name, err)
+	}
+	return label, nil
+}
+
+func checkLabelIsolation(store repository.Store) error {
+	other, err := newWorkspace(store, "Other", 1)
+	if err != nil {
+		return err
+	}
+	mine, err := newLabel(store, ws, "Bug")
+	if err != nil {
+		return err
+	}
+	foreign, err := newLabel(store, other.ID, "Bug")
+	if err != nil {
+		return fmt.Errorf("same name in another workspace: %w", err)
+	}
+
+	labels, err := store.GetLabels(ws)
+	if err != nil {
+		return err
+	}
+	if len(labels) != 1 || labels[0].ID != mine.ID || labels[0].Color != "#d73a4a" {
+		return fmt.Errorf("default workspace labels = %+v, want only label %d", labels, mine.ID)
+	}
+
+	// Names are unique per workspace, ignoring case
+	if _, err := newLabel(store, ws, "BUG"); !errors.Is(err, repository.ErrLabelNameTaken) {
+		return fmt.Errorf("duplicate name: err = %v, want ErrLabelNameTaken", err)
+	}
+	ui, err := newLabel(store, ws, "UI")
+	if err != nil {
+		return err
+	}
+	ui.Name = "bug"
+	if _, err := store.UpdateLabel(ui); !errors.Is(err, repository.ErrLabelNameTaken) {
+		return fmt.Errorf("rename onto another label: err = %v, want ErrLabelNameTaken", err)
+	}
+	mine.Name = "BUG"
+	mine.Color = "#0e8a16"
+	if _, err := store.UpdateLabel(mine); err != nil {
+		return fmt.Errorf("change case of own name: %w", err)
+	}
+	if found, err := store.GetLabel(ws, mine.ID); err != nil || found.Name != "BUG" || found.Color != "#0e8a16" {
+		return fmt.Errorf("label after update = %+v, %v", found, err)
+	}
+
+	// A label of another workspace must look exactly like a missing one
+	if _, err := store.GetLabel(ws, foreign.ID); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("GetLabel across workspaces: err = %v, want ErrLabelNotFound", err)
+	}
+	hijack := *foreign
+	hijack.WorkspaceID = ws
+	hijack.Name = "hijacked"
+	if _, err := store.UpdateLabel(&hijack); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("UpdateLabel across workspaces: err = %v, want ErrLabelNotFound", err)
+	}
+	if err := store.DeleteLabel(ws, foreign.ID); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("DeleteLabel across workspaces: err = %v, want ErrLabelNotFound", err)
+	}
+
+	todo := newTodo("smuggled", 1)
+	todo.LabelIDs = []int{mine.ID, foreign.ID}
+	if _, err := store.Create(todo); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("create with a foreign label: err = %v, want ErrLabelNotFound", err)
+	}
+	created, err := mustCreate(store, "mine", 1)
+	if err != nil {
+		return err
+	}
+	if created.LabelIDs == nil || len(created.LabelIDs) != 0 {
+		return fmt.Errorf("labels of unlabeled todo = %#v, want empty", created.LabelIDs)
+	}
+	created.LabelIDs = []int{foreign.ID}
+	if _, err := store.Update(created); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("update with a foreign label: err = %v, want ErrLabelNotFound", err)
+	}
+
+	if _, err := store.CreateLabel(&models.Label{WorkspaceID: 12345, Name: "ghost"}); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("create label in unknown workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+
+	// Labels go with their workspace
+	if err := store.DeleteWorkspace(other.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetLabel(other.ID, foreign.ID); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("label of deleted workspace: err = %v, want ErrLabelNotFound", err)
+	}
+	return nil
+}
+
+func checkLabelFilter(store repository.Store) error {
+	bug, err := newLabel(store, ws, "bug")
+	if err != nil {
+		return err
+	}
+	ui, err := newLabel(store, ws, "ui")
+	if err != nil {
+		return err
+	}
+
+	create := func(text string, priority models.Priority, labelIDs ...int) error {
+		todo := newTodo(text, 1)
+		todo.Priority = priority
+		// Duplicates and order must not matter
+		todo.LabelIDs = append(labelIDs, labelIDs...)
+		_, err := store.Create(todo)
+		return err
+	}
+	for _, err := range []error{
+		create("plain", models.PriorityNone),
+		create("crash", models.PriorityUrgent, bug.ID),
+		create("glitch", models.PriorityHigh, ui.ID, bug.ID),
+		create("polish", models.PriorityLow, ui.ID),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	all, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if got := fmt.Sprint(all[2].LabelIDs); got != fmt.Sprint([]int{bug.ID, ui.ID}) {
+		return fmt.Errorf("labels of glitch = %s, want sorted and unique", got)
+	}
+
+	texts := func(filter repository.TodoFilter) (string, error) {
+		todos, err := store.FindAll(ws, filter)
+		if err != nil {
+			return "", err
+		}
+		var names []string
+		for _, todo := range todos {
+			names = append(names, todo.Text)
+		}
+		return strings.Join(names, ","), nil
+	}
+	for _, tc := range []struct {
+		filter repository.TodoFilter
+		want   string
+	}{
+		{repository.TodoFilter{Priorities: []models.Priority{models.PriorityHigh}}, "glitch"},
+		{repository.TodoFilter{Priorities: []models.Priority{models.PriorityUrgent, models.PriorityNone}}, "plain,crash"},
+		{repository.TodoFilter{LabelIDs: []int{bug.ID}}, "crash,glitch"},
+		{repository.TodoFilter{LabelIDs: []int{bug.ID, ui.ID}}, "crash,glitch,polish"},
+		{repository.TodoFilter{LabelIDs: []int{bug.ID, ui.ID}, AllLabels: true}, "glitch"},
+		{repository.TodoFilter{LabelIDs: []int{bug.ID, bug.ID}, AllLabels: true}, "crash,glitch"},
+		{repository.TodoFilter{LabelIDs: []int{ui.ID}, Priorities: []models.Priority{models.PriorityLow}}, "polish"},
+	} {
+		got, err := texts(tc.filter)
+		if err != nil {
+			return err
+		}
+		if got != tc.want {
+			return fmt.Errorf("FindAll(%+v) = %q, want %q", tc.filter, got, tc.want)
+		}
+	}
+	return nil
+}
+
+func checkDeleteLabel(store repository.Store) error {
+	doomed, err := newLabel(store, ws, "doomed")
+	if err != nil {
+		return err
+	}
+	kept, err := newLabel(store, ws, "kept")
+	if err != nil {
+		return err
+	}
+	todo := newTodo("tagged", 1)
+	todo.LabelIDs = []int{doomed.ID, kept.ID}
+	if todo, err = store.Create(todo); err != nil {
+		return err
+	}
+
+	if err := store.DeleteLabel(ws, doomed.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetLabel(ws, doomed.ID); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("deleted label: err = %v, want ErrLabelNotFound", err)
+	}
+	found, err := store.FindByID(ws, todo.ID)
+	if err != nil {
+		return err
+	}
+	if len(found.LabelIDs) != 1 || found.LabelIDs[0] != kept.ID {
+		return fmt.Errorf("labels after delete = %v, want [%d]", found.LabelIDs, kept.ID)
+	}
+	if err := store.DeleteLabel(ws, doomed.ID); !errors.Is(err, repository.ErrLabelNotFound) {
+		return fmt.Errorf("delete twice: err = %v, want ErrLabelNotFound", err)
+	}
+
+	next, err := newLabel(store, ws, "doomed")
+	if err != nil {
+		return fmt.Errorf("reuse name of deleted label: %w", err)
+	}
+	if next.ID <= doomed.ID {
+		return fmt.Errorf("id %d of deleted label was reused (got %d)", doomed.ID, next.ID)
+	}
+	return nil
+}
+
+// newSubtask creates a todo of user 1 that is a subtask of parentID
+func newSubtask(store repository.Store, text string, parentID int) (*models.Todo, error) {
+	todo := newTodo(text, 1)
+	todo.ParentID = parentID
+	created, err := store.Create(todo)
+	if err != nil {
+		return nil, fmt.Errorf("create subtask %q: %w", text, err)
+	}
+	return created, nil
+}
+
+func checkSubtasks(store repository.Store) error {
+	parent, err := mustCreate(store, "parent", 1)
+	if err != nil {
+		return err
+	}
+	child, err := newSubtask(store, "child", parent.ID)
+	if err != nil {
+		return err
+	}
+	if child.ParentID != parent.ID {
+		return fmt.Errorf("parent_id = %d, want %d", child.ParentID, parent.ID)
+	}
+
+	other, err := newWorkspace(store, "Other", 1)
+	if err != nil {
+		return err
+	}
+	foreign := newTodo("foreign parent", 1)
+	foreign.WorkspaceID = other.ID
+	foreign.ParentID = parent.ID
+	if _, err := store.Create(foreign); !errors.Is(err, repository.ErrParentNotFound) {
+		return fmt.Errorf("parent of another workspace: err = %v, want ErrParentNotFound", err)
+	}
+	child.ParentID = 12345
+	if _, err := store.Update(child); !errors.Is(err, repository.ErrParentNotFound) {
+		return fmt.Errorf("update to unknown parent: err = %v, want ErrParentNotFound", err)
+	}
+
+	// A todo cannot move below itself
+	grandchild, err := newSubtask(store, "grandchild", child.ID)
+	if err != nil {
+		return err
+	}
+	for _, parentID := range []int{parent.ID, child.ID, grandchild.ID} {
+		cyclic := *parent
+		cyclic.ParentID = parentID
+		if _, err := store.Update(&cyclic); !errors.Is(err, repository.ErrSubtaskCycle) {
+			return fmt.Errorf("update to parent %d below itself: err = %v, want ErrSubtaskCycle", parentID, err)
+		}
+	}
+	if found, err := store.FindByID(ws, parent.ID); err != nil || found.ParentID != 0 {
+		return fmt.Errorf("parent after refused cycles = %+v (err %v), want top-level", found, err)
+	}
+
+	top := 0
+	roots, err := store.FindAll(ws, repository.TodoFilter{ParentID: &top})
+	if err != nil {
+		return err
+	}
+	children, err := store.FindAll(ws, repository.TodoFilter{ParentID: &parent.ID})
+	if err != nil {
+		return err
+	}
+	if len(roots) != 1 || roots[0].ID != parent.ID || len(children) != 1 || children[0].ID != child.ID {
+		return fmt.Errorf("top-level = %+v, subtasks = %+v", roots, children)
+	}
+	return nil
+}
+
+func checkDeleteSubtasks(store repository.Store) error {
+	root, err := mustCreate(store, "root", 1)
+	if err != nil {
+		return err
+	}
+	middle, err := newSubtask(store, "middle", root.ID)
+	if err != nil {
+		return err
+	}
+	leaf, err := newSubtask(store, "leaf", middle.ID)
+	if err != nil {
+		return err
+	}
+
+	// Deleting the middle todo hands its subtask to the root
+	if err := store.Delete(ws, middle.ID); err != nil {
+		return err
+	}
+	found, err := store.FindByID(ws, leaf.ID)
+	if err != nil {
+		return err
+	}
+	if found.ParentID != root.ID {
+		return fmt.Errorf("promoted parent_id = %d, want %d", found.ParentID, root.ID)
+	}
+
+	kept, err := mustCreate(store, "kept", 1)
+	if err != nil {
+		return err
+	}
+	if err := store.DeleteTree(ws, root.ID); err != nil {
+		return err
+	}
+	left, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(left) != 1 || left[0].ID != kept.ID {
+		return fmt.Errorf("todos after delete tree = %+v, want only %q", left, kept.Text)
+	}
+	if err := store.DeleteTree(ws, root.ID); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("delete tree twice: err = %v, want ErrTodoNotFound", err)
+	}
+
+	// A subtask loses its parent when the parent's owner is deleted with their todos
+	owner, err := store.CreateUser(newUser("Owner", "owner@example.com"))
+	if err != nil {
+		return err
+	}
+	owned, err := mustCreate(store, "owned", owner.ID)
+	if err != nil {
+		return err
+	}
+	orphan, err := newSubtask(store, "orphan", owned.ID)
+	if err != nil {
+		return err
+	}
+	if err := store.DeleteUser(owner.ID, repository.TodoDisposition{Cascade: true}); err != nil {
+		return err
+	}
+	if found, err = store.FindByID(ws, orphan.ID); err != nil {
+		return err
+	}
+	if found.ParentID != 0 {
+		return fmt.Errorf("parent_id after owner delete = %d, want 0", found.ParentID)
+	}
+	return nil
+}
+
+func checkChecklist(store repository.Store) error {
+	plain, err := mustCreate(store, "plain", 1)
+	if err != nil {
+		return err
+	}
+	if plain.Checklist == nil {
+		return fmt.Errorf("checklist of a new todo is nil, want empty")
+	}
+
+	items := []models.ChecklistItem{{ID: 1, Text: "first"}, {ID: 2, Text: "second", Done: true}}
+	plain.Checklist = items
+	if _, err := store.Update(plain); err != nil {
+		return err
+	}
+	items[0].Text = "changed by the caller"
+
+	found, err := store.FindByID(ws, plain.ID)
+	if err != nil {
+		return err
+	}
+	if len(found.Checklist) != 2 || found.Checklist[0].Text != "first" || found.Checklist[1] != (models.ChecklistItem{ID: 2, Text: "second", Done: true}) {
+		return fmt.Errorf("checklist = %+v", found.Checklist)
+	}
+	return nil
+}
+
+func checkBlockers(store repository.Store) error {
+	first, err := mustCreate(store, "first", 1)
+	if err != nil {
+		return err
+	}
+	second, err := mustCreate(store, "second", 1)
+	if err != nil {
+		return err
+	}
+	blocked := newTodo("blocked", 1)
+	blocked.BlockedBy = []int{second.ID, first.ID, second.ID}
+	if blocked, err = store.Create(blocked); err != nil {
+		return err
+	}
+	if len(blocked.BlockedBy) != 2 || blocked.BlockedBy[0] != first.ID || blocked.BlockedBy[1] != second.ID {
+		return fmt.Errorf("blocked_by = %v, want [%d %d]", blocked.BlockedBy, first.ID, second.ID)
+	}
+	if plain, err := store.FindByID(ws, first.ID); err != nil || plain.BlockedBy == nil {
+		return fmt.Errorf("blocked_by of a todo without blockers = %v (err %v), want empty", plain, err)
+	}
+
+	other, err := newWorkspace(store, "Other", 1)
+	if err != nil {
+		return err
+	}
+	foreign := newTodo("foreign blocker", 1)
+	foreign.WorkspaceID = other.ID
+	foreign.BlockedBy = []int{first.ID}
+	if _, err := store.Create(foreign); !errors.Is(err, repository.ErrBlockerNotFound) {
+		return fmt.Errorf("blocker of another workspace: err = %v, want ErrBlockerNotFound", err)
+	}
+	unknown := *blocked
+	unknown.BlockedBy = []int{12345}
+	if _, err := store.Update(&unknown); !errors.Is(err, repository.ErrBlockerNotFound) {
+		return fmt.Errorf("update to unknown blocker: err = %v, want ErrBlockerNotFound", err)
+	}
+
+	// Neither first nor second may wait for blocked, which waits for them,
+	// and no todo may wait for itself
+	for _, target := range []*models.Todo{first, second} {
+		cyclic := *target
+		cyclic.BlockedBy = []int{blocked.ID}
+		if _, err := store.Update(&cyclic); !errors.Is(err, repository.ErrDependencyCycle) {
+			return fmt.Errorf("todo %d waiting for a todo that waits for it: err = %v, want ErrDependencyCycle", target.ID, err)
+		}
+	}
+	self := *blocked
+	self.BlockedBy = []int{first.ID, blocked.ID}
+	if _, err := store.Update(&self); !errors.Is(err, repository.ErrDependencyCycle) {
+		return fmt.Errorf("todo waiting for itself: err = %v, want ErrDependencyCycle", err)
+	}
+	waiting := newTodo("waiting for blocked", 1)
+	waiting.BlockedBy = []int{blocked.ID}
+	if waiting, err = store.Create(waiting); err != nil {
+		return err
+	}
+	transitive := *first
+	transitive.BlockedBy = []int{waiting.ID}
+	if _, err := store.Update(&transitive); !errors.Is(err, repository.ErrDependencyCycle) {
+		return fmt.Errorf("cycle through two todos: err = %v, want ErrDependencyCycle", err)
+	}
+	if found, err := store.FindByID(ws, first.ID); err != nil || len(found.BlockedBy) != 0 {
+		return fmt.Errorf("todo after refused cycles = %+v (err %v), want no blockers", found, err)
+	}
+
+	// Deleted blockers drop out of blocked_by, whichever way they go
+	if err := store.Delete(ws, first.ID); err != nil {
+		return err
+	}
+	if err := store.DeleteTree(ws, second.ID); err != nil {
+		return err
+	}
+	owner, err := store.CreateUser(newUser("Owner", "owner@example.com"))
+	if err != nil {
+		return err
+	}
+	owned, err := mustCreate(store, "owned", owner.ID)
+	if err != nil {
+		return err
+	}
+	found, err := store.FindByID(ws, blocked.ID)
+	if err != nil {
+		return err
+	}
+	if len(found.BlockedBy) != 0 {
+		return fmt.Errorf("blocked_by after deleting blockers = %v, want none", found.BlockedBy)
+	}
+	found.BlockedBy = []int{owned.ID}
+	if _, err := store.Update(found); err != nil {
+		return err
+	}
+	if err := store.DeleteUser(owner.ID, repository.TodoDisposition{Cascade: true}); err != nil {
+		return err
+	}
+	if found, err = store.FindByID(ws, blocked.ID); err != nil {
+		return err
+	}
+	if len(found.BlockedBy) != 0 {
+		return fmt.Errorf("blocked_by after owner delete = %v, want none", found.BlockedBy)
+	}
+	return nil
+}
+
+func checkAssignees(store repository.Store) error {
+	worker, err := store.CreateUser(newUser("Worker", "worker@example.com"))
+	if err != nil {
+		return err
+	}
+	assigned := newTodo("assigned", 1)
+	assigned.AssigneeIDs = []int{worker.ID, 2, worker.ID}
+	if assigned, err = store.Create(assigned); err != nil {
+		return err
+	}
+	if len(assigned.AssigneeIDs) != 2 || assigned.AssigneeIDs[0] != 2 || assigned.AssigneeIDs[1] != worker.ID {
+		return fmt.Errorf("assignee_ids = %v, want [2 %d]", assigned.AssigneeIDs, worker.ID)
+	}
+	unassigned, err := mustCreate(store, "unassigned", 1)
+	if err != nil {
+		return err
+	}
+	if unassigned.AssigneeIDs == nil {
+		return fmt.Errorf("assignee_ids of a new todo is nil, want empty")
+	}
+
+	unknown := *unassigned
+	unknown.AssigneeIDs = []int{12345}
+	if _, err := store.Update(&unknown); !errors.Is(err, repository.ErrAssigneeNotFound) {
+		return fmt.Errorf("update to unknown assignee: err = %v, want ErrAssigneeNotFound", err)
+	}
+
+	for _, tc := range []struct {
+		assigneeID int
+		want       []int
+	}{
+		{worker.ID, []int{assigned.ID}},
+		{1, nil},
+		{0, []int{unassigned.ID}},
+	} {
+		assigneeID := tc.assigneeID
+		found, err := store.FindAll(ws, repository.TodoFilter{AssigneeID: &assigneeID})
+		if err != nil {
+			return err
+		}
+		if len(found) != len(tc.want) || (len(found) == 1 && found[0].ID != tc.want[0]) {
+			return fmt.Errorf("assignee %d: todos = %+v, want IDs %v", assigneeID, found, tc.want)
+		}
+	}
+
+	// The worker owns no todos, so deleting them only drops their assignments
+	if err := store.DeleteUser(worker.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	found, err := store.FindByID(ws, assigned.ID)
+	if err != nil {
+		return err
+	}
+	if len(found.AssigneeIDs) != 1 || found.AssigneeIDs[0] != 2 {
+		return fmt.Errorf("assignee_ids after user delete = %v, want [2]", found.AssigneeIDs)
+	}
+	return nil
+}
+
+func checkComments(store repository.Store) error {
+	author, err := store.CreateUser(newUser("Author", "author@example.com"))
+	if err != nil {
+		return err
+	}
+	todo, err := mustCreate(store, "discussed", 1)
+	if err != nil {
+		return err
+	}
+	other, err := mustCreate(store, "other", 1)
+	if err != nil {
+		return err
+	}
+
+	first, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: todo.ID, AuthorID: author.ID, Body: "first", Mentions: []int{2, 1, 2}, CreatedAt: *at(0)})
+	if err != nil {
+		return err
+	}
+	if len(first.Mentions) != 2 || first.Mentions[0] != 1 || first.Mentions[1] != 2 {
+		return fmt.Errorf("mentions = %v, want [1 2]", first.Mentions)
+	}
+	reply, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: todo.ID, ReplyToID: first.ID, AuthorID: 1, Body: "reply", CreatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	if reply.Mentions == nil || reply.ID <= first.ID {
+		return fmt.Errorf("reply = %+v, want a later ID and empty mentions", *reply)
+	}
+
+	for _, tc := range []struct {
+		comment models.Comment
+		want    error
+	}{
+		{models.Comment{WorkspaceID: ws, TodoID: 12345, AuthorID: 1, Body: "x"}, repository.ErrTodoNotFound},
+		{models.Comment{WorkspaceID: ws, TodoID: other.ID, ReplyToID: first.ID, AuthorID: 1, Body: "x"}, repository.ErrReplyNotFound},
+		{models.Comment{WorkspaceID: ws, TodoID: todo.ID, AuthorID: 12345, Body: "x"}, repository.ErrUserNotFound},
+		{models.Comment{WorkspaceID: ws, TodoID: todo.ID, AuthorID: 1, Body: "x", Mentions: []int{12345}}, repository.ErrUserNotFound},
+	} {
+		comment := tc.comment
+		if _, err := store.CreateComment(&comment); !errors.Is(err, tc.want) {
+			return fmt.Errorf("create %+v: err = %v, want %v", tc.comment, err, tc.want)
+		}
+	}
+
+	// Comments are invisible from another workspace
+	workspace, err := newWorkspace(store, "Other", 1)
+	if err != nil {
+		return err
+	}
+	if _, err := store.GetComment(workspace.ID, first.ID); !errors.Is(err, repository.ErrCommentNotFound) {
+		return fmt.Errorf("comment from another workspace: err = %v, want ErrCommentNotFound", err)
+	}
+	if _, err := store.GetComments(workspace.ID, todo.ID); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("comments of a todo of another workspace: err = %v, want ErrTodoNotFound", err)
+	}
+
+	edited := *first
+	edited.Body = "first, edited"
+	edited.Mentions = []int{2}
+	edited.EditedAt = at(2)
+	edited.AuthorID = 1
+	updated, err := store.UpdateComment(&edited)
+	if err != nil {
+		return err
+	}
+	if updated.Body != "first, edited" || updated.AuthorID != author.ID || len(updated.Mentions) != 1 || updated.EditedAt == nil {
+		return fmt.Errorf("updated comment = %+v", *updated)
+	}
+
+	if _, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: other.ID, AuthorID: 1, Body: "gone soon", CreatedAt: *at(3)}); err != nil {
+		return err
+	}
+	counts, err := store.CountComments(ws, []int{todo.ID, other.ID, 12345})
+	if err != nil {
+		return err
+	}
+	if len(counts) != 2 || counts[todo.ID] != 2 || counts[other.ID] != 1 {
+		return fmt.Errorf("counts = %v, want %d:2 %d:1", counts, todo.ID, other.ID)
+	}
+	if counts, err = store.CountComments(ws, []int{other.ID}); err != nil || len(counts) != 1 || counts[other.ID] != 1 {
+		return fmt.Errorf("counts of one todo = %v, %v, want %d:1", counts, err, other.ID)
+	}
+	if counts, err = store.CountComments(workspace.ID, []int{todo.ID}); err != nil || len(counts) != 0 {
+		return fmt.Errorf("counts in another workspace = %v, %v, want none", counts, err)
+	}
+	all, err := store.GetAllComments(ws)
+	if err != nil {
+		return err
+	}
+	if len(all) != 3 || all[0].ID != first.ID || all[1].ID != reply.ID || all[2].TodoID != other.ID || len(all[0].Mentions) != 1 {
+		return fmt.Errorf("all comments = %+v, want first, reply and the one on %d", all, other.ID)
+	}
+	if all, err = store.GetAllComments(workspace.ID); err != nil || len(all) != 0 {
+		return fmt.Errorf("all comments of another workspace = %v, %v, want none", all, err)
+	}
+
+	// Deleting the todo deletes its comments
+	if err := store.Delete(ws, other.ID); err != nil {
+		return err
+	}
+	if counts, err = store.CountComments(ws, []int{todo.ID, other.ID}); err != nil {
+		return err
+	}
+	if len(counts) != 1 {
+		return fmt.Errorf("counts after todo delete = %v, want only %d", counts, todo.ID)
+	}
+
+	// The author's comment stays without an author, the reply without a parent
+	if err := store.DeleteUser(author.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	if err := store.DeleteComment(ws, 12345); !errors.Is(err, repository.ErrCommentNotFound) {
+		return fmt.Errorf("delete unknown comment: err = %v, want ErrCommentNotFound", err)
+	}
+	kept, err := store.GetComment(ws, first.ID)
+	if err != nil {
+		return err
+	}
+	if kept.AuthorID != 0 {
+		return fmt.Errorf("author after user delete = %d, want 0", kept.AuthorID)
+	}
+	if err := store.DeleteComment(ws, first.ID); err != nil {
+		return err
+	}
+	comments, err := store.GetComments(ws, todo.ID)
+	if err != nil {
+		return err
+	}
+	if len(comments) != 1 || comments[0].ID != reply.ID || comments[0].ReplyToID != 0 {
+		return fmt.Errorf("comments after delete = %+v, want the reply without a parent", comments)
+	}
+	return nil
+}
+
+func checkNotifications(store repository.Store) error {
+	todo, err := mustCreate(store, "noticed", 1)
+	if err != nil {
+		return err
+	}
+	comment, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: todo.ID, AuthorID: 1, Body: "@jane", Mentions: []int{2}, CreatedAt: *at(0)})
+	if err != nil {
+		return err
+	}
+	actor, err := store.CreateUser(newUser("Actor", "actor@example.com"))
+	if err != nil {
+		return err
+	}
+
+	notify := func(userID, actorID int, kind models.NotificationType, commentID int) (*models.Notification, error) {
+		return store.CreateNotification(&models.Notification{
+			UserID: userID, WorkspaceID: ws, TodoID: todo.ID, CommentID: commentID, ActorID: actorID,
+			Type: kind, Message: string(kind), CreatedAt: *at(0),
+		})
+	}
+	mentioned, err := notify(2, 1, models.NotificationMention, comment.ID)
+	if err != nil {
+		return err
+	}
+	assigned, err := notify(2, actor.ID, models.NotificationAssigned, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := notify(3, 1, models.NotificationCompleted, 0); err != nil {
+		return err
+	}
+	if _, err := notify(12345, 1, models.NotificationMention, 0); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("notify unknown user: err = %v, want ErrUserNotFound", err)
+	}
+	if _, err := notify(2, 1, models.NotificationMention, 12345); !errors.Is(err, repository.ErrCommentNotFound) {
+		return fmt.Errorf("notify about unknown comment: err = %v, want ErrCommentNotFound", err)
+	}
+
+	inbox, err := store.GetNotifications(2, false)
+	if err != nil {
+		return err
+	}
+	if len(inbox) != 2 || inbox[0].ID != assigned.ID || inbox[1].ID != mentioned.ID {
+		return fmt.Errorf("inbox = %+v, want notifications %d and %d, newest first", inbox, assigned.ID, mentioned.ID)
+	}
+
+	// Only the recipient can read a notification
+	if _, err := store.MarkNotificationRead(3, mentioned.ID, *at(1)); !errors.Is(err, repository.ErrNotificationNotFound) {
+		return fmt.Errorf("read notification of another user: err = %v, want ErrNotificationNotFound", err)
+	}
+	read, err := store.MarkNotificationRead(2, mentioned.ID, *at(1))
+	if err != nil {
+		return err
+	}
+	if read.ReadAt == nil || !read.ReadAt.Equal(*at(1)) {
+		return fmt.Errorf("read_at = %v, want %v", read.ReadAt, at(1))
+	}
+	if read, err = store.MarkNotificationRead(2, mentioned.ID, *at(2)); err != nil || !read.ReadAt.Equal(*at(1)) {
+		return fmt.Errorf("read twice: read_at = %v, err = %v, want the first read", read, err)
+	}
+	unread, err := store.GetNotifications(2, true)
+	if err != nil {
+		return err
+	}
+	if len(unread) != 1 || unread[0].ID != assigned.ID {
+		return fmt.Errorf("unread inbox = %+v, want only %d", unread, assigned.ID)
+	}
+
+	// Deleting the comment and the actor keeps the notifications
+	if err := store.DeleteComment(ws, comment.ID); err != nil {
+		return err
+	}
+	if err := store.DeleteUser(actor.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	if inbox, err = store.GetNotifications(2, false); err != nil {
+		return err
+	}
+	if len(inbox) != 2 || inbox[0].ActorID != 0 || inbox[1].CommentID != 0 {
+		return fmt.Errorf("inbox after comment and actor delete = %+v", inbox)
+	}
+
+	marked, err := store.MarkAllNotificationsRead(2, *at(3))
+	if err != nil {
+		return err
+	}
+	if marked != 1 {
+		return fmt.Errorf("marked %d notifications read, want 1", marked)
+	}
+	for userID, want := range map[int]int{2: 0, 3: 1} {
+		count, err := store.CountUnreadNotifications(userID)
+		if err != nil {
+			return err
+		}
+		if count != want {
+			return fmt.Errorf("unread count of user %d = %d, want %d", userID, count, want)
+		}
+	}
+
+	// Deleting the todo deletes the notifications about it
+	if err := store.Delete(ws, todo.ID); err != nil {
+		return err
+	}
+	if inbox, err = store.GetNotifications(2, false); err != nil {
+		return err
+	}
+	if len(inbox) != 0 {
+		return fmt.Errorf("inbox after todo delete = %+v, want empty", inbox)
+	}
+	return nil
+}
+
+func checkAttachments(store repository.Store) error {
+	todo, err := mustCreate(store, "attached", 1)
+	if err != nil {
+		return err
+	}
+	other, err := mustCreate(store, "other", 1)
+	if err != nil {
+		return err
+	}
+	uploader, err := store.CreateUser(newUser("Uploader", "uploader@example.com"))
+	if err != nil {
+		return err
+	}
+	workspace, err := newWorkspace(store, "Attachments", 1)
+	if err != nil {
+		return err
+	}
+
+	attach := func(workspaceID, todoID, uploaderID int, key string) (*models.Attachment, error) {
+		return store.CreateAttachment(&models.Attachment{
+			WorkspaceID: workspaceID, TodoID: todoID, UploaderID: uploaderID,
+			FileName: "notes.txt", ContentType: "text/plain; charset=utf-8", Size: 5, BlobKey: key, CreatedAt: *at(0),
+		})
+	}
+	first, err := attach(ws, todo.ID, uploader.ID, "aaaa")
+	if err != nil {
+		return err
+	}
+	// Identical content shares its blob
+	second, err := attach(ws, todo.ID, 1, "aaaa")
+	if err != nil {
+		return err
+	}
+	if _, err := attach(ws, other.ID, 1, "bbbb"); err != nil {
+		return err
+	}
+	if _, err := attach(workspace.ID, todo.ID, 1, "cccc"); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("attach to todo of another workspace: err = %v, want ErrTodoNotFound", err)
+	}
+	if _, err := attach(ws, todo.ID, 12345, "cccc"); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("attach by unknown user: err = %v, want ErrUserNotFound", err)
+	}
+
+	attachments, err := store.GetAttachments(ws, todo.ID)
+	if err != nil {
+		return err
+	}
+	if len(attachments) != 2 || attachments[0].ID != first.ID || attachments[1].ID != second.ID || attachments[0].Size != 5 || attachments[0].BlobKey != "aaaa" {
+		return fmt.Errorf("attachments = %+v, want %d and %d", attachments, first.ID, second.ID)
+	}
+	if _, err := store.GetAttachments(workspace.ID, todo.ID); !errors.Is(err, repository.ErrTodoNotFound) {
+		return fmt.Errorf("attachments of todo in another workspace: err = %v, want ErrTodoNotFound", err)
+	}
+	if _, err := store.GetAttachment(workspace.ID, first.ID); !errors.Is(err, repository.ErrAttachmentNotFound) {
+		return fmt.Errorf("attachment from another workspace: err = %v, want ErrAttachmentNotFound", err)
+	}
+
+	// Deleting the uploader keeps their attachments
+	if err := store.DeleteUser(uploader.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	kept, err := store.GetAttachment(ws, first.ID)
+	if err != nil {
+		return err
+	}
+	if kept.UploaderID != 0 {
+		return fmt.Errorf("uploader_id after user delete = %d, want 0", kept.UploaderID)
+	}
+
+	// Blobs stay referenced while any attachment uses them
+	if err := store.DeleteAttachment(ws, first.ID); err != nil {
+		return err
+	}
+	if err := store.DeleteAttachment(ws, first.ID); !errors.Is(err, repository.ErrAttachmentNotFound) {
+		return fmt.Errorf("delete twice: err = %v, want ErrAttachmentNotFound", err)
+	}
+	keys, err := store.BlobKeys()
+	if err != nil {
+		return err
+	}
+	if len(keys) != 2 || !keys["aaaa"] || !keys["bbbb"] {
+		return fmt.Errorf("blob keys = %v, want aaaa and bbbb", keys)
+	}
+
+	// Deleting the todo deletes its attachments
+	if err := store.Delete(ws, todo.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetAttachment(ws, second.ID); !errors.Is(err, repository.ErrAttachmentNotFound) {
+		return fmt.Errorf("attachment of deleted todo: err = %v, want ErrAttachmentNotFound", err)
+	}
+	if keys, err = store.BlobKeys(); err != nil {
+		return err
+	}
+	if len(keys) != 1 || !keys["bbbb"] {
+		return fmt.Errorf("blob keys after todo delete = %v, want bbbb", keys)
+	}
+	return nil
+}
+
+func checkWebhooks(store repository.Store) error {
+	creator, err := store.CreateUser(newUser("Integrator", "integrator@example.com"))
+	if err != nil {
+		return err
+	}
+	workspace, err := newWorkspace(store, "Webhooks", 1)
+	if err != nil {
+		return err
+	}
+
+	hook := func(workspaceID, createdBy int, url string) (*models.Webhook, error) {
+		return store.CreateWebhook(&models.Webhook{
+			WorkspaceID: workspaceID, URL: url, Events: []string{"todo.created"}, Secret: "s3cret",
+			Active: true, CreatedBy: createdBy, CreatedAt: *at(0), UpdatedAt: *at(0),
+		})
+	}
+	first, err := hook(ws, creator.ID, "http://example.com/a")
+	if err != nil {
+		return err
+	}
+	second, err := hook(ws, 1, "http://example.com/b")
+	if err != nil {
+		return err
+	}
+	other, err := hook(workspace.ID, 1, "http://example.com/c")
+	if err != nil {
+		return err
+	}
+	if _, err := hook(12345, 1, "http://example.com/d"); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("webhook in unknown workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	if _, err := hook(ws, 12345, "http://example.com/d"); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("webhook by unknown user: err = %v, want ErrUserNotFound", err)
+	}
+
+	webhooks, err := store.GetWebhooks(ws)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) != 2 || webhooks[0].ID != first.ID || webhooks[1].ID != second.ID || webhooks[0].Secret != "s3cret" || len(webhooks[0].Events) != 1 {
+		return fmt.Errorf("webhooks = %+v, want %d and %d", webhooks, first.ID, second.ID)
+	}
+	if _, err := store.GetWebhook(workspace.ID, first.ID); !errors.Is(err, repository.ErrWebhookNotFound) {
+		return fmt.Errorf("webhook from another workspace: err = %v, want ErrWebhookNotFound", err)
+	}
+
+	first.URL = "http://example.com/a2"
+	first.Events = []string{"todo.created", "todo.deleted"}
+	first.Active = false
+	first.UpdatedAt = *at(1)
+	updated, err := store.UpdateWebhook(first)
+	if err != nil {
+		return err
+	}
+	if updated.URL != "http://example.com/a2" || len(updated.Events) != 2 || updated.Active || !updated.UpdatedAt.Equal(*at(1)) || updated.CreatedBy != creator.ID {
+		return fmt.Errorf("updated webhook = %+v", *updated)
+	}
+	other.WorkspaceID = ws
+	if _, err := store.UpdateWebhook(other); !errors.Is(err, repository.ErrWebhookNotFound) {
+		return fmt.Errorf("update webhook of another workspace: err = %v, want ErrWebhookNotFound", err)
+	}
+
+	// Deleting the creator keeps their webhooks
+	if err := store.DeleteUser(creator.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	if kept, err := store.GetWebhook(ws, first.ID); err != nil || kept.CreatedBy != 0 {
+		return fmt.Errorf("webhook after creator delete = %+v, %v, want created_by 0", kept, err)
+	}
+
+	enqueue := func(webhookID int, eventID string, due int) (*models.WebhookDelivery, error) {
+		return store.CreateDelivery(&models.WebhookDelivery{
+			WebhookID: webhookID, WorkspaceID: ws, EventID: eventID, EventType: "todo.created",
+			Payload: []byte(`{"id":"` + eventID + `"}`), Status: models.DeliveryPending, NextAttemptAt: at(due), CreatedAt: *at(0),
+		})
+	}
+	late, err := enqueue(first.ID, "e-1", 2)
+	if err != nil {
+		return err
+	}
+	early, err := enqueue(first.ID, "e-2", 1)
+	if err != nil {
+		return err
+	}
+	kept, err := enqueue(second.ID, "e-3", 1)
+	if err != nil {
+		return err
+	}
+	if _, err := store.CreateDelivery(&models.WebhookDelivery{WebhookID: other.ID, WorkspaceID: ws, Status: models.DeliveryPending}); !errors.Is(err, repository.ErrWebhookNotFound) {
+		return fmt.Errorf("delivery for webhook of another workspace: err = %v, want ErrWebhookNotFound", err)
+	}
+	if string(late.Payload) != `{"id":"e-1"}` || late.Attempts == nil {
+		return fmt.Errorf("delivery = %+v", *late)
+	}
+
+	due, err := store.DueDeliveries(*at(1), 10)
+	if err != nil {
+		return err
+	}
+	if len(due) != 2 || due[0].ID != early.ID || due[1].ID != kept.ID {
+		return fmt.Errorf("due at +1h = %+v, want %d and %d", due, early.ID, kept.ID)
+	}
+	if due, err = store.DueDeliveries(*at(5), 1); err != nil {
+		return err
+	}
+	if len(due) != 1 || due[0].ID != early.ID {
+		return fmt.Errorf("first due delivery = %+v, want %d", due, early.ID)
+	}
+
+	// A failed attempt reschedules, a successful one completes
+	early.Attempts = append(early.Attempts, models.DeliveryAttempt{At: *at(1), StatusCode: 500, Error: "boom", DurationMs: 12})
+	early.NextAttemptAt = at(3)
+	if _, err := store.UpdateDelivery(early); err != nil {
+		return err
+	}
+	late.Status = models.DeliverySucceeded
+	late.Attempts = append(late.Attempts, models.DeliveryAttempt{At: *at(2), StatusCode: 204})
+	late.NextAttemptAt = nil
+	late.CompletedAt = at(2)
+	if _, err := store.UpdateDelivery(late); err != nil {
+		return err
+	}
+	if due, err = store.DueDeliveries(*at(2), 10); err != nil {
+		return err
+	}
+	if len(due) != 1 || due[0].ID != kept.ID {
+		return fmt.Errorf("due after attempts = %+v, want %d", due, kept.ID)
+	}
+
+	deliveries, err := store.GetDeliveries(ws, first.ID, 10)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != early.ID || deliveries[1].ID != late.ID {
+		return fmt.Errorf("deliveries = %+v, want %d and %d", deliveries, early.ID, late.ID)
+	}
+	if a := deliveries[0].Attempts; len(a) != 1 || a[0].StatusCode != 500 || a[0].Error != "boom" || a[0].DurationMs != 12 || !a[0].At.Equal(*at(1)) {
+		return fmt.Errorf("attempts = %+v", a)
+	}
+	if d := deliveries[1]; d.Status != models.DeliverySucceeded || d.NextAttemptAt != nil || d.CompletedAt == nil || !d.CompletedAt.Equal(*at(2)) {
+		return fmt.Errorf("completed delivery = %+v", d)
+	}
+	if deliveries, err = store.GetDeliveries(ws, first.ID, 1); err != nil {
+		return err
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != early.ID {
+		return fmt.Errorf("latest delivery = %+v, want %d", deliveries, early.ID)
+	}
+	if _, err := store.GetDeliveries(workspace.ID, first.ID, 10); !errors.Is(err, repository.ErrWebhookNotFound) {
+		return fmt.Errorf("deliveries of webhook in another workspace: err = %v, want ErrWebhookNotFound", err)
+	}
+	if _, err := store.GetDelivery(ws, second.ID, early.ID); !errors.Is(err, repository.ErrDeliveryNotFound) {
+		return fmt.Errorf("delivery of another webhook: err = %v, want ErrDeliveryNotFound", err)
+	}
+
+	// Only completed deliveries are pruned
+	if pruned, err := store.PruneDeliveries(*at(1)); err != nil || pruned != 0 {
+		return fmt.Errorf("prune before completion = %d, %v, want 0", pruned, err)
+	}
+	if pruned, err := store.PruneDeliveries(*at(3)); err != nil || pruned != 1 {
+		return fmt.Errorf("prune = %d, %v, want 1", pruned, err)
+	}
+	if _, err := store.GetDelivery(ws, first.ID, late.ID); !errors.Is(err, repository.ErrDeliveryNotFound) {
+		return fmt.Errorf("pruned delivery: err = %v, want ErrDeliveryNotFound", err)
+	}
+
+	// Deleting a webhook deletes its deliveries
+	if err := store.DeleteWebhook(ws, first.ID); err != nil {
+		return err
+	}
+	if err := store.DeleteWebhook(ws, first.ID); !errors.Is(err, repository.ErrWebhookNotFound) {
+		return fmt.Errorf("delete twice: err = %v, want ErrWebhookNotFound", err)
+	}
+	if due, err = store.DueDeliveries(*at(5), 10); err != nil {
+		return err
+	}
+	if len(due) != 1 || due[0].ID != kept.ID {
+		return fmt.Errorf("due after webhook delete = %+v, want %d", due, kept.ID)
+	}
+
+	// Deleting a workspace deletes its webhooks
+	if err := store.DeleteWorkspace(workspace.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetWebhook(workspace.ID, other.ID); !errors.Is(err, repository.ErrWebhookNotFound) {
+		return fmt.Errorf("webhook of deleted workspace: err = %v, want ErrWebhookNotFound", err)
+	}
+	return nil
+}
+
+func checkViews(store repository.Store) error {
+	owner, err := store.CreateUser(newUser("Planner", "planner@example.com"))
+	if err != nil {
+		return err
+	}
+	workspace, err := newWorkspace(store, "Views", 1)
+	if err != nil {
+		return err
+	}
+
+	view := func(workspaceID, userID int, name string, shared bool) (*models.View, error) {
+		return store.CreateView(&models.View{
+			WorkspaceID: workspaceID, UserID: userID, Name: name, Filter: "completed:false", Sort: "due",
+			GroupBy: models.GroupByPriority, Shared: shared, CreatedAt: *at(0), UpdatedAt: *at(0),
+		})
+	}
+	mine, err := view(ws, owner.ID, "mine", false)
+	if err != nil {
+		return err
+	}
+	if mine.ID <= 0 || mine.Filter != "completed:false" || mine.Sort != "due" || mine.GroupBy != models.GroupByPriority || mine.Shared {
+		return fmt.Errorf("created view = %+v", *mine)
+	}
+	private, err := view(ws, 2, "private", false)
+	if err != nil {
+		return err
+	}
+	shared, err := view(ws, 2, "shared", true)
+	if err != nil {
+		return err
+	}
+	if _, err := view(workspace.ID, owner.ID, "elsewhere", true); err != nil {
+		return err
+	}
+	if _, err := view(12345, owner.ID, "x", false); !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("view in unknown workspace: err = %v, want ErrWorkspaceNotFound", err)
+	}
+	if _, err := view(ws, 12345, "x", false); !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("view of unknown user: err = %v, want ErrUserNotFound", err)
+	}
+
+	// Others' views are listed only when shared, and only in their workspace
+	views, err := store.GetViews(ws, owner.ID)
+	if err != nil {
+		return err
+	}
+	if len(views) != 2 || views[0].ID != mine.ID || views[1].ID != shared.ID {
+		return fmt.Errorf("views = %+v, want %d and %d", views, mine.ID, shared.ID)
+	}
+	if views, err = store.GetViews(ws, 2); err != nil || len(views) != 2 || views[0].ID != private.ID {
+		return fmt.Errorf("views of user 2 = %+v, %v, want %d and %d", views, err, private.ID, shared.ID)
+	}
+
+	edited := *private
+	edited.Name = "renamed"
+	edited.Filter = ""
+	edited.GroupBy = models.GroupByNone
+	edited.Shared = true
+	edited.UserID = owner.ID
+	edited.WorkspaceID = workspace.ID
+	edited.UpdatedAt = *at(1)
+	updated, err := store.UpdateView(&edited)
+	if err != nil {
+		return err
+	}
+	if updated.Name != "renamed" || updated.Filter != "" || updated.GroupBy != models.GroupByNone || !updated.Shared ||
+		updated.UserID != 2 || updated.WorkspaceID != ws || !updated.UpdatedAt.Equal(*at(1)) {
+		return fmt.Errorf("updated view = %+v", *updated)
+	}
+	if _, err := store.UpdateView(&models.View{ID: 12345, Name: "x"}); !errors.Is(err, repository.ErrViewNotFound) {
+		return fmt.Errorf("update unknown view: err = %v, want ErrViewNotFound", err)
+	}
+
+	if err := store.DeleteView(shared.ID); err != nil {
+		return err
+	}
+	if _, err := store.GetView(shared.ID); !errors.Is(err, repository.ErrViewNotFound) {
+		return fmt.Errorf("deleted view: err = %v, want ErrViewNotFound", err)
+	}
+	if err := store.DeleteView(shared.ID); !errors.Is(err, repository.ErrViewNotFound) {
+		return fmt.Errorf("delete deleted view: err = %v, want ErrViewNotFound", err)
+	}
+
+	// Views go away with their owner and with their workspace
+	if err := store.DeleteUser(owner.ID, repository.TodoDisposition{}); err != nil {
+		return err
+	}
+	if _, err := store.GetView(mine.ID); !errors.Is(err, repository.ErrViewNotFound) {
+		return fmt.Errorf("view of deleted user: err = %v, want ErrViewNotFound", err)
+	}
+	if err := store.DeleteWorkspace(ws); err != nil {
+		return err
+	}
+	if _, err := store.GetView(private.ID); !errors.Is(err, repository.ErrViewNotFound) {
+		return fmt.Errorf("view of deleted workspace: err = %v, want ErrViewNotFound", err)
+	}
+	return nil
+}
+
+// changeSummary describes changes as "+id" for a created todo, "-id" for a
+// deleted one and "~id" for a changed one
+func changeSummary(changes []repository.TodoChange) string {
+	parts := make([]string, len(changes))
+	for i, change := range changes {
+		switch {
+		case change.Before == nil:
+			parts[i] = fmt.Sprintf("+%d", change.After.ID)
+		case change.After == nil:
+			parts[i] = fmt.Sprintf("-%d", change.Before.ID)
+		default:
+			parts[i] = fmt.Sprintf("~%d", change.After.ID)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func checkObserveTodos(store repository.Store) error {
+	var calls []string
+	var last []repository.TodoChange
+	store.ObserveTodos(func(changes []repository.TodoChange) {
+		calls = append(calls, changeSummary(changes))
+		last = changes
+	})
+	expect := func(what string, want ...string) error {
+		got := calls
+		calls = nil
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			return fmt.Errorf("%s: observed %q, want %q", what, got, want)
+		}
+		return nil
+	}
+
+	root, err := mustCreate(store, "root", 1)
+	if err != nil {
+		return err
+	}
+	middle, err := newSubtask(store, "middle", root.ID)
+	if err != nil {
+		return err
+	}
+	leaf, err := newSubtask(store, "leaf", middle.ID)
+	if err != nil {
+		return err
+	}
+	waiting := newTodo("waiting", 1)
+	waiting.BlockedBy = []int{middle.ID}
+	if waiting, err = store.Create(waiting); err != nil {
+		return err
+	}
+	if err := expect("create", fmt.Sprint("+", root.ID), fmt.Sprint("+", middle.ID), fmt.Sprint("+", leaf.ID), fmt.Sprint("+", waiting.ID)); err != nil {
+		return err
+	}
+
+	// Updates that change nothing or fail are not reported
+	if _, err := store.Update(waiting); err != nil {
+		return err
+	}
+	unknown := *waiting
+	unknown.BlockedBy = []int{12345}
+	if _, err := store.Update(&unknown); !errors.Is(err, repository.ErrBlockerNotFound) {
+		return fmt.Errorf("update to unknown blocker: err = %v, want ErrBlockerNotFound", err)
+	}
+	completed := *root
+	completed.Completed = true
+	if _, err := store.Update(&completed); err != nil {
+		return err
+	}
+	if err := expect("update", fmt.Sprint("~", root.ID)); err != nil {
+		return err
+	}
+	if last[0].Before.Completed || !last[0].After.Completed {
+		return fmt.Errorf("completed before/after = %v/%v, want false/true", last[0].Before.Completed, last[0].After.Completed)
+	}
+
+	// Deleting the middle todo promotes its subtask and unblocks the todo waiting for it
+	if err := store.Delete(ws, middle.ID); err != nil {
+		return err
+	}
+	if err := expect("delete", fmt.Sprintf("-%d ~%d ~%d", middle.ID, leaf.ID, waiting.ID)); err != nil {
+		return err
+	}
+	if last[1].Before.ParentID != middle.ID || last[1].After.ParentID != root.ID {
+		return fmt.Errorf("promoted parent_id before/after = %d/%d, want %d/%d", last[1].Before.ParentID, last[1].After.ParentID, middle.ID, root.ID)
+	}
+	if len(last[2].After.BlockedBy) != 0 {
+		return fmt.Errorf("blocked_by after deleting the blocker = %v, want none", last[2].After.BlockedBy)
+	}
+
+	// Indirect changes are reported too
+	label, err := newLabel(store, ws, "urgent")
+	if err != nil {
+		return err
+	}
+	waiting.BlockedBy = nil
+	waiting.LabelIDs = []int{label.ID}
+	if _, err := store.Update(waiting); err != nil {
+		return err
+	}
+	if err := store.DeleteLabel(ws, label.ID); err != nil {
+		return err
+	}
+	if err := expect("delete label", fmt.Sprint("~", waiting.ID), fmt.Sprint("~", waiting.ID)); err != nil {
+		return err
+	}
+	user, err := store.GetUserByID(1)
+	if err != nil {
+		return err
+	}
+	user.Name = "Renamed"
+	if _, err := store.UpdateUser(user); err != nil {
+		return err
+	}
+	if err := expect("rename user", fmt.Sprintf("~%d ~%d ~%d", root.ID, leaf.ID, waiting.ID)); err != nil {
+		return err
+	}
+	if err := store.DeleteTree(ws, root.ID); err != nil {
+		return err
+	}
+	return expect("delete tree", fmt.Sprintf("-%d -%d", root.ID, leaf.ID))
+}
+
+// RunDurability checks that a persistent backend keeps its data (users,
+// workspaces and lists included) and its ID sequence across a close and reopen
+func RunDurability(open Opener) error {
+	store, closeStore, err := open()
+	if err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+
+	user, err := store.CreateUser(newUser("Durable", "durable@example.com"))
+	if err != nil {
+		return err
+	}
+	workspace, err := newWorkspace(store, "Durable", user.ID)
+	if err != nil {
+		return err
+	}
+
+	list, err := newList(store, ws, "Durable")
+	if err != nil {
+		return err
+	}
+	label, err := newLabel(store, ws, "durable")
+	if err != nil {
+		return err
+	}
+
+	kept, err := mustCreate(store, "kept", 1)
+	if err != nil {
+		return err
+	}
+	middle := newTodo("middle", 2)
+	middle.ListID = list.ID
+	middle.ParentID = kept.ID
+	middle.BlockedBy = []int{kept.ID}
+	if _, err := store.Create(middle); err != nil {
+		return err
+	}
+	deleted, err := mustCreate(store, "deleted", 1)
+	if err != nil {
+		return err
+	}
+	kept.Completed = true
+	kept.DueAt = at(3)
+	kept.Recurrence = "FREQ=DAILY"
+	kept.Occurrence = 1
+	kept.Priority = models.PriorityHigh
+	kept.LabelIDs = []int{label.ID}
+	kept.Checklist = []models.ChecklistItem{{ID: 1, Text: "step", Done: true}}
+	kept.AssigneeIDs = []int{user.ID}
+	if _, err := store.Update(kept); err != nil {
+		return err
+	}
+	comment, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: kept.ID, AuthorID: 2, Body: "thanks", Mentions: []int{user.ID}, CreatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	comment.EditedAt = at(2)
+	if _, err := store.UpdateComment(comment); err != nil {
+		return err
+	}
+	if _, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: deleted.ID, AuthorID: 2, Body: "deleted", CreatedAt: *at(1)}); err != nil {
+		return err
+	}
+	notification, err := store.CreateNotification(&models.Notification{UserID: user.ID, WorkspaceID: ws, TodoID: kept.ID, CommentID: comment.ID, ActorID: 2, Type: models.NotificationMention, Message: "mention", CreatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	if _, err := store.MarkAllNotificationsRead(user.ID, *at(2)); err != nil {
+		return err
+	}
+	attachment, err := store.CreateAttachment(&models.Attachment{WorkspaceID: ws, TodoID: kept.ID, UploaderID: user.ID, FileName: "plan.pdf", ContentType: "application/pdf", Size: 42, BlobKey: "dddd", CreatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	webhook, err := store.CreateWebhook(&models.Webhook{WorkspaceID: ws, URL: "http://example.com/hook", Events: []string{"todo.updated"}, Secret: "s3cret", Active: true, CreatedBy: user.ID, CreatedAt: *at(1), UpdatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	delivery, err := store.CreateDelivery(&models.WebhookDelivery{WebhookID: webhook.ID, WorkspaceID: ws, EventID: "e-1", EventType: "todo.updated", Payload: []byte(`{"id":"e-1"}`), Status: models.DeliveryPending, NextAttemptAt: at(1), CreatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	delivery.Attempts = []models.DeliveryAttempt{{At: *at(1), StatusCode: 503}}
+	delivery.NextAttemptAt = at(2)
+	if _, err := store.UpdateDelivery(delivery); err != nil {
+		return err
+	}
+	view, err := store.CreateView(&models.View{WorkspaceID: ws, UserID: user.ID, Name: "Mine", Filter: "assignee:2", Sort: "due:desc", GroupBy: models.GroupByLabel, CreatedAt: *at(1), UpdatedAt: *at(1)})
+	if err != nil {
+		return err
+	}
+	view.Shared = true
+	if _, err := store.UpdateView(view); err != nil {
+		return err
+	}
+	if err := store.Delete(ws, deleted.ID); err != nil {
+		return err
+	}
+	if err := closeStore(); err != nil {
+		return fmt.Errorf("close store: %w", err)
+	}
+
+	store, closeStore, err = open()
+	if err != nil {
+		return fmt.Errorf("reopen store: %w", err)
+	}
+	defer closeStore()
+
+	reopened, err := store.GetUserByID(user.ID)
+	if err != nil {
+		return fmt.Errorf("user after reopen: %w", err)
+	}
+	if reopened.Email != user.Email || reopened.PasswordHash != user.PasswordHash {
+		return fmt.Errorf("user after reopen = %+v", *reopened)
+	}
+	membership, err := store.GetMembership(workspace.ID, user.ID)
+	if err != nil {
+		return fmt.Errorf("membership after reopen: %w", err)
+	}
+	if membership.Role != "admin" {
+		return fmt.Errorf("membership after reopen = %+v", *membership)
+	}
+
+	all, err := store.FindAll(ws, repository.TodoFilter{})
+	if err != nil {
+		return err
+	}
+	if len(all) != 2 || all[0].Text != "kept" || !all[0].Completed || all[1].Text != "middle" || all[1].ListID != list.ID || all[1].ParentID != kept.ID {
+		return fmt.Errorf("state after reopen = %+v", all)
+	}
+	if due := all[0].DueAt; due == nil || !due.Equal(*at(3)) {
+		return fmt.Errorf("due_at after reopen = %v, want %v", due, at(3))
+	}
+	if all[0].Recurrence != "FREQ=DAILY" || all[0].Occurrence != 1 {
+		return fmt.Errorf("recurrence after reopen = %q #%d", all[0].Recurrence, all[0].Occurrence)
+	}
+	if all[0].Priority != models.PriorityHigh || len(all[0].LabelIDs) != 1 || all[0].LabelIDs[0] != label.ID {
+		return fmt.Errorf("priority and labels after reopen = %q %v", all[0].Priority, all[0].LabelIDs)
+	}
+	if len(all[0].Checklist) != 1 || !all[0].Checklist[0].Done {
+		return fmt.Errorf("checklist after reopen = %+v", all[0].Checklist)
+	}
+	if len(all[0].AssigneeIDs) != 1 || all[0].AssigneeIDs[0] != user.ID {
+		return fmt.Errorf("assignee_ids after reopen = %v, want [%d]", all[0].AssigneeIDs, user.ID)
+	}
+	if len(all[1].BlockedBy) != 1 || all[1].BlockedBy[0] != kept.ID {
+		return fmt.Errorf("blocked_by after reopen = %v, want [%d]", all[1].BlockedBy, kept.ID)
+	}
+	if _, err := store.GetLabel(ws, label.ID); err != nil {
+		return fmt.Errorf("label after reopen: %w", err)
+	}
+	if _, err := store.GetList(ws, list.ID); err != nil {
+		return fmt.Errorf("list after reopen: %w", err)
+	}
+
+	comments, err := store.GetComments(ws, kept.ID)
+	if err != nil {
+		return err
+	}
+	if len(comments) != 1 || comments[0].Body != "thanks" || comments[0].EditedAt == nil || len(comments[0].Mentions) != 1 || comments[0].Mentions[0] != user.ID {
+		return fmt.Errorf("comments after reopen = %+v", comments)
+	}
+	notifications, err := store.GetNotifications(user.ID, false)
+	if err != nil {
+		return err
+	}
+	if len(notifications) != 1 || notifications[0].ID != notification.ID || notifications[0].CommentID != comment.ID || notifications[0].ReadAt == nil {
+		return fmt.Errorf("notifications after reopen = %+v", notifications)
+	}
+	attachments, err := store.GetAttachments(ws, kept.ID)
+	if err != nil {
+		return err
+	}
+	if len(attachments) != 1 || attachments[0].ID != attachment.ID || attachments[0].FileName != "plan.pdf" || attachments[0].Size != 42 || attachments[0].BlobKey != "dddd" {
+		return fmt.Errorf("attachments after reopen = %+v", attachments)
+	}
+	webhooks, err := store.GetWebhooks(ws)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) != 1 || webhooks[0].ID != webhook.ID || webhooks[0].Secret != "s3cret" || len(webhooks[0].Events) != 1 || webhooks[0].Events[0] != "todo.updated" {
+		return fmt.Errorf("webhooks after reopen = %+v", webhooks)
+	}
+	due, err := store.DueDeliveries(*at(2), 10)
+	if err != nil {
+		return err
+	}
+	if len(due) != 1 || due[0].ID != delivery.ID || string(due[0].Payload) != `{"id":"e-1"}` || len(due[0].Attempts) != 1 || due[0].Attempts[0].StatusCode != 503 {
+		return fmt.Errorf("due deliveries after reopen = %+v", due)
+	}
+	views, err := store.GetViews(ws, 1)
+	if err != nil {
+		return err
+	}
+	if len(views) != 1 || views[0].ID != view.ID || views[0].Filter != "assignee:2" || views[0].Sort != "due:desc" || views[0].GroupBy != models.GroupByLabel || !views[0].Shared {
+		return fmt.Errorf("views after reopen = %+v", views)
+	}
+	nextComment, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: kept.ID, AuthorID: 1, Body: "next", CreatedAt: *at(4)})
+	if err != nil {
+		return err
+	}
+	if nextComment.ID <= comment.ID+1 {
+		return fmt.Errorf("comment id %d was reused after reopen (got %d)", comment.ID+1, nextComment.ID)
+	}
+
+	next, err := mustCreate(store, "next", 1)
+	if err != nil {
+		return err
+	}
+	if next.ID <= deleted.ID {
+		return fmt.Errorf("id %d was reused after reopen (got %d)", deleted.ID, next.ID)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"reflect"
+	"sort"
+
+	"test_mekari/internal/models"
+)
+
+// changeSet collects the todos one mutation changes by ID, keeping the state
+// of each from before its first change (nil for a todo the mutation created)
+type changeSet map[int]*TodoChange
+
+// record notes that the todo with id went from before to after, nil standing
+// for a todo that does not exist
+func (c changeSet) record(id int, before, after *models.Todo) {
+	if change, exists := c[id]; exists {
+		change.After = after
+		return
+	}
+	c[id] = &TodoChange{Before: before, After: after}
+}
+
+// list returns copies of the changes in ID order, leaving out the todos that
+// ended up as they started
+func (c changeSet) list() []TodoChange {
+	ids := make([]int, 0, len(c))
+	for id, change := range c {
+		if change.Before == nil && change.After == nil {
+			continue
+		}
+		if change.Before != nil && change.After != nil && reflect.DeepEqual(*change.Before, *change.After) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	changes := make([]TodoChange, len(ids))
+	for i, id := range ids {
+		if before := c[id].Before; before != nil {
+			todo := *before
+			changes[i].Before = &todo
+		}
+		if after := c[id].After; after != nil {
+			todo := *after
+			changes[i].After = &todo
+		}
+	}
+	return changes
+}
+
+// notify calls every observer with changes, unless nothing changed
+func notify(observers []func([]TodoChange), changes []TodoChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, fn := range observers {
+		fn(changes)
+	}
+}
@@ -1,80 +1,531 @@
 package repository
 
 import (
-	"test_mekari/internal/models"
 	"errors"
+	"fmt"
+	"log"
+	"sort"
 	"sync"
+	"test_mekari/internal/models"
+	"time"
 )
 
 var (
 	ErrTodoNotFound = errors.New("todo not found")
 )
 
-// TodoRepository handles data access for todos
+// TodoRepository is the in-memory Store backend
 type TodoRepository struct {
-	todos   []models.Todo
-	users   map[int]models.User
-	nextID  int
-	mu      sync.RWMutex
+	todos      *todoTable
+	users      map[int]models.User
+	nextID     int
+	nextUserID int
+	// workspaceID -> userID -> membership
+	workspaces         map[int]models.Workspace
+	members            map[int]map[int]models.Membership
+	nextWorkspaceID    int
+	lists              map[int]models.List
+	nextListID         int
+	labels             map[int]models.Label
+	nextLabelID        int
+	comments           map[int]models.Comment
+	commentCounts      map[int]int // comments per todo ID
+	nextCommentID      int
+	notifications      map[int]models.Notification
+	nextNotificationID int
+	attachments        map[int]models.Attachment
+	nextAttachmentID   int
+	webhooks           map[int]models.Webhook
+	nextWebhookID      int
+	deliveries         map[int]models.WebhookDelivery
+	nextDeliveryID     int
+	views              map[int]models.View
+	nextViewID         int
+	mu                 sync.RWMutex
+	journal            *Journal
+	observers          []func([]TodoChange)
 }
 
-// NewTodoRepository creates a new instance of TodoRepository
-func NewTodoRepository() *TodoRepository {
-	return &TodoRepository{
-		todos:  make([]models.Todo, 0),
-		users:  SeedUsers(), // Use seeder function to populate initial users
-		nextID: 1,
+// NewTodoRepository creates a new instance of TodoRepository.
+// With a non-nil journal the previous state is replayed from disk and every
+// mutation is journaled; with a nil journal the data lives only in memory.
+func NewTodoRepository(journal *Journal) (*TodoRepository, error) {
+	repo := &TodoRepository{
+		todos:              newTodoTable(),
+		users:              SeedUsers(), // Use seeder function to populate initial users
+		nextID:             1,
+		workspaces:         SeedWorkspaces(),
+		lists:              make(map[int]models.List),
+		nextListID:         1,
+		labels:             make(map[int]models.Label),
+		nextLabelID:        1,
+		comments:           make(map[int]models.Comment),
+		commentCounts:      make(map[int]int),
+		nextCommentID:      1,
+		notifications:      make(map[int]models.Notification),
+		nextNotificationID: 1,
+		attachments:        make(map[int]models.Attachment),
+		nextAttachmentID:   1,
+		webhooks:           make(map[int]models.Webhook),
+		nextWebhookID:      1,
+		deliveries:         make(map[int]models.WebhookDelivery),
+		nextDeliveryID:     1,
+		views:              make(map[int]models.View),
+		nextViewID:         1,
+		journal:            journal,
 	}
+	repo.nextUserID = maxUserID(repo.users) + 1
+	repo.nextWorkspaceID = DefaultWorkspaceID + 1
+	repo.members = defaultMembers(repo.users)
+
+	if journal != nil {
+		if err := repo.replay(); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
 }
 
-// FindAll returns all todos
-func (r *TodoRepository) FindAll() []models.Todo {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// replay rebuilds the in-memory state from the journal's snapshot and records
+func (r *TodoRepository) replay() error {
+	snap, records, err := r.journal.load()
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range snap.Todos {
+		legacyWorkspace(&todo)
+		legacyPriority(&todo)
+		normalizeTodo(&todo)
+		r.todos.put(todo)
+	}
+	if snap.NextID > r.nextID {
+		r.nextID = snap.NextID
+	}
+	// Snapshots written before users were journaled keep the seeded users
+	if snap.Users != nil {
+		r.users = make(map[int]models.User, len(snap.Users))
+		for _, stored := range snap.Users {
+			r.users[stored.ID] = stored.toUser()
+		}
+	}
+	if snap.NextUserID > r.nextUserID {
+		r.nextUserID = snap.NextUserID
+	}
+
+	if snap.Workspaces != nil {
+		r.workspaces = make(map[int]models.Workspace, len(snap.Workspaces))
+		for _, workspace := range snap.Workspaces {
+			r.workspaces[workspace.ID] = workspace
+		}
+	}
+	if snap.NextWorkspaceID > r.nextWorkspaceID {
+		r.nextWorkspaceID = snap.NextWorkspaceID
+	}
+	if snap.Members != nil {
+		r.members = make(map[int]map[int]models.Membership)
+		for _, membership := range snap.Members {
+			r.putMember(membership)
+		}
+	} else {
+		// Snapshots written before workspaces existed: everyone joins the default workspace
+		r.members = defaultMembers(r.users)
+	}
+	for _, list := range snap.Lists {
+		r.lists[list.ID] = list
+	}
+	if snap.NextListID > r.nextListID {
+		r.nextListID = snap.NextListID
+	}
+	for _, label := range snap.Labels {
+		r.labels[label.ID] = label
+	}
+	if snap.NextLabelID > r.nextLabelID {
+		r.nextLabelID = snap.NextLabelID
+	}
+	for _, comment := range snap.Comments {
+		r.putComment(comment)
+	}
+	if snap.NextCommentID > r.nextCommentID {
+		r.nextCommentID = snap.NextCommentID
+	}
+	for _, n := range snap.Notifications {
+		r.notifications[n.ID] = n
+	}
+	if snap.NextNotificationID > r.nextNotificationID {
+		r.nextNotificationID = snap.NextNotificationID
+	}
+	for _, attachment := range snap.Attachments {
+		r.attachments[attachment.ID] = attachment
+	}
+	if snap.NextAttachmentID > r.nextAttachmentID {
+		r.nextAttachmentID = snap.NextAttachmentID
+	}
+	for _, webhook := range snap.Webhooks {
+		r.webhooks[webhook.ID] = webhook
+	}
+	if snap.NextWebhookID > r.nextWebhookID {
+		r.nextWebhookID = snap.NextWebhookID
+	}
+	for _, delivery := range snap.Deliveries {
+		r.deliveries[delivery.ID] = delivery
+	}
+	if snap.NextDeliveryID > r.nextDeliveryID {
+		r.nextDeliveryID = snap.NextDeliveryID
+	}
+	for _, view := range snap.Views {
+		r.views[view.ID] = view
+	}
+	if snap.NextViewID > r.nextViewID {
+		r.nextViewID = snap.NextViewID
+	}
 
-	// Return a copy to prevent external modifications
-	todosCopy := make([]models.Todo, len(r.todos))
-	copy(todosCopy, r.todos)
-	return todosCopy
+	for _, rec := range records {
+		if rec.Todo != nil {
+			legacyWorkspace(rec.Todo)
+			legacyPriority(rec.Todo)
+		}
+		if err := r.apply(rec); err != nil {
+			return fmt.Errorf("replay journal record %d: %w", rec.Seq, err)
+		}
+	}
+	return nil
 }
 
-// FindByID finds a todo by its ID
-func (r *TodoRepository) FindByID(id int) (*models.Todo, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// apply performs a journaled mutation on the in-memory state (lock must be held).
+// Live mutations and replay both go through apply, so they cannot diverge.
+func (r *TodoRepository) apply(rec journalRecord) (err error) {
+	if changesTodos(rec) {
+		// FindByID does not wait for r.mu, hold it back until every todo changed
+		r.todos.batch(func() { err = r.applyEntity(rec) })
+		return err
+	}
+	return r.applyEntity(rec)
+}
+
+// changesTodos reports whether applying rec may change several todos
+func changesTodos(rec journalRecord) bool {
+	switch rec.Entity {
+	case entityTodo:
+		return rec.Op == opDelete || rec.Op == opSave
+	case entityList, entityLabel, entityWorkspace:
+		return rec.Op == opDelete
+	case entityUser:
+		return rec.Op != opCreate
+	}
+	return false
+}
+
+// applyEntity applies rec to the state of its entity (lock must be held)
+func (r *TodoRepository) applyEntity(rec journalRecord) error {
+	switch rec.Entity {
+	case entityTodo:
+		return r.applyTodo(rec)
+	case entityUser:
+		return r.applyUser(rec)
+	case entityWorkspace:
+		return r.applyWorkspace(rec)
+	case entityMember:
+		return r.applyMember(rec)
+	case entityList:
+		return r.applyList(rec)
+	case entityLabel:
+		return r.applyLabel(rec)
+	case entityComment:
+		return r.applyComment(rec)
+	case entityNotification:
+		return r.applyNotification(rec)
+	case entityAttachment:
+		return r.applyAttachment(rec)
+	case entityWebhook:
+		return r.applyWebhook(rec)
+	case entityDelivery:
+		return r.applyDelivery(rec)
+	case entityView:
+		return r.applyView(rec)
+	default:
+		return fmt.Errorf("unknown entity %q", rec.Entity)
+	}
+}
 
-	for _, todo := range r.todos {
-		if todo.ID == id {
-			todoCopy := todo
-			return &todoCopy, nil
+// applyTodo applies a todo record (lock must be held)
+func (r *TodoRepository) applyTodo(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		todo := *rec.Todo
+		normalizeTodo(&todo)
+		r.todos.put(todo)
+		// IDs are never reused, even if the highest todo was deleted afterwards
+		if rec.Todo.ID >= r.nextID {
+			r.nextID = rec.Todo.ID + 1
+		}
+	case opUpdate:
+		if r.todos.get(rec.Todo.ID) == nil {
+			return ErrTodoNotFound
 		}
+		todo := *rec.Todo
+		normalizeTodo(&todo)
+		r.todos.put(todo)
+	case opSave:
+		for _, todo := range rec.Todos {
+			normalizeTodo(&todo)
+			r.todos.put(todo)
+			if todo.ID >= r.nextID {
+				r.nextID = todo.ID + 1
+			}
+		}
+	case opDelete:
+		deleted := r.todos.get(rec.ID)
+		if deleted == nil {
+			return ErrTodoNotFound
+		}
+		if rec.Subtasks {
+			r.removeTodos(r.subtree(rec.ID))
+			return nil
+		}
+		// Subtasks move up to the parent of the deleted todo
+		for _, id := range r.todos.byParent.ids(rec.ID) {
+			subtask := *r.todos.get(id)
+			subtask.ParentID = deleted.ParentID
+			r.todos.put(subtask)
+		}
+		r.removeTodos(map[int]bool{rec.ID: true})
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
 	}
-	return nil, ErrTodoNotFound
+	return nil
+}
+
+// commit journals rec, applies it, compacts when due and tells the observers
+// about the todos it changed (lock must be held).
+// Write-ahead: the record must be durable before the change is visible.
+func (r *TodoRepository) commit(rec journalRecord) error {
+	if r.journal != nil {
+		if err := r.journal.append(rec); err != nil {
+			return err
+		}
+	}
+	if len(r.observers) > 0 {
+		r.todos.changes = make(changeSet)
+		defer func() { r.todos.changes = nil }()
+	}
+	if err := r.apply(rec); err != nil {
+		return err
+	}
+	r.compact()
+	notify(r.observers, r.todos.changes.list())
+	return nil
+}
+
+// ObserveTodos has fn called with the todos every later mutation changed,
+// from within commit
+func (r *TodoRepository) ObserveTodos(fn func([]TodoChange)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observers = append(r.observers, fn)
+}
+
+// compact writes a snapshot when the journal asks for one (lock must be held).
+// A failed snapshot is not fatal: the journal still holds every record.
+func (r *TodoRepository) compact() {
+	if r.journal == nil || !r.journal.snapshotDue() {
+		return
+	}
+	if err := r.journal.writeSnapshot(r.snapshotLocked()); err != nil {
+		log.Printf("⚠️  Journal: snapshot failed: %v", err)
+	}
+}
+
+// snapshotLocked captures the current state (lock must be held)
+func (r *TodoRepository) snapshotLocked() snapshot {
+	todos := r.todos.all()
+
+	users := make([]storedUser, 0, len(r.users))
+	for _, user := range sortedUsers(r.users) {
+		users = append(users, newStoredUser(user))
+	}
+
+	return snapshot{
+		NextID:             r.nextID,
+		Todos:              todos,
+		NextUserID:         r.nextUserID,
+		Users:              users,
+		NextWorkspaceID:    r.nextWorkspaceID,
+		Workspaces:         r.sortedWorkspaces(func(models.Workspace) bool { return true }),
+		Members:            r.sortedMembers(),
+		NextListID:         r.nextListID,
+		Lists:              r.sortedLists(func(models.List) bool { return true }),
+		NextLabelID:        r.nextLabelID,
+		Labels:             r.sortedLabels(func(models.Label) bool { return true }),
+		NextCommentID:      r.nextCommentID,
+		Comments:           r.sortedComments(func(models.Comment) bool { return true }),
+		NextNotificationID: r.nextNotificationID,
+		Notifications:      r.sortedNotifications(),
+		NextAttachmentID:   r.nextAttachmentID,
+		Attachments:        r.sortedAttachments(func(models.Attachment) bool { return true }),
+		NextWebhookID:      r.nextWebhookID,
+		Webhooks:           r.sortedWebhooks(func(models.Webhook) bool { return true }),
+		NextDeliveryID:     r.nextDeliveryID,
+		Deliveries:         r.sortedDeliveries(func(models.WebhookDelivery) bool { return true }),
+		NextViewID:         r.nextViewID,
+		Views:              r.sortedViews(func(models.View) bool { return true }),
+	}
+}
+
+// Close writes a final snapshot and closes the journal
+func (r *TodoRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.journal == nil {
+		return nil
+	}
+	if err := r.journal.writeSnapshot(r.snapshotLocked()); err != nil {
+		r.journal.Close()
+		return err
+	}
+	return r.journal.Close()
+}
+
+// legacyWorkspace moves a todo stored before workspaces existed into the default workspace
+func legacyWorkspace(todo *models.Todo) {
+	if todo.WorkspaceID == 0 {
+		todo.WorkspaceID = DefaultWorkspaceID
+	}
+}
+
+// normalizeTodo prepares a todo for storage: label, blocker and assignee IDs are
+// normalized, the checklist is copied so the caller's slice is never shared
+// with the repository, and the computed fields are dropped
+func normalizeTodo(todo *models.Todo) {
+	todo.LabelIDs = normalizeIDs(todo.LabelIDs)
+	todo.BlockedBy = normalizeIDs(todo.BlockedBy)
+	todo.AssigneeIDs = normalizeIDs(todo.AssigneeIDs)
+	checklist := make([]models.ChecklistItem, len(todo.Checklist))
+	copy(checklist, todo.Checklist)
+	todo.Checklist = checklist
+	todo.Progress = nil
+	todo.Blocked = false
+	todo.CommentCount = 0
+}
+
+// legacyPriority gives a todo stored before priorities existed the "none" priority
+func legacyPriority(todo *models.Todo) {
+	if todo.Priority == "" {
+		todo.Priority = models.PriorityNone
+	}
+}
+
+// matches reports whether todo passes filter (lock must be held)
+func (r *TodoRepository) matches(todo models.Todo, filter TodoFilter) bool {
+	if filter.UserID != 0 && todo.UserID != filter.UserID {
+		return false
+	}
+	if filter.DueAfter != nil && (todo.DueAt == nil || !todo.DueAt.After(*filter.DueAfter)) {
+		return false
+	}
+	if filter.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*filter.DueBefore)) {
+		return false
+	}
+	if filter.OverdueAt != nil && (todo.Completed || todo.DueAt == nil || !todo.DueAt.Before(*filter.OverdueAt)) {
+		return false
+	}
+	if len(filter.Priorities) > 0 && !containsPriority(filter.Priorities, todo.Priority) {
+		return false
+	}
+	if len(filter.LabelIDs) > 0 && !hasLabels(todo, filter.LabelIDs, filter.AllLabels) {
+		return false
+	}
+	if filter.ParentID != nil && todo.ParentID != *filter.ParentID {
+		return false
+	}
+	if filter.AssigneeID != nil && !isAssigned(todo, *filter.AssigneeID) {
+		return false
+	}
+	if filter.Where != nil && !matchWhere(filter.Where, todo) {
+		return false
+	}
+	if filter.ListID != nil {
+		return todo.ListID == *filter.ListID
+	}
+	return filter.IncludeArchived || todo.ListID == 0 || !r.lists[todo.ListID].Archived
+}
+
+// isAssigned reports whether userID is an assignee of todo, or with userID 0 whether todo has none
+func isAssigned(todo models.Todo, userID int) bool {
+	if userID == 0 {
+		return len(todo.AssigneeIDs) == 0
+	}
+	return containsID(todo.AssigneeIDs, userID)
 }
 
-// FindByUserID finds all todos for a specific user
-func (r *TodoRepository) FindByUserID(userID int) []models.Todo {
+// hasLabels reports whether todo carries all (or with all unset, any) of ids
+func hasLabels(todo models.Todo, ids []int, all bool) bool {
+	for _, id := range ids {
+		if containsID(todo.LabelIDs, id) != all {
+			return !all
+		}
+	}
+	return all
+}
+
+func containsPriority(priorities []models.Priority, priority models.Priority) bool {
+	for _, p := range priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAll returns the todos of a workspace that match filter
+func (r *TodoRepository) FindAll(workspaceID int, filter TodoFilter) ([]models.Todo, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	userTodos := make([]models.Todo, 0)
-	for _, todo := range r.todos {
-		if todo.UserID == userID {
-			userTodos = append(userTodos, todo)
+	// In ID order the scan can stop once the page is full; other orders sort
+	// every match
+	byID := filter.Sort == SortByID
+	after := 0
+	if byID && filter.After != nil {
+		after = filter.After.ID
+	}
+
+	matched := make([]*models.Todo, 0)
+	r.todos.scan(workspaceID, r.todos.candidates(workspaceID, filter), filter.Desc, after, func(todo *models.Todo) bool {
+		if r.matches(*todo, filter) {
+			matched = append(matched, todo)
 		}
+		return !byID || filter.Limit == 0 || len(matched) < filter.Limit
+	})
+	// sortTodos returns copies, to prevent external modifications
+	return sortTodos(matched, filter), nil
+}
+
+// FindByID finds a todo by its ID within a workspace. It only takes the lock
+// of the shard of the todo, so it does not wait for other readers or writers.
+func (r *TodoRepository) FindByID(workspaceID, id int) (*models.Todo, error) {
+	if todo := r.todos.lookup(id); todo != nil && todo.WorkspaceID == workspaceID {
+		todoCopy := *todo
+		return &todoCopy, nil
 	}
-	return userTodos
+	return nil, ErrTodoNotFound
 }
 
-// Create creates a new todo
+// Create creates a new todo in todo.WorkspaceID
 func (r *TodoRepository) Create(todo *models.Todo) (*models.Todo, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	todo.ID = r.nextID
-	r.nextID++
+	if err := r.checkCreate(todo); err != nil {
+		return nil, err
+	}
+	normalizeTodo(todo)
 
-	r.todos = append(r.todos, *todo)
+	todo.ID = r.nextID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityTodo, ID: todo.ID, Todo: todo}); err != nil {
+		return nil, err
+	}
 
 	// Return a copy
 	todoCopy := *todo
@@ -86,51 +537,247 @@ func (r *TodoRepository) Update(todo *models.Todo) (*models.Todo, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for i, t := range r.todos {
-		if t.ID == todo.ID {
-			r.todos[i] = *todo
-			todoCopy := *todo
-			return &todoCopy, nil
+	if err := r.checkUpdate(todo); err != nil {
+		return nil, err
+	}
+	normalizeTodo(todo)
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityTodo, ID: todo.ID, Todo: todo}); err != nil {
+		return nil, err
+	}
+
+	todoCopy := *todo
+	return &todoCopy, nil
+}
+
+// SaveTodos updates and creates todos in one journal record
+func (r *TodoRepository) SaveTodos(updates, creates []*models.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, todo := range updates {
+		if err := r.checkUpdate(todo); err != nil {
+			return err
 		}
 	}
-	return nil, ErrTodoNotFound
+	for _, todo := range creates {
+		if err := r.checkCreate(todo); err != nil {
+			return err
+		}
+	}
+
+	todos := make([]models.Todo, 0, len(updates)+len(creates))
+	for _, todo := range updates {
+		normalizeTodo(todo)
+		todos = append(todos, *todo)
+	}
+	for i, todo := range creates {
+		normalizeTodo(todo)
+		todo.ID = r.nextID + i
+		todos = append(todos, *todo)
+	}
+	return r.commit(journalRecord{Op: opSave, Entity: entityTodo, Todos: todos})
+}
+
+// checkCreate verifies todo may be created (lock must be held)
+func (r *TodoRepository) checkCreate(todo *models.Todo) error {
+	if _, exists := r.workspaces[todo.WorkspaceID]; !exists {
+		return ErrWorkspaceNotFound
+	}
+	return r.checkReferences(todo)
+}
+
+// checkUpdate verifies todo may be stored over the todo with its ID (lock
+// must be held)
+func (r *TodoRepository) checkUpdate(todo *models.Todo) error {
+	if r.todos.inWorkspace(todo.WorkspaceID, todo.ID) == nil {
+		return ErrTodoNotFound
+	}
+	if err := r.checkReferences(todo); err != nil {
+		return err
+	}
+	if r.subtaskCycle(todo) {
+		return ErrSubtaskCycle
+	}
+	if r.dependencyCycle(todo) {
+		return ErrDependencyCycle
+	}
+	return nil
 }
 
-// Delete deletes a todo by its ID
-func (r *TodoRepository) Delete(id int) error {
+// checkReferences verifies the list, labels, parent and blockers of todo are
+// in its workspace and its assignees exist (lock must be held)
+func (r *TodoRepository) checkReferences(todo *models.Todo) error {
+	if !r.listInWorkspace(todo.WorkspaceID, todo.ListID) {
+		return ErrListNotFound
+	}
+	if !r.labelsInWorkspace(todo.WorkspaceID, todo.LabelIDs) {
+		return ErrLabelNotFound
+	}
+	if todo.ParentID != 0 && r.todos.inWorkspace(todo.WorkspaceID, todo.ParentID) == nil {
+		return ErrParentNotFound
+	}
+	for _, blockerID := range todo.BlockedBy {
+		if r.todos.inWorkspace(todo.WorkspaceID, blockerID) == nil {
+			return ErrBlockerNotFound
+		}
+	}
+	for _, userID := range todo.AssigneeIDs {
+		if _, exists := r.users[userID]; !exists {
+			return ErrAssigneeNotFound
+		}
+	}
+	return nil
+}
+
+// subtaskCycle reports whether todo.ParentID is todo itself or one of its
+// subtasks, walking up from the new parent (lock must be held)
+func (r *TodoRepository) subtaskCycle(todo *models.Todo) bool {
+	seen := make(map[int]bool)
+	for id := todo.ParentID; id != 0 && !seen[id]; {
+		if id == todo.ID {
+			return true
+		}
+		seen[id] = true
+		parent := r.todos.get(id)
+		if parent == nil {
+			break
+		}
+		id = parent.ParentID
+	}
+	return false
+}
+
+// dependencyCycle reports whether one of todo.BlockedBy is todo itself or
+// waits for it, directly or through other todos (lock must be held)
+func (r *TodoRepository) dependencyCycle(todo *models.Todo) bool {
+	seen := make(map[int]bool)
+	stack := append([]int{}, todo.BlockedBy...)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == todo.ID {
+			return true
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if blocker := r.todos.get(id); blocker != nil {
+			stack = append(stack, blocker.BlockedBy...)
+		}
+	}
+	return false
+}
+
+// Delete deletes a todo by its ID within a workspace
+func (r *TodoRepository) Delete(workspaceID, id int) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for i, todo := range r.todos {
-		if todo.ID == id {
-			// Remove the todo from slice
-			r.todos = append(r.todos[:i], r.todos[i+1:]...)
-			return nil
+	if r.todos.inWorkspace(workspaceID, id) == nil {
+		return ErrTodoNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityTodo, ID: id})
+}
+
+// DeleteTree deletes a todo and all of its subtasks in one journal record
+func (r *TodoRepository) DeleteTree(workspaceID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.todos.inWorkspace(workspaceID, id) == nil {
+		return ErrTodoNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityTodo, ID: id, Subtasks: true})
+}
+
+// subtree returns the IDs of a todo and of all of its subtasks (lock must be held)
+func (r *TodoRepository) subtree(id int) map[int]bool {
+	ids := map[int]bool{id: true}
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for subtaskID := range r.todos.byParent[queue[0]] {
+			if !ids[subtaskID] {
+				ids[subtaskID] = true
+				queue = append(queue, subtaskID)
+			}
+		}
+	}
+	return ids
+}
+
+// removeTodos removes the todos with the given IDs, their comments, notifications and attachments; their remaining
+// subtasks become top-level and the todos they blocked lose them as blockers (lock must be held)
+func (r *TodoRepository) removeTodos(ids map[int]bool) {
+	for id := range ids {
+		for _, subtaskID := range r.todos.byParent.ids(id) {
+			if !ids[subtaskID] {
+				subtask := *r.todos.get(subtaskID)
+				subtask.ParentID = 0
+				r.todos.put(subtask)
+			}
+		}
+		for _, blockedID := range r.todos.byBlocker.ids(id) {
+			if !ids[blockedID] {
+				blocked := *r.todos.get(blockedID)
+				blocked.BlockedBy = withoutID(blocked.BlockedBy, id)
+				r.todos.put(blocked)
+			}
+		}
+	}
+	for id := range ids {
+		r.todos.remove(id)
+	}
+	for id, comment := range r.comments {
+		if ids[comment.TodoID] {
+			r.deleteComment(id)
+		}
+	}
+	for id, n := range r.notifications {
+		if ids[n.TodoID] {
+			delete(r.notifications, id)
+		}
+	}
+	for id, attachment := range r.attachments {
+		if ids[attachment.TodoID] {
+			delete(r.attachments, id)
 		}
 	}
-	return ErrTodoNotFound
 }
 
-// GetUserByID retrieves a user by ID
-func (r *TodoRepository) GetUserByID(userID int) (*models.User, error) {
+// DueReminders returns the open todos whose reminder is due and has not fired yet
+func (r *TodoRepository) DueReminders(now time.Time) ([]models.Todo, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if user, exists := r.users[userID]; exists {
-		userCopy := user
-		return &userCopy, nil
+	due := make([]models.Todo, 0)
+	for id := range r.todos.reminders {
+		if todo := r.todos.get(id); !todo.RemindAt.After(now) {
+			due = append(due, *todo)
+		}
 	}
-	return nil, errors.New("user not found")
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].RemindAt.Equal(*due[j].RemindAt) {
+			return due[i].RemindAt.Before(*due[j].RemindAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	return due, nil
 }
 
-// GetAllUsers returns all users
-func (r *TodoRepository) GetAllUsers() []models.User {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// MarkReminded records that the reminder of a todo fired, unless it was rescheduled meanwhile
+func (r *TodoRepository) MarkReminded(id int, remindAt, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	users := make([]models.User, 0, len(r.users))
-	for _, user := range r.users {
-		users = append(users, user)
+	stored := r.todos.get(id)
+	if stored == nil {
+		return ErrTodoNotFound
+	}
+	todo := *stored
+	if todo.RemindAt == nil || !todo.RemindAt.Equal(remindAt) {
+		return nil
 	}
-	return users
+
+	todo.RemindedAt = &at
+	return r.commit(journalRecord{Op: opUpdate, Entity: entityTodo, ID: id, Todo: &todo})
 }
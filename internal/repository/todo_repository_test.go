@@ -0,0 +1,211 @@
+package repository_test
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+var (
+	benchTodos = flag.Int("todos", 1000000, "todos in the in-memory store of the benchmarks")
+	benchUsers = flag.Int("users", 100, "users the todos of the benchmarks are spread over")
+)
+
+const ws = repository.DefaultWorkspaceID
+
+var (
+	benchOnce  sync.Once
+	benchStore *repository.TodoRepository
+	benchErr   error
+	// benchUserIDs are the users owning and assigned the todos
+	benchUserIDs []int
+	// benchRecreated are the todos of the second half, which
+	// BenchmarkDeleteAndCreate deletes and creates again
+	benchRecreated []int
+)
+
+// benchmarkStore returns the in-memory store the benchmarks share, filled on
+// first use with -todos todos of -users users
+func benchmarkStore(b *testing.B) *repository.TodoRepository {
+	benchOnce.Do(func() {
+		store, err := repository.NewTodoRepository(nil)
+		if err != nil {
+			benchErr = err
+			return
+		}
+		for i := 0; i < *benchUsers; i++ {
+			user, err := store.CreateUser(&models.User{
+				Name:  fmt.Sprintf("Bench User %d", i),
+				Email: fmt.Sprintf("bench%d@example.com", i),
+				Role:  "member",
+			})
+			if err != nil {
+				benchErr = err
+				return
+			}
+			benchUserIDs = append(benchUserIDs, user.ID)
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < *benchTodos; i++ {
+			todo := &models.Todo{
+				WorkspaceID: ws,
+				Text:        fmt.Sprintf("Todo #%d", i),
+				UserID:      benchUserIDs[rng.Intn(len(benchUserIDs))],
+				Completed:   rng.Intn(10) < 7,
+				Priority:    models.PriorityNone,
+			}
+			if rng.Intn(2) == 0 {
+				todo.AssigneeIDs = []int{benchUserIDs[rng.Intn(len(benchUserIDs))]}
+			}
+			created, err := store.Create(todo)
+			if err != nil {
+				benchErr = err
+				return
+			}
+			if i >= *benchTodos/2 {
+				benchRecreated = append(benchRecreated, created.ID)
+			}
+		}
+		benchStore = store
+	})
+	if benchErr != nil {
+		b.Fatal(benchErr)
+	}
+	b.ResetTimer()
+	return benchStore
+}
+
+// randomID picks a todo of the first half, which is never deleted
+func randomID(rng *rand.Rand) int {
+	return 1 + rng.Intn(*benchTodos/2)
+}
+
+func BenchmarkFindByID(b *testing.B) {
+	store := benchmarkStore(b)
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < b.N; i++ {
+		if _, err := store.FindByID(ws, randomID(rng)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFindByIDParallel(b *testing.B) {
+	store := benchmarkStore(b)
+	var seed int64
+	b.RunParallel(func(pb *testing.PB) {
+		rng := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for pb.Next() {
+			if _, err := store.FindByID(ws, randomID(rng)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkFindByIDParallelWrites has one lookup in ten followed by an update
+func BenchmarkFindByIDParallelWrites(b *testing.B) {
+	store := benchmarkStore(b)
+	var seed int64
+	b.RunParallel(func(pb *testing.PB) {
+		rng := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for i := 0; pb.Next(); i++ {
+			todo, err := store.FindByID(ws, randomID(rng))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			if i%10 == 0 {
+				todo.Completed = !todo.Completed
+				if _, err := store.Update(todo); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	store := benchmarkStore(b)
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < b.N; i++ {
+		todo, err := store.FindByID(ws, randomID(rng))
+		if err != nil {
+			b.Fatal(err)
+		}
+		todo.Completed = !todo.Completed
+		if _, err := store.Update(todo); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDeleteAndCreate creates every todo it deletes again, so the store
+// keeps its size
+func BenchmarkDeleteAndCreate(b *testing.B) {
+	store := benchmarkStore(b)
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < b.N; i++ {
+		k := rng.Intn(len(benchRecreated))
+		todo, err := store.FindByID(ws, benchRecreated[k])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := store.Delete(ws, todo.ID); err != nil {
+			b.Fatal(err)
+		}
+		todo.ID = 0
+		created, err := store.Create(todo)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchRecreated[k] = created.ID
+	}
+}
+
+func BenchmarkFindAll(b *testing.B) {
+	store := benchmarkStore(b)
+	user, assignee := benchUserIDs[0], benchUserIDs[1%len(benchUserIDs)]
+	openOfAssignee, err := expr.Parse(fmt.Sprintf("completed:false AND assignee:%d", assignee))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, bench := range []struct {
+		name   string
+		filter repository.TodoFilter
+	}{
+		{"user first 50", repository.TodoFilter{UserID: user, Limit: 50}},
+		{"user by due date", repository.TodoFilter{UserID: user, Sort: repository.SortByDue, Limit: 50}},
+		{"open of assignee", repository.TodoFilter{Where: openOfAssignee}},
+		{"first 50", repository.TodoFilter{Limit: 50}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := store.FindAll(ws, bench.filter); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDueReminders(b *testing.B) {
+	store := benchmarkStore(b)
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.DueReminders(now); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"sort"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// sortTodos orders todos and applies filter.After and filter.Limit, the way
+// the SQLite backend does in SQL, and returns copies of the todos that remain.
+// todos must be in ascending ID order, or descending with filter.Desc.
+func sortTodos(todos []*models.Todo, filter TodoFilter) []models.Todo {
+	if filter.Sort != SortByID {
+		sort.SliceStable(todos, func(i, j int) bool {
+			return compareTodos(todos[i], todos[j], filter.Sort, filter.Desc) < 0
+		})
+	}
+
+	if filter.After != nil {
+		start := sort.Search(len(todos), func(i int) bool {
+			return compareTodos(todos[i], filter.After, filter.Sort, filter.Desc) > 0
+		})
+		todos = todos[start:]
+	}
+	if filter.Limit > 0 && len(todos) > filter.Limit {
+		todos = todos[:filter.Limit]
+	}
+
+	sorted := make([]models.Todo, len(todos))
+	for i, todo := range todos {
+		sorted[i] = *todo
+	}
+	return sorted
+}
+
+// compareTodos returns -1, 0 or 1 as a comes before, is, or comes after b
+// when ordered by field and then ID, both reversed with desc. Todos without a
+// due date come last in both directions.
+func compareTodos(a, b *models.Todo, field TodoSort, desc bool) int {
+	if field == SortByDue && (a.DueAt == nil) != (b.DueAt == nil) {
+		if a.DueAt == nil {
+			return 1
+		}
+		return -1
+	}
+
+	c := 0
+	switch field {
+	case SortByCreatedAt:
+		c = compareInts(sortMillis(a.CreatedAt), sortMillis(b.CreatedAt))
+	case SortByUpdatedAt:
+		c = compareInts(sortMillis(a.UpdatedAt), sortMillis(b.UpdatedAt))
+	case SortByDue:
+		if a.DueAt != nil {
+			c = compareInts(sortMillis(*a.DueAt), sortMillis(*b.DueAt))
+		}
+	case SortByPriority:
+		c = compareInts(int64(a.Priority.Rank()), int64(b.Priority.Rank()))
+	case SortByText:
+		c = compareFolded(a.Text, b.Text)
+	}
+	if c == 0 {
+		c = compareInts(int64(a.ID), int64(b.ID))
+	}
+	if desc {
+		c = -c
+	}
+	return c
+}
+
+// sortMillis rounds t to the millisecond like SQLite's julianday does
+func sortMillis(t time.Time) int64 {
+	return t.Round(time.Millisecond).UnixMilli()
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareFolded compares strings byte by byte with ASCII letters folded to
+// lower case, like SQLite's NOCASE collation
+func compareFolded(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := foldASCII(a[i]), foldASCII(b[i])
+		if ca != cb {
+			return compareInts(int64(ca), int64(cb))
+		}
+	}
+	return compareInts(int64(len(a)), int64(len(b)))
+}
+
+// foldASCIIString folds the ASCII letters of s to lower case, like SQLite's lower
+func foldASCIIString(s string) string {
+	b := []byte(s)
+	for i := range b {
+		b[i] = foldASCII(b[i])
+	}
+	return string(b)
+}
+
+func foldASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,428 @@
+package repository
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/models"
+)
+
+// todoShards is the number of locks todo lookups by ID are spread over
+const todoShards = 64
+
+// todoTable holds the todos of the in-memory backend by ID, with secondary
+// indexes for the filters that select few todos out of many.
+//
+// Locking: every change is made holding TodoRepository.mu for writing, and the
+// lock of the shard of the todo. Readers hold either r.mu, or only the shard
+// lock to look a single todo up (FindByID), so lookups by ID never wait for
+// readers of the rest of the store and only contend within a shard. A change
+// to several todos at once runs in batch, holding every shard, so it is seen
+// in full or not at all.
+//
+// Stored todos are never modified in place: a change stores a new copy, so a
+// todo read under a shard lock stays valid after the lock is released.
+type todoTable struct {
+	shards   [todoShards]todoShard
+	batching bool // every shard lock is held by the writer
+
+	// The following are only accessed holding r.mu
+	workspaces map[int]*idList // workspace ID -> its todo IDs in ascending order
+	byUser     idIndex         // creator ID -> todo IDs
+	byAssignee idIndex         // assignee ID -> todo IDs
+	byParent   idIndex         // parent ID -> IDs of its subtasks
+	byBlocker  idIndex         // blocker ID -> IDs of the todos waiting for it
+	byStatus   [2]idIndex      // [open, completed]: workspace ID -> todo IDs
+	reminders  idSet           // IDs of the open todos with a reminder yet to fire
+
+	// changes records every put and remove while a mutation that has
+	// observers is applied, nil otherwise
+	changes changeSet
+}
+
+// todoShard is a part of the todos by ID with its own lock, padded so that
+// the locks of neighbouring shards do not share a cache line
+type todoShard struct {
+	mu    sync.RWMutex
+	todos map[int]*models.Todo
+	_     [32]byte
+}
+
+func newTodoTable() *todoTable {
+	t := &todoTable{
+		workspaces: make(map[int]*idList),
+		byUser:     make(idIndex),
+		byAssignee: make(idIndex),
+		byParent:   make(idIndex),
+		byBlocker:  make(idIndex),
+		byStatus:   [2]idIndex{make(idIndex), make(idIndex)},
+		reminders:  make(idSet),
+	}
+	for i := range t.shards {
+		t.shards[i].todos = make(map[int]*models.Todo)
+	}
+	return t
+}
+
+func (t *todoTable) shard(id int) *todoShard {
+	return &t.shards[uint(id)%todoShards]
+}
+
+// get returns the todo with id, or nil (r.mu must be held)
+func (t *todoTable) get(id int) *models.Todo {
+	return t.shard(id).todos[id]
+}
+
+// lookup returns the todo with id, or nil, holding only its shard lock
+func (t *todoTable) lookup(id int) *models.Todo {
+	s := t.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.todos[id]
+}
+
+// inWorkspace returns the todo with id if it is in workspaceID, or nil (r.mu must be held)
+func (t *todoTable) inWorkspace(workspaceID, id int) *models.Todo {
+	if todo := t.get(id); todo != nil && todo.WorkspaceID == workspaceID {
+		return todo
+	}
+	return nil
+}
+
+// put stores a todo, replacing the one with its ID (r.mu must be held for writing).
+// A todo never changes workspace.
+func (t *todoTable) put(todo models.Todo) {
+	old := t.get(todo.ID)
+	stored := &todo
+
+	s := t.shard(todo.ID)
+	if !t.batching {
+		s.mu.Lock()
+	}
+	s.todos[todo.ID] = stored
+	if !t.batching {
+		s.mu.Unlock()
+	}
+
+	if t.changes != nil {
+		t.changes.record(todo.ID, old, stored)
+	}
+	if old != nil {
+		t.unindex(old)
+	} else {
+		list := t.workspaces[todo.WorkspaceID]
+		if list == nil {
+			list = &idList{}
+			t.workspaces[todo.WorkspaceID] = list
+		}
+		list.add(todo.ID)
+	}
+	t.index(stored)
+}
+
+// remove deletes the todo with id (r.mu must be held for writing)
+func (t *todoTable) remove(id int) {
+	old := t.get(id)
+	if old == nil {
+		return
+	}
+
+	s := t.shard(id)
+	if !t.batching {
+		s.mu.Lock()
+	}
+	delete(s.todos, id)
+	if !t.batching {
+		s.mu.Unlock()
+	}
+
+	if t.changes != nil {
+		t.changes.record(id, old, nil)
+	}
+	t.unindex(old)
+	list := t.workspaces[old.WorkspaceID]
+	list.live--
+	switch {
+	case list.live == 0:
+		delete(t.workspaces, old.WorkspaceID)
+	case len(list.ids) > 2*list.live:
+		kept := list.ids[:0]
+		for _, id := range list.ids {
+			if t.get(id) != nil {
+				kept = append(kept, id)
+			}
+		}
+		list.ids = kept
+	}
+}
+
+// batch runs fn holding every shard lock, for changes to several todos (r.mu
+// must be held for writing)
+func (t *todoTable) batch(fn func()) {
+	for i := range t.shards {
+		t.shards[i].mu.Lock()
+	}
+	t.batching = true
+	defer func() {
+		t.batching = false
+		for i := range t.shards {
+			t.shards[i].mu.Unlock()
+		}
+	}()
+	fn()
+}
+
+func (t *todoTable) index(todo *models.Todo) {
+	t.byUser.add(todo.UserID, todo.ID)
+	for _, userID := range todo.AssigneeIDs {
+		t.byAssignee.add(userID, todo.ID)
+	}
+	if todo.ParentID != 0 {
+		t.byParent.add(todo.ParentID, todo.ID)
+	}
+	for _, blockerID := range todo.BlockedBy {
+		t.byBlocker.add(blockerID, todo.ID)
+	}
+	t.byStatus[status(todo.Completed)].add(todo.WorkspaceID, todo.ID)
+	if !todo.Completed && todo.RemindAt != nil && todo.RemindedAt == nil {
+		t.reminders[todo.ID] = struct{}{}
+	}
+}
+
+func (t *todoTable) unindex(todo *models.Todo) {
+	t.byUser.remove(todo.UserID, todo.ID)
+	for _, userID := range todo.AssigneeIDs {
+		t.byAssignee.remove(userID, todo.ID)
+	}
+	if todo.ParentID != 0 {
+		t.byParent.remove(todo.ParentID, todo.ID)
+	}
+	for _, blockerID := range todo.BlockedBy {
+		t.byBlocker.remove(blockerID, todo.ID)
+	}
+	t.byStatus[status(todo.Completed)].remove(todo.WorkspaceID, todo.ID)
+	delete(t.reminders, todo.ID)
+}
+
+// status is the byStatus slot of a todo
+func status(completed bool) int {
+	if completed {
+		return 1
+	}
+	return 0
+}
+
+// len returns the number of todos in a workspace (r.mu must be held)
+func (t *todoTable) len(workspaceID int) int {
+	if list := t.workspaces[workspaceID]; list != nil {
+		return list.live
+	}
+	return 0
+}
+
+// ids returns the IDs of the todos of a workspace in ascending order, a copy
+// the caller may keep while changing the table (r.mu must be held)
+func (t *todoTable) ids(workspaceID int) []int {
+	ids := make([]int, 0, t.len(workspaceID))
+	t.scan(workspaceID, nil, false, 0, func(todo *models.Todo) bool {
+		ids = append(ids, todo.ID)
+		return true
+	})
+	return ids
+}
+
+// all returns every todo ordered by ID (r.mu must be held)
+func (t *todoTable) all() []models.Todo {
+	ids := make([]int, 0)
+	for _, list := range t.workspaces {
+		for _, id := range list.ids {
+			if t.get(id) != nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	todos := make([]models.Todo, len(ids))
+	for i, id := range ids {
+		todos[i] = *t.get(id)
+	}
+	return todos
+}
+
+// scan calls fn with the todos of a workspace in ascending ID order, or
+// descending with desc, starting after the todo with ID after (0 = from the
+// start), until fn returns false. With candidates only those IDs are visited.
+// (r.mu must be held)
+func (t *todoTable) scan(workspaceID int, candidates idSet, desc bool, after int, fn func(*models.Todo) bool) {
+	if candidates != nil {
+		ids := make([]int, 0, len(candidates))
+		for id := range candidates {
+			if after == 0 || (desc && id < after) || (!desc && id > after) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Ints(ids)
+		visit := func(id int) bool {
+			todo := t.get(id)
+			return todo == nil || todo.WorkspaceID != workspaceID || fn(todo)
+		}
+		if desc {
+			for i := len(ids) - 1; i >= 0 && visit(ids[i]); i-- {
+			}
+		} else {
+			for i := 0; i < len(ids) && visit(ids[i]); i++ {
+			}
+		}
+		return
+	}
+
+	list := t.workspaces[workspaceID]
+	if list == nil {
+		return
+	}
+	visit := func(id int) bool {
+		// Removed IDs stay in the list until it is compacted
+		todo := t.get(id)
+		return todo == nil || fn(todo)
+	}
+	if desc {
+		start := len(list.ids) - 1
+		if after != 0 {
+			start = sort.SearchInts(list.ids, after) - 1
+		}
+		for i := start; i >= 0 && visit(list.ids[i]); i-- {
+		}
+	} else {
+		for i := sort.SearchInts(list.ids, after+1); i < len(list.ids) && visit(list.ids[i]); i++ {
+		}
+	}
+}
+
+// candidates returns the smallest indexed set of todo IDs filter narrows a
+// workspace down to, or nil when no index beats scanning the workspace
+// (r.mu must be held). The set may hold todos of other workspaces, and todos
+// that do not match the rest of the filter.
+//
+// A set is only worth it below an eighth of the workspace, as its IDs have to
+// be sorted. A page in ID order is cheaper yet to scan for when the set holds
+// more than sqrt(limit * workspace) todos: the scan visits about
+// limit * workspace / set todos before the page is full.
+func (t *todoTable) candidates(workspaceID int, filter TodoFilter) idSet {
+	var best idSet
+	size := t.len(workspaceID)
+	bestLen := size / 8
+	if filter.Sort == SortByID && filter.Limit > 0 {
+		if pageScan := int(math.Sqrt(float64(filter.Limit) * float64(size))); pageScan < bestLen {
+			bestLen = pageScan
+		}
+	}
+	consider := func(set idSet) {
+		if len(set) < bestLen {
+			best, bestLen = set, len(set)
+			if best == nil {
+				// Nothing has the key: no todo can match
+				best = idSet{}
+			}
+		}
+	}
+
+	if filter.UserID != 0 {
+		consider(t.byUser[filter.UserID])
+	}
+	if filter.AssigneeID != nil && *filter.AssigneeID != 0 {
+		consider(t.byAssignee[*filter.AssigneeID])
+	}
+	if filter.ParentID != nil && *filter.ParentID != 0 {
+		consider(t.byParent[*filter.ParentID])
+	}
+	if filter.OverdueAt != nil {
+		consider(t.byStatus[status(false)][workspaceID])
+	}
+	for _, c := range requiredConds(filter.Where) {
+		switch {
+		case c.Op != expr.Eq:
+		case c.Field == expr.FieldCompleted:
+			consider(t.byStatus[status(c.Bool)][workspaceID])
+		case c.Field == expr.FieldUserID:
+			consider(t.byUser[c.Int])
+		case c.Field == expr.FieldAssignee && c.Int != 0:
+			consider(t.byAssignee[c.Int])
+		case c.Field == expr.FieldParentID && c.Int != 0:
+			consider(t.byParent[c.Int])
+		}
+	}
+	return best
+}
+
+// requiredConds returns the conditions every todo matching n must match: the
+// conditions joined to the top of n by AND only
+func requiredConds(n expr.Node) []*expr.Cond {
+	switch n := n.(type) {
+	case *expr.And:
+		return append(requiredConds(n.X), requiredConds(n.Y)...)
+	case *expr.Cond:
+		return []*expr.Cond{n}
+	}
+	return nil
+}
+
+// idSet is a set of todo IDs
+type idSet map[int]struct{}
+
+// idIndex maps a key to the set of todo IDs that have it
+type idIndex map[int]idSet
+
+func (x idIndex) add(key, id int) {
+	set := x[key]
+	if set == nil {
+		set = make(idSet)
+		x[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+func (x idIndex) remove(key, id int) {
+	set := x[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(x, key)
+	}
+}
+
+// ids returns the IDs with key in ascending order, a copy the caller may keep
+// while changing the index
+func (x idIndex) ids(key int) []int {
+	ids := make([]int, 0, len(x[key]))
+	for id := range x[key] {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// idList keeps the todo IDs of a workspace in ascending order. Removed IDs
+// are left in place for readers to skip until they make up half of the list,
+// which keeps removal O(1) amortized.
+type idList struct {
+	ids  []int
+	live int
+}
+
+// add inserts id; new todos have the highest ID, so this is an append
+func (l *idList) add(id int) {
+	l.live++
+	if n := len(l.ids); n == 0 || l.ids[n-1] < id {
+		l.ids = append(l.ids, id)
+		return
+	}
+	i := sort.SearchInts(l.ids, id)
+	if i < len(l.ids) && l.ids[i] == id {
+		return
+	}
+	l.ids = append(l.ids, 0)
+	copy(l.ids[i+1:], l.ids[i:])
+	l.ids[i] = id
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"strings"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/models"
+)
+
+// matchWhere reports whether todo matches a filter expression, the way
+// whereSQL does in SQL: timestamps compare at millisecond precision and text
+// ignores the case of ASCII letters only
+func matchWhere(n expr.Node, todo models.Todo) bool {
+	switch n := n.(type) {
+	case *expr.And:
+		return matchWhere(n.X, todo) && matchWhere(n.Y, todo)
+	case *expr.Or:
+		return matchWhere(n.X, todo) || matchWhere(n.Y, todo)
+	case *expr.Not:
+		return !matchWhere(n.X, todo)
+	case *expr.Cond:
+		return matchCond(n, todo)
+	}
+	return true
+}
+
+// matchCond reports whether todo matches a single condition
+func matchCond(c *expr.Cond, todo models.Todo) bool {
+	switch c.Field {
+	case expr.FieldID:
+		return compareOp(compareInts(int64(todo.ID), int64(c.Int)), c.Op)
+	case expr.FieldUserID:
+		return compareOp(compareInts(int64(todo.UserID), int64(c.Int)), c.Op)
+	case expr.FieldListID:
+		return todo.ListID == c.Int
+	case expr.FieldParentID:
+		return todo.ParentID == c.Int
+	case expr.FieldAssignee:
+		return isAssigned(todo, c.Int)
+	case expr.FieldLabel:
+		if c.None {
+			return len(todo.LabelIDs) == 0
+		}
+		return containsID(todo.LabelIDs, c.Int)
+	case expr.FieldCompleted:
+		return todo.Completed == c.Bool
+	case expr.FieldText:
+		if c.Op == expr.Contains {
+			return strings.Contains(foldASCIIString(todo.Text), foldASCIIString(c.Text))
+		}
+		return compareFolded(todo.Text, c.Text) == 0
+	case expr.FieldPriority:
+		return compareOp(compareInts(int64(todo.Priority.Rank()), int64(c.Priority.Rank())), c.Op)
+	case expr.FieldCreatedAt:
+		return compareOp(compareInts(sortMillis(todo.CreatedAt), sortMillis(c.Time)), c.Op)
+	case expr.FieldUpdatedAt:
+		return compareOp(compareInts(sortMillis(todo.UpdatedAt), sortMillis(c.Time)), c.Op)
+	case expr.FieldDueAt:
+		if c.None || todo.DueAt == nil {
+			return c.None && todo.DueAt == nil
+		}
+		return compareOp(compareInts(sortMillis(*todo.DueAt), sortMillis(c.Time)), c.Op)
+	}
+	return false
+}
+
+// compareOp reports whether the result c of comparing a field with a value satisfies op
+func compareOp(c int, op expr.Op) bool {
+	switch op {
+	case expr.Lt:
+		return c < 0
+	case expr.Le:
+		return c <= 0
+	case expr.Gt:
+		return c > 0
+	case expr.Ge:
+		return c >= 0
+	}
+	return c == 0
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"test_mekari/internal/expr"
+	"test_mekari/internal/migrations"
+	"test_mekari/internal/models"
+)
+
+// parseWhere parses a filter expression, resolving label names with labels
+func parseWhere(t *testing.T, src string, labels map[string]int) expr.Node {
+	t.Helper()
+	n, err := expr.Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	expr.Walk(n, func(c *expr.Cond) error {
+		if c.Field == expr.FieldLabel && !c.None {
+			c.Int = labels[strings.ToLower(c.Text)]
+		}
+		return nil
+	})
+	return n
+}
+
+// TestWhereSQLParameters checks every value of an expression reaches SQL as
+// an argument, never as part of the statement
+func TestWhereSQLParameters(t *testing.T) {
+	table := []struct {
+		src  string
+		args []any
+	}{
+		{"completed:false", []any{false}},
+		{"id>3 AND user_id:2", []any{3, 2}},
+		{`text:"x' OR '1'='1"`, []any{"x' or '1'='1"}},
+		{`text="'); DROP TABLE todos; --"`, []any{"'); DROP TABLE todos; --"}},
+		{`text:"50%_off"`, []any{"50%_off"}},
+		{"priority>=high OR NOT list_id:4", []any{3, 4}},
+		{"assignee:7 label:none", []any{7}},
+		{"due:none OR created_at<2026-01-01T10:00:00+07:00", []any{"2026-01-01T10:00:00+07:00"}},
+		{"updated_at=2026-01-01", []any{"2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"}},
+	}
+	for _, row := range table {
+		sql, args := whereSQL(parseWhere(t, row.src, nil))
+		if fmt.Sprint(args) != fmt.Sprint(row.args) {
+			t.Errorf("whereSQL(%q) args = %v, want %v", row.src, args, row.args)
+		}
+		if got := strings.Count(sql, "?"); got != len(args) {
+			t.Errorf("whereSQL(%q) = %s with %d placeholders for %d args", row.src, sql, got, len(args))
+		}
+		// The priority ranks are the only literals of the statement
+		literals := strings.ReplaceAll(sql, priorityRank, "")
+		for _, forbidden := range []string{"'", "--", "DROP", "%"} {
+			if strings.Contains(literals, forbidden) {
+				t.Errorf("whereSQL(%q) = %s, holds %q of a value", row.src, sql, forbidden)
+			}
+		}
+	}
+}
+
+// TestWhereAgreement checks an expression finds the same todos in SQL as in
+// memory, where matchWhere evaluates it
+func TestWhereAgreement(t *testing.T) {
+	memory, err := NewTodoRepository(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(false); err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := NewSQLiteRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	stores := []Store{memory, sqlite}
+
+	// The same lists, labels and todos get the same IDs in both stores
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	labels := make(map[string]int)
+	for _, store := range stores {
+		if _, err := store.CreateList(&models.List{WorkspaceID: DefaultWorkspaceID, Name: "Errands", CreatedAt: now, UpdatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"bug", "urgent"} {
+			label, err := store.CreateLabel(&models.Label{WorkspaceID: DefaultWorkspaceID, Name: name, Color: "#d73a4a", CreatedAt: now, UpdatedAt: now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels[name] = label.ID
+		}
+	}
+	at := func(value string) *time.Time {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &parsed
+	}
+	todos := []models.Todo{
+		{Text: "Call the bank", Priority: models.PriorityHigh, UserID: 1, LabelIDs: []int{labels["urgent"]}, DueAt: at("2026-03-11T09:00:00+07:00")},
+		{Text: "call THE BANK again", Completed: true, Priority: models.PriorityLow, UserID: 2, AssigneeIDs: []int{1}},
+		{Text: "Ärger mit dem Amt", Priority: models.PriorityUrgent, UserID: 1, ListID: 1, LabelIDs: []int{labels["bug"], labels["urgent"]}},
+		{Text: "ärger", Priority: models.PriorityNone, UserID: 2, ListID: 1, AssigneeIDs: []int{1, 2}, DueAt: at("2026-03-10T23:59:59.999Z")},
+		{Text: "50% off, don't miss", Priority: models.PriorityMedium, UserID: 1, ParentID: 1, DueAt: at("2026-03-11T00:00:00Z")},
+		{Text: "x' OR '1'='1", Completed: true, Priority: models.PriorityMedium, UserID: 2, ParentID: 1, LabelIDs: []int{labels["bug"]}},
+		{Text: "", Priority: models.PriorityNone, UserID: 1, DueAt: at("2026-03-09T22:00:00-05:00")},
+	}
+	for i := range todos {
+		todo := todos[i]
+		todo.WorkspaceID = DefaultWorkspaceID
+		todo.CreatedBy = fmt.Sprintf("user %d", todo.UserID)
+		todo.CreatedAt = now.Add(time.Duration(i) * 6 * time.Hour)
+		todo.UpdatedAt = todo.CreatedAt.Add(time.Duration(i) * time.Millisecond)
+		for _, store := range stores {
+			copied := todo
+			if _, err := store.Create(&copied); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	all, err := memory.FindAll(DefaultWorkspaceID, TodoFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []string{
+		"completed:true",
+		"completed:false AND priority>=medium",
+		"NOT completed:false",
+		"id<=3 OR id>6",
+		"user_id!=1",
+		"priority<high",
+		"priority=none",
+		"text:bank",
+		"text:BANK",
+		"text=\"call the bank\"",
+		"text:ärger",
+		"text:ÄRGER",
+		"text=ärger",
+		"text:\"50%\"",
+		"text:\"_\"",
+		"text:\"'1'='1\"",
+		"text=\"\"",
+		"assignee:1",
+		"assignee:2 OR assignee:none",
+		"assignee!=1",
+		"label:bug",
+		"label:urgent AND NOT label:bug",
+		"label!=urgent",
+		"label:none",
+		"list_id:1",
+		"list_id:none",
+		"parent_id:1",
+		"parent_id:0 AND list_id:0",
+		"due:none",
+		"due_at!=none",
+		"due_at=2026-03-11",
+		"due_at<2026-03-11",
+		"due_at<=2026-03-10",
+		"due_at>=2026-03-11T00:00:00Z",
+		"due_at>2026-03-11T09:00:00+07:00",
+		"due_at<2026-03-10T03:00:00Z",
+		"created_at>2026-03-10",
+		"created_at>=2026-03-11T00:00:00+00:00",
+		"created_at<2026-03-11T07:00:00+07:00",
+		"updated_at=2026-03-11T06:00:00.001Z",
+		"updated_at>2026-03-11T06:00:00Z",
+		"(completed:true OR label:urgent) AND NOT (assignee:1 OR due:none)",
+		"NOT (id>2 AND (text:a OR priority>low))",
+	} {
+		n := parseWhere(t, src, labels)
+		var want []int
+		for _, todo := range all {
+			if matchWhere(n, todo) {
+				want = append(want, todo.ID)
+			}
+		}
+		for i, store := range stores {
+			found, err := store.FindAll(DefaultWorkspaceID, TodoFilter{Where: n})
+			if err != nil {
+				t.Fatalf("%s: FindAll(%q): %v", []string{"memory", "sqlite"}[i], src, err)
+			}
+			got := make([]int, len(found))
+			for j, todo := range found {
+				got[j] = todo.ID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s: FindAll(%q) = %v, matchWhere finds %v", []string{"memory", "sqlite"}[i], src, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"test_mekari/internal/models"
+)
+
+// GetUserByID retrieves a user by ID
+func (r *TodoRepository) GetUserByID(userID int) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if user, exists := r.users[userID]; exists {
+		userCopy := user
+		return &userCopy, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+// GetUserByEmail retrieves a user by email, ignoring case
+func (r *TodoRepository) GetUserByEmail(email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if user, exists := r.userByEmail(email); exists {
+		return &user, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+// GetAllUsers returns all users ordered by ID
+func (r *TodoRepository) GetAllUsers() ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedUsers(r.users), nil
+}
+
+// CreateUser stores a new user with the next user ID
+func (r *TodoRepository) CreateUser(user *models.User) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.userByEmail(user.Email); taken {
+		return nil, ErrEmailTaken
+	}
+
+	user.ID = r.nextUserID
+	stored := newStoredUser(*user)
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityUser, ID: user.ID, User: &stored}); err != nil {
+		return nil, err
+	}
+
+	userCopy := *user
+	return &userCopy, nil
+}
+
+// UpdateUser replaces a user and renames CreatedBy on every todo they own
+func (r *TodoRepository) UpdateUser(user *models.User) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; !exists {
+		return nil, ErrUserNotFound
+	}
+	if other, taken := r.userByEmail(user.Email); taken && other.ID != user.ID {
+		return nil, ErrEmailTaken
+	}
+
+	stored := newStoredUser(*user)
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityUser, ID: user.ID, User: &stored}); err != nil {
+		return nil, err
+	}
+
+	userCopy := *user
+	return &userCopy, nil
+}
+
+// DeleteUser removes a user and disposes of their todos in the same step
+func (r *TodoRepository) DeleteUser(id int, disposition TodoDisposition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return ErrUserNotFound
+	}
+	if disposition.ReassignTo != 0 {
+		if _, exists := r.users[disposition.ReassignTo]; !exists || disposition.ReassignTo == id {
+			return ErrUserNotFound
+		}
+	} else if !disposition.Cascade && r.ownsTodos(id) {
+		return ErrUserHasTodos
+	}
+
+	return r.commit(journalRecord{
+		Op:          opDelete,
+		Entity:      entityUser,
+		ID:          id,
+		Disposition: &disposition,
+	})
+}
+
+// applyUser applies a user record (lock must be held)
+func (r *TodoRepository) applyUser(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		user := rec.User.toUser()
+		r.users[rec.ID] = user
+		if rec.ID >= r.nextUserID {
+			r.nextUserID = rec.ID + 1
+		}
+		if _, exists := r.workspaces[DefaultWorkspaceID]; exists {
+			r.putMember(defaultMembership(user))
+		}
+	case opUpdate:
+		if _, exists := r.users[rec.ID]; !exists {
+			return ErrUserNotFound
+		}
+		user := rec.User.toUser()
+		r.users[rec.ID] = user
+		// Keep the denormalized creator name in sync with a rename
+		for _, id := range r.todos.byUser.ids(rec.ID) {
+			todo := *r.todos.get(id)
+			todo.CreatedBy = user.Name
+			r.todos.put(todo)
+		}
+	case opDelete:
+		if _, exists := r.users[rec.ID]; !exists {
+			return ErrUserNotFound
+		}
+		disposition := TodoDisposition{}
+		if rec.Disposition != nil {
+			disposition = *rec.Disposition
+		}
+
+		for _, id := range r.todos.byAssignee.ids(rec.ID) {
+			todo := *r.todos.get(id)
+			todo.AssigneeIDs = withoutID(todo.AssigneeIDs, rec.ID)
+			r.todos.put(todo)
+		}
+		removed := make(map[int]bool)
+		for _, id := range r.todos.byUser.ids(rec.ID) {
+			todo := *r.todos.get(id)
+			if disposition.ReassignTo != 0 {
+				todo.UserID = disposition.ReassignTo
+				todo.CreatedBy = r.users[disposition.ReassignTo].Name
+				r.todos.put(todo)
+			} else if disposition.Cascade {
+				removed[todo.ID] = true
+			}
+		}
+		// Subtasks of other users in a removed todo become top-level
+		r.removeTodos(removed)
+		// Comments outlive their author
+		for id, comment := range r.comments {
+			if comment.AuthorID == rec.ID {
+				comment.AuthorID = 0
+			}
+			comment.Mentions = withoutID(comment.Mentions, rec.ID)
+			r.comments[id] = comment
+		}
+		for id, n := range r.notifications {
+			if n.UserID == rec.ID {
+				delete(r.notifications, id)
+			} else if n.ActorID == rec.ID {
+				n.ActorID = 0
+				r.notifications[id] = n
+			}
+		}
+		for id, attachment := range r.attachments {
+			if attachment.UploaderID == rec.ID {
+				attachment.UploaderID = 0
+				r.attachments[id] = attachment
+			}
+		}
+		for id, webhook := range r.webhooks {
+			if webhook.CreatedBy == rec.ID {
+				webhook.CreatedBy = 0
+				r.webhooks[id] = webhook
+			}
+		}
+		for id, view := range r.views {
+			if view.UserID == rec.ID {
+				delete(r.views, id)
+			}
+		}
+		for _, members := range r.members {
+			delete(members, rec.ID)
+		}
+		delete(r.users, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// userByEmail finds a user by email ignoring case (lock must be held)
+func (r *TodoRepository) userByEmail(email string) (models.User, bool) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// ownsTodos reports whether any todo belongs to userID (lock must be held)
+func (r *TodoRepository) ownsTodos(userID int) bool {
+	return len(r.todos.byUser[userID]) > 0
+}
+
+// sortedUsers copies a user map into a slice ordered by ID
+func sortedUsers(users map[int]models.User) []models.User {
+	sorted := make([]models.User, 0, len(users))
+	for _, user := range users {
+		sorted = append(sorted, user)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// maxUserID returns the highest ID in users, or 0
+func maxUserID(users map[int]models.User) int {
+	max := 0
+	for id := range users {
+		if id > max {
+			max = id
+		}
+	}
+	return max
+}
@@ -1,33 +1,63 @@
 package repository
 
 import (
+	"log"
+	"sync"
+	"test_mekari/internal/auth"
 	"test_mekari/internal/models"
 	"time"
 )
 
+// SeedPassword is the login password of every seeded user
+const SeedPassword = "password"
+
+var (
+	seedHashOnce sync.Once
+	seedHash     string
+)
+
+// seedPasswordHash hashes SeedPassword once per process, the KDF is deliberately slow
+func seedPasswordHash() string {
+	seedHashOnce.Do(func() {
+		hash, err := auth.HashPassword(SeedPassword)
+		if err != nil {
+			log.Fatal("❌ Failed to hash seed password:", err)
+		}
+		seedHash = hash
+	})
+	return seedHash
+}
+
 // SeedUsers returns initial user data for the application
 // This is called during repository initialization to populate sample users
 func SeedUsers() map[int]models.User {
 	now := time.Now()
+	hash := seedPasswordHash()
 
 	return map[int]models.User{
 		1: {
-			ID:        1,
-			Name:      "John Doe",
-			Email:     "john@example.com",
-			CreatedAt: now,
+			ID:           1,
+			Name:         "John Doe",
+			Email:        "john@example.com",
+			Role:         "admin",
+			PasswordHash: hash,
+			CreatedAt:    now,
 		},
 		2: {
-			ID:        2,
-			Name:      "Jane Smith",
-			Email:     "jane@example.com",
-			CreatedAt: now,
+			ID:           2,
+			Name:         "Jane Smith",
+			Email:        "jane@example.com",
+			Role:         "member",
+			PasswordHash: hash,
+			CreatedAt:    now,
 		},
 		3: {
-			ID:        3,
-			Name:      "Bob Johnson",
-			Email:     "bob@example.com",
-			CreatedAt: now,
+			ID:           3,
+			Name:         "Bob Johnson",
+			Email:        "bob@example.com",
+			Role:         "member",
+			PasswordHash: hash,
+			CreatedAt:    now,
 		},
 	}
 }
@@ -36,9 +66,11 @@ func SeedUsers() map[int]models.User {
 // This function can be used to add more users programmatically
 func AddUserToSeed(users map[int]models.User, id int, name, email string) {
 	users[id] = models.User{
-		ID:        id,
-		Name:      name,
-		Email:     email,
-		CreatedAt: time.Now(),
+		ID:           id,
+		Name:         name,
+		Email:        email,
+		Role:         "member",
+		PasswordHash: seedPasswordHash(),
+		CreatedAt:    time.Now(),
 	}
 }
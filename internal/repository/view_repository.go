@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"test_mekari/internal/models"
+)
+
+// GetViews returns the views of a workspace userID saved or others shared, ordered by ID
+func (r *TodoRepository) GetViews(workspaceID, userID int) ([]models.View, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedViews(func(v models.View) bool {
+		return v.WorkspaceID == workspaceID && (v.UserID == userID || v.Shared)
+	}), nil
+}
+
+// GetView retrieves a view by ID
+func (r *TodoRepository) GetView(id int) (*models.View, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if view, exists := r.views[id]; exists {
+		return &view, nil
+	}
+	return nil, ErrViewNotFound
+}
+
+// CreateView stores a new view in v.WorkspaceID
+func (r *TodoRepository) CreateView(v *models.View) (*models.View, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[v.WorkspaceID]; !exists {
+		return nil, ErrWorkspaceNotFound
+	}
+	if _, exists := r.users[v.UserID]; !exists {
+		return nil, ErrUserNotFound
+	}
+
+	view := *v
+	view.ID = r.nextViewID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityView, ID: view.ID, View: &view}); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// UpdateView changes the name, filter, sort, grouping and sharing of a view
+func (r *TodoRepository) UpdateView(v *models.View) (*models.View, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	view, exists := r.views[v.ID]
+	if !exists {
+		return nil, ErrViewNotFound
+	}
+	view.Name = v.Name
+	view.Filter = v.Filter
+	view.Sort = v.Sort
+	view.GroupBy = v.GroupBy
+	view.Shared = v.Shared
+	view.UpdatedAt = v.UpdatedAt
+
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityView, ID: view.ID, View: &view}); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// DeleteView removes a view
+func (r *TodoRepository) DeleteView(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.views[id]; !exists {
+		return ErrViewNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityView, ID: id})
+}
+
+// applyView applies a view record (lock must be held)
+func (r *TodoRepository) applyView(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.views[rec.ID] = *rec.View
+		if rec.ID >= r.nextViewID {
+			r.nextViewID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.views[rec.ID]; !exists {
+			return ErrViewNotFound
+		}
+		r.views[rec.ID] = *rec.View
+	case opDelete:
+		if _, exists := r.views[rec.ID]; !exists {
+			return ErrViewNotFound
+		}
+		delete(r.views, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// sortedViews returns the views matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedViews(keep func(models.View) bool) []models.View {
+	views := make([]models.View, 0)
+	for _, view := range r.views {
+		if keep(view) {
+			views = append(views, view)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].ID < views[j].ID })
+	return views
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// GetWebhooks returns the webhooks of a workspace ordered by ID
+func (r *TodoRepository) GetWebhooks(workspaceID int) ([]models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedWebhooks(func(w models.Webhook) bool { return w.WorkspaceID == workspaceID }), nil
+}
+
+// GetWebhook retrieves a webhook by ID within a workspace
+func (r *TodoRepository) GetWebhook(workspaceID, id int) (*models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if webhook, exists := r.webhooks[id]; exists && webhook.WorkspaceID == workspaceID {
+		return copyWebhook(webhook), nil
+	}
+	return nil, ErrWebhookNotFound
+}
+
+// CreateWebhook stores a new webhook in w.WorkspaceID
+func (r *TodoRepository) CreateWebhook(w *models.Webhook) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[w.WorkspaceID]; !exists {
+		return nil, ErrWorkspaceNotFound
+	}
+	if _, exists := r.users[w.CreatedBy]; !exists {
+		return nil, ErrUserNotFound
+	}
+
+	webhook := *copyWebhook(*w)
+	webhook.ID = r.nextWebhookID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityWebhook, ID: webhook.ID, Webhook: &webhook}); err != nil {
+		return nil, err
+	}
+	return copyWebhook(webhook), nil
+}
+
+// UpdateWebhook changes the URL, events, secret and active flag of a webhook
+func (r *TodoRepository) UpdateWebhook(w *models.Webhook) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, exists := r.webhooks[w.ID]
+	if !exists || webhook.WorkspaceID != w.WorkspaceID {
+		return nil, ErrWebhookNotFound
+	}
+	webhook.URL = w.URL
+	webhook.Events = append([]string{}, w.Events...)
+	webhook.Secret = w.Secret
+	webhook.Active = w.Active
+	webhook.UpdatedAt = w.UpdatedAt
+
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityWebhook, ID: webhook.ID, Webhook: &webhook}); err != nil {
+		return nil, err
+	}
+	return copyWebhook(webhook), nil
+}
+
+// DeleteWebhook removes a webhook and its deliveries
+func (r *TodoRepository) DeleteWebhook(workspaceID, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if webhook, exists := r.webhooks[id]; !exists || webhook.WorkspaceID != workspaceID {
+		return ErrWebhookNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityWebhook, ID: id})
+}
+
+// GetDeliveries returns the latest limit deliveries of a webhook, newest first
+func (r *TodoRepository) GetDeliveries(workspaceID, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if webhook, exists := r.webhooks[webhookID]; !exists || webhook.WorkspaceID != workspaceID {
+		return nil, ErrWebhookNotFound
+	}
+	deliveries := r.sortedDeliveries(func(d models.WebhookDelivery) bool { return d.WebhookID == webhookID })
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// GetDelivery retrieves a delivery of a webhook by ID
+func (r *TodoRepository) GetDelivery(workspaceID, webhookID, id int) (*models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if d, exists := r.deliveries[id]; exists && d.WorkspaceID == workspaceID && d.WebhookID == webhookID {
+		return copyDelivery(d), nil
+	}
+	return nil, ErrDeliveryNotFound
+}
+
+// CreateDelivery queues a delivery for d.WebhookID
+func (r *TodoRepository) CreateDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if webhook, exists := r.webhooks[d.WebhookID]; !exists || webhook.WorkspaceID != d.WorkspaceID {
+		return nil, ErrWebhookNotFound
+	}
+
+	delivery := *copyDelivery(*d)
+	delivery.ID = r.nextDeliveryID
+	if err := r.commit(journalRecord{Op: opCreate, Entity: entityDelivery, ID: delivery.ID, Delivery: &delivery}); err != nil {
+		return nil, err
+	}
+	return copyDelivery(delivery), nil
+}
+
+// UpdateDelivery records the outcome of an attempt
+func (r *TodoRepository) UpdateDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, exists := r.deliveries[d.ID]
+	if !exists {
+		return nil, ErrDeliveryNotFound
+	}
+	updated := *copyDelivery(*d)
+	delivery.Status = updated.Status
+	delivery.Attempts = updated.Attempts
+	delivery.NextAttemptAt = updated.NextAttemptAt
+	delivery.CompletedAt = updated.CompletedAt
+
+	if err := r.commit(journalRecord{Op: opUpdate, Entity: entityDelivery, ID: delivery.ID, Delivery: &delivery}); err != nil {
+		return nil, err
+	}
+	return copyDelivery(delivery), nil
+}
+
+// DueDeliveries returns at most limit pending deliveries due at now, earliest first
+func (r *TodoRepository) DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	due := r.sortedDeliveries(func(d models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now)
+	})
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// PruneDeliveries deletes the deliveries completed before the given time in one record
+func (r *TodoRepository) PruneDeliveries(before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	for _, d := range r.deliveries {
+		if completedBefore(d, before) {
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if err := r.commit(journalRecord{Op: opPrune, Entity: entityDelivery, Before: &before}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// applyWebhook applies a webhook record (lock must be held)
+func (r *TodoRepository) applyWebhook(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.webhooks[rec.ID] = *rec.Webhook
+		if rec.ID >= r.nextWebhookID {
+			r.nextWebhookID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.webhooks[rec.ID]; !exists {
+			return ErrWebhookNotFound
+		}
+		r.webhooks[rec.ID] = *rec.Webhook
+	case opDelete:
+		if _, exists := r.webhooks[rec.ID]; !exists {
+			return ErrWebhookNotFound
+		}
+		delete(r.webhooks, rec.ID)
+		for id, d := range r.deliveries {
+			if d.WebhookID == rec.ID {
+				delete(r.deliveries, id)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// applyDelivery applies a delivery record (lock must be held)
+func (r *TodoRepository) applyDelivery(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.deliveries[rec.ID] = *rec.Delivery
+		if rec.ID >= r.nextDeliveryID {
+			r.nextDeliveryID = rec.ID + 1
+		}
+	case opUpdate:
+		if _, exists := r.deliveries[rec.ID]; !exists {
+			return ErrDeliveryNotFound
+		}
+		r.deliveries[rec.ID] = *rec.Delivery
+	case opPrune:
+		for id, d := range r.deliveries {
+			if completedBefore(d, *rec.Before) {
+				delete(r.deliveries, id)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// completedBefore reports whether a delivery succeeded or went dead before t
+func completedBefore(d models.WebhookDelivery, t time.Time) bool {
+	return d.Status != models.DeliveryPending && d.CompletedAt != nil && d.CompletedAt.Before(t)
+}
+
+// sortedWebhooks returns copies of the webhooks matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedWebhooks(keep func(models.Webhook) bool) []models.Webhook {
+	webhooks := make([]models.Webhook, 0)
+	for _, webhook := range r.webhooks {
+		if keep(webhook) {
+			webhooks = append(webhooks, *copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks
+}
+
+// sortedDeliveries returns copies of the deliveries matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedDeliveries(keep func(models.WebhookDelivery) bool) []models.WebhookDelivery {
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if keep(d) {
+			deliveries = append(deliveries, *copyDelivery(d))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID < deliveries[j].ID })
+	return deliveries
+}
+
+// copyWebhook returns a copy of w that shares no slices with it
+func copyWebhook(w models.Webhook) *models.Webhook {
+	w.Events = append(make([]string, 0, len(w.Events)), w.Events...)
+	return &w
+}
+
+// copyDelivery returns a copy of d that shares no slices or pointers with it
+func copyDelivery(d models.WebhookDelivery) *models.WebhookDelivery {
+	d.Payload = append([]byte(nil), d.Payload...)
+	d.Attempts = append(make([]models.DeliveryAttempt, 0, len(d.Attempts)), d.Attempts...)
+	if d.NextAttemptAt != nil {
+		next := *d.NextAttemptAt
+		d.NextAttemptAt = &next
+	}
+	if d.CompletedAt != nil {
+		completed := *d.CompletedAt
+		d.CompletedAt = &completed
+	}
+	return &d
+}
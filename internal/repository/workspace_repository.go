@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+
+	"test_mekari/internal/models"
+)
+
+// GetWorkspace retrieves a workspace by ID
+func (r *TodoRepository) GetWorkspace(id int) (*models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if workspace, exists := r.workspaces[id]; exists {
+		return &workspace, nil
+	}
+	return nil, ErrWorkspaceNotFound
+}
+
+// GetAllWorkspaces returns every workspace ordered by ID
+func (r *TodoRepository) GetAllWorkspaces() ([]models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedWorkspaces(func(models.Workspace) bool { return true }), nil
+}
+
+// GetWorkspacesForUser returns the workspaces userID is a member of
+func (r *TodoRepository) GetWorkspacesForUser(userID int) ([]models.Workspace, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sortedWorkspaces(func(workspace models.Workspace) bool {
+		_, member := r.members[workspace.ID][userID]
+		return member
+	}), nil
+}
+
+// CreateWorkspace stores a new workspace and its first member
+func (r *TodoRepository) CreateWorkspace(workspace *models.Workspace, owner models.Membership) (*models.Workspace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[owner.UserID]; !exists {
+		return nil, ErrUserNotFound
+	}
+
+	workspace.ID = r.nextWorkspaceID
+	owner.WorkspaceID = workspace.ID
+	err := r.commit(journalRecord{
+		Op:         opCreate,
+		Entity:     entityWorkspace,
+		ID:         workspace.ID,
+		Workspace:  workspace,
+		Membership: &owner,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceCopy := *workspace
+	return &workspaceCopy, nil
+}
+
+// DeleteWorkspace removes a workspace with its todos, lists, labels and members
+func (r *TodoRepository) DeleteWorkspace(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[id]; !exists {
+		return ErrWorkspaceNotFound
+	}
+	return r.commit(journalRecord{Op: opDelete, Entity: entityWorkspace, ID: id})
+}
+
+// GetMembership returns the membership of userID in workspaceID
+func (r *TodoRepository) GetMembership(workspaceID, userID int) (*models.Membership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if membership, exists := r.members[workspaceID][userID]; exists {
+		return &membership, nil
+	}
+	return nil, ErrMembershipNotFound
+}
+
+// GetMembers returns the members of a workspace ordered by user ID
+func (r *TodoRepository) GetMembers(workspaceID int) ([]models.Membership, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.workspaces[workspaceID]; !exists {
+		return nil, ErrWorkspaceNotFound
+	}
+
+	members := make([]models.Membership, 0, len(r.members[workspaceID]))
+	for _, membership := range r.members[workspaceID] {
+		members = append(members, membership)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return members, nil
+}
+
+// SaveMembership adds a member or changes their role
+func (r *TodoRepository) SaveMembership(membership *models.Membership) (*models.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workspaces[membership.WorkspaceID]; !exists {
+		return nil, ErrWorkspaceNotFound
+	}
+	if _, exists := r.users[membership.UserID]; !exists {
+		return nil, ErrUserNotFound
+	}
+	// Changing a role keeps the original join time
+	if existing, exists := r.members[membership.WorkspaceID][membership.UserID]; exists {
+		membership.CreatedAt = existing.CreatedAt
+	}
+
+	err := r.commit(journalRecord{Op: opUpdate, Entity: entityMember, ID: membership.WorkspaceID, Membership: membership})
+	if err != nil {
+		return nil, err
+	}
+
+	membershipCopy := *membership
+	return &membershipCopy, nil
+}
+
+// RemoveMembership removes userID from workspaceID; their todos stay in the workspace
+func (r *TodoRepository) RemoveMembership(workspaceID, userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.members[workspaceID][userID]; !exists {
+		return ErrMembershipNotFound
+	}
+	return r.commit(journalRecord{
+		Op:         opDelete,
+		Entity:     entityMember,
+		ID:         workspaceID,
+		Membership: &models.Membership{WorkspaceID: workspaceID, UserID: userID},
+	})
+}
+
+// applyWorkspace applies a workspace record (lock must be held)
+func (r *TodoRepository) applyWorkspace(rec journalRecord) error {
+	switch rec.Op {
+	case opCreate:
+		r.workspaces[rec.ID] = *rec.Workspace
+		if rec.ID >= r.nextWorkspaceID {
+			r.nextWorkspaceID = rec.ID + 1
+		}
+		if rec.Membership != nil {
+			r.putMember(*rec.Membership)
+		}
+	case opDelete:
+		if _, exists := r.workspaces[rec.ID]; !exists {
+			return ErrWorkspaceNotFound
+		}
+		for _, id := range r.todos.ids(rec.ID) {
+			r.todos.remove(id)
+		}
+		for id, list := range r.lists {
+			if list.WorkspaceID == rec.ID {
+				delete(r.lists, id)
+			}
+		}
+		for id, label := range r.labels {
+			if label.WorkspaceID == rec.ID {
+				delete(r.labels, id)
+			}
+		}
+		for id, comment := range r.comments {
+			if comment.WorkspaceID == rec.ID {
+				r.deleteComment(id)
+			}
+		}
+		for id, n := range r.notifications {
+			if n.WorkspaceID == rec.ID {
+				delete(r.notifications, id)
+			}
+		}
+		for id, attachment := range r.attachments {
+			if attachment.WorkspaceID == rec.ID {
+				delete(r.attachments, id)
+			}
+		}
+		for id, webhook := range r.webhooks {
+			if webhook.WorkspaceID == rec.ID {
+				delete(r.webhooks, id)
+			}
+		}
+		for id, delivery := range r.deliveries {
+			if delivery.WorkspaceID == rec.ID {
+				delete(r.deliveries, id)
+			}
+		}
+		for id, view := range r.views {
+			if view.WorkspaceID == rec.ID {
+				delete(r.views, id)
+			}
+		}
+		delete(r.members, rec.ID)
+		delete(r.workspaces, rec.ID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// applyMember applies a membership record (lock must be held)
+func (r *TodoRepository) applyMember(rec journalRecord) error {
+	switch rec.Op {
+	case opUpdate:
+		r.putMember(*rec.Membership)
+	case opDelete:
+		delete(r.members[rec.Membership.WorkspaceID], rec.Membership.UserID)
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// putMember stores a membership, creating the workspace's member map if needed (lock must be held)
+func (r *TodoRepository) putMember(membership models.Membership) {
+	if r.members[membership.WorkspaceID] == nil {
+		r.members[membership.WorkspaceID] = make(map[int]models.Membership)
+	}
+	r.members[membership.WorkspaceID][membership.UserID] = membership
+}
+
+// sortedWorkspaces returns the workspaces matching keep ordered by ID (lock must be held)
+func (r *TodoRepository) sortedWorkspaces(keep func(models.Workspace) bool) []models.Workspace {
+	workspaces := make([]models.Workspace, 0, len(r.workspaces))
+	for _, workspace := range r.workspaces {
+		if keep(workspace) {
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].ID < workspaces[j].ID })
+	return workspaces
+}
+
+// sortedMembers returns every membership ordered by workspace, then user (lock must be held)
+func (r *TodoRepository) sortedMembers() []models.Membership {
+	members := make([]models.Membership, 0)
+	for _, workspaceMembers := range r.members {
+		for _, membership := range workspaceMembers {
+			members = append(members, membership)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].WorkspaceID != members[j].WorkspaceID {
+			return members[i].WorkspaceID < members[j].WorkspaceID
+		}
+		return members[i].UserID < members[j].UserID
+	})
+	return members
+}
+
+// defaultMembers makes every user a member of the default workspace
+func defaultMembers(users map[int]models.User) map[int]map[int]models.Membership {
+	members := make(map[int]models.Membership, len(users))
+	for _, user := range users {
+		members[user.ID] = defaultMembership(user)
+	}
+	return map[int]map[int]models.Membership{DefaultWorkspaceID: members}
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"time"
+
+	"test_mekari/internal/models"
+)
+
+// SeedWorkspaces returns the initial workspaces: just the default one
+func SeedWorkspaces() map[int]models.Workspace {
+	return map[int]models.Workspace{
+		DefaultWorkspaceID: {
+			ID:        DefaultWorkspaceID,
+			Name:      "Default",
+			CreatedAt: time.Now(),
+		},
+	}
+}
+
+// defaultMembership is the membership a user gets in the default workspace,
+// carrying over their user role
+func defaultMembership(user models.User) models.Membership {
+	role := user.Role
+	if role == "" {
+		role = "member"
+	}
+	return models.Membership{
+		WorkspaceID: DefaultWorkspaceID,
+		UserID:      user.ID,
+		Role:        role,
+		CreatedAt:   user.CreatedAt,
+	}
+}
@@ -7,12 +7,31 @@ import (
 	"test_mekari/internal/handler"
 	"test_mekari/internal/helpers"
 	"test_mekari/internal/middleware"
+	"test_mekari/internal/service"
 
 	"github.com/gorilla/mux"
 )
 
+// Handlers holds the handlers the routes dispatch to
+type Handlers struct {
+	Todo         *handler.TodoHandler
+	List         *handler.ListHandler
+	Label        *handler.LabelHandler
+	Comment      *handler.CommentHandler
+	Attachment   *handler.AttachmentHandler
+	Notification *handler.NotificationHandler
+	Event        *handler.EventHandler
+	Collab       *handler.CollabHandler
+	Webhook      *handler.WebhookHandler
+	Search       *handler.SearchHandler
+	View         *handler.ViewHandler
+	User         *handler.UserHandler
+	Workspace    *handler.WorkspaceHandler
+	Auth         *handler.AuthHandler
+}
+
 // SetupRoutes configures all application routes
-func SetupRoutes(todoHandler *handler.TodoHandler) *mux.Router {
+func SetupRoutes(h Handlers, authService *service.AuthService) *mux.Router {
 	router := mux.NewRouter()
 
 	// Apply middleware
@@ -20,15 +39,106 @@ func SetupRoutes(todoHandler *handler.TodoHandler) *mux.Router {
 	router.Use(middleware.LoggingMiddleware)
 
 	// Define routes
+	// Auth routes (public)
+	router.HandleFunc("/auth/login", h.Auth.Login).Methods("POST", "OPTIONS")
+	router.HandleFunc("/auth/logout", h.Auth.Logout).Methods("POST", "OPTIONS")
+
+	// Everything below requires a bearer token or session cookie
+	protected := router.NewRoute().Subrouter()
+	protected.Use(middleware.Authenticate(authService))
+
+	protected.HandleFunc("/me", h.Auth.Me).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/me/notifications", h.Notification.GetNotifications).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/me/notifications/read-all", h.Notification.MarkAllRead).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/me/notifications/{nid}/read", h.Notification.MarkRead).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/me/views", h.View.GetViews).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/me/views", h.View.CreateView).Methods("POST", "OPTIONS")
+
+	// Saved view routes, a view knows its workspace
+	protected.HandleFunc("/views/{vid}", h.View.GetView).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/views/{vid}", h.View.UpdateView).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/views/{vid}", h.View.DeleteView).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/views/{vid}/todos", h.View.GetViewTodos).Methods("GET", "OPTIONS")
+
 	// User routes
-	router.HandleFunc("/users", todoHandler.GetUsers).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users", h.User.GetUsers).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users", h.User.CreateUser).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/users/{id}", h.User.GetUser).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/users/{id}", h.User.UpdateUser).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/users/{id}", h.User.DeleteUser).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/users/{id}/deactivate", h.User.DeactivateUser).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/users/{id}/activate", h.User.ActivateUser).Methods("POST", "OPTIONS")
+
+	// Workspace routes
+	protected.HandleFunc("/workspaces", h.Workspace.GetWorkspaces).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/workspaces", h.Workspace.CreateWorkspace).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/workspaces/{wid}", h.Workspace.GetWorkspace).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/workspaces/{wid}", h.Workspace.DeleteWorkspace).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/workspaces/{wid}/members", h.Workspace.GetMembers).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/workspaces/{wid}/members/{uid}", h.Workspace.SaveMember).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/workspaces/{wid}/members/{uid}", h.Workspace.RemoveMember).Methods("DELETE", "OPTIONS")
 
-	// Todo routes
-	router.HandleFunc("/todos", todoHandler.GetTodos).Methods("GET", "OPTIONS")
-	router.HandleFunc("/todos", todoHandler.CreateTodo).Methods("POST", "OPTIONS")
-	router.HandleFunc("/todos/{id}", todoHandler.DeleteTodo).Methods("DELETE", "OPTIONS")
-	router.HandleFunc("/todos/{id}", todoHandler.UpdateTodo).Methods("PUT", "OPTIONS")
-	router.HandleFunc("/todos/{id}/toggle", todoHandler.ToggleTodo).Methods("PATCH", "OPTIONS")
+	// Todo, list and label routes, scoped to a workspace. The unscoped /todos, /lists and /labels routes act on the default workspace.
+	for _, prefix := range []string{"", "/workspaces/{wid}"} {
+		protected.HandleFunc(prefix+"/lists", h.List.GetLists).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/lists", h.List.CreateList).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/lists/{lid}", h.List.GetList).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/lists/{lid}", h.List.RenameList).Methods("PUT", "OPTIONS")
+		protected.HandleFunc(prefix+"/lists/{lid}", h.List.DeleteList).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/lists/{lid}/archive", h.List.ArchiveList).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/lists/{lid}/unarchive", h.List.UnarchiveList).Methods("POST", "OPTIONS")
+
+		protected.HandleFunc(prefix+"/labels", h.Label.GetLabels).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/labels", h.Label.CreateLabel).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/labels/{lbid}", h.Label.GetLabel).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/labels/{lbid}", h.Label.UpdateLabel).Methods("PUT", "OPTIONS")
+		protected.HandleFunc(prefix+"/labels/{lbid}", h.Label.DeleteLabel).Methods("DELETE", "OPTIONS")
+
+		protected.HandleFunc(prefix+"/events", h.Event.Stream).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/ws", h.Collab.Connect).Methods("GET")
+
+		protected.HandleFunc(prefix+"/webhooks", h.Webhook.GetWebhooks).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks", h.Webhook.CreateWebhook).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks/{whid}", h.Webhook.GetWebhook).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks/{whid}", h.Webhook.UpdateWebhook).Methods("PUT", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks/{whid}", h.Webhook.DeleteWebhook).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks/{whid}/deliveries", h.Webhook.GetDeliveries).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks/{whid}/deliveries/{dlid}", h.Webhook.GetDelivery).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/webhooks/{whid}/deliveries/{dlid}/redeliver", h.Webhook.Redeliver).Methods("POST", "OPTIONS")
+
+		protected.HandleFunc(prefix+"/search", h.Search.Search).Methods("GET", "OPTIONS")
+
+		protected.HandleFunc(prefix+"/todos", h.Todo.GetTodos).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos", h.Todo.CreateTodo).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/plan", h.Todo.GetPlan).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}", h.Todo.DeleteTodo).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}", h.Todo.UpdateTodo).Methods("PUT", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/toggle", h.Todo.ToggleTodo).Methods("PATCH", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/move", h.Todo.MoveTodo).Methods("PATCH", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/occurrences", h.Todo.GetOccurrences).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/recurrence", h.Todo.StopRecurrence).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/checklist", h.Todo.AddChecklistItem).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/checklist/{cid}", h.Todo.UpdateChecklistItem).Methods("PATCH", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/checklist/{cid}", h.Todo.DeleteChecklistItem).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/dependencies", h.Todo.AddDependency).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/dependencies/{bid}", h.Todo.RemoveDependency).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/assignees", h.Todo.AssignTodo).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/assignees/{uid}", h.Todo.UnassignTodo).Methods("DELETE", "OPTIONS")
+
+		protected.HandleFunc(prefix+"/todos/{id}/comments", h.Comment.GetComments).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/comments", h.Comment.CreateComment).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/comments/{cmid}", h.Comment.UpdateComment).Methods("PUT", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/comments/{cmid}", h.Comment.DeleteComment).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments", h.Attachment.GetAttachments).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments", h.Attachment.UploadAttachment).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/uploads", h.Attachment.StartUpload).Methods("POST", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/uploads/{upid}", h.Attachment.GetUpload).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/uploads/{upid}", h.Attachment.AppendUpload).Methods("PATCH", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/uploads/{upid}", h.Attachment.CancelUpload).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/{aid}", h.Attachment.GetAttachment).Methods("GET", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/{aid}", h.Attachment.DeleteAttachment).Methods("DELETE", "OPTIONS")
+		protected.HandleFunc(prefix+"/todos/{id}/attachments/{aid}/download", h.Attachment.DownloadAttachment).Methods("GET", "OPTIONS")
+	}
 
 	// Health check endpoint
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
@@ -58,15 +168,131 @@ func apiInfoHandler(w http.ResponseWriter, r *http.Request) {
 		"name":    "Collaborative Todo List API",
 		"version": "1.0.0",
 		"endpoints": map[string]string{
-			"GET /users":               "Get all users",
-			"GET /todos":               "Get all todos (optional: ?user_id=1 to filter by user)",
-			"POST /todos":              "Create a new todo (user_id must exist)",
-			"DELETE /todos/{id}":       "Delete a todo",
-			"PUT /todos/{id}":          "Update a todo",
-			"PATCH /todos/{id}/toggle": "Toggle todo completed status",
-			"GET /health":              "Health check",
-			"GET /api":                 "API documentation",
-			"GET /":                    "Web interface",
+			"POST /auth/login":                                               "Log in with email + password, returns a bearer token and sets a session cookie",
+			"POST /auth/logout":                                              "End the cookie session",
+			"GET /me":                                                        "Get the authenticated user",
+			"GET /me/notifications":                                          "Get your notifications, newest first, with the unread count (optional: ?unread=true)",
+			"POST /me/notifications/{nid}/read":                              "Mark a notification read",
+			"POST /me/notifications/read-all":                                "Mark all your notifications read",
+			"GET /me/views":                                                  "Get your saved views and the views shared in your workspaces (optional: ?workspace_id=)",
+			"POST /me/views":                                                 "Save a view ({\"workspace_id\", \"name\", \"filter\", \"sort\", \"group_by\": completed|priority|user_id|assignee|list_id|label, \"shared\"})",
+			"GET /views/{vid}":                                               "Get a saved view",
+			"PUT /views/{vid}":                                               "Change a saved view, sharing it with its workspace with \"shared\": true (owner)",
+			"DELETE /views/{vid}":                                            "Delete a saved view (owner or workspace admin)",
+			"GET /views/{vid}/todos":                                         "Run a saved view, its todos in its sort order with their groups in meta.groups (optional: ?limit=50, ?cursor= from meta.next_cursor, ?fields=text,completed)",
+			"GET /users":                                                     "Get all users",
+			"POST /users":                                                    "Create a user (admin)",
+			"GET /users/{id}":                                                "Get a user",
+			"PUT /users/{id}":                                                "Update a user (self or admin; changing role is admin only)",
+			"DELETE /users/{id}":                                             "Delete a user (admin; ?on_todos=block|reassign|cascade&reassign_to=ID)",
+			"POST /users/{id}/deactivate":                                    "Deactivate a user (admin)",
+			"POST /users/{id}/activate":                                      "Reactivate a user (admin)",
+			"GET /workspaces":                                                "List your workspaces",
+			"POST /workspaces":                                               "Create a workspace, you become its admin",
+			"GET /workspaces/{wid}":                                          "Get a workspace",
+			"DELETE /workspaces/{wid}":                                       "Delete a workspace and its todos (workspace admin)",
+			"GET /workspaces/{wid}/members":                                  "List workspace members",
+			"PUT /workspaces/{wid}/members/{uid}":                            "Add a member or change their role (workspace admin)",
+			"DELETE /workspaces/{wid}/members/{uid}":                         "Remove a member (workspace admin)",
+			"GET /workspaces/{wid}/lists":                                    "Get the lists of a workspace (optional: ?include_archived=true)",
+			"POST /workspaces/{wid}/lists":                                   "Create a list in a workspace",
+			"GET /workspaces/{wid}/lists/{lid}":                              "Get a list",
+			"PUT /workspaces/{wid}/lists/{lid}":                              "Rename a list",
+			"DELETE /workspaces/{wid}/lists/{lid}":                           "Delete a list, its todos are kept without a list (workspace admin)",
+			"POST /workspaces/{wid}/lists/{lid}/archive":                     "Archive a list, hiding its todos",
+			"POST /workspaces/{wid}/lists/{lid}/unarchive":                   "Unarchive a list",
+			"GET /workspaces/{wid}/labels":                                   "Get the labels of a workspace",
+			"POST /workspaces/{wid}/labels":                                  "Create a label ({\"name\", \"color\": \"#rrggbb\"})",
+			"GET /workspaces/{wid}/labels/{lbid}":                            "Get a label",
+			"PUT /workspaces/{wid}/labels/{lbid}":                            "Rename or recolor a label",
+			"DELETE /workspaces/{wid}/labels/{lbid}":                         "Delete a label, removing it from its todos (workspace admin)",
+			"GET /workspaces/{wid}/events":                                   "Stream todo.created, todo.updated, todo.toggled and todo.deleted as Server-Sent Events (optional: ?user_id=, ?list_id=, ?assignee=; resumes from Last-Event-ID)",
+			"GET /workspaces/{wid}/ws":                                       "WebSocket channel with presence, editing locks and live todo changes (messages: subscribe, heartbeat, editing, stop_editing)",
+			"GET /workspaces/{wid}/webhooks":                                 "Get the webhooks of a workspace (workspace admin)",
+			"POST /workspaces/{wid}/webhooks":                                "Create a webhook ({\"url\", \"events\": [\"todo.created\", ...], optional \"secret\", \"active\"}; workspace admin)",
+			"GET /workspaces/{wid}/webhooks/{whid}":                          "Get a webhook (workspace admin)",
+			"PUT /workspaces/{wid}/webhooks/{whid}":                          "Change a webhook, a new secret rotates it (workspace admin)",
+			"DELETE /workspaces/{wid}/webhooks/{whid}":                       "Delete a webhook and its delivery log (workspace admin)",
+			"GET /workspaces/{wid}/webhooks/{whid}/deliveries":               "Get the latest deliveries of a webhook with their attempts (optional: ?limit=50)",
+			"GET /workspaces/{wid}/webhooks/{whid}/deliveries/{dlid}":        "Get a delivery with its payload and attempts (POST .../{dlid}/redeliver queues a completed one again)",
+			"GET /workspaces/{wid}/search":                                   "Search the text of todos and the bodies of comments (?q=invoice, optional: ?limit=20), words match as prefixes, best first with <mark>ed snippets",
+			"GET /workspaces/{wid}/todos":                                    "Get the todos of a workspace (optional: ?user_id=1 (created by), ?assignee=2 or 0 for unassigned, ?list_id=2 or 0 for none, ?include_archived=true, ?overdue=true, ?due_before=, ?due_after=, ?priority=high,urgent, ?label=bug,ui&label_match=any|all, ?parent_id=5 or 0 for top-level, ?filter=completed:false AND (assignee:2 OR label:urgent); pages: ?sort=due:desc, ?limit=50, ?cursor= from meta.next_cursor, ?fields=text,completed)",
+			"POST /workspaces/{wid}/todos":                                   "Create a todo in a workspace (optional parent_id makes it a subtask)",
+			"GET /workspaces/{wid}/todos/plan":                               "Get the open todos in stages by dependency, stage 1 can be worked on now (optional: ?user_id=1)",
+			"PUT /workspaces/{wid}/todos/{id}":                               "Update a todo",
+			"DELETE /workspaces/{wid}/todos/{id}":                            "Delete a todo (?on_subtasks=block|promote|cascade)",
+			"PATCH /workspaces/{wid}/todos/{id}/toggle":                      "Toggle todo completed status (optional: ?complete_subtasks=true, ?force=true when blocked)",
+			"PATCH /workspaces/{wid}/todos/{id}/move":                        "Move a todo to another list ({\"list_id\": 0} for none)",
+			"GET /workspaces/{wid}/todos/{id}/occurrences":                   "Preview the next occurrences of a recurring todo (optional: ?count=5)",
+			"DELETE /workspaces/{wid}/todos/{id}/recurrence":                 "Stop the series of a recurring todo",
+			"POST /workspaces/{wid}/todos/{id}/checklist":                    "Add a checklist item ({\"text\", \"done\"})",
+			"PATCH /workspaces/{wid}/todos/{id}/checklist/{cid}":             "Rename or check off a checklist item",
+			"DELETE /workspaces/{wid}/todos/{id}/checklist/{cid}":            "Delete a checklist item",
+			"POST /workspaces/{wid}/todos/{id}/dependencies":                 "Make a todo wait for another todo ({\"blocker_id\": 3}), cycles are rejected",
+			"DELETE /workspaces/{wid}/todos/{id}/dependencies/{bid}":         "Stop a todo from waiting for todo {bid}",
+			"POST /workspaces/{wid}/todos/{id}/assignees":                    "Assign a workspace member to a todo ({\"user_id\": 2})",
+			"DELETE /workspaces/{wid}/todos/{id}/assignees/{uid}":            "Unassign user {uid} from a todo",
+			"GET /workspaces/{wid}/todos/{id}/comments":                      "Get the comments on a todo, oldest first",
+			"POST /workspaces/{wid}/todos/{id}/comments":                     "Comment on a todo ({\"body\", \"reply_to_id\"}), @mentions resolve to workspace members",
+			"PUT /workspaces/{wid}/todos/{id}/comments/{cmid}":               "Edit a comment (author)",
+			"DELETE /workspaces/{wid}/todos/{id}/comments/{cmid}":            "Delete a comment, its replies are kept (author or workspace admin)",
+			"GET /workspaces/{wid}/todos/{id}/attachments":                   "Get the attachments of a todo, oldest first",
+			"POST /workspaces/{wid}/todos/{id}/attachments":                  "Upload a file (multipart/form-data field \"file\"; images, PDF or plain text up to ATTACHMENT_MAX_SIZE)",
+			"POST /workspaces/{wid}/todos/{id}/attachments/uploads":          "Start a resumable upload ({\"file_name\", \"size\"})",
+			"GET /workspaces/{wid}/todos/{id}/attachments/uploads/{upid}":    "Get the progress of a resumable upload (Upload-Offset header)",
+			"PATCH /workspaces/{wid}/todos/{id}/attachments/uploads/{upid}":  "Send the next chunk of a resumable upload (Upload-Offset header), the last one creates the attachment",
+			"DELETE /workspaces/{wid}/todos/{id}/attachments/uploads/{upid}": "Cancel a resumable upload",
+			"GET /workspaces/{wid}/todos/{id}/attachments/{aid}":             "Get an attachment with its download_url",
+			"GET /workspaces/{wid}/todos/{id}/attachments/{aid}/download":    "Download the content of an attachment (supports Range)",
+			"DELETE /workspaces/{wid}/todos/{id}/attachments/{aid}":          "Delete an attachment (uploader or workspace admin)",
+			"GET /lists":                                    "Get the lists of the default workspace (optional: ?include_archived=true)",
+			"POST /lists":                                   "Create a list in the default workspace",
+			"PUT /lists/{lid}":                              "Rename a list",
+			"DELETE /lists/{lid}":                           "Delete a list, its todos are kept without a list (workspace admin)",
+			"POST /lists/{lid}/archive":                     "Archive a list, hiding its todos",
+			"POST /lists/{lid}/unarchive":                   "Unarchive a list",
+			"GET /labels":                                   "Get the labels of the default workspace",
+			"POST /labels":                                  "Create a label in the default workspace",
+			"PUT /labels/{lbid}":                            "Rename or recolor a label",
+			"DELETE /labels/{lbid}":                         "Delete a label, removing it from its todos (workspace admin)",
+			"GET /events":                                   "Stream the todo changes of the default workspace as Server-Sent Events",
+			"GET /ws":                                       "WebSocket channel of the default workspace",
+			"GET /webhooks":                                 "Get the webhooks of the default workspace (workspace admin)",
+			"POST /webhooks":                                "Create a webhook in the default workspace (workspace admin)",
+			"GET /webhooks/{whid}/deliveries":               "Get the latest deliveries of a webhook (optional: ?limit=50)",
+			"GET /search":                                   "Search the todos and comments of the default workspace (?q=invoice, optional: ?limit=20)",
+			"GET /todos":                                    "Get all todos of the default workspace (optional: ?user_id=1 (created by), ?assignee=2 or 0 for unassigned, ?list_id=2 or 0 for none, ?include_archived=true, ?overdue=true, ?due_before=, ?due_after=, ?priority=high,urgent, ?label=bug,ui&label_match=any|all, ?parent_id=5 or 0 for top-level, ?filter=completed:false AND (assignee:2 OR label:urgent); pages: ?sort=due:desc, ?limit=50, ?cursor= from meta.next_cursor, ?fields=text,completed)",
+			"POST /todos":                                   "Create a new todo owned by the authenticated user (optional list_id, parent_id, priority, label_ids)",
+			"GET /todos/plan":                               "Get the open todos in stages by dependency, stage 1 can be worked on now (optional: ?user_id=1)",
+			"DELETE /todos/{id}":                            "Delete a todo (?on_subtasks=block|promote|cascade)",
+			"PUT /todos/{id}":                               "Update a todo",
+			"PATCH /todos/{id}/toggle":                      "Toggle todo completed status (optional: ?complete_subtasks=true, ?force=true when blocked)",
+			"PATCH /todos/{id}/move":                        "Move a todo to another list",
+			"GET /todos/{id}/occurrences":                   "Preview the next occurrences of a recurring todo (optional: ?count=5)",
+			"DELETE /todos/{id}/recurrence":                 "Stop the series of a recurring todo",
+			"POST /todos/{id}/checklist":                    "Add a checklist item",
+			"PATCH /todos/{id}/checklist/{cid}":             "Rename or check off a checklist item",
+			"DELETE /todos/{id}/checklist/{cid}":            "Delete a checklist item",
+			"POST /todos/{id}/dependencies":                 "Make a todo wait for another todo, cycles are rejected",
+			"DELETE /todos/{id}/dependencies/{bid}":         "Stop a todo from waiting for todo {bid}",
+			"POST /todos/{id}/assignees":                    "Assign a workspace member to a todo",
+			"DELETE /todos/{id}/assignees/{uid}":            "Unassign user {uid} from a todo",
+			"GET /todos/{id}/comments":                      "Get the comments on a todo, oldest first",
+			"POST /todos/{id}/comments":                     "Comment on a todo, @mentions resolve to workspace members",
+			"PUT /todos/{id}/comments/{cmid}":               "Edit a comment (author)",
+			"DELETE /todos/{id}/comments/{cmid}":            "Delete a comment, its replies are kept (author or workspace admin)",
+			"GET /todos/{id}/attachments":                   "Get the attachments of a todo, oldest first",
+			"POST /todos/{id}/attachments":                  "Upload a file (multipart/form-data field \"file\")",
+			"POST /todos/{id}/attachments/uploads":          "Start a resumable upload",
+			"GET /todos/{id}/attachments/uploads/{upid}":    "Get the progress of a resumable upload",
+			"PATCH /todos/{id}/attachments/uploads/{upid}":  "Send the next chunk of a resumable upload",
+			"DELETE /todos/{id}/attachments/uploads/{upid}": "Cancel a resumable upload",
+			"GET /todos/{id}/attachments/{aid}":             "Get an attachment with its download_url",
+			"GET /todos/{id}/attachments/{aid}/download":    "Download the content of an attachment",
+			"DELETE /todos/{id}/attachments/{aid}":          "Delete an attachment (uploader or workspace admin)",
+			"GET /health":                                   "Health check",
+			"GET /api":                                      "API documentation",
+			"GET /":                                         "Web interface",
 		},
 	}
 	msg := "Welcome to Collaborative Todo List API"
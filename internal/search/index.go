@@ -0,0 +1,478 @@
+// Package search implements full-text search over the text of todos and the
+// bodies of comments with an in-process inverted index.
+//
+// Text is split into words that are case folded (see tokenize). A query
+// matches the documents holding any of its words, or a word they start: "inv"
+// finds "invoice". Documents are ranked with BM25; documents matching more of
+// the query words rank higher, and whole words count more than prefixes.
+//
+// The index lives in memory, one shard per workspace. Rebuild fills it from a
+// store on startup, and IndexingStore keeps it current on every mutation.
+package search
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// Kind is the kind of document a hit is
+type Kind string
+
+const (
+	KindTodo    Kind = "todo"
+	KindComment Kind = "comment"
+)
+
+// Ranking parameters
+const (
+	bm25K1 = 1.2  // term frequency saturation
+	bm25B  = 0.75 // document length normalization
+
+	prefixWeight  = 0.5  // a word a query word starts, at most, relative to the whole word
+	commentWeight = 0.75 // a comment, relative to the text of a todo
+	maxExpansions = 64   // words a query word can match as a prefix
+)
+
+// Hit is a todo or comment matching a query
+type Hit struct {
+	Kind      Kind    `json:"kind"`
+	TodoID    int     `json:"todo_id"`
+	CommentID int     `json:"comment_id,omitempty"`
+	TodoText  string  `json:"todo_text"`
+	Score     float64 `json:"score"`
+	Snippet   string  `json:"snippet"` // HTML escaped, matching words wrapped in <mark>
+}
+
+// Index is an inverted index of the todos and comments of every workspace.
+// It is safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	shards map[int]*shard
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{shards: make(map[int]*shard)}
+}
+
+// docKey identifies a document of a shard
+type docKey struct {
+	kind Kind
+	id   int
+}
+
+// doc is an indexed todo text or comment body
+type doc struct {
+	key    docKey
+	todoID int
+	text   string
+	terms  []string // distinct
+	length int      // number of words
+}
+
+// posting is a document holding a term, and how often it does
+type posting struct {
+	slot int32
+	freq int32
+}
+
+// shard indexes the documents of one workspace. Documents live in slots so
+// postings and scores are plain slices rather than maps.
+type shard struct {
+	docs     []*doc // by slot, nil when free
+	free     []int32
+	slots    map[docKey]int32
+	postings map[string][]posting
+	terms    []string             // sorted keys of postings, for prefix matching
+	comments map[int]map[int]bool // todo ID -> IDs of its comments
+	count    int                  // documents
+	length   int                  // words in all documents
+}
+
+func newShard() *shard {
+	return &shard{
+		slots:    make(map[docKey]int32),
+		postings: make(map[string][]posting),
+		comments: make(map[int]map[int]bool),
+	}
+}
+
+// Rebuild replaces the contents of the index with every todo and comment of
+// store. Terms are sorted once per workspace rather than on every insert.
+func (x *Index) Rebuild(store repository.Store) (todos, comments int, err error) {
+	workspaces, err := store.GetAllWorkspaces()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	shards := make(map[int]*shard, len(workspaces))
+	for _, workspace := range workspaces {
+		all, err := store.FindAll(workspace.ID, repository.TodoFilter{IncludeArchived: true})
+		if err != nil {
+			return 0, 0, err
+		}
+		found, err := store.GetAllComments(workspace.ID)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		s := newShard()
+		for _, todo := range all {
+			s.add(docKey{KindTodo, todo.ID}, todo.ID, todo.Text, false)
+		}
+		for _, comment := range found {
+			s.addComment(comment, false)
+		}
+		sort.Strings(s.terms)
+		shards[workspace.ID] = s
+		todos += len(all)
+		comments += len(found)
+	}
+
+	x.mu.Lock()
+	x.shards = shards
+	x.mu.Unlock()
+	return todos, comments, nil
+}
+
+// PutTodo indexes a todo, replacing what was indexed for it before
+func (x *Index) PutTodo(todo models.Todo) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.shard(todo.WorkspaceID).add(docKey{KindTodo, todo.ID}, todo.ID, todo.Text, true)
+}
+
+// PutComment indexes a comment, replacing what was indexed for it before. A
+// comment of a todo that is not indexed, because it was deleted in the
+// meantime, is left out.
+func (x *Index) PutComment(comment models.Comment) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	s, ok := x.shards[comment.WorkspaceID]
+	if !ok {
+		return
+	}
+	if _, indexed := s.slots[docKey{KindTodo, comment.TodoID}]; indexed {
+		s.addComment(comment, true)
+	}
+}
+
+// RemoveTodo removes a todo and its comments from the index
+func (x *Index) RemoveTodo(workspaceID, id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if s, ok := x.shards[workspaceID]; ok {
+		s.removeTodo(id)
+	}
+}
+
+// RemoveComment removes a comment from the index
+func (x *Index) RemoveComment(workspaceID, id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if s, ok := x.shards[workspaceID]; ok {
+		s.remove(docKey{KindComment, id})
+	}
+}
+
+// RemoveWorkspace removes every document of a workspace from the index
+func (x *Index) RemoveWorkspace(workspaceID int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.shards, workspaceID)
+}
+
+// Search returns the best limit hits for query among the documents of a
+// workspace, best first
+func (x *Index) Search(workspaceID int, query string, limit int) []Hit {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	s, ok := x.shards[workspaceID]
+	words := queryTerms(query)
+	if !ok || len(words) == 0 || s.count == 0 {
+		return []Hit{}
+	}
+
+	// Each query word scores a document by the best word of it that it
+	// matches; documents matching more query words rank higher
+	expansions := make([][]string, len(words))
+	found := false
+	for i, word := range words {
+		expansions[i] = s.expand(word)
+		found = found || len(expansions[i]) > 0
+	}
+	if !found {
+		return []Hit{}
+	}
+
+	avgLength := float64(s.length) / float64(s.count)
+	scores := make([]float64, len(s.docs))
+	matched := make([]int32, len(s.docs))
+	best := make([]float64, len(s.docs))
+	var touched, candidates []int32
+	var expanded []string
+	for i, word := range words {
+		touched = touched[:0]
+		for _, term := range expansions[i] {
+			expanded = append(expanded, term)
+			weight := 1.0
+			if term != word {
+				weight = prefixWeight * float64(len(word)) / float64(len(term))
+			}
+			postings := s.postings[term]
+			n := float64(len(postings))
+			idf := math.Log(1 + (float64(s.count)-n+0.5)/(n+0.5))
+			for _, p := range postings {
+				tf := float64(p.freq)
+				length := float64(s.docs[p.slot].length)
+				score := weight * idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+				if best[p.slot] == 0 {
+					touched = append(touched, p.slot)
+				}
+				if score > best[p.slot] {
+					best[p.slot] = score
+				}
+			}
+		}
+		for _, slot := range touched {
+			if matched[slot] == 0 {
+				candidates = append(candidates, slot)
+			}
+			scores[slot] += best[slot]
+			matched[slot]++
+			best[slot] = 0
+		}
+	}
+
+	top := topHits{limit: limit}
+	for _, slot := range candidates {
+		d := s.docs[slot]
+		score := scores[slot] * float64(matched[slot]) / float64(len(words))
+		if d.key.kind == KindComment {
+			score *= commentWeight
+		}
+		hit := Hit{Kind: d.key.kind, TodoID: d.todoID, Score: math.Round(score*1000) / 1000}
+		if d.key.kind == KindComment {
+			hit.CommentID = d.key.id
+		}
+		top.add(hit)
+	}
+	hits := top.sorted()
+
+	sort.Strings(expanded)
+	matches := func(term string) bool {
+		i := sort.SearchStrings(expanded, term)
+		return i < len(expanded) && expanded[i] == term
+	}
+	for i := range hits {
+		key := docKey{hits[i].Kind, hits[i].TodoID}
+		if hits[i].Kind == KindComment {
+			key.id = hits[i].CommentID
+		}
+		hits[i].Snippet = snippet(s.docs[s.slots[key]].text, matches)
+		if slot, ok := s.slots[docKey{KindTodo, hits[i].TodoID}]; ok {
+			hits[i].TodoText = s.docs[slot].text
+		}
+	}
+	return hits
+}
+
+// ranksBefore reports whether hit a ranks before hit b
+func ranksBefore(a, b Hit) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	// Newer todos first, a todo before its comments, older comments first
+	if a.TodoID != b.TodoID {
+		return a.TodoID > b.TodoID
+	}
+	return a.CommentID < b.CommentID
+}
+
+// topHits keeps the best limit hits added to it, all of them without a limit
+type topHits struct {
+	limit int
+	hits  []Hit // a heap with the worst hit first once limit is reached
+}
+
+func (t *topHits) Len() int           { return len(t.hits) }
+func (t *topHits) Less(i, j int) bool { return ranksBefore(t.hits[j], t.hits[i]) }
+func (t *topHits) Swap(i, j int)      { t.hits[i], t.hits[j] = t.hits[j], t.hits[i] }
+func (t *topHits) Push(x any)         { t.hits = append(t.hits, x.(Hit)) }
+func (t *topHits) Pop() any {
+	hit := t.hits[len(t.hits)-1]
+	t.hits = t.hits[:len(t.hits)-1]
+	return hit
+}
+
+func (t *topHits) add(hit Hit) {
+	switch {
+	case t.limit <= 0:
+		t.hits = append(t.hits, hit)
+	case len(t.hits) < t.limit:
+		heap.Push(t, hit)
+	case ranksBefore(hit, t.hits[0]):
+		t.hits[0] = hit
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the hits kept, best first
+func (t *topHits) sorted() []Hit {
+	hits := t.hits
+	if hits == nil {
+		hits = []Hit{}
+	}
+	sort.Slice(hits, func(i, j int) bool { return ranksBefore(hits[i], hits[j]) })
+	return hits
+}
+
+// queryTerms returns the distinct terms of a query
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range tokenize(query) {
+		if len(t.term) <= maxTermLength && !seen[t.term] {
+			seen[t.term] = true
+			terms = append(terms, t.term)
+		}
+	}
+	return terms
+}
+
+// shard returns the shard of a workspace, creating it (lock must be held)
+func (x *Index) shard(workspaceID int) *shard {
+	s, ok := x.shards[workspaceID]
+	if !ok {
+		s = newShard()
+		x.shards[workspaceID] = s
+	}
+	return s
+}
+
+// expand returns the terms of the shard a query word matches: the word
+// itself, and words it starts unless it is a single character
+func (s *shard) expand(word string) []string {
+	var terms []string
+	if _, ok := s.postings[word]; ok {
+		terms = append(terms, word)
+	}
+	if len([]rune(word)) < 2 {
+		return terms
+	}
+	for i := sort.SearchStrings(s.terms, word); i < len(s.terms) && len(terms) < maxExpansions; i++ {
+		if !strings.HasPrefix(s.terms[i], word) {
+			break
+		}
+		if s.terms[i] != word {
+			terms = append(terms, s.terms[i])
+		}
+	}
+	return terms
+}
+
+func (s *shard) addComment(comment models.Comment, sorted bool) {
+	s.add(docKey{KindComment, comment.ID}, comment.TodoID, comment.Body, sorted)
+	if s.comments[comment.TodoID] == nil {
+		s.comments[comment.TodoID] = make(map[int]bool)
+	}
+	s.comments[comment.TodoID][comment.ID] = true
+}
+
+// add indexes a document, replacing the one with the same key. With sorted
+// new terms are inserted in order, otherwise appended for the caller to sort.
+func (s *shard) add(key docKey, todoID int, text string, sorted bool) {
+	s.remove(key)
+
+	// Count the words by sorting them, a map per document costs more
+	tokens := tokenize(text)
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if len(t.term) <= maxTermLength {
+			words = append(words, t.term)
+		}
+	}
+	sort.Strings(words)
+
+	var slot int32
+	if n := len(s.free); n > 0 {
+		slot, s.free = s.free[n-1], s.free[:n-1]
+	} else {
+		slot = int32(len(s.docs))
+		s.docs = append(s.docs, nil)
+	}
+	d := &doc{key: key, todoID: todoID, text: text, length: len(words)}
+	for i := 0; i < len(words); {
+		j := i + 1
+		for j < len(words) && words[j] == words[i] {
+			j++
+		}
+		term := words[i]
+		d.terms = append(d.terms, term)
+		postings, ok := s.postings[term]
+		if !ok && sorted {
+			k := sort.SearchStrings(s.terms, term)
+			s.terms = append(s.terms, "")
+			copy(s.terms[k+1:], s.terms[k:])
+			s.terms[k] = term
+		} else if !ok {
+			s.terms = append(s.terms, term)
+		}
+		s.postings[term] = append(postings, posting{slot: slot, freq: int32(j - i)})
+		i = j
+	}
+	s.docs[slot] = d
+	s.slots[key] = slot
+	s.count++
+	s.length += d.length
+}
+
+// remove removes a document, and the terms no other document has
+func (s *shard) remove(key docKey) {
+	slot, ok := s.slots[key]
+	if !ok {
+		return
+	}
+	d := s.docs[slot]
+	for _, term := range d.terms {
+		postings := s.postings[term]
+		for i, p := range postings {
+			if p.slot == slot {
+				postings[i] = postings[len(postings)-1]
+				postings = postings[:len(postings)-1]
+				break
+			}
+		}
+		if len(postings) > 0 {
+			s.postings[term] = postings
+			continue
+		}
+		delete(s.postings, term)
+		i := sort.SearchStrings(s.terms, term)
+		s.terms = append(s.terms[:i], s.terms[i+1:]...)
+	}
+	s.docs[slot] = nil
+	s.free = append(s.free, slot)
+	delete(s.slots, key)
+	s.count--
+	s.length -= d.length
+	if key.kind == KindComment {
+		delete(s.comments[d.todoID], key.id)
+	}
+}
+
+// removeTodo removes a todo and its comments
+func (s *shard) removeTodo(id int) {
+	for commentID := range s.comments[id] {
+		s.remove(docKey{KindComment, commentID})
+	}
+	delete(s.comments, id)
+	s.remove(docKey{KindTodo, id})
+}
@@ -0,0 +1,130 @@
+package search
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+var (
+	benchTodos        = flag.Int("todos", 100000, "todos in the store of the benchmarks")
+	benchCommentEvery = flag.Int("comment-every", 4, "one todo in this many has comments in the benchmarks")
+)
+
+// words the synthetic todos and comments are made of
+var words = strings.Fields(`invoice review deploy release budget meeting client report
+	design draft backend frontend database migration schedule quarterly payroll
+	contract renewal onboarding interview feedback roadmap sprint planning
+	bug crash login checkout payment refund shipping warehouse inventory audit
+	security patch upgrade server cluster backup restore monitoring alert
+	marketing campaign newsletter webinar launch pricing discount partner
+	translation documentation tutorial workshop training hiring offer`)
+
+// sentence returns n random words, the first one capitalized to exercise
+// case folding
+func sentence(rng *rand.Rand, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[rng.Intn(len(words))]
+	}
+	parts[0] = strings.ToUpper(parts[0][:1]) + parts[0][1:]
+	return strings.Join(parts, " ")
+}
+
+var (
+	benchOnce  sync.Once
+	benchStore repository.Store
+	benchErr   error
+)
+
+// benchmarkStore returns the store the benchmarks share, filled on first use
+// with -todos todos and comments on every -comment-every-th of them
+func benchmarkStore(b *testing.B) repository.Store {
+	benchOnce.Do(func() {
+		rng := rand.New(rand.NewSource(1))
+		store, err := repository.NewTodoRepository(nil)
+		if err != nil {
+			benchErr = err
+			return
+		}
+		for i := 0; i < *benchTodos; i++ {
+			todo, err := store.Create(&models.Todo{
+				WorkspaceID: repository.DefaultWorkspaceID,
+				Text:        fmt.Sprintf("%s #%d", sentence(rng, 4+rng.Intn(8)), i),
+				UserID:      1,
+			})
+			if err != nil {
+				benchErr = err
+				return
+			}
+			if i%*benchCommentEvery != 0 {
+				continue
+			}
+			for j := 0; j < 1+rng.Intn(3); j++ {
+				_, err := store.CreateComment(&models.Comment{
+					WorkspaceID: repository.DefaultWorkspaceID,
+					TodoID:      todo.ID,
+					AuthorID:    1,
+					Body:        sentence(rng, 8+rng.Intn(16)),
+				})
+				if err != nil {
+					benchErr = err
+					return
+				}
+			}
+		}
+		benchStore = store
+	})
+	if benchErr != nil {
+		b.Fatal(benchErr)
+	}
+	return benchStore
+}
+
+// benchmarkIndex returns an index of the benchmark store
+func benchmarkIndex(b *testing.B) *Index {
+	index := NewIndex()
+	if _, _, err := index.Rebuild(benchmarkStore(b)); err != nil {
+		b.Fatal(err)
+	}
+	return index
+}
+
+func BenchmarkRebuild(b *testing.B) {
+	store := benchmarkStore(b)
+	index := NewIndex()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := index.Rebuild(store); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	index := benchmarkIndex(b)
+	for _, query := range []string{"invoice", "inv", "security patch", "quarterly payroll audit", "nothingmatches"} {
+		b.Run(query, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				index.Search(repository.DefaultWorkspaceID, query, 20)
+			}
+		})
+	}
+}
+
+func BenchmarkPutTodo(b *testing.B) {
+	index := benchmarkIndex(b)
+	rng := rand.New(rand.NewSource(2))
+	todo := models.Todo{ID: 1, WorkspaceID: repository.DefaultWorkspaceID}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		todo.Text = sentence(rng, 8)
+		index.PutTodo(todo)
+	}
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"sync"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// IndexingStore is a repository.Store that keeps an Index current with every
+// todo and comment a mutation creates, changes or deletes, including todos
+// deleted along with a parent, user or workspace. Todos are indexed from the
+// changes the backend reports as it commits, so the index sees them in commit
+// order; reads go straight to the backend.
+type IndexingStore struct {
+	repository.Store
+	index *Index
+	// comments orders comment writes with their indexing, so an edit cannot
+	// put back a comment deleted in the meantime
+	comments sync.Mutex
+}
+
+// NewIndexingStore wraps store so its todos and comments are kept in index
+func NewIndexingStore(store repository.Store, index *Index) *IndexingStore {
+	s := &IndexingStore{Store: store, index: index}
+	store.ObserveTodos(s.indexTodos)
+	return s
+}
+
+// indexTodos indexes created todos and changed texts, and removes deleted
+// todos with their comments
+func (s *IndexingStore) indexTodos(changes []repository.TodoChange) {
+	for _, change := range changes {
+		switch {
+		case change.After == nil:
+			s.index.RemoveTodo(change.Before.WorkspaceID, change.Before.ID)
+		case change.Before == nil || change.Before.Text != change.After.Text:
+			s.index.PutTodo(*change.After)
+		}
+	}
+}
+
+// DeleteWorkspace drops the shard of the workspace along with its todos
+func (s *IndexingStore) DeleteWorkspace(id int) error {
+	if err := s.Store.DeleteWorkspace(id); err != nil {
+		return err
+	}
+	s.index.RemoveWorkspace(id)
+	return nil
+}
+
+// CreateComment stores a comment and indexes it
+func (s *IndexingStore) CreateComment(comment *models.Comment) (*models.Comment, error) {
+	s.comments.Lock()
+	defer s.comments.Unlock()
+
+	created, err := s.Store.CreateComment(comment)
+	if err != nil {
+		return nil, err
+	}
+	s.index.PutComment(*created)
+	return created, nil
+}
+
+// UpdateComment stores a comment and indexes its body again
+func (s *IndexingStore) UpdateComment(comment *models.Comment) (*models.Comment, error) {
+	s.comments.Lock()
+	defer s.comments.Unlock()
+
+	updated, err := s.Store.UpdateComment(comment)
+	if err != nil {
+		return nil, err
+	}
+	s.index.PutComment(*updated)
+	return updated, nil
+}
+
+// DeleteComment removes a comment
+func (s *IndexingStore) DeleteComment(workspaceID, id int) error {
+	s.comments.Lock()
+	defer s.comments.Unlock()
+
+	if err := s.Store.DeleteComment(workspaceID, id); err != nil {
+		return err
+	}
+	s.index.RemoveComment(workspaceID, id)
+	return nil
+}
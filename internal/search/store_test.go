@@ -0,0 +1,111 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+const ws = repository.DefaultWorkspaceID
+
+// found returns the kinds and IDs of the hits for query, sorted and joined
+// as in "comment 2, todo 1"
+func found(index *Index, query string) string {
+	hits := index.Search(ws, query, 20)
+	parts := make([]string, len(hits))
+	for i, hit := range hits {
+		id := hit.TodoID
+		if hit.Kind == KindComment {
+			id = hit.CommentID
+		}
+		parts[i] = fmt.Sprintf("%s %d", hit.Kind, id)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// TestIndexingStore checks the index follows the todos and comments of the
+// store, however they change
+func TestIndexingStore(t *testing.T) {
+	backend, err := repository.NewTodoRepository(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := NewIndex()
+	store := NewIndexingStore(backend, index)
+
+	owner, err := store.CreateUser(&models.User{Name: "Owner", Email: "owner@example.com", Role: "member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(text string, userID, parentID int) *models.Todo {
+		t.Helper()
+		todo, err := store.Create(&models.Todo{WorkspaceID: ws, Text: text, UserID: userID, ParentID: parentID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+	comment := func(todoID int, body string) *models.Comment {
+		t.Helper()
+		created, err := store.CreateComment(&models.Comment{WorkspaceID: ws, TodoID: todoID, AuthorID: 1, Body: body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	expect := func(query, want string) {
+		t.Helper()
+		if got := found(index, query); got != want {
+			t.Errorf("search %q = [%s], want [%s]", query, got, want)
+		}
+	}
+
+	root := create("quarterly invoice", 1, 0)
+	sub := create("invoice draft", 1, root.ID)
+	owned := create("payroll of the owner", owner.ID, 0)
+	note := comment(sub.ID, "payroll numbers")
+	expect("payroll", fmt.Sprintf("comment %d, todo %d", note.ID, owned.ID))
+
+	// A changed text is indexed again, other changes leave it
+	sub.Text = "budget draft"
+	if _, err := store.Update(sub); err != nil {
+		t.Fatal(err)
+	}
+	expect("invoice", fmt.Sprintf("todo %d", root.ID))
+	expect("budget", fmt.Sprintf("todo %d", sub.ID))
+
+	// Deleting a tree removes the subtasks and their comments too
+	if err := store.DeleteTree(ws, root.ID); err != nil {
+		t.Fatal(err)
+	}
+	expect("draft", "")
+	expect("payroll", fmt.Sprintf("todo %d", owned.ID))
+
+	// So does deleting a user with their todos
+	if err := store.DeleteUser(owner.ID, repository.TodoDisposition{Cascade: true}); err != nil {
+		t.Fatal(err)
+	}
+	expect("payroll", "")
+
+	// A comment whose todo is gone by the time it is indexed stays out
+	index.PutComment(models.Comment{ID: 99, WorkspaceID: ws, TodoID: root.ID, Body: "orphaned payroll"})
+	expect("payroll", "")
+
+	kept := create("kept", 1, 0)
+	edited := comment(kept.ID, "first words")
+	edited.Body = "second words"
+	if _, err := store.UpdateComment(edited); err != nil {
+		t.Fatal(err)
+	}
+	expect("first", "")
+	expect("second", fmt.Sprintf("comment %d", edited.ID))
+	if err := store.DeleteComment(ws, edited.ID); err != nil {
+		t.Fatal(err)
+	}
+	expect("second", "")
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTermLength leaves longer words (hashes, URLs without separators, ...) out of the index
+const maxTermLength = 64
+
+// Snippet window around the first match, in bytes
+const (
+	snippetBefore = 60
+	snippetLength = 200
+)
+
+// token is a word of a text with its byte offsets
+type token struct {
+	term       string // the word case folded
+	start, end int
+}
+
+// tokenize splits text into words: runs of letters, digits and combining
+// marks. Terms are case folded, so "Invoice" and "INVOICE" are the same term.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{term: fold(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: fold(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// fold case folds a word
+func fold(word string) string {
+	return strings.ToLower(word)
+}
+
+// snippet returns the part of text around its first word that matches, HTML
+// escaped, with every matching word in it wrapped in <mark>. Cut ends are
+// marked with an ellipsis.
+func snippet(text string, matches func(term string) bool) string {
+	tokens := tokenize(text)
+	first := -1
+	for i, t := range tokens {
+		if matches(t.term) {
+			first = i
+			break
+		}
+	}
+
+	// Start a few words before the first match, end on a whole word
+	start, end := 0, len(text)
+	if first >= 0 && tokens[first].start > snippetBefore {
+		for k := first; k >= 0 && tokens[first].start-tokens[k].start <= snippetBefore; k-- {
+			start = tokens[k].start
+		}
+	}
+	if end-start > snippetLength {
+		end = start + snippetLength
+		for _, t := range tokens {
+			if t.start < end && t.end > end {
+				end = t.start
+				break
+			}
+		}
+		for end > start && !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, t := range tokens {
+		if t.start < start || t.end > end || !matches(t.term) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
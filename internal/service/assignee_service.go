@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidAssigneeID = errors.New("user_id is required")
+	ErrInvalidAssignee   = errors.New("assignee must be an active member of this workspace")
+	ErrNotAssigned       = errors.New("user is not assigned to this todo")
+)
+
+// AssignTodo adds a user to the assignees of a todo. Assigning a user who is
+// already an assignee changes nothing.
+func (s *TodoService) AssignTodo(user *models.User, workspaceID, id int, req dto.AssigneeRequest) (*models.Todo, error) {
+	if req.UserID == nil || *req.UserID <= 0 {
+		return nil, ErrInvalidAssigneeID
+	}
+
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionAssignTodo, todo); err != nil {
+		return nil, err
+	}
+
+	assignee, err := s.users.GetUserByID(*req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAssignee(workspaceID, assignee); err != nil {
+		return nil, err
+	}
+	if indexOfID(todo.AssigneeIDs, assignee.ID) >= 0 {
+		return s.withTodoComputed(todo, nil)
+	}
+
+	// AssigneeIDs may be shared with the store, so it is replaced, never modified in place
+	assigneeIDs := make([]int, 0, len(todo.AssigneeIDs)+1)
+	todo.AssigneeIDs = append(append(assigneeIDs, todo.AssigneeIDs...), assignee.ID)
+	todo.UpdatedAt = time.Now()
+	updated, err := s.todos.Update(todo)
+	if err != nil {
+		return nil, err
+	}
+	s.notify.assigned(user, updated, assignee.ID)
+	return s.withTodoComputed(updated, nil)
+}
+
+// UnassignTodo removes a user from the assignees of a todo
+func (s *TodoService) UnassignTodo(user *models.User, workspaceID, id, userID int) (*models.Todo, error) {
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionAssignTodo, todo); err != nil {
+		return nil, err
+	}
+
+	i := indexOfID(todo.AssigneeIDs, userID)
+	if i < 0 {
+		return nil, ErrNotAssigned
+	}
+	assigneeIDs := make([]int, 0, len(todo.AssigneeIDs)-1)
+	todo.AssigneeIDs = append(append(assigneeIDs, todo.AssigneeIDs[:i]...), todo.AssigneeIDs[i+1:]...)
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// checkAssignee returns ErrInvalidAssignee unless assignee is active and
+// belongs to the workspace; global admins belong to every workspace
+func (s *TodoService) checkAssignee(workspaceID int, assignee *models.User) error {
+	if assignee.Deactivated {
+		return ErrInvalidAssignee
+	}
+	if _, err := s.workspaces.GetMembership(workspaceID, assignee.ID); err != nil {
+		if !errors.Is(err, repository.ErrMembershipNotFound) {
+			return err
+		}
+		if policy.NewActor(assignee).Role != policy.RoleAdmin {
+			return ErrInvalidAssignee
+		}
+	}
+	return nil
+}
@@ -0,0 +1,490 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"test_mekari/internal/blob"
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed, upload an image, PDF or plain text file")
+	ErrEmptyFile          = errors.New("file cannot be empty")
+	ErrFileTooLarge       = errors.New("file is larger than the attachment size limit")
+	ErrUploadNotFound     = errors.New("upload not found")
+	ErrUploadOffset       = errors.New("upload offset does not match the bytes received so far")
+	ErrUploadBusy         = errors.New("another chunk of this upload is being received")
+	ErrUploadFailed       = errors.New("the received file could not be stored and the upload was discarded, start a new upload")
+)
+
+// allowedContentTypes are the sniffed types attachments may have
+var allowedContentTypes = map[string]bool{
+	"image/png":                 true,
+	"image/jpeg":                true,
+	"image/gif":                 true,
+	"image/webp":                true,
+	"application/pdf":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+const (
+	// sniffLen is how much content http.DetectContentType looks at
+	sniffLen = 512
+	// maxFileNameLen caps stored file names, in bytes
+	maxFileNameLen = 255
+	// uploadTTL is how long a resumable upload may stay unfinished
+	uploadTTL = 24 * time.Hour
+	// blobGracePeriod protects freshly written blobs from the sweep until their attachment is stored
+	blobGracePeriod = time.Hour
+)
+
+// upload is a resumable upload in progress; mu is held while a chunk is appended
+type upload struct {
+	models.Upload
+	mu sync.Mutex
+}
+
+// AttachmentService handles business logic for the files attached to todos
+type AttachmentService struct {
+	attachments repository.AttachmentStore
+	todos       repository.TodoStore
+	workspaces  repository.WorkspaceStore
+	blobs       blob.Store
+	staging     *blob.Staging
+	maxSize     int64
+
+	mu      sync.Mutex
+	uploads map[string]*upload
+}
+
+// NewAttachmentService creates a new instance of AttachmentService. Attachments
+// larger than maxSize bytes are refused; unfinished resumable uploads are kept in staging.
+func NewAttachmentService(store repository.Store, blobs blob.Store, staging *blob.Staging, maxSize int64) *AttachmentService {
+	return &AttachmentService{
+		attachments: store,
+		todos:       store,
+		workspaces:  store,
+		blobs:       blobs,
+		staging:     staging,
+		maxSize:     maxSize,
+		uploads:     make(map[string]*upload),
+	}
+}
+
+// MaxSize returns the largest attachment accepted, in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// GetAttachments returns the attachments of a todo, oldest first
+func (s *AttachmentService) GetAttachments(user *models.User, workspaceID, todoID int) ([]models.Attachment, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachments.GetAttachments(workspaceID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		withDownloadURL(&attachments[i])
+	}
+	return attachments, nil
+}
+
+// GetAttachment returns the metadata of one attachment of a todo
+func (s *AttachmentService) GetAttachment(user *models.User, workspaceID, todoID, id int) (*models.Attachment, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	return s.getAttachment(workspaceID, todoID, id)
+}
+
+// OpenAttachment returns an attachment together with its content; the caller closes the content
+func (s *AttachmentService) OpenAttachment(user *models.User, workspaceID, todoID, id int) (*models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := s.GetAttachment(user, workspaceID, todoID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.blobs.Open(attachment.BlobKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, fmt.Errorf("content of attachment %d is missing: %w", attachment.ID, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// Upload stores content as a new attachment of a todo in one go
+func (s *AttachmentService) Upload(user *models.User, workspaceID, todoID int, fileName string, content io.Reader) (*models.Attachment, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateAttachment, nil); err != nil {
+		return nil, err
+	}
+	// Check the todo before receiving a possibly large file for nothing
+	if _, err := s.todos.FindByID(workspaceID, todoID); err != nil {
+		return nil, err
+	}
+	return s.store(user, workspaceID, todoID, fileName, content)
+}
+
+// DeleteAttachment removes an attachment; the uploader or an admin may
+func (s *AttachmentService) DeleteAttachment(user *models.User, workspaceID, todoID, id int) error {
+	attachment, err := s.getAttachment(workspaceID, todoID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionDeleteAttachment, attachment); err != nil {
+		return err
+	}
+	// The content stays until the sweep finds no attachment using it anymore
+	return s.attachments.DeleteAttachment(workspaceID, id)
+}
+
+// StartUpload begins a resumable upload of a file to a todo. The content is
+// then sent in chunks with AppendUpload.
+func (s *AttachmentService) StartUpload(user *models.User, workspaceID, todoID int, req dto.UploadRequest) (*models.Upload, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateAttachment, nil); err != nil {
+		return nil, err
+	}
+	if req.Size <= 0 {
+		return nil, ErrEmptyFile
+	}
+	if req.Size > s.maxSize {
+		return nil, ErrFileTooLarge
+	}
+	if _, err := s.todos.FindByID(workspaceID, todoID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	u := &upload{Upload: models.Upload{
+		WorkspaceID: workspaceID,
+		TodoID:      todoID,
+		UserID:      user.ID,
+		FileName:    sanitizeFileName(req.FileName),
+		Size:        req.Size,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(uploadTTL),
+	}}
+	// The upload is staged with its description, so it survives a restart
+	meta, err := json.Marshal(u.Upload)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.staging.Create(meta)
+	if err != nil {
+		return nil, err
+	}
+	u.ID = id
+
+	s.mu.Lock()
+	s.uploads[id] = u
+	s.mu.Unlock()
+
+	started := u.Upload
+	return &started, nil
+}
+
+// RestoreUploads picks up the resumable uploads staged before a restart and
+// returns how many there were. What reached the disk says how far each got;
+// expired ones are left to the sweep.
+func (s *AttachmentService) RestoreUploads() (int, error) {
+	staged, err := s.staging.Uploads()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range staged {
+		u := &upload{}
+		if err := json.Unmarshal(st.Meta, &u.Upload); err != nil {
+			log.Printf("⚠️  Attachments: discarding upload %s: %v", st.ID, err)
+			s.staging.Remove(st.ID)
+			continue
+		}
+		u.ID = st.ID
+		u.Offset = st.Size
+		s.uploads[st.ID] = u
+	}
+	return len(s.uploads), nil
+}
+
+// GetUpload returns the progress of a resumable upload, so an interrupted client knows where to resume
+func (s *AttachmentService) GetUpload(user *models.User, workspaceID, todoID int, id string) (*models.Upload, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateAttachment, nil); err != nil {
+		return nil, err
+	}
+	u, err := s.getUpload(user, workspaceID, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	progress := u.Upload
+	return &progress, nil
+}
+
+// AppendUpload adds a chunk starting at offset to a resumable upload. Once
+// the last byte arrived the upload becomes an attachment, which is returned;
+// until then the attachment is nil. A complete upload is discarded even when
+// it cannot become an attachment, as nothing could be appended to it anymore:
+// the error then says why the file was refused, or is ErrUploadFailed.
+func (s *AttachmentService) AppendUpload(user *models.User, workspaceID, todoID int, id string, offset int64, chunk io.Reader) (*models.Upload, *models.Attachment, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateAttachment, nil); err != nil {
+		return nil, nil, err
+	}
+	u, err := s.getUpload(user, workspaceID, todoID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !u.mu.TryLock() {
+		return nil, nil, ErrUploadBusy
+	}
+	defer u.mu.Unlock()
+
+	received, err := s.staging.Append(id, offset, chunk, u.Size)
+	switch {
+	case errors.Is(err, blob.ErrOffsetMismatch):
+		return nil, nil, ErrUploadOffset
+	case errors.Is(err, blob.ErrTooLarge):
+		return nil, nil, ErrFileTooLarge
+	case errors.Is(err, blob.ErrNotFound):
+		return nil, nil, ErrUploadNotFound
+	case err != nil:
+		return nil, nil, err
+	}
+	u.Offset = received
+	progress := u.Upload
+	if received < u.Size {
+		return &progress, nil, nil
+	}
+
+	attachment, err := s.storeUpload(user, workspaceID, todoID, u)
+	s.discardUpload(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &progress, attachment, nil
+}
+
+// storeUpload stores the staged content of a complete upload as an attachment.
+// Errors the client cannot act on are logged and reported as ErrUploadFailed.
+func (s *AttachmentService) storeUpload(user *models.User, workspaceID, todoID int, u *upload) (*models.Attachment, error) {
+	content, err := s.staging.Open(u.ID)
+	if err == nil {
+		defer content.Close()
+		var attachment *models.Attachment
+		if attachment, err = s.store(user, workspaceID, todoID, u.FileName, content); err == nil {
+			return attachment, nil
+		}
+	}
+
+	switch err {
+	case ErrFileTypeNotAllowed, ErrEmptyFile, ErrFileTooLarge, repository.ErrTodoNotFound:
+		return nil, err
+	}
+	log.Printf("⚠️  Attachments: failed to store upload %s: %v", u.ID, err)
+	return nil, ErrUploadFailed
+}
+
+// CancelUpload abandons a resumable upload and discards what was received
+func (s *AttachmentService) CancelUpload(user *models.User, workspaceID, todoID int, id string) error {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateAttachment, nil); err != nil {
+		return err
+	}
+	u, err := s.getUpload(user, workspaceID, todoID, id)
+	if err != nil {
+		return err
+	}
+	if !u.mu.TryLock() {
+		return ErrUploadBusy
+	}
+	defer u.mu.Unlock()
+
+	s.discardUpload(id)
+	return nil
+}
+
+// Sweep discards resumable uploads that expired before now and deletes blobs
+// no attachment refers to anymore. Blobs written in the last hour are kept,
+// their attachment may still be on its way into the store.
+func (s *AttachmentService) Sweep(now time.Time) (uploads, blobs int, err error) {
+	s.mu.Lock()
+	expired := make([]string, 0)
+	for id, u := range s.uploads {
+		// Uploads receiving a chunk right now are left for the next sweep
+		if now.After(u.ExpiresAt) && u.mu.TryLock() {
+			expired = append(expired, id)
+			u.mu.Unlock()
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range expired {
+		s.discardUpload(id)
+	}
+
+	inUse, err := s.attachments.BlobKeys()
+	if err != nil {
+		return len(expired), 0, err
+	}
+	removed, err := s.blobs.Sweep(func(key string) bool { return inUse[key] }, now.Add(-blobGracePeriod))
+	return len(expired), removed, err
+}
+
+// RunSweeper calls Sweep every interval until ctx is done
+func (s *AttachmentService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		uploads, blobs, err := s.Sweep(time.Now())
+		if err != nil {
+			log.Printf("⚠️  Attachments: %v", err)
+		}
+		if uploads > 0 || blobs > 0 {
+			log.Printf("🧹 Attachments: discarded %d expired uploads and %d unused files", uploads, blobs)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// store checks the type of content, writes it to blob storage and records the attachment
+func (s *AttachmentService) store(user *models.User, workspaceID, todoID int, fileName string, content io.Reader) (*models.Attachment, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrEmptyFile
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowedContentTypes[contentType] {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	stored, err := s.blobs.Put(io.MultiReader(bytes.NewReader(head), content), s.maxSize)
+	if errors.Is(err, blob.ErrTooLarge) {
+		return nil, ErrFileTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A blob left behind by a failed insert is removed by the sweep
+	attachment, err := s.attachments.CreateAttachment(&models.Attachment{
+		WorkspaceID: workspaceID,
+		TodoID:      todoID,
+		UploaderID:  user.ID,
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        stored.Size,
+		BlobKey:     stored.Key,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	withDownloadURL(attachment)
+	return attachment, nil
+}
+
+// getAttachment returns attachment id of a todo; attachments of other todos are not found
+func (s *AttachmentService) getAttachment(workspaceID, todoID, id int) (*models.Attachment, error) {
+	if _, err := s.todos.FindByID(workspaceID, todoID); err != nil {
+		return nil, err
+	}
+	attachment, err := s.attachments.GetAttachment(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.TodoID != todoID {
+		return nil, repository.ErrAttachmentNotFound
+	}
+	withDownloadURL(attachment)
+	return attachment, nil
+}
+
+// getUpload returns a resumable upload to a todo; uploads are private to the user who started them
+func (s *AttachmentService) getUpload(user *models.User, workspaceID, todoID int, id string) (*upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, exists := s.uploads[id]
+	if !exists || u.UserID != user.ID || u.WorkspaceID != workspaceID || u.TodoID != todoID {
+		return nil, ErrUploadNotFound
+	}
+	return u, nil
+}
+
+// discardUpload forgets a resumable upload and deletes its staged content
+func (s *AttachmentService) discardUpload(id string) {
+	s.mu.Lock()
+	delete(s.uploads, id)
+	s.mu.Unlock()
+
+	if err := s.staging.Remove(id); err != nil && !errors.Is(err, blob.ErrNotFound) {
+		log.Printf("⚠️  Attachments: failed to discard upload %s: %v", id, err)
+	}
+}
+
+// authorize checks action against the role user holds in the workspace
+func (s *AttachmentService) authorize(user *models.User, workspaceID int, action policy.Action, attachment *models.Attachment) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	if attachment == nil {
+		return can(actor, action, nil)
+	}
+	return can(actor, action, attachment)
+}
+
+// withDownloadURL sets the URL the content of an attachment is served from
+func withDownloadURL(attachment *models.Attachment) {
+	attachment.DownloadURL = fmt.Sprintf("/workspaces/%d/todos/%d/attachments/%d/download", attachment.WorkspaceID, attachment.TodoID, attachment.ID)
+}
+
+// sanitizeFileName keeps only the base name of a client supplied file name,
+// without control characters and at most maxFileNameLen bytes long
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	for len(name) > maxFileNameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
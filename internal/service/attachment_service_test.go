@@ -0,0 +1,224 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"test_mekari/internal/blob"
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+const ws = repository.DefaultWorkspaceID
+
+// failingBlobs is a blob store whose Puts fail
+type failingBlobs struct {
+	blob.Store
+}
+
+func (failingBlobs) Put(io.Reader, int64) (blob.Blob, error) {
+	return blob.Blob{}, errors.New("disk full")
+}
+
+// attachmentFixture is an attachment service over an in-memory store, with
+// a todo to attach files to
+type attachmentFixture struct {
+	service *AttachmentService
+	store   *repository.TodoRepository
+	blobs   *blob.LocalStore
+	staging *blob.Staging
+	user    *models.User
+	todo    *models.Todo
+}
+
+func newAttachmentFixture(t *testing.T, maxSize int64) *attachmentFixture {
+	t.Helper()
+	store, err := repository.NewTodoRepository(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	blobs, err := blob.NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staging, err := blob.NewStaging(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.GetUserByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todo, err := store.Create(&models.Todo{WorkspaceID: ws, Text: "attach here", UserID: user.ID, Priority: models.PriorityNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &attachmentFixture{
+		service: NewAttachmentService(store, blobs, staging, maxSize),
+		store:   store,
+		blobs:   blobs,
+		staging: staging,
+		user:    user,
+		todo:    todo,
+	}
+}
+
+// start begins a resumable upload of size bytes
+func (f *attachmentFixture) start(t *testing.T, size int64) *models.Upload {
+	t.Helper()
+	upload, err := f.service.StartUpload(f.user, ws, f.todo.ID, dto.UploadRequest{FileName: "notes.txt", Size: size})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return upload
+}
+
+// png is the start of a PNG file
+const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestUploadContentTypes(t *testing.T) {
+	f := newAttachmentFixture(t, 64)
+	for _, row := range []struct {
+		name        string
+		content     string
+		contentType string
+		err         error
+	}{
+		{"png", png, "image/png", nil},
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF", "image/jpeg", nil},
+		{"gif", "GIF89a\x01\x00\x01\x00", "image/gif", nil},
+		{"pdf", "%PDF-1.7\n", "application/pdf", nil},
+		{"plain text", "buy milk\n", "text/plain; charset=utf-8", nil},
+		{"html", "<html><script>alert(1)</script></html>", "", ErrFileTypeNotAllowed},
+		{"zip", "PK\x03\x04\x14\x00\x00\x00", "", ErrFileTypeNotAllowed},
+		{"binary", "\x00\x01\x02\x03", "", ErrFileTypeNotAllowed},
+		{"empty", "", "", ErrEmptyFile},
+		{"too large", strings.Repeat("x", 65), "", ErrFileTooLarge},
+	} {
+		t.Run(row.name, func(t *testing.T) {
+			attachment, err := f.service.Upload(f.user, ws, f.todo.ID, "file", strings.NewReader(row.content))
+			if err != row.err {
+				t.Fatalf("err = %v, want %v", err, row.err)
+			}
+			if err == nil && (attachment.ContentType != row.contentType || attachment.Size != int64(len(row.content))) {
+				t.Errorf("stored %s of %d bytes, want %s of %d", attachment.ContentType, attachment.Size, row.contentType, len(row.content))
+			}
+		})
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	f := newAttachmentFixture(t, 64)
+	upload := f.start(t, 11)
+
+	progress, attachment, err := f.service.AppendUpload(f.user, ws, f.todo.ID, upload.ID, 0, strings.NewReader("hello "))
+	if err != nil || attachment != nil || progress.Offset != 6 {
+		t.Fatalf("first chunk: %+v, %v, %v; want offset 6 and no attachment", progress, attachment, err)
+	}
+	if _, _, err := f.service.AppendUpload(f.user, ws, f.todo.ID, upload.ID, 0, strings.NewReader("hello ")); err != ErrUploadOffset {
+		t.Errorf("chunk sent again: err = %v, want ErrUploadOffset", err)
+	}
+	other, err := f.store.CreateUser(&models.User{Name: "Other", Email: "other@example.com", Role: "member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.GetUpload(other, ws, f.todo.ID, upload.ID); err != ErrUploadNotFound {
+		t.Errorf("upload of another user: err = %v, want ErrUploadNotFound", err)
+	}
+
+	// A restart resumes from what reached the disk
+	restarted := NewAttachmentService(f.store, f.blobs, f.staging, 64)
+	if n, err := restarted.RestoreUploads(); err != nil || n != 1 {
+		t.Fatalf("restored %d uploads (err %v), want 1", n, err)
+	}
+	resumed, err := restarted.GetUpload(f.user, ws, f.todo.ID, upload.ID)
+	if err != nil || resumed.Offset != 6 || resumed.FileName != "notes.txt" {
+		t.Fatalf("resumed upload = %+v (err %v), want notes.txt at offset 6", resumed, err)
+	}
+
+	progress, attachment, err = restarted.AppendUpload(f.user, ws, f.todo.ID, upload.ID, 6, strings.NewReader("world"))
+	if err != nil || attachment == nil || progress.Offset != 11 {
+		t.Fatalf("last chunk: %+v, %v, %v; want offset 11 and an attachment", progress, attachment, err)
+	}
+	if attachment.FileName != "notes.txt" || attachment.Size != 11 || attachment.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("attachment = %+v, want notes.txt, 11 bytes of plain text", attachment)
+	}
+	if _, err := restarted.GetUpload(f.user, ws, f.todo.ID, upload.ID); err != ErrUploadNotFound {
+		t.Errorf("finished upload: err = %v, want ErrUploadNotFound", err)
+	}
+}
+
+func TestFinishedUploadRefused(t *testing.T) {
+	for _, row := range []struct {
+		name    string
+		content string
+		blobs   func(f *attachmentFixture) blob.Store
+		err     error
+	}{
+		{"type not allowed", "\x00\x01\x02\x03", nil, ErrFileTypeNotAllowed},
+		{"storing fails", "text", func(f *attachmentFixture) blob.Store { return failingBlobs{f.blobs} }, ErrUploadFailed},
+	} {
+		t.Run(row.name, func(t *testing.T) {
+			f := newAttachmentFixture(t, 64)
+			if row.blobs != nil {
+				f.service.blobs = row.blobs(f)
+			}
+			upload := f.start(t, int64(len(row.content)))
+
+			_, attachment, err := f.service.AppendUpload(f.user, ws, f.todo.ID, upload.ID, 0, strings.NewReader(row.content))
+			if err != row.err || attachment != nil {
+				t.Fatalf("last chunk: %v, %v; want %v", attachment, err, row.err)
+			}
+			// The upload is gone rather than stuck complete in staging
+			if _, err := f.service.GetUpload(f.user, ws, f.todo.ID, upload.ID); err != ErrUploadNotFound {
+				t.Errorf("refused upload: err = %v, want ErrUploadNotFound", err)
+			}
+			if staged, err := f.staging.Uploads(); err != nil || len(staged) != 0 {
+				t.Errorf("staging holds %d uploads (err %v), want none", len(staged), err)
+			}
+		})
+	}
+}
+
+func TestAttachmentSweep(t *testing.T) {
+	f := newAttachmentFixture(t, 64)
+	kept, err := f.service.Upload(f.user, ws, f.todo.ID, "kept.txt", strings.NewReader("kept"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := f.service.Upload(f.user, ws, f.todo.ID, "deleted.txt", strings.NewReader("deleted"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.service.DeleteAttachment(f.user, ws, f.todo.ID, deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	upload := f.start(t, 10)
+
+	// Within the grace period nothing goes
+	uploads, blobs, err := f.service.Sweep(time.Now())
+	if err != nil || uploads != 0 || blobs != 0 {
+		t.Errorf("sweep now: %d uploads and %d blobs (err %v), want none", uploads, blobs, err)
+	}
+
+	uploads, blobs, err = f.service.Sweep(time.Now().Add(uploadTTL + time.Minute))
+	if err != nil || uploads != 1 || blobs != 1 {
+		t.Errorf("sweep a day later: %d uploads and %d blobs (err %v), want 1 and 1", uploads, blobs, err)
+	}
+	if _, err := f.service.GetUpload(f.user, ws, f.todo.ID, upload.ID); err != ErrUploadNotFound {
+		t.Errorf("expired upload: err = %v, want ErrUploadNotFound", err)
+	}
+	_, content, err := f.service.OpenAttachment(f.user, ws, f.todo.ID, kept.ID)
+	if err != nil {
+		t.Fatalf("content of the kept attachment: %v", err)
+	}
+	content.Close()
+	if _, err := f.blobs.Open(deleted.BlobKey); err != blob.ErrNotFound {
+		t.Errorf("content of the deleted attachment: err = %v, want ErrNotFound", err)
+	}
+}
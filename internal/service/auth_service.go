@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"test_mekari/internal/auth"
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrUserDeactivated    = errors.New("this account has been deactivated")
+)
+
+// LoginResult holds both credentials issued by a successful login
+type LoginResult struct {
+	User             *models.User
+	Token            string
+	TokenExpiresAt   time.Time
+	SessionID        string
+	SessionExpiresAt time.Time
+}
+
+// AuthService handles login and resolves credentials to users
+type AuthService struct {
+	users     repository.UserStore
+	tokens    *auth.TokenSigner
+	sessions  *auth.SessionStore
+	dummyHash string
+}
+
+// NewAuthService creates a new instance of AuthService
+func NewAuthService(users repository.UserStore, tokens *auth.TokenSigner, sessions *auth.SessionStore) (*AuthService, error) {
+	// Unknown emails are checked against a dummy hash so both failure paths take the same time
+	dummyHash, err := auth.HashPassword("dummy password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthService{
+		users:     users,
+		tokens:    tokens,
+		sessions:  sessions,
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Login verifies email + password and issues a bearer token and a cookie session
+func (s *AuthService) Login(req dto.LoginRequest) (*LoginResult, error) {
+	email := strings.TrimSpace(req.Email)
+	if email == "" || req.Password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			auth.VerifyPassword(req.Password, s.dummyHash)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	ok, err := auth.VerifyPassword(req.Password, user.PasswordHash)
+	if err != nil || !ok {
+		return nil, ErrInvalidCredentials
+	}
+	// Only reported after the password matched, so it does not reveal accounts
+	if user.Deactivated {
+		return nil, ErrUserDeactivated
+	}
+
+	token, tokenExpiresAt, err := s.tokens.Issue(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, sessionExpiresAt, err := s.sessions.Create(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		User:             user,
+		Token:            token,
+		TokenExpiresAt:   tokenExpiresAt,
+		SessionID:        sessionID,
+		SessionExpiresAt: sessionExpiresAt,
+	}, nil
+}
+
+// AuthenticateToken resolves a bearer token to its user
+func (s *AuthService) AuthenticateToken(token string) (*models.User, error) {
+	userID, err := s.tokens.Verify(token)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	return s.currentUser(userID)
+}
+
+// AuthenticateSession resolves a session cookie to its user
+func (s *AuthService) AuthenticateSession(sessionID string) (*models.User, error) {
+	userID, err := s.sessions.Lookup(sessionID)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	return s.currentUser(userID)
+}
+
+// Logout ends a cookie session. Bearer tokens are stateless and simply expire.
+func (s *AuthService) Logout(sessionID string) {
+	s.sessions.Delete(sessionID)
+}
+
+// currentUser loads the user behind valid credentials; a deleted or
+// deactivated user is unauthenticated, which also revokes their sessions and tokens
+func (s *AuthService) currentUser(userID int) (*models.User, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+	if user.Deactivated {
+		return nil, ErrUnauthenticated
+	}
+	return user, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"test_mekari/internal/collab"
+	"test_mekari/internal/events"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+// CollabService handles who is watching a workspace live and which todos they
+// are editing, next to the todo events they receive
+type CollabService struct {
+	hub        *collab.Hub
+	events     *EventService
+	todos      repository.TodoStore
+	workspaces repository.WorkspaceStore
+}
+
+// NewCollabService creates a new instance of CollabService
+func NewCollabService(hub *collab.Hub, events *EventService, store repository.Store) *CollabService {
+	return &CollabService{
+		hub:        hub,
+		events:     events,
+		todos:      store,
+		workspaces: store,
+	}
+}
+
+// Join marks user present in a workspace they may view. Every successful Join
+// must be paired with a Leave when the connection ends.
+func (s *CollabService) Join(user *models.User, workspaceID int) error {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return err
+	}
+	s.hub.Join(workspaceID, user)
+	return nil
+}
+
+// Leave ends a connection opened with Join, releasing the user's edit locks
+// when it was their last one to the workspace
+func (s *CollabService) Leave(user *models.User, workspaceID int) {
+	s.hub.Leave(workspaceID, user.ID)
+}
+
+// Heartbeat renews the edit locks user holds in a workspace
+func (s *CollabService) Heartbeat(user *models.User, workspaceID int) {
+	s.hub.Touch(workspaceID, user.ID)
+}
+
+// Subscribe starts delivering the events of filter.WorkspaceID like
+// EventService.Subscribe, and returns who is present and editing right now.
+// Changes after that are delivered as events, so the state is never missed.
+func (s *CollabService) Subscribe(user *models.User, filter events.Filter, lastEventID string) (*events.Subscription, []events.Event, bool, []events.Presence, []events.Lock, error) {
+	sub, replay, resync, err := s.events.Subscribe(user, filter, lastEventID)
+	if err != nil {
+		return nil, nil, false, nil, nil, err
+	}
+	return sub, replay, resync, s.hub.Presence(filter.WorkspaceID), s.hub.Locks(filter.WorkspaceID), nil
+}
+
+// CheckAccess reports whether user may still view a workspace they are
+// connected to, see EventService.CheckAccess
+func (s *CollabService) CheckAccess(user *models.User, workspaceID int) error {
+	return s.events.CheckAccess(user, workspaceID)
+}
+
+// StartEditing takes the edit lock on a todo user may update. When another
+// user holds it collab.ErrLocked is returned together with their lock.
+func (s *CollabService) StartEditing(user *models.User, workspaceID, todoID int) (*events.Lock, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	todo, err := s.todos.FindByID(workspaceID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+
+	lock, err := s.hub.Lock(workspaceID, todoID, user)
+	return &lock, err
+}
+
+// StopEditing releases the edit lock user holds on a todo
+func (s *CollabService) StopEditing(user *models.User, workspaceID, todoID int) error {
+	return s.hub.Unlock(workspaceID, todoID, user.ID)
+}
+
+// Done is closed when the server shuts down and connections should end
+func (s *CollabService) Done() <-chan struct{} {
+	return s.hub.Done()
+}
+
+// authorize checks action against the role user holds in the workspace
+func (s *CollabService) authorize(user *models.User, workspaceID int, action policy.Action, todo *models.Todo) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	if todo == nil {
+		return can(actor, action, nil)
+	}
+	return can(actor, action, todo)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/mention"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var ErrInvalidCommentBody = errors.New("comment body cannot be empty")
+
+// CommentService handles business logic for the discussion on todos
+type CommentService struct {
+	comments   repository.CommentStore
+	todos      repository.TodoStore
+	users      repository.UserStore
+	workspaces repository.WorkspaceStore
+	notify     notifier
+}
+
+// NewCommentService creates a new instance of CommentService
+func NewCommentService(store repository.Store) *CommentService {
+	return &CommentService{
+		comments:   store,
+		todos:      store,
+		users:      store,
+		workspaces: store,
+		notify:     notifier{notifications: store, users: store, workspaces: store},
+	}
+}
+
+// GetComments returns the comments on a todo, oldest first
+func (s *CommentService) GetComments(user *models.User, workspaceID, todoID int) ([]models.Comment, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	return s.comments.GetComments(workspaceID, todoID)
+}
+
+// CreateComment adds a comment by user to a todo, resolving the @mentions in its body
+func (s *CommentService) CreateComment(user *models.User, workspaceID, todoID int, req dto.CommentRequest) (*models.Comment, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateComment, nil); err != nil {
+		return nil, err
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, ErrInvalidCommentBody
+	}
+	todo, err := s.todos.FindByID(workspaceID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := s.resolveMentions(workspaceID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.comments.CreateComment(&models.Comment{
+		WorkspaceID: workspaceID,
+		TodoID:      todoID,
+		ReplyToID:   req.ReplyToID,
+		AuthorID:    user.ID,
+		Body:        body,
+		Mentions:    mentions,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify.commentMentioned(user, todo, comment, nil)
+	return comment, nil
+}
+
+// UpdateComment changes the body of a comment; only its author may
+func (s *CommentService) UpdateComment(user *models.User, workspaceID, todoID, id int, req dto.CommentRequest) (*models.Comment, error) {
+	todo, comment, err := s.getComment(workspaceID, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateComment, comment); err != nil {
+		return nil, err
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, ErrInvalidCommentBody
+	}
+	if body == comment.Body {
+		return comment, nil
+	}
+	mentions, err := s.resolveMentions(workspaceID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := comment.Mentions
+	now := time.Now()
+	comment.Body = body
+	comment.Mentions = mentions
+	comment.EditedAt = &now
+	updated, err := s.comments.UpdateComment(comment)
+	if err != nil {
+		return nil, err
+	}
+	s.notify.commentMentioned(user, todo, updated, previous)
+	return updated, nil
+}
+
+// DeleteComment removes a comment; replies to it are kept
+func (s *CommentService) DeleteComment(user *models.User, workspaceID, todoID, id int) error {
+	_, comment, err := s.getComment(workspaceID, todoID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionDeleteComment, comment); err != nil {
+		return err
+	}
+	return s.comments.DeleteComment(workspaceID, id)
+}
+
+// getComment returns a todo and its comment id; comments on other todos are not found
+func (s *CommentService) getComment(workspaceID, todoID, id int) (*models.Todo, *models.Comment, error) {
+	todo, err := s.todos.FindByID(workspaceID, todoID)
+	if err != nil {
+		return nil, nil, err
+	}
+	comment, err := s.comments.GetComment(workspaceID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comment.TodoID != todoID {
+		return nil, nil, repository.ErrCommentNotFound
+	}
+	return todo, comment, nil
+}
+
+// resolveMentions returns the users of the workspace that text @mentions
+func (s *CommentService) resolveMentions(workspaceID int, text string) ([]int, error) {
+	if len(mention.Handles(text)) == 0 {
+		return []int{}, nil
+	}
+	candidates, err := mentionableUsers(s.users, s.workspaces, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return mention.Resolve(text, candidates), nil
+}
+
+// authorize checks action against the role user holds in the workspace
+func (s *CommentService) authorize(user *models.User, workspaceID int, action policy.Action, comment *models.Comment) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	if comment == nil {
+		return can(actor, action, nil)
+	}
+	return can(actor, action, comment)
+}
+
+// mentionableUsers returns the users who can be @mentioned in a workspace:
+// its active members, and active global admins, who belong to every workspace
+func mentionableUsers(users repository.UserStore, workspaces repository.WorkspaceStore, workspaceID int) ([]models.User, error) {
+	members, err := workspaces.GetMembers(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	isMember := make(map[int]bool, len(members))
+	for _, member := range members {
+		isMember[member.UserID] = true
+	}
+
+	all, err := users.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]models.User, 0, len(members))
+	for _, user := range all {
+		if !user.Deactivated && (isMember[user.ID] || policy.NewActor(&user).Role == policy.RoleAdmin) {
+			candidates = append(candidates, user)
+		}
+	}
+	return candidates, nil
+}
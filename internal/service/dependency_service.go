@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidBlockerID   = errors.New("blocker_id is required")
+	ErrDependencyNotFound = errors.New("todo does not depend on that todo")
+	ErrTodoBlocked        = errors.New("todo is blocked by open todos, complete them first or force it")
+)
+
+// AddDependency makes a todo wait for another todo of the workspace. Adding
+// a dependency the todo already has changes nothing; one that would close a
+// cycle is refused by the store, atomically with the update.
+func (s *TodoService) AddDependency(user *models.User, workspaceID, id int, req dto.DependencyRequest) (*models.Todo, error) {
+	if req.BlockerID == nil || *req.BlockerID <= 0 {
+		return nil, ErrInvalidBlockerID
+	}
+	blockerID := *req.BlockerID
+
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+	if indexOfID(todo.BlockedBy, blockerID) >= 0 {
+		return s.withTodoComputed(todo, nil)
+	}
+
+	// BlockedBy may be shared with the store, so it is replaced, never modified in place
+	blockedBy := make([]int, 0, len(todo.BlockedBy)+1)
+	todo.BlockedBy = append(append(blockedBy, todo.BlockedBy...), blockerID)
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// RemoveDependency stops a todo from waiting for blockerID
+func (s *TodoService) RemoveDependency(user *models.User, workspaceID, id, blockerID int) (*models.Todo, error) {
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+
+	i := indexOfID(todo.BlockedBy, blockerID)
+	if i < 0 {
+		return nil, ErrDependencyNotFound
+	}
+	blockedBy := make([]int, 0, len(todo.BlockedBy)-1)
+	todo.BlockedBy = append(append(blockedBy, todo.BlockedBy[:i]...), todo.BlockedBy[i+1:]...)
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// GetPlan orders the open todos of a workspace (of one owner unless userID is 0)
+// into stages: stage 1 holds the todos that can be worked on now, stage 2 those
+// waiting only for stage 1 and so on. Stages count the dependencies on todos of
+// every owner, so a stage the owner has nothing in is left out. Within a stage
+// the most urgent todos come first.
+func (s *TodoService) GetPlan(user *models.User, workspaceID, userID int) ([]models.Stage, error) {
+	if userID < 0 {
+		return nil, ErrInvalidUserID
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	if userID != 0 {
+		if _, err := s.users.GetUserByID(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	todos, err := s.todos.FindAll(workspaceID, repository.TodoFilter{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	// Stages follow chains of blockers anywhere in the workspace
+	all, err := s.todos.FindAll(workspaceID, repository.TodoFilter{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+	tree := newTodoTree(all)
+	stages := tree.stages()
+
+	byStage := make(map[int][]models.Todo)
+	for _, todo := range todos {
+		stage, open := stages[todo.ID]
+		if !open {
+			continue
+		}
+		todo.Progress = tree.progress(todo)
+		todo.Blocked = tree.blocked(todo)
+		byStage[stage] = append(byStage[stage], todo)
+	}
+
+	plan := make([]models.Stage, 0, len(byStage))
+	for stage, todos := range byStage {
+		sort.Slice(todos, func(i, j int) bool {
+			if todos[i].Priority.Rank() != todos[j].Priority.Rank() {
+				return todos[i].Priority.Rank() > todos[j].Priority.Rank()
+			}
+			return todos[i].ID < todos[j].ID
+		})
+		plan = append(plan, models.Stage{Stage: stage, Todos: todos})
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Stage < plan[j].Stage })
+	return plan, nil
+}
+
+// checkBlockers returns ErrTodoBlocked when one of todos is waiting for an
+// open todo that is not among todos itself
+func (s *TodoService) checkBlockers(workspaceID int, todos []models.Todo) error {
+	completing := make(map[int]bool, len(todos))
+	for _, todo := range todos {
+		completing[todo.ID] = true
+	}
+	for _, todo := range todos {
+		for _, blockerID := range todo.BlockedBy {
+			if completing[blockerID] {
+				continue
+			}
+			blocker, err := s.todos.FindByID(workspaceID, blockerID)
+			if err == repository.ErrTodoNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !blocker.Completed {
+				return ErrTodoBlocked
+			}
+		}
+	}
+	return nil
+}
+
+// blocked reports whether todo is open and waiting for an open todo
+func (t *todoTree) blocked(todo models.Todo) bool {
+	if todo.Completed {
+		return false
+	}
+	for _, blockerID := range todo.BlockedBy {
+		if blocker, exists := t.byID[blockerID]; exists && !blocker.Completed {
+			return true
+		}
+	}
+	return false
+}
+
+// stages returns the stage of every open todo: 1 when none of its blockers is
+// open, otherwise one more than the latest stage among its open blockers.
+// Todos on a cycle, which the store refuses but older data may hold, never
+// become ready and are left out.
+func (t *todoTree) stages() map[int]int {
+	waiting := make(map[int]int)
+	dependents := make(map[int][]int)
+	for id, todo := range t.byID {
+		if todo.Completed {
+			continue
+		}
+		waiting[id] = 0
+		for _, blockerID := range todo.BlockedBy {
+			if blocker, exists := t.byID[blockerID]; exists && !blocker.Completed {
+				waiting[id]++
+				dependents[blockerID] = append(dependents[blockerID], id)
+			}
+		}
+	}
+
+	stages := make(map[int]int, len(waiting))
+	var ready []int
+	for id, count := range waiting {
+		if count == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for stage := 1; len(ready) > 0; stage++ {
+		var next []int
+		for _, id := range ready {
+			stages[id] = stage
+			for _, dependentID := range dependents[id] {
+				waiting[dependentID]--
+				if waiting[dependentID] == 0 {
+					next = append(next, dependentID)
+				}
+			}
+		}
+		ready = next
+	}
+	return stages
+}
+
+// indexOfID returns the index of id in ids, or -1
+func indexOfID(ids []int, id int) int {
+	for i, other := range ids {
+		if other == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+
+	"test_mekari/internal/events"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+// EventService hands out live subscriptions to the todo changes of a workspace
+type EventService struct {
+	bus        *events.Bus
+	users      repository.UserStore
+	workspaces repository.WorkspaceStore
+}
+
+// NewEventService creates a new instance of EventService
+func NewEventService(bus *events.Bus, store repository.Store) *EventService {
+	return &EventService{
+		bus:        bus,
+		users:      store,
+		workspaces: store,
+	}
+}
+
+// Subscribe starts delivering the todo events of filter.WorkspaceID to user,
+// who must be allowed to view its todos. See events.Bus.Subscribe for lastEventID.
+func (s *EventService) Subscribe(user *models.User, filter events.Filter, lastEventID string) (*events.Subscription, []events.Event, bool, error) {
+	if err := s.CheckAccess(user, filter.WorkspaceID); err != nil {
+		return nil, nil, false, err
+	}
+	return s.bus.Subscribe(filter, lastEventID)
+}
+
+// CheckAccess reports whether user may still view the todos of a workspace,
+// as stored right now rather than when they connected. Streams check before
+// delivering, so a user who was removed from the workspace, lost their role
+// or was deleted stops receiving its events.
+func (s *EventService) CheckAccess(user *models.User, workspaceID int) error {
+	if user == nil {
+		return ErrUnauthenticated
+	}
+	current, err := s.users.GetUserByID(user.ID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrUnauthenticated
+	}
+	if err != nil {
+		return err
+	}
+	actor, err := workspaceActor(s.workspaces, current, workspaceID)
+	if err != nil {
+		return err
+	}
+	return can(actor, policy.ActionViewTodo, nil)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidLabelName  = errors.New("label name cannot be empty or contain a comma")
+	ErrInvalidLabelColor = errors.New("label color must be a hex color like #d73a4a")
+	ErrLabelNameTaken    = errors.New("label name is already in use in this workspace")
+)
+
+// defaultLabelColor is used when a label is created without a color
+const defaultLabelColor = "#8b949e"
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// LabelService handles business logic for the labels of a workspace
+type LabelService struct {
+	labels     repository.LabelStore
+	workspaces repository.WorkspaceStore
+}
+
+// NewLabelService creates a new instance of LabelService
+func NewLabelService(store repository.Store) *LabelService {
+	return &LabelService{
+		labels:     store,
+		workspaces: store,
+	}
+}
+
+// GetLabels returns the labels of a workspace
+func (s *LabelService) GetLabels(user *models.User, workspaceID int) ([]models.Label, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo); err != nil {
+		return nil, err
+	}
+	return s.labels.GetLabels(workspaceID)
+}
+
+// GetLabel returns a single label of a workspace
+func (s *LabelService) GetLabel(user *models.User, workspaceID, id int) (*models.Label, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo); err != nil {
+		return nil, err
+	}
+	return s.labels.GetLabel(workspaceID, id)
+}
+
+// CreateLabel creates a new label in a workspace
+func (s *LabelService) CreateLabel(user *models.User, workspaceID int, req dto.LabelRequest) (*models.Label, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateLabel); err != nil {
+		return nil, err
+	}
+
+	name, color, err := validateLabelRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	label, err := s.labels.CreateLabel(&models.Label{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Color:       color,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if errors.Is(err, repository.ErrLabelNameTaken) {
+		return nil, ErrLabelNameTaken
+	}
+	return label, err
+}
+
+// UpdateLabel renames and/or recolors a label
+func (s *LabelService) UpdateLabel(user *models.User, workspaceID, id int, req dto.LabelRequest) (*models.Label, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateLabel); err != nil {
+		return nil, err
+	}
+
+	name, color, err := validateLabelRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := s.labels.GetLabel(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	label.Name = name
+	label.Color = color
+	label.UpdatedAt = time.Now()
+
+	updated, err := s.labels.UpdateLabel(label)
+	if errors.Is(err, repository.ErrLabelNameTaken) {
+		return nil, ErrLabelNameTaken
+	}
+	return updated, err
+}
+
+// DeleteLabel deletes a label and detaches it from every todo
+func (s *LabelService) DeleteLabel(user *models.User, workspaceID, id int) error {
+	if err := s.authorize(user, workspaceID, policy.ActionDeleteLabel); err != nil {
+		return err
+	}
+	return s.labels.DeleteLabel(workspaceID, id)
+}
+
+// authorize checks action against the role user holds in the workspace
+func (s *LabelService) authorize(user *models.User, workspaceID int, action policy.Action) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	return can(actor, action, nil)
+}
+
+// validateLabelRequest returns the trimmed name and the lower-case color of a request
+func validateLabelRequest(req dto.LabelRequest) (name, color string, err error) {
+	// Commas separate labels in GET /todos?label=
+	name = strings.TrimSpace(req.Name)
+	if name == "" || strings.Contains(name, ",") {
+		return "", "", ErrInvalidLabelName
+	}
+
+	color = strings.ToLower(strings.TrimSpace(req.Color))
+	if color == "" {
+		color = defaultLabelColor
+	}
+	if !labelColorPattern.MatchString(color) {
+		return "", "", ErrInvalidLabelColor
+	}
+	return name, color, nil
+}
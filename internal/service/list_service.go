@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidListName = errors.New("list name cannot be empty")
+	ErrListArchived    = errors.New("list is archived, unarchive it before adding todos")
+)
+
+// ListService handles business logic for the todo lists of a workspace
+type ListService struct {
+	lists      repository.ListStore
+	workspaces repository.WorkspaceStore
+}
+
+// NewListService creates a new instance of ListService
+func NewListService(store repository.Store) *ListService {
+	return &ListService{
+		lists:      store,
+		workspaces: store,
+	}
+}
+
+// GetLists returns the lists of a workspace; archived lists only when includeArchived is set
+func (s *ListService) GetLists(user *models.User, workspaceID int, includeArchived bool) ([]models.List, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo); err != nil {
+		return nil, err
+	}
+
+	lists, err := s.lists.GetLists(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if includeArchived {
+		return lists, nil
+	}
+
+	active := make([]models.List, 0, len(lists))
+	for _, list := range lists {
+		if !list.Archived {
+			active = append(active, list)
+		}
+	}
+	return active, nil
+}
+
+// GetList returns a single list of a workspace
+func (s *ListService) GetList(user *models.User, workspaceID, id int) (*models.List, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo); err != nil {
+		return nil, err
+	}
+	return s.lists.GetList(workspaceID, id)
+}
+
+// CreateList creates a new list in a workspace
+func (s *ListService) CreateList(user *models.User, workspaceID int, req dto.ListRequest) (*models.List, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateList); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidListName
+	}
+
+	now := time.Now()
+	return s.lists.CreateList(&models.List{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+// RenameList changes the name of a list
+func (s *ListService) RenameList(user *models.User, workspaceID, id int, req dto.ListRequest) (*models.List, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateList); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidListName
+	}
+
+	list, err := s.lists.GetList(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	list.Name = name
+	list.UpdatedAt = time.Now()
+	return s.lists.UpdateList(list)
+}
+
+// SetArchived archives or unarchives a whole list. The todos stay in the
+// list, but are hidden from todo listings while it is archived.
+func (s *ListService) SetArchived(user *models.User, workspaceID, id int, archived bool) (*models.List, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateList); err != nil {
+		return nil, err
+	}
+
+	list, err := s.lists.GetList(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if list.Archived == archived {
+		return list, nil
+	}
+	list.Archived = archived
+	list.UpdatedAt = time.Now()
+	return s.lists.UpdateList(list)
+}
+
+// DeleteList deletes a list. Its todos are kept, without a list.
+func (s *ListService) DeleteList(user *models.User, workspaceID, id int) error {
+	if err := s.authorize(user, workspaceID, policy.ActionDeleteList); err != nil {
+		return err
+	}
+	return s.lists.DeleteList(workspaceID, id)
+}
+
+// authorize checks action against the role user holds in the workspace
+func (s *ListService) authorize(user *models.User, workspaceID int, action policy.Action) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	return can(actor, action, nil)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"test_mekari/internal/mention"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// NotificationService serves the notification inbox of the authenticated user
+type NotificationService struct {
+	notifications repository.NotificationStore
+}
+
+// NewNotificationService creates a new instance of NotificationService
+func NewNotificationService(notifications repository.NotificationStore) *NotificationService {
+	return &NotificationService{
+		notifications: notifications,
+	}
+}
+
+// GetInbox returns the notifications of user, newest first (only the unread
+// ones with unreadOnly), and the number of unread notifications
+func (s *NotificationService) GetInbox(user *models.User, unreadOnly bool) (*models.Inbox, error) {
+	if user == nil {
+		return nil, ErrUnauthenticated
+	}
+	notifications, err := s.notifications.GetNotifications(user.ID, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notifications.CountUnreadNotifications(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Inbox{UnreadCount: unread, Notifications: notifications}, nil
+}
+
+// MarkRead marks a notification of user read; notifications of other users are not found
+func (s *NotificationService) MarkRead(user *models.User, id int) (*models.Notification, error) {
+	if user == nil {
+		return nil, ErrUnauthenticated
+	}
+	return s.notifications.MarkNotificationRead(user.ID, id, time.Now())
+}
+
+// MarkAllRead marks every notification of user read and returns how many were unread
+func (s *NotificationService) MarkAllRead(user *models.User) (int, error) {
+	if user == nil {
+		return 0, ErrUnauthenticated
+	}
+	return s.notifications.MarkAllNotificationsRead(user.ID, time.Now())
+}
+
+// notifier fills the inboxes of the users a change concerns. The change is
+// saved by then, so notifying is best effort: failures are logged, not returned.
+// The user who made the change is never notified about it.
+type notifier struct {
+	notifications repository.NotificationStore
+	users         repository.UserStore
+	workspaces    repository.WorkspaceStore
+}
+
+// todoMentioned notifies the users @mentioned in the text of todo, except
+// those previousText mentioned already (previousText is "" for a new todo)
+func (n notifier) todoMentioned(actor *models.User, todo *models.Todo, previousText string) {
+	if len(mention.Handles(todo.Text)) == 0 {
+		return
+	}
+	candidates, err := mentionableUsers(n.users, n.workspaces, todo.WorkspaceID)
+	if err != nil {
+		n.failed(err)
+		return
+	}
+	previous := mention.Resolve(previousText, candidates)
+	for _, userID := range mention.Resolve(todo.Text, candidates) {
+		if indexOfID(previous, userID) < 0 {
+			n.send(actor, todo, 0, userID, models.NotificationMention, fmt.Sprintf("%s mentioned you in %q", actor.Name, todo.Text))
+		}
+	}
+}
+
+// commentMentioned notifies the users comment mentions, except those in previous
+// (the mentions of the comment before it was edited, nil for a new comment)
+func (n notifier) commentMentioned(actor *models.User, todo *models.Todo, comment *models.Comment, previous []int) {
+	for _, userID := range comment.Mentions {
+		if indexOfID(previous, userID) < 0 {
+			n.send(actor, todo, comment.ID, userID, models.NotificationMention, fmt.Sprintf("%s mentioned you in a comment on %q", actor.Name, todo.Text))
+		}
+	}
+}
+
+// assigned notifies a user that todo was assigned to them
+func (n notifier) assigned(actor *models.User, todo *models.Todo, assigneeID int) {
+	n.send(actor, todo, 0, assigneeID, models.NotificationAssigned, fmt.Sprintf("%s assigned %q to you", actor.Name, todo.Text))
+}
+
+// completed notifies the owners of todos that they were completed
+func (n notifier) completed(actor *models.User, todos ...models.Todo) {
+	for i := range todos {
+		n.send(actor, &todos[i], 0, todos[i].UserID, models.NotificationCompleted, fmt.Sprintf("%s completed %q", actor.Name, todos[i].Text))
+	}
+}
+
+// send stores one notification for userID, unless they are the actor
+func (n notifier) send(actor *models.User, todo *models.Todo, commentID, userID int, kind models.NotificationType, message string) {
+	if userID == actor.ID {
+		return
+	}
+	_, err := n.notifications.CreateNotification(&models.Notification{
+		UserID:      userID,
+		WorkspaceID: todo.WorkspaceID,
+		TodoID:      todo.ID,
+		CommentID:   commentID,
+		ActorID:     actor.ID,
+		Type:        kind,
+		Message:     message,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		n.failed(err)
+	}
+}
+
+func (n notifier) failed(err error) {
+	log.Printf("⚠️  Notifications: %v", err)
+}
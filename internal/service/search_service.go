@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/search"
+)
+
+var ErrEmptyQuery = errors.New("q cannot be empty")
+
+// SearchService searches the todos and comments of a workspace
+type SearchService struct {
+	index      *search.Index
+	workspaces repository.WorkspaceStore
+}
+
+// NewSearchService creates a new instance of SearchService
+func NewSearchService(index *search.Index, workspaces repository.WorkspaceStore) *SearchService {
+	return &SearchService{
+		index:      index,
+		workspaces: workspaces,
+	}
+}
+
+// Search returns the best limit todos and comments of a workspace matching
+// query, best first; user must be allowed to view its todos
+func (s *SearchService) Search(user *models.User, workspaceID int, query string, limit int) ([]search.Hit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := can(actor, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	return s.index.Search(workspaceID, query, limit), nil
+}
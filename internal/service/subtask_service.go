@@ -0,0 +1,354 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrSubtaskTooDeep            = errors.New("subtasks cannot be nested more than 3 levels deep")
+	ErrTodoHasSubtasks           = errors.New("todo still has subtasks")
+	ErrInvalidSubtaskDisposition = errors.New("on_subtasks must be block, promote or cascade")
+
+	ErrInvalidChecklistText  = errors.New("checklist item text cannot be empty")
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+)
+
+// MaxSubtaskDepth is how many levels todos nest: a top-level todo, its
+// subtasks and their subtasks
+const MaxSubtaskDepth = 3
+
+// Subtask dispositions accepted by DeleteTodo
+const (
+	SubtasksBlock   = "block"
+	SubtasksPromote = "promote"
+	SubtasksCascade = "cascade"
+)
+
+// AddChecklistItem appends an item to the checklist of a todo
+func (s *TodoService) AddChecklistItem(user *models.User, workspaceID, id int, req dto.ChecklistItemRequest) (*models.Todo, error) {
+	if req.Text == nil || strings.TrimSpace(*req.Text) == "" {
+		return nil, ErrInvalidChecklistText
+	}
+
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+
+	// Item IDs only need to be unique within the todo
+	item := models.ChecklistItem{ID: 1, Text: strings.TrimSpace(*req.Text), Done: req.Done != nil && *req.Done}
+	for _, existing := range todo.Checklist {
+		if existing.ID >= item.ID {
+			item.ID = existing.ID + 1
+		}
+	}
+
+	// The checklist may be shared with the store, so it is replaced, never modified in place
+	checklist := make([]models.ChecklistItem, 0, len(todo.Checklist)+1)
+	todo.Checklist = append(append(checklist, todo.Checklist...), item)
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// UpdateChecklistItem renames and/or checks off a checklist item. Only
+// checking it off (or back on) needs no more than the toggle permission.
+func (s *TodoService) UpdateChecklistItem(user *models.User, workspaceID, id, itemID int, req dto.ChecklistItemRequest) (*models.Todo, error) {
+	if req.Text != nil && strings.TrimSpace(*req.Text) == "" {
+		return nil, ErrInvalidChecklistText
+	}
+
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	action := policy.ActionToggleTodo
+	if req.Text != nil {
+		action = policy.ActionUpdateTodo
+	}
+	if err := s.authorize(user, workspaceID, action, todo); err != nil {
+		return nil, err
+	}
+
+	checklist := make([]models.ChecklistItem, len(todo.Checklist))
+	copy(checklist, todo.Checklist)
+	i := checklistIndex(checklist, itemID)
+	if i < 0 {
+		return nil, ErrChecklistItemNotFound
+	}
+	if req.Text != nil {
+		checklist[i].Text = strings.TrimSpace(*req.Text)
+	}
+	if req.Done != nil {
+		checklist[i].Done = *req.Done
+	}
+
+	todo.Checklist = checklist
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// DeleteChecklistItem removes an item from the checklist of a todo
+func (s *TodoService) DeleteChecklistItem(user *models.User, workspaceID, id, itemID int) (*models.Todo, error) {
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+
+	i := checklistIndex(todo.Checklist, itemID)
+	if i < 0 {
+		return nil, ErrChecklistItemNotFound
+	}
+	checklist := make([]models.ChecklistItem, 0, len(todo.Checklist)-1)
+	todo.Checklist = append(append(checklist, todo.Checklist[:i]...), todo.Checklist[i+1:]...)
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// checkParent verifies todo (nil on create) may become a subtask of parentID
+// (0 = top-level): the parent must be a todo of the workspace and todo's own
+// subtasks must still fit within MaxSubtaskDepth. A parent that is todo or one
+// of its subtasks is refused by the store, atomically with the update.
+func (s *TodoService) checkParent(workspaceID int, todo *models.Todo, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+
+	// The parent's level, 1 for a top-level todo
+	depth := 0
+	for id := parentID; id != 0 && depth <= MaxSubtaskDepth; depth++ {
+		if todo != nil && id == todo.ID {
+			// The parent is below todo, the store refuses the cycle
+			return nil
+		}
+		ancestor, err := s.todos.FindByID(workspaceID, id)
+		if err == repository.ErrTodoNotFound && id == parentID {
+			return repository.ErrParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		id = ancestor.ParentID
+	}
+
+	levels := 1
+	if todo != nil {
+		height, err := s.height(workspaceID, todo.ID, 0)
+		if err != nil {
+			return err
+		}
+		levels += height
+	}
+	if depth+levels > MaxSubtaskDepth {
+		return ErrSubtaskTooDeep
+	}
+	return nil
+}
+
+// height returns how many levels of subtasks are below id (0 for none)
+func (s *TodoService) height(workspaceID, id, level int) (int, error) {
+	if level > MaxSubtaskDepth {
+		return 0, nil
+	}
+	subtasks, err := s.todos.FindAll(workspaceID, repository.TodoFilter{ParentID: &id, IncludeArchived: true})
+	if err != nil {
+		return 0, err
+	}
+	height := 0
+	for _, subtask := range subtasks {
+		below, err := s.height(workspaceID, subtask.ID, level+1)
+		if err != nil {
+			return 0, err
+		}
+		height = max(height, 1+below)
+	}
+	return height, nil
+}
+
+// withComputed fills in the Progress, Blocked flag and CommentCount of todos of a workspace
+func (s *TodoService) withComputed(workspaceID int, todos []models.Todo) ([]models.Todo, error) {
+	if len(todos) == 0 {
+		return todos, nil
+	}
+	tree, err := s.loadTree(workspaceID, todos)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+	}
+	counts, err := s.comments.CountComments(workspaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range todos {
+		todos[i].Progress = tree.progress(todos[i])
+		todos[i].Blocked = tree.blocked(todos[i])
+		todos[i].CommentCount = counts[todos[i].ID]
+	}
+	return todos, nil
+}
+
+// withTodoComputed is withComputed for the todo a service method returns,
+// it takes the method's results as they are
+func (s *TodoService) withTodoComputed(todo *models.Todo, err error) (*models.Todo, error) {
+	if err != nil {
+		return nil, err
+	}
+	todos, err := s.withComputed(todo.WorkspaceID, []models.Todo{*todo})
+	if err != nil {
+		return nil, err
+	}
+	return &todos[0], nil
+}
+
+// todoTree indexes todos of a workspace by ID and by parent
+type todoTree struct {
+	byID     map[int]models.Todo
+	children map[int][]int
+}
+
+// newTodoTree indexes todos, which hold every subtask of each other
+func newTodoTree(todos []models.Todo) *todoTree {
+	tree := &todoTree{byID: make(map[int]models.Todo, len(todos)), children: make(map[int][]int)}
+	for _, todo := range todos {
+		tree.byID[todo.ID] = todo
+		if todo.ParentID != 0 {
+			tree.children[todo.ParentID] = append(tree.children[todo.ParentID], todo.ID)
+		}
+	}
+	return tree
+}
+
+// loadTree reads what the progress and blocked state of todos depend on: their
+// subtasks at every level, archived lists included, and the todos blocking them
+func (s *TodoService) loadTree(workspaceID int, todos []models.Todo) (*todoTree, error) {
+	tree := &todoTree{byID: make(map[int]models.Todo, len(todos)), children: make(map[int][]int)}
+	queue := make([]int, 0, len(todos))
+	for _, todo := range todos {
+		tree.byID[todo.ID] = todo
+		queue = append(queue, todo.ID)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if _, read := tree.children[id]; read {
+			continue
+		}
+		subtasks, err := s.todos.FindAll(workspaceID, repository.TodoFilter{ParentID: &id, IncludeArchived: true})
+		if err != nil {
+			return nil, err
+		}
+		tree.children[id] = make([]int, len(subtasks))
+		for i, subtask := range subtasks {
+			tree.byID[subtask.ID] = subtask
+			tree.children[id][i] = subtask.ID
+			queue = append(queue, subtask.ID)
+		}
+	}
+
+	for _, todo := range todos {
+		for _, blockerID := range todo.BlockedBy {
+			if _, read := tree.byID[blockerID]; read {
+				continue
+			}
+			blocker, err := s.todos.FindByID(workspaceID, blockerID)
+			if err == repository.ErrTodoNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			tree.byID[blockerID] = *blocker
+		}
+	}
+	return tree, nil
+}
+
+// descendants returns the subtasks of id, their subtasks and so on, nearest first
+func (t *todoTree) descendants(id int) []models.Todo {
+	var descendants []models.Todo
+	seen := map[int]bool{id: true}
+	queue := append([]int{}, t.children[id]...)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		descendants = append(descendants, t.byID[next])
+		queue = append(queue, t.children[next]...)
+	}
+	return descendants
+}
+
+// progress returns the percentage of a todo that is done, nil when it has
+// neither subtasks nor checklist items
+func (t *todoTree) progress(todo models.Todo) *int {
+	if len(todo.Checklist) == 0 && len(t.children[todo.ID]) == 0 {
+		return nil
+	}
+	// The epsilon keeps e.g. 29 of 100 items from flooring to 28%
+	percent := int(math.Floor(t.done(todo, 0)*100 + 1e-9))
+	return &percent
+}
+
+// done returns the share of a todo that is done, from 0 to 1. A completed
+// todo is done; otherwise every checklist item and every direct subtask weighs
+// the same, a subtask counting with its own share.
+func (t *todoTree) done(todo models.Todo, level int) float64 {
+	if todo.Completed {
+		return 1
+	}
+	children := t.children[todo.ID]
+	units := len(todo.Checklist) + len(children)
+	if units == 0 || level > MaxSubtaskDepth {
+		return 0
+	}
+
+	var sum float64
+	for _, item := range todo.Checklist {
+		if item.Done {
+			sum++
+		}
+	}
+	for _, childID := range children {
+		sum += t.done(t.byID[childID], level+1)
+	}
+	return sum / float64(units)
+}
+
+// checklistIndex returns the index of the item with itemID, or -1
+func checklistIndex(checklist []models.ChecklistItem, itemID int) int {
+	for i, item := range checklist {
+		if item.ID == itemID {
+			return i
+		}
+	}
+	return -1
+}
+
+// resetChecklist returns a copy of checklist with every item open again
+func resetChecklist(checklist []models.ChecklistItem) []models.ChecklistItem {
+	reset := make([]models.ChecklistItem, len(checklist))
+	for i, item := range checklist {
+		item.Done = false
+		reset[i] = item
+	}
+	return reset
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidSort   = errors.New("sort must be id, created_at, updated_at, due, priority or text, optionally followed by :asc or :desc")
+	ErrInvalidCursor = errors.New("cursor is invalid or belongs to another sort order")
+)
+
+// TodoPage is the page of todos GetTodos returns
+type TodoPage struct {
+	Todos []models.Todo
+	// NextCursor continues after the last todo of this page (empty on the last page)
+	NextCursor string
+}
+
+// ParseTodoSort parses a sort order such as "due" or "priority:desc". An
+// empty order sorts by ID, i.e. in creation order.
+func ParseTodoSort(raw string) (repository.TodoSort, bool, error) {
+	field, dir, _ := strings.Cut(strings.ToLower(strings.TrimSpace(raw)), ":")
+	sort := repository.TodoSort(field)
+	if field == "id" {
+		sort = repository.SortByID
+	}
+	if !sort.Valid() || (field == "" && dir != "") {
+		return "", false, ErrInvalidSort
+	}
+
+	switch dir {
+	case "", "asc":
+		return sort, false, nil
+	case "desc":
+		return sort, true, nil
+	}
+	return "", false, ErrInvalidSort
+}
+
+// todoCursor is the position after a todo in one sort order. Cursors are
+// opaque to clients, and keep working when todos are added or deleted.
+type todoCursor struct {
+	Sort  repository.TodoSort `json:"s,omitempty"`
+	Desc  bool                `json:"d,omitempty"`
+	ID    int                 `json:"id"`
+	Value json.RawMessage     `json:"v,omitempty"` // the sorted field of the todo
+}
+
+// encodeTodoCursor returns the cursor continuing after todo
+func encodeTodoCursor(todo models.Todo, sort repository.TodoSort, desc bool) string {
+	cursor := todoCursor{Sort: sort, Desc: desc, ID: todo.ID}
+	if field := sortField(&todo, sort); field != nil {
+		cursor.Value, _ = json.Marshal(field)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTodoCursor returns the todo a cursor continues after, with only its ID
+// and sorted field set, for repository.TodoFilter.After
+func decodeTodoCursor(raw string, sort repository.TodoSort, desc bool) (*models.Todo, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor todoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+
+	after := &models.Todo{ID: cursor.ID}
+	if field := sortField(after, sort); field != nil {
+		if len(cursor.Value) == 0 || json.Unmarshal(cursor.Value, field) != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return after, nil
+}
+
+// sortField returns a pointer to the field of todo that sort orders by (nil for ID)
+func sortField(todo *models.Todo, sort repository.TodoSort) any {
+	switch sort {
+	case repository.SortByCreatedAt:
+		return &todo.CreatedAt
+	case repository.SortByUpdatedAt:
+		return &todo.UpdatedAt
+	case repository.SortByDue:
+		return &todo.DueAt
+	case repository.SortByPriority:
+		return &todo.Priority
+	case repository.SortByText:
+		return &todo.Text
+	}
+	return nil
+}
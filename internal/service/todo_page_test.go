@@ -0,0 +1,109 @@
+package service
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+func TestParseTodoSort(t *testing.T) {
+	for _, row := range []struct {
+		raw  string
+		sort repository.TodoSort
+		desc bool
+		err  error
+	}{
+		{"", repository.SortByID, false, nil},
+		{"id", repository.SortByID, false, nil},
+		{"id:desc", repository.SortByID, true, nil},
+		{"due", repository.SortByDue, false, nil},
+		{"Priority:DESC", repository.SortByPriority, true, nil},
+		{" text:asc ", repository.SortByText, false, nil},
+		{"created_at", repository.SortByCreatedAt, false, nil},
+		{"updated_at:desc", repository.SortByUpdatedAt, true, nil},
+		{":desc", "", false, ErrInvalidSort},
+		{"due:up", "", false, ErrInvalidSort},
+		{"owner", "", false, ErrInvalidSort},
+	} {
+		sort, desc, err := ParseTodoSort(row.raw)
+		if sort != row.sort || desc != row.desc || err != row.err {
+			t.Errorf("ParseTodoSort(%q) = %q, %v, %v; want %q, %v, %v", row.raw, sort, desc, err, row.sort, row.desc, row.err)
+		}
+	}
+}
+
+func TestTodoCursorRoundTrip(t *testing.T) {
+	due := time.Date(2025, time.March, 1, 9, 30, 0, 123456789, time.UTC)
+	todo := models.Todo{
+		ID:        42,
+		Text:      "Write tests",
+		Priority:  models.PriorityHigh,
+		DueAt:     &due,
+		CreatedAt: time.Date(2025, time.January, 2, 3, 4, 5, 6, time.UTC),
+		UpdatedAt: time.Date(2025, time.February, 3, 4, 5, 6, 7, time.UTC),
+	}
+	undated := todo
+	undated.DueAt = nil
+
+	for _, row := range []struct {
+		name string
+		todo models.Todo
+		sort repository.TodoSort
+		// want is the todo the cursor continues after: the ID and the sorted field
+		want models.Todo
+	}{
+		{"id", todo, repository.SortByID, models.Todo{ID: 42}},
+		{"created_at", todo, repository.SortByCreatedAt, models.Todo{ID: 42, CreatedAt: todo.CreatedAt}},
+		{"updated_at", todo, repository.SortByUpdatedAt, models.Todo{ID: 42, UpdatedAt: todo.UpdatedAt}},
+		{"due", todo, repository.SortByDue, models.Todo{ID: 42, DueAt: &due}},
+		{"due without a date", undated, repository.SortByDue, models.Todo{ID: 42}},
+		{"priority", todo, repository.SortByPriority, models.Todo{ID: 42, Priority: models.PriorityHigh}},
+		{"text", todo, repository.SortByText, models.Todo{ID: 42, Text: "Write tests"}},
+	} {
+		for _, desc := range []bool{false, true} {
+			cursor := encodeTodoCursor(row.todo, row.sort, desc)
+			after, err := decodeTodoCursor(cursor, row.sort, desc)
+			if err != nil {
+				t.Errorf("%s (desc %v): decode: %v", row.name, desc, err)
+				continue
+			}
+			if !reflect.DeepEqual(*after, row.want) {
+				t.Errorf("%s (desc %v): decoded %+v, want %+v", row.name, desc, *after, row.want)
+			}
+		}
+	}
+}
+
+func TestDecodeTodoCursorRejects(t *testing.T) {
+	todo := models.Todo{ID: 7, Text: "Write tests"}
+	textCursor := encodeTodoCursor(todo, repository.SortByText, false)
+	encode := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+
+	for _, row := range []struct {
+		name   string
+		cursor string
+		sort   repository.TodoSort
+		desc   bool
+	}{
+		{"another sort", textCursor, repository.SortByPriority, false},
+		{"the other direction", textCursor, repository.SortByText, true},
+		{"an ID cursor for a sort by text", encodeTodoCursor(todo, repository.SortByID, false), repository.SortByText, false},
+		{"not base64", "not a cursor!", repository.SortByText, false},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"id":7}`)), repository.SortByID, false},
+		{"not JSON", encode("garbage"), repository.SortByID, false},
+		{"a JSON array", encode(`[7]`), repository.SortByID, false},
+		{"no ID", encode(`{"s":"text","v":"a"}`), repository.SortByText, false},
+		{"a negative ID", encode(`{"id":-1}`), repository.SortByID, false},
+		{"no value", encode(`{"s":"text","id":7}`), repository.SortByText, false},
+		{"a value of the wrong type", encode(`{"s":"due","id":7,"v":"soon"}`), repository.SortByDue, false},
+		{"empty", "", repository.SortByID, false},
+	} {
+		if after, err := decodeTodoCursor(row.cursor, row.sort, row.desc); err != ErrInvalidCursor {
+			t.Errorf("%s: decoded %+v, %v; want ErrInvalidCursor", row.name, after, err)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"test_mekari/internal/dto"
+	"test_mekari/internal/expr"
 	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/recurrence"
 	"test_mekari/internal/repository"
 	"errors"
 	"strings"
@@ -14,163 +17,769 @@ var (
 	ErrInvalidUserID   = errors.New("invalid user ID")
 	ErrUserNotFound    = errors.New("user_id not found")
 	ErrUnauthorized    = errors.New("unauthorized to perform this action")
+
+	ErrReminderAfterDue = errors.New("remind_at cannot be later than due_at")
+
+	ErrInvalidPriority = errors.New("priority must be none, low, medium, high or urgent")
+
+	ErrRecurrenceNeedsDue = errors.New("a recurring todo needs a due_at")
+	ErrNotRecurring       = errors.New("todo is not recurring")
 )
 
-// TodoService handles business logic for todos
+// TodoService handles business logic for todos.
+// Every operation is scoped to one workspace the caller must belong to.
 type TodoService struct {
-	repo *repository.TodoRepository
+	todos      repository.TodoStore
+	users      repository.UserStore
+	workspaces repository.WorkspaceStore
+	lists      repository.ListStore
+	labels     repository.LabelStore
+	comments   repository.CommentStore
+	notify     notifier
 }
 
 // NewTodoService creates a new instance of TodoService
-func NewTodoService(repo *repository.TodoRepository) *TodoService {
+func NewTodoService(store repository.Store) *TodoService {
 	return &TodoService{
-		repo: repo,
+		todos:      store,
+		users:      store,
+		workspaces: store,
+		lists:      store,
+		labels:     store,
+		comments:   store,
+		notify:     notifier{notifications: store, users: store, workspaces: store},
 	}
 }
 
-// GetAllTodos returns all todos
-func (s *TodoService) GetAllTodos() ([]models.Todo, error) {
-	todos := s.repo.FindAll()
-	return todos, nil
+// TodoQuery selects the todos GetTodos returns. Labels are given by name
+// (ignoring case) and resolved to the filter's LabelIDs. Filter is an
+// expression parsed into the filter's Where (see package expr). Cursor
+// continues a previous page of the same sort order, and Limit is the page
+// size (0 = all).
+type TodoQuery struct {
+	repository.TodoFilter
+	Labels []string
+	Filter string
+	Cursor string
 }
 
-// GetAllUsers returns all users
-func (s *TodoService) GetAllUsers() ([]models.User, error) {
-	users := s.repo.GetAllUsers()
-	return users, nil
-}
-
-// GetTodosByUser returns todos filtered by user ID
-func (s *TodoService) GetTodosByUser(userID int) ([]models.Todo, error) {
-	if userID <= 0 {
+// GetTodos returns a page of the todos of a workspace matching query (by owner, assignee, list, due date, priority, labels)
+func (s *TodoService) GetTodos(user *models.User, workspaceID int, query TodoQuery) (*TodoPage, error) {
+	filter := query.TodoFilter
+	if filter.UserID < 0 || (filter.AssigneeID != nil && *filter.AssigneeID < 0) {
 		return nil, ErrInvalidUserID
 	}
+	if !filter.Sort.Valid() {
+		return nil, ErrInvalidSort
+	}
+	if query.Cursor != "" {
+		after, err := decodeTodoCursor(query.Cursor, filter.Sort, filter.Desc)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
 
 	// Check if user exists
-	_, err := s.repo.GetUserByID(userID)
+	if filter.UserID != 0 {
+		if _, err := s.users.GetUserByID(filter.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if filter.AssigneeID != nil && *filter.AssigneeID != 0 {
+		if _, err := s.users.GetUserByID(*filter.AssigneeID); err != nil {
+			return nil, err
+		}
+	}
+	// Check if list exists, so an unknown list is a 404 rather than an empty result
+	if filter.ListID != nil && *filter.ListID != 0 {
+		if _, err := s.lists.GetList(workspaceID, *filter.ListID); err != nil {
+			return nil, err
+		}
+	}
+	// Unknown labels are a 404 as well
+	if len(query.Labels) > 0 {
+		ids, err := s.labelIDs(workspaceID, query.Labels)
+		if err != nil {
+			return nil, err
+		}
+		filter.LabelIDs = append(filter.LabelIDs, ids...)
+	}
+	if query.Filter != "" {
+		where, err := s.parseFilter(workspaceID, query.Filter)
+		if err != nil {
+			return nil, err
+		}
+		filter.Where = where
+	}
+
+	// Ask for one more todo than the page holds, to know whether another page follows
+	if filter.Limit > 0 {
+		filter.Limit++
+	}
+	todos, err := s.todos.FindAll(workspaceID, filter)
 	if err != nil {
 		return nil, err
 	}
-
-	todos := s.repo.FindByUserID(userID)
-	return todos, nil
+	page := &TodoPage{}
+	if query.Limit > 0 && len(todos) > query.Limit {
+		todos = todos[:query.Limit]
+		page.NextCursor = encodeTodoCursor(todos[len(todos)-1], filter.Sort, filter.Desc)
+	}
+	if page.Todos, err = s.withComputed(workspaceID, todos); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
-// GetTodoByID returns a single todo by ID
-func (s *TodoService) GetTodoByID(id int) (*models.Todo, error) {
+// GetTodoByID returns a single todo of a workspace by ID
+func (s *TodoService) GetTodoByID(user *models.User, workspaceID, id int) (*models.Todo, error) {
 	if id <= 0 {
 		return nil, errors.New("invalid todo ID")
 	}
 
-	return s.repo.FindByID(id)
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	return s.withTodoComputed(s.todos.FindByID(workspaceID, id))
 }
 
-// CreateTodo creates a new todo
-func (s *TodoService) CreateTodo(req dto.CreateTodoRequest) (*models.Todo, error) {
+// CreateTodo creates a new todo in a workspace, owned by the authenticated user
+func (s *TodoService) CreateTodo(user *models.User, workspaceID int, req dto.CreateTodoRequest) (*models.Todo, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionCreateTodo, nil); err != nil {
+		return nil, err
+	}
+
 	// Validate input
-	if err := s.validateTodoRequest(req); err != nil {
+	if err := s.validateTodoRequest(req, nil); err != nil {
 		return nil, err
 	}
 
-	// Get user information - must exist
-	user, err := s.repo.GetUserByID(req.UserID)
-	if err != nil {
-		return nil, ErrUserNotFound
+	listID := 0
+	if req.ListID != nil {
+		listID = *req.ListID
+	}
+	if err := s.checkTargetList(workspaceID, listID); err != nil {
+		return nil, err
+	}
+	parentID := 0
+	if req.ParentID != nil {
+		parentID = *req.ParentID
+	}
+	if err := s.checkParent(workspaceID, nil, parentID); err != nil {
+		return nil, err
 	}
 
 	// Create todo object
 	now := time.Now()
 	todo := &models.Todo{
-		Text:      strings.TrimSpace(req.Text),
-		Completed: req.Completed,
-		UserID:    req.UserID,
-		CreatedBy: user.Name,
-		CreatedAt: now,
-		UpdatedAt: now,
+		WorkspaceID: workspaceID,
+		ListID:      listID,
+		ParentID:    parentID,
+		Text:        strings.TrimSpace(req.Text),
+		Completed:   req.Completed,
+		UserID:      user.ID,
+		CreatedBy:   user.Name,
+		DueAt:       req.DueAt.Time,
+		RemindAt:    req.RemindAt.Time,
+		Recurrence:  recurrenceFor(req, nil),
+		Priority:    priorityFor(req, nil),
+		LabelIDs:    labelsFor(req, nil),
+		Checklist:   []models.ChecklistItem{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if todo.Recurrence != "" {
+		todo.Occurrence = 1
 	}
 
 	// Save to repository
-	createdTodo, err := s.repo.Create(todo)
+	created, err := s.todos.Create(todo)
 	if err != nil {
 		return nil, err
 	}
-
-	return createdTodo, nil
+	// A recurring todo created completed moves on to its next occurrence, as
+	// completing it afterwards would
+	if created.Completed && created.Recurrence != "" {
+		if created, err = s.completeOccurrence(created); err != nil {
+			return nil, err
+		}
+	}
+	s.notify.todoMentioned(user, created, "")
+	return s.withTodoComputed(created, nil)
 }
 
-// DeleteTodo deletes a todo by ID
-func (s *TodoService) DeleteTodo(id int) error {
+// DeleteTodo deletes a todo by ID. onSubtasks says what happens to its
+// subtasks: block (the default) refuses while there are any, promote moves
+// them up to the todo's parent and cascade deletes them with the todo.
+func (s *TodoService) DeleteTodo(user *models.User, workspaceID, id int, onSubtasks string) error {
 	if id <= 0 {
 		return errors.New("invalid todo ID")
 	}
 
 	// Check if todo exists
-	_, err := s.repo.FindByID(id)
+	todo, err := s.todos.FindByID(workspaceID, id)
 	if err != nil {
 		return err
 	}
 
+	if err := s.authorize(user, workspaceID, policy.ActionDeleteTodo, todo); err != nil {
+		return err
+	}
+
+	switch onSubtasks {
+	case "", SubtasksBlock:
+		subtasks, err := s.todos.FindAll(workspaceID, repository.TodoFilter{ParentID: &id, IncludeArchived: true, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(subtasks) > 0 {
+			return ErrTodoHasSubtasks
+		}
+	case SubtasksPromote:
+	case SubtasksCascade:
+		// Every subtask going with the todo must be deletable by the user as well
+		tree, err := s.loadTree(workspaceID, []models.Todo{*todo})
+		if err != nil {
+			return err
+		}
+		for _, subtask := range tree.descendants(id) {
+			if err := s.authorize(user, workspaceID, policy.ActionDeleteTodo, &subtask); err != nil {
+				return err
+			}
+		}
+		return s.todos.DeleteTree(workspaceID, id)
+	default:
+		return ErrInvalidSubtaskDisposition
+	}
+
 	// Delete the todo
-	return s.repo.Delete(id)
+	return s.todos.Delete(workspaceID, id)
+}
+
+// ToggleOptions change how ToggleTodo completes a todo. CompleteSubtasks also
+// completes all of its open subtasks; Force completes it even while todos it
+// depends on are still open.
+type ToggleOptions struct {
+	CompleteSubtasks bool
+	Force            bool
 }
 
-// ToggleTodo toggles the completed status of a todo
-func (s *TodoService) ToggleTodo(id int) (*models.Todo, error) {
+// ToggleTodo toggles the completed status of a todo. Completing a todo that
+// is blocked by open todos fails with ErrTodoBlocked unless forced.
+func (s *TodoService) ToggleTodo(user *models.User, workspaceID, id int, opts ToggleOptions) (*models.Todo, error) {
 	if id <= 0 {
 		return nil, errors.New("invalid todo ID")
 	}
 
 	// Find the todo
-	todo, err := s.repo.FindByID(id)
+	todo, err := s.todos.FindByID(workspaceID, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.authorize(user, workspaceID, policy.ActionToggleTodo, todo); err != nil {
+		return nil, err
+	}
+
 	// Toggle completed status
 	todo.Completed = !todo.Completed
 	todo.UpdatedAt = time.Now()
 
-	// Update in repository
-	return s.repo.Update(todo)
+	if !todo.Completed {
+		// Update in repository
+		return s.withTodoComputed(s.todos.Update(todo))
+	}
+
+	// Subtasks are all checked before anything changes
+	var subtasks []models.Todo
+	if opts.CompleteSubtasks {
+		tree, err := s.loadTree(workspaceID, []models.Todo{*todo})
+		if err != nil {
+			return nil, err
+		}
+		for _, subtask := range tree.descendants(id) {
+			if subtask.Completed {
+				continue
+			}
+			if err := s.authorize(user, workspaceID, policy.ActionToggleTodo, &subtask); err != nil {
+				return nil, err
+			}
+			subtasks = append(subtasks, subtask)
+		}
+	}
+
+	// Blockers completed along with the todo do not count
+	if !opts.Force {
+		if err := s.checkBlockers(workspaceID, append([]models.Todo{*todo}, subtasks...)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The todo and its subtasks are completed together or not at all;
+	// completing an occurrence of a series schedules the next one
+	completing := []*models.Todo{todo}
+	for i := range subtasks {
+		subtasks[i].Completed = true
+		subtasks[i].UpdatedAt = todo.UpdatedAt
+		completing = append(completing, &subtasks[i])
+	}
+	if err := s.completeOccurrences(completing...); err != nil {
+		return nil, err
+	}
+	s.notify.completed(user, append([]models.Todo{*todo}, subtasks...)...)
+	return s.withTodoComputed(todo, nil)
 }
 
 // UpdateTodo updates a todo
-func (s *TodoService) UpdateTodo(id int, req dto.CreateTodoRequest) (*models.Todo, error) {
+func (s *TodoService) UpdateTodo(user *models.User, workspaceID, id int, req dto.CreateTodoRequest) (*models.Todo, error) {
 	if id <= 0 {
 		return nil, errors.New("invalid todo ID")
 	}
 
-	// Validate input
-	if err := s.validateTodoRequest(req); err != nil {
+	// Find the existing todo
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
 		return nil, err
 	}
 
-	// Find the existing todo
-	todo, err := s.repo.FindByID(id)
-	if err != nil {
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+
+	// Validate input against the todo, schedule fields left out keep their values
+	if err := s.validateTodoRequest(req, todo); err != nil {
 		return nil, err
 	}
 
+	// A list_id in the body moves the todo, leaving it out keeps the current list
+	if req.ListID != nil && *req.ListID != todo.ListID {
+		if err := s.checkTargetList(workspaceID, *req.ListID); err != nil {
+			return nil, err
+		}
+		todo.ListID = *req.ListID
+	}
+	// Likewise parent_id moves the todo under another parent, 0 makes it top-level
+	if req.ParentID != nil && *req.ParentID != todo.ParentID {
+		if err := s.checkParent(workspaceID, todo, *req.ParentID); err != nil {
+			return nil, err
+		}
+		todo.ParentID = *req.ParentID
+	}
+
 	// Update fields
+	completing := req.Completed && !todo.Completed
+	previousText := todo.Text
 	todo.Text = strings.TrimSpace(req.Text)
 	todo.Completed = req.Completed
+	todo.Priority = priorityFor(req, todo)
+	todo.LabelIDs = labelsFor(req, todo)
+	todo.Recurrence = recurrenceFor(req, todo)
+	if todo.Recurrence != "" && todo.Occurrence == 0 {
+		todo.Occurrence = 1
+	}
+	dueAt, remindAt := scheduleFor(req, todo)
+	todo.DueAt = dueAt
+	if !sameInstant(todo.RemindAt, remindAt) {
+		// A rescheduled reminder fires again
+		todo.RemindAt = remindAt
+		todo.RemindedAt = nil
+	}
 	todo.UpdatedAt = time.Now()
 
+	if completing {
+		// Only ToggleTodo can force a blocked todo to completion
+		if err := s.checkBlockers(workspaceID, []models.Todo{*todo}); err != nil {
+			return nil, err
+		}
+		completed, err := s.completeOccurrence(todo)
+		if err != nil {
+			return nil, err
+		}
+		s.notify.todoMentioned(user, completed, previousText)
+		s.notify.completed(user, *completed)
+		return s.withTodoComputed(completed, nil)
+	}
+
 	// Save changes
-	return s.repo.Update(todo)
+	updated, err := s.todos.Update(todo)
+	if err != nil {
+		return nil, err
+	}
+	s.notify.todoMentioned(user, updated, previousText)
+	return s.withTodoComputed(updated, nil)
+}
+
+// StopRecurrence ends the series of a recurring todo. The todo itself is kept,
+// completing it no longer schedules a next occurrence.
+func (s *TodoService) StopRecurrence(user *models.User, workspaceID, id int) (*models.Todo, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid todo ID")
+	}
+
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(user, workspaceID, policy.ActionUpdateTodo, todo); err != nil {
+		return nil, err
+	}
+	if todo.Recurrence == "" {
+		return s.withTodoComputed(todo, nil)
+	}
+
+	todo.Recurrence = ""
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// PreviewOccurrences returns up to n occurrences that will follow a recurring todo
+func (s *TodoService) PreviewOccurrences(user *models.User, workspaceID, id, n int) ([]models.Occurrence, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid todo ID")
+	}
+
+	if err := s.authorize(user, workspaceID, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.Recurrence == "" || todo.DueAt == nil {
+		return nil, ErrNotRecurring
+	}
+
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]models.Occurrence, 0, n)
+	for i, dueAt := range rule.Occurrences(*todo.DueAt, todo.Occurrence, n) {
+		occurrences = append(occurrences, models.Occurrence{
+			Occurrence: todo.Occurrence + i + 1,
+			DueAt:      dueAt,
+			RemindAt:   shiftReminder(todo, dueAt),
+		})
+	}
+	return occurrences, nil
+}
+
+// labelIDs resolves label names of a workspace to their IDs, ignoring case
+func (s *TodoService) labelIDs(workspaceID int, names []string) ([]int, error) {
+	labels, err := s.labels.GetLabels(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, label := range labels {
+			if strings.EqualFold(label.Name, strings.TrimSpace(name)) {
+				ids = append(ids, label.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, repository.ErrLabelNotFound
+		}
+	}
+	return ids, nil
+}
+
+// parseFilter parses a filter expression and resolves its label names to
+// IDs; an unknown label is an *expr.Error like any other invalid value
+func (s *TodoService) parseFilter(workspaceID int, src string) (expr.Node, error) {
+	where, err := expr.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := s.labels.GetLabels(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = expr.Walk(where, func(c *expr.Cond) error {
+		if c.Field != expr.FieldLabel || c.None {
+			return nil
+		}
+		for _, label := range labels {
+			if strings.EqualFold(label.Name, strings.TrimSpace(c.Text)) {
+				c.Int = label.ID
+				return nil
+			}
+		}
+		return c.ValueError(src, "unknown label %q", c.Text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return where, nil
+}
+
+// completeOccurrence saves a todo that was just completed, see completeOccurrences
+func (s *TodoService) completeOccurrence(todo *models.Todo) (*models.Todo, error) {
+	if err := s.completeOccurrences(todo); err != nil {
+		return nil, err
+	}
+	completed := *todo
+	return &completed, nil
+}
+
+// completeOccurrences saves todos that were just completed in one store
+// operation. For a recurring todo the rule moves on to a new todo for the next
+// occurrence, so completing the same occurrence twice (toggling it back and
+// forth) does not repeat it.
+func (s *TodoService) completeOccurrences(todos ...*models.Todo) error {
+	var next []*models.Todo
+	for _, todo := range todos {
+		occurrence, err := nextOccurrence(todo)
+		if err != nil {
+			return err
+		}
+		if occurrence != nil {
+			next = append(next, occurrence)
+		}
+	}
+	return s.todos.SaveTodos(todos, next)
+}
+
+// nextOccurrence takes the rule off a recurring todo and returns the todo of
+// its next occurrence, nil when the todo does not recur or the series ends
+func nextOccurrence(todo *models.Todo) (*models.Todo, error) {
+	if todo.Recurrence == "" || todo.DueAt == nil {
+		return nil, nil
+	}
+
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	nextDue, more := rule.Next(*todo.DueAt, todo.Occurrence)
+
+	next := *todo
+	todo.Recurrence = ""
+	if !more {
+		return nil, nil
+	}
+
+	// Same owner, list and rule; the reminder keeps its distance to the due date
+	next.ID = 0
+	next.Completed = false
+	next.DueAt = &nextDue
+	next.RemindAt = shiftReminder(todo, nextDue)
+	next.RemindedAt = nil
+	next.Checklist = resetChecklist(todo.Checklist)
+	next.Occurrence++
+	next.CreatedAt = todo.UpdatedAt
+	return &next, nil
+}
+
+// shiftReminder returns the reminder of the occurrence due at dueAt, as far
+// ahead of it as todo's reminder is ahead of todo's due date
+func shiftReminder(todo *models.Todo, dueAt time.Time) *time.Time {
+	if todo.RemindAt == nil || todo.DueAt == nil {
+		return nil
+	}
+	remindAt := dueAt.Add(todo.RemindAt.Sub(*todo.DueAt)).In(todo.RemindAt.Location())
+	return &remindAt
+}
+
+// MoveTodo moves a todo into another list of its workspace, or out of its list with listID 0
+func (s *TodoService) MoveTodo(user *models.User, workspaceID, id int, req dto.MoveTodoRequest) (*models.Todo, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid todo ID")
+	}
+
+	// Find the todo
+	todo, err := s.todos.FindByID(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(user, workspaceID, policy.ActionMoveTodo, todo); err != nil {
+		return nil, err
+	}
+	if req.ListID == todo.ListID {
+		return s.withTodoComputed(todo, nil)
+	}
+	if err := s.checkTargetList(workspaceID, req.ListID); err != nil {
+		return nil, err
+	}
+
+	todo.ListID = req.ListID
+	todo.UpdatedAt = time.Now()
+	return s.withTodoComputed(s.todos.Update(todo))
+}
+
+// checkTargetList verifies a todo may be put into listID (0 = no list):
+// the list must belong to the workspace and must not be archived
+func (s *TodoService) checkTargetList(workspaceID, listID int) error {
+	if listID == 0 {
+		return nil
+	}
+	list, err := s.lists.GetList(workspaceID, listID)
+	if err != nil {
+		return err
+	}
+	if list.Archived {
+		return ErrListArchived
+	}
+	return nil
 }
 
-// validateTodoRequest validates a todo creation/update request
-func (s *TodoService) validateTodoRequest(req dto.CreateTodoRequest) error {
+// authorize checks the policy rules for user acting on todo (nil for create
+// and listing) with the role they hold in the workspace
+func (s *TodoService) authorize(user *models.User, workspaceID int, action policy.Action, todo *models.Todo) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	if todo == nil {
+		// Pass an untyped nil, a nil *Todo inside the interface is not nil
+		return can(actor, action, nil)
+	}
+	return can(actor, action, todo)
+}
+
+// authorize checks the policy rules for user acting on resource with their global role
+func authorize(user *models.User, action policy.Action, resource policy.Resource) error {
+	if user == nil {
+		return ErrUnauthenticated
+	}
+	return can(policy.NewActor(user), action, resource)
+}
+
+// can maps a policy decision to ErrUnauthorized
+func can(actor policy.Actor, action policy.Action, resource policy.Resource) error {
+	if !policy.Can(actor, action, resource) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// workspaceActor resolves the role user holds inside a workspace. Workspaces
+// the user cannot see are reported as not found, so their existence is not revealed.
+func workspaceActor(workspaces repository.WorkspaceStore, user *models.User, workspaceID int) (policy.Actor, error) {
+	if user == nil {
+		return policy.Actor{}, ErrUnauthenticated
+	}
+
+	membership, err := workspaces.GetMembership(workspaceID, user.ID)
+	if err != nil && !errors.Is(err, repository.ErrMembershipNotFound) {
+		return policy.Actor{}, err
+	}
+
+	actor := policy.NewMemberActor(user, membership)
+	if membership == nil {
+		if actor.Role != policy.RoleAdmin {
+			return policy.Actor{}, repository.ErrWorkspaceNotFound
+		}
+		if _, err := workspaces.GetWorkspace(workspaceID); err != nil {
+			return policy.Actor{}, err
+		}
+	}
+	return actor, nil
+}
+
+// validateTodoRequest validates a todo creation/update request.
+// current is the todo being updated, nil on create.
+func (s *TodoService) validateTodoRequest(req dto.CreateTodoRequest, current *models.Todo) error {
 	// Validate text
 	if strings.TrimSpace(req.Text) == "" {
 		return ErrInvalidTodoText
 	}
 
-	// Validate user ID
-	if req.UserID <= 0 {
-		return ErrInvalidUserID
+	// A reminder after the deadline is useless; compare as instants, offsets may differ
+	dueAt, remindAt := scheduleFor(req, current)
+	if dueAt != nil && remindAt != nil && remindAt.After(*dueAt) {
+		return ErrReminderAfterDue
+	}
+
+	if !priorityFor(req, current).Valid() {
+		return ErrInvalidPriority
+	}
+
+	// Occurrences are counted from the due date
+	if rule := recurrenceFor(req, current); rule != "" {
+		if _, err := recurrence.Parse(rule); err != nil {
+			return err
+		}
+		if dueAt == nil {
+			return ErrRecurrenceNeedsDue
+		}
 	}
 
 	return nil
 }
+
+// priorityFor returns the priority a request results in; left out it keeps
+// the priority of current, or "none" on create
+func priorityFor(req dto.CreateTodoRequest, current *models.Todo) models.Priority {
+	if req.Priority == nil {
+		if current != nil {
+			return current.Priority
+		}
+		return models.PriorityNone
+	}
+	return models.Priority(strings.ToLower(strings.TrimSpace(*req.Priority)))
+}
+
+// labelsFor returns the label IDs a request results in; left out it keeps
+// the labels of current, or none on create
+func labelsFor(req dto.CreateTodoRequest, current *models.Todo) []int {
+	if req.LabelIDs == nil {
+		if current != nil {
+			return current.LabelIDs
+		}
+		return []int{}
+	}
+	return *req.LabelIDs
+}
+
+// recurrenceFor returns the canonical rule a request results in ("" for none).
+// Leaving it out of the request keeps the rule of current (nil on create).
+// An invalid rule is returned as given, validateTodoRequest rejects it.
+func recurrenceFor(req dto.CreateTodoRequest, current *models.Todo) string {
+	if req.Recurrence == nil {
+		if current != nil {
+			return current.Recurrence
+		}
+		return ""
+	}
+	rule, err := recurrence.Parse(*req.Recurrence)
+	if err != nil {
+		return strings.TrimSpace(*req.Recurrence)
+	}
+	return rule.String()
+}
+
+// scheduleFor returns the due and reminder times a request results in.
+// Fields left out of the request keep the values of current (nil on create).
+func scheduleFor(req dto.CreateTodoRequest, current *models.Todo) (dueAt, remindAt *time.Time) {
+	if current != nil {
+		dueAt, remindAt = current.DueAt, current.RemindAt
+	}
+	if req.DueAt.Set {
+		dueAt = req.DueAt.Time
+	}
+	if req.RemindAt.Set {
+		remindAt = req.RemindAt.Time
+	}
+	return dueAt, remindAt
+}
+
+// sameInstant reports whether two optional timestamps are both unset or the same instant
+func sameInstant(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
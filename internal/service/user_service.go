@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"test_mekari/internal/auth"
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+const minPasswordLength = 8
+
+var (
+	ErrInvalidName         = errors.New("name cannot be empty")
+	ErrInvalidEmail        = errors.New("email is not a valid address")
+	ErrEmailTaken          = errors.New("email is already in use")
+	ErrWeakPassword        = errors.New("password must be at least 8 characters")
+	ErrInvalidRole         = errors.New("role must be admin, member or viewer")
+	ErrInvalidDisposition  = errors.New("on_todos must be block, reassign or cascade")
+	ErrInvalidReassignUser = errors.New("reassign_to must be another existing user")
+	ErrUserHasTodos        = errors.New("user still owns todos, reassign or cascade them")
+	ErrCannotModifySelf    = errors.New("you cannot change the role of, deactivate or delete your own account")
+)
+
+// Todo dispositions accepted by DeleteUser
+const (
+	DispositionBlock    = "block"
+	DispositionReassign = "reassign"
+	DispositionCascade  = "cascade"
+)
+
+// UserService handles business logic for user accounts
+type UserService struct {
+	users repository.UserStore
+}
+
+// NewUserService creates a new instance of UserService
+func NewUserService(users repository.UserStore) *UserService {
+	return &UserService{users: users}
+}
+
+// GetAllUsers returns all users
+func (s *UserService) GetAllUsers() ([]models.User, error) {
+	return s.users.GetAllUsers()
+}
+
+// GetUserByID returns a single user by ID
+func (s *UserService) GetUserByID(id int) (*models.User, error) {
+	if id <= 0 {
+		return nil, ErrInvalidUserID
+	}
+	return s.users.GetUserByID(id)
+}
+
+// CreateUser creates a new account; only admins may do this
+func (s *UserService) CreateUser(actor *models.User, req dto.CreateUserRequest) (*models.User, error) {
+	if err := authorize(actor, policy.ActionCreateUser, nil); err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = string(policy.RoleMember)
+	}
+	name, email, err := validateUserFields(req.Name, req.Email, role)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Password) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.CreateUser(&models.User{
+		Name:         name,
+		Email:        email,
+		Role:         role,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	})
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return nil, ErrEmailTaken
+	}
+	return user, err
+}
+
+// UpdateUser changes a user's profile. Users may edit themselves; changing a
+// role needs an admin. Renaming also renames CreatedBy on the user's todos.
+func (s *UserService) UpdateUser(actor *models.User, id int, req dto.UpdateUserRequest) (*models.User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(actor, policy.ActionUpdateUser, user); err != nil {
+		return nil, err
+	}
+
+	role := user.Role
+	if req.Role != "" && req.Role != user.Role {
+		if err := authorize(actor, policy.ActionManageUser, user); err != nil {
+			return nil, err
+		}
+		// An admin demoting themselves could leave nobody able to manage users
+		if actor.ID == user.ID {
+			return nil, ErrCannotModifySelf
+		}
+		role = req.Role
+	}
+
+	name, email, err := validateUserFields(req.Name, req.Email, role)
+	if err != nil {
+		return nil, err
+	}
+	user.Name = name
+	user.Email = email
+	user.Role = role
+
+	if req.Password != "" {
+		if len(req.Password) < minPasswordLength {
+			return nil, ErrWeakPassword
+		}
+		if user.PasswordHash, err = auth.HashPassword(req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.users.UpdateUser(user)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return nil, ErrEmailTaken
+	}
+	return updated, err
+}
+
+// SetDeactivated deactivates or reactivates an account. Deactivated users
+// keep their todos but can no longer log in or use existing credentials.
+func (s *UserService) SetDeactivated(actor *models.User, id int, deactivated bool) (*models.User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(actor, policy.ActionManageUser, user); err != nil {
+		return nil, err
+	}
+	if actor.ID == user.ID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user.Deactivated = deactivated
+	return s.users.UpdateUser(user)
+}
+
+// DeleteUser deletes an account. onTodos decides what happens to the user's
+// todos: block (the default) refuses while any remain, reassign moves them to
+// reassignTo and cascade deletes them.
+func (s *UserService) DeleteUser(actor *models.User, id int, onTodos string, reassignTo int) error {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if err := authorize(actor, policy.ActionDeleteUser, user); err != nil {
+		return err
+	}
+	if actor.ID == user.ID {
+		return ErrCannotModifySelf
+	}
+
+	var disposition repository.TodoDisposition
+	switch onTodos {
+	case "", DispositionBlock:
+	case DispositionCascade:
+		disposition.Cascade = true
+	case DispositionReassign:
+		if reassignTo <= 0 || reassignTo == id {
+			return ErrInvalidReassignUser
+		}
+		disposition.ReassignTo = reassignTo
+	default:
+		return ErrInvalidDisposition
+	}
+
+	err = s.users.DeleteUser(id, disposition)
+	switch {
+	case errors.Is(err, repository.ErrUserHasTodos):
+		return ErrUserHasTodos
+	case errors.Is(err, repository.ErrUserNotFound) && disposition.ReassignTo != 0:
+		// The user itself was found above, so the missing one is the target
+		return ErrInvalidReassignUser
+	}
+	return err
+}
+
+// validateUserFields trims and validates the fields shared by create and update
+func validateUserFields(name, email, role string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", ErrInvalidName
+	}
+
+	email = strings.TrimSpace(email)
+	// Reject display-name forms like "Jane <jane@example.com>", only a bare address is stored
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", "", ErrInvalidEmail
+	}
+
+	if !policy.IsAssignable(policy.Role(role)) {
+		return "", "", ErrInvalidRole
+	}
+	return name, email, nil
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidViewName = errors.New("view name cannot be empty")
+	ErrInvalidGroupBy  = errors.New("group_by must be completed, priority, user_id, assignee, list_id or label")
+)
+
+// ViewPage is a page of the todos of a view, grouped the way the view groups them
+type ViewPage struct {
+	View *models.View
+	TodoPage
+	// Groups holds the todos of this page per group, nil without grouping
+	Groups []TodoGroup
+}
+
+// TodoGroup is the todos sharing a value of the field a view groups by
+type TodoGroup struct {
+	Key     any   `json:"key"`      // the value, null for todos without one (no list, no assignee, ...)
+	TodoIDs []int `json:"todo_ids"` // in the order of the view
+}
+
+// ViewService handles business logic for saved views
+type ViewService struct {
+	views      repository.ViewStore
+	workspaces repository.WorkspaceStore
+	todos      *TodoService
+}
+
+// NewViewService creates a new instance of ViewService. Views are run
+// through todos, so they filter and sort exactly as GET /todos does.
+func NewViewService(store repository.Store, todos *TodoService) *ViewService {
+	return &ViewService{
+		views:      store,
+		workspaces: store,
+		todos:      todos,
+	}
+}
+
+// GetViews returns the views user saved and the views shared with them, in
+// one workspace, or in every workspace they belong to when workspaceID is 0
+func (s *ViewService) GetViews(user *models.User, workspaceID int) ([]models.View, error) {
+	if user == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	workspaceIDs := []int{workspaceID}
+	if workspaceID == 0 {
+		workspaces, err := s.workspaces.GetWorkspacesForUser(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		workspaceIDs = workspaceIDs[:0]
+		for _, workspace := range workspaces {
+			workspaceIDs = append(workspaceIDs, workspace.ID)
+		}
+	}
+
+	views := make([]models.View, 0)
+	for _, id := range workspaceIDs {
+		actor, err := workspaceActor(s.workspaces, user, id)
+		if err != nil {
+			return nil, err
+		}
+		if !policy.Can(actor, policy.ActionViewTodo, nil) {
+			// Listing every workspace skips those whose todos are hidden
+			if workspaceID == 0 {
+				continue
+			}
+			return nil, ErrUnauthorized
+		}
+		found, err := s.views.GetViews(id, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, found...)
+	}
+	return views, nil
+}
+
+// GetView returns a view user saved or that is shared with them
+func (s *ViewService) GetView(user *models.User, id int) (*models.View, error) {
+	view, _, err := s.visibleView(user, id)
+	return view, err
+}
+
+// CreateView saves a view for user, checking its filter, sort and grouping
+func (s *ViewService) CreateView(user *models.User, req dto.ViewRequest) (*models.View, error) {
+	workspaceID := req.WorkspaceID
+	if workspaceID == 0 {
+		workspaceID = repository.DefaultWorkspaceID
+	}
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := can(actor, policy.ActionViewTodo, nil); err != nil {
+		return nil, err
+	}
+
+	view := &models.View{WorkspaceID: workspaceID, UserID: user.ID}
+	if err := s.applyRequest(view, req); err != nil {
+		return nil, err
+	}
+	view.Shared = req.Shared != nil && *req.Shared
+	view.CreatedAt = time.Now()
+	view.UpdatedAt = view.CreatedAt
+	return s.views.CreateView(view)
+}
+
+// UpdateView changes the name, filter, sort and grouping of a view of user,
+// and whether it is shared when given
+func (s *ViewService) UpdateView(user *models.User, id int, req dto.ViewRequest) (*models.View, error) {
+	view, actor, err := s.visibleView(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := can(actor, policy.ActionUpdateView, view); err != nil {
+		return nil, err
+	}
+
+	if err := s.applyRequest(view, req); err != nil {
+		return nil, err
+	}
+	if req.Shared != nil {
+		view.Shared = *req.Shared
+	}
+	view.UpdatedAt = time.Now()
+	return s.views.UpdateView(view)
+}
+
+// DeleteView deletes a view of user; workspace admins may delete shared views
+func (s *ViewService) DeleteView(user *models.User, id int) error {
+	view, actor, err := s.visibleView(user, id)
+	if err != nil {
+		return err
+	}
+	if err := can(actor, policy.ActionDeleteView, view); err != nil {
+		return err
+	}
+	return s.views.DeleteView(id)
+}
+
+// GetViewTodos runs a view: a page of the todos of its workspace matching its
+// filter in its sort order, grouped. page carries the limit and cursor.
+func (s *ViewService) GetViewTodos(user *models.User, id int, page TodoQuery) (*ViewPage, error) {
+	view, _, err := s.visibleView(user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := TodoQuery{Filter: view.Filter, Cursor: page.Cursor}
+	query.Limit = page.Limit
+	if query.Sort, query.Desc, err = ParseTodoSort(view.Sort); err != nil {
+		return nil, err
+	}
+	todos, err := s.todos.GetTodos(user, view.WorkspaceID, query)
+	if err != nil {
+		return nil, err
+	}
+	return &ViewPage{View: view, TodoPage: *todos, Groups: groupTodos(todos.Todos, view.GroupBy)}, nil
+}
+
+// visibleView returns a view with the role user holds in its workspace. Views
+// of others that are not shared, or whose todos user may not see, are not found.
+func (s *ViewService) visibleView(user *models.User, id int) (*models.View, policy.Actor, error) {
+	if user == nil {
+		return nil, policy.Actor{}, ErrUnauthenticated
+	}
+	view, err := s.views.GetView(id)
+	if err != nil {
+		return nil, policy.Actor{}, err
+	}
+	if view.UserID != user.ID && !view.Shared {
+		return nil, policy.Actor{}, repository.ErrViewNotFound
+	}
+
+	actor, err := workspaceActor(s.workspaces, user, view.WorkspaceID)
+	if errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return nil, policy.Actor{}, repository.ErrViewNotFound
+	}
+	if err != nil {
+		return nil, policy.Actor{}, err
+	}
+	if !policy.Can(actor, policy.ActionViewTodo, nil) {
+		return nil, policy.Actor{}, repository.ErrViewNotFound
+	}
+	return view, actor, nil
+}
+
+// applyRequest validates the name, filter, sort and grouping of req and sets
+// them on view. The filter is parsed against the labels of the view's workspace.
+func (s *ViewService) applyRequest(view *models.View, req dto.ViewRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return ErrInvalidViewName
+	}
+	filter := strings.TrimSpace(req.Filter)
+	if _, err := s.todos.parseFilter(view.WorkspaceID, filter); err != nil {
+		return err
+	}
+	sortOrder := strings.ToLower(strings.TrimSpace(req.Sort))
+	if _, _, err := ParseTodoSort(sortOrder); err != nil {
+		return err
+	}
+	groupBy := models.GroupBy(strings.ToLower(strings.TrimSpace(req.GroupBy)))
+	if !groupBy.Valid() {
+		return ErrInvalidGroupBy
+	}
+
+	view.Name = name
+	view.Filter = filter
+	view.Sort = sortOrder
+	view.GroupBy = groupBy
+	return nil
+}
+
+// groupTodos groups todos by a field, keeping their order within each group.
+// Groups come in a fixed order: open before completed, urgent to none, and
+// IDs ascending with the group of todos without one last.
+func groupTodos(todos []models.Todo, by models.GroupBy) []TodoGroup {
+	if by == models.GroupByNone {
+		return nil
+	}
+
+	groups := make(map[any]*TodoGroup)
+	order := make(map[any]int)
+	add := func(todoID int, key any, rank int) {
+		group, ok := groups[key]
+		if !ok {
+			group = &TodoGroup{Key: key, TodoIDs: []int{}}
+			groups[key] = group
+			order[key] = rank
+		}
+		group.TodoIDs = append(group.TodoIDs, todoID)
+	}
+	// IDs group by themselves, none (0) last
+	addIDs := func(todoID int, ids ...int) {
+		if len(ids) == 0 || (len(ids) == 1 && ids[0] == 0) {
+			add(todoID, nil, math.MaxInt)
+			return
+		}
+		for _, id := range ids {
+			add(todoID, id, id)
+		}
+	}
+
+	for _, todo := range todos {
+		switch by {
+		case models.GroupByCompleted:
+			rank := 0
+			if todo.Completed {
+				rank = 1
+			}
+			add(todo.ID, todo.Completed, rank)
+		case models.GroupByPriority:
+			add(todo.ID, todo.Priority, -todo.Priority.Rank())
+		case models.GroupByOwner:
+			addIDs(todo.ID, todo.UserID)
+		case models.GroupByAssignee:
+			addIDs(todo.ID, todo.AssigneeIDs...)
+		case models.GroupByList:
+			addIDs(todo.ID, todo.ListID)
+		case models.GroupByLabel:
+			addIDs(todo.ID, todo.LabelIDs...)
+		}
+	}
+
+	sorted := make([]TodoGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return order[sorted[i].Key] < order[sorted[j].Key] })
+	return sorted
+}
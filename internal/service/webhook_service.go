@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/events"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/webhook"
+)
+
+var (
+	ErrInvalidWebhookURL    = errors.New("webhook url must be an absolute http or https URL")
+	ErrUnknownWebhookHost   = errors.New("webhook host cannot be resolved")
+	ErrInvalidWebhookEvents = errors.New("webhook events must list at least one of todo.created, todo.updated, todo.toggled and todo.deleted")
+	ErrInvalidWebhookSecret = errors.New("webhook secret must be at least 16 characters")
+	ErrDeliveryPending      = errors.New("delivery is still pending, it will be retried")
+)
+
+// minWebhookSecret is the shortest secret a client may choose
+const minWebhookSecret = 16
+
+// WebhookService handles business logic for the webhooks of a workspace
+type WebhookService struct {
+	webhooks   repository.WebhookStore
+	workspaces repository.WorkspaceStore
+	addresses  *webhook.AddressFilter
+}
+
+// NewWebhookService creates a new instance of WebhookService; webhook URLs
+// must point at hosts addresses lets deliveries reach
+func NewWebhookService(store repository.Store, addresses *webhook.AddressFilter) *WebhookService {
+	return &WebhookService{
+		webhooks:   store,
+		workspaces: store,
+		addresses:  addresses,
+	}
+}
+
+// GetWebhooks returns the webhooks of a workspace, without their secrets
+func (s *WebhookService) GetWebhooks(user *models.User, workspaceID int) ([]models.Webhook, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhooks.GetWebhooks(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a single webhook of a workspace, without its secret
+func (s *WebhookService) GetWebhook(user *models.User, workspaceID, id int) (*models.Webhook, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+
+	hook, err := s.webhooks.GetWebhook(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+// CreateWebhook creates a webhook in a workspace. The secret is returned this
+// once, so the receiver can be set up to verify signatures.
+func (s *WebhookService) CreateWebhook(user *models.User, workspaceID int, req dto.WebhookRequest) (*models.Webhook, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+
+	webhookURL, eventTypes, err := s.validateWebhookRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	now := time.Now()
+	return s.webhooks.CreateWebhook(&models.Webhook{
+		WorkspaceID: workspaceID,
+		URL:         webhookURL,
+		Events:      eventTypes,
+		Secret:      secret,
+		Active:      active,
+		CreatedBy:   user.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+// UpdateWebhook changes the URL and events of a webhook, and its secret and
+// active flag when given. The secret is only returned when it was rotated.
+func (s *WebhookService) UpdateWebhook(user *models.User, workspaceID, id int, req dto.WebhookRequest) (*models.Webhook, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+
+	webhookURL, eventTypes, err := s.validateWebhookRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := s.webhooks.GetWebhook(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	hook.URL = webhookURL
+	hook.Events = eventTypes
+	rotated := strings.TrimSpace(req.Secret) != ""
+	if rotated {
+		hook.Secret = strings.TrimSpace(req.Secret)
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	hook.UpdatedAt = time.Now()
+
+	updated, err := s.webhooks.UpdateWebhook(hook)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		updated.Secret = ""
+	}
+	return updated, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(user *models.User, workspaceID, id int) error {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return err
+	}
+	return s.webhooks.DeleteWebhook(workspaceID, id)
+}
+
+// GetDeliveries returns the latest limit deliveries of a webhook, newest first
+func (s *WebhookService) GetDeliveries(user *models.User, workspaceID, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.GetDeliveries(workspaceID, webhookID, limit)
+}
+
+// GetDelivery returns a single delivery of a webhook with all its attempts
+func (s *WebhookService) GetDelivery(user *models.User, workspaceID, webhookID, id int) (*models.WebhookDelivery, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.GetDelivery(workspaceID, webhookID, id)
+}
+
+// Redeliver queues the payload of a completed delivery again as a new
+// delivery, e.g. once a receiver that was down for too long is back
+func (s *WebhookService) Redeliver(user *models.User, workspaceID, webhookID, id int) (*models.WebhookDelivery, error) {
+	if err := s.authorize(user, workspaceID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.webhooks.GetDelivery(workspaceID, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	now := time.Now()
+	return s.webhooks.CreateDelivery(&models.WebhookDelivery{
+		WebhookID:     delivery.WebhookID,
+		WorkspaceID:   delivery.WorkspaceID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Payload:       delivery.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	})
+}
+
+// authorize checks that user may manage the webhooks of the workspace
+func (s *WebhookService) authorize(user *models.User, workspaceID int) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	return can(actor, policy.ActionManageWebhook, nil)
+}
+
+// validateWebhookRequest returns the URL and the deduplicated event types of a request
+func (s *WebhookService) validateWebhookRequest(req dto.WebhookRequest) (string, []string, error) {
+	rawURL := strings.TrimSpace(req.URL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", nil, ErrInvalidWebhookURL
+	}
+	// Deliveries check every address they connect to as well, this only
+	// refuses a host that is known to be out of reach already
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.addresses.CheckHost(ctx, parsed.Hostname()); err != nil {
+		if errors.Is(err, webhook.ErrForbiddenAddress) {
+			return "", nil, err
+		}
+		return "", nil, ErrUnknownWebhookHost
+	}
+
+	if len(req.Events) == 0 {
+		return "", nil, ErrInvalidWebhookEvents
+	}
+	seen := make(map[string]bool)
+	eventTypes := make([]string, 0, len(req.Events))
+	for _, raw := range req.Events {
+		eventType := strings.TrimSpace(raw)
+		if !webhookEventType(eventType) {
+			return "", nil, ErrInvalidWebhookEvents
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	if secret := strings.TrimSpace(req.Secret); secret != "" && len(secret) < minWebhookSecret {
+		return "", nil, ErrInvalidWebhookSecret
+	}
+	return rawURL, eventTypes, nil
+}
+
+// webhookEventType reports whether webhooks can subscribe to eventType
+func webhookEventType(eventType string) bool {
+	for _, t := range webhook.EventTypes {
+		if events.Type(eventType) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// newWebhookSecret generates a random secret for a webhook created without one
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"test_mekari/internal/dto"
+	"test_mekari/internal/models"
+	"test_mekari/internal/policy"
+	"test_mekari/internal/repository"
+)
+
+var (
+	ErrInvalidWorkspaceName   = errors.New("workspace name cannot be empty")
+	ErrDefaultWorkspaceLocked = errors.New("the default workspace cannot be deleted")
+	ErrCannotModifyOwnMember  = errors.New("you cannot change or remove your own membership")
+)
+
+// WorkspaceService handles business logic for workspaces and their members
+type WorkspaceService struct {
+	workspaces repository.WorkspaceStore
+}
+
+// NewWorkspaceService creates a new instance of WorkspaceService
+func NewWorkspaceService(workspaces repository.WorkspaceStore) *WorkspaceService {
+	return &WorkspaceService{
+		workspaces: workspaces,
+	}
+}
+
+// ListWorkspaces returns the workspaces user belongs to; global admins see all of them
+func (s *WorkspaceService) ListWorkspaces(user *models.User) ([]models.Workspace, error) {
+	if user == nil {
+		return nil, ErrUnauthenticated
+	}
+	if policy.NewActor(user).Role == policy.RoleAdmin {
+		return s.workspaces.GetAllWorkspaces()
+	}
+	return s.workspaces.GetWorkspacesForUser(user.ID)
+}
+
+// GetWorkspace returns a workspace user can see
+func (s *WorkspaceService) GetWorkspace(user *models.User, workspaceID int) (*models.Workspace, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewWorkspace); err != nil {
+		return nil, err
+	}
+	return s.workspaces.GetWorkspace(workspaceID)
+}
+
+// CreateWorkspace creates a workspace with user as its first admin
+func (s *WorkspaceService) CreateWorkspace(user *models.User, req dto.CreateWorkspaceRequest) (*models.Workspace, error) {
+	if err := authorize(user, policy.ActionCreateWorkspace, nil); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidWorkspaceName
+	}
+
+	now := time.Now()
+	return s.workspaces.CreateWorkspace(
+		&models.Workspace{Name: name, CreatedAt: now},
+		models.Membership{UserID: user.ID, Role: string(policy.RoleAdmin), CreatedAt: now},
+	)
+}
+
+// DeleteWorkspace deletes a workspace together with its todos
+func (s *WorkspaceService) DeleteWorkspace(user *models.User, workspaceID int) error {
+	if err := s.authorize(user, workspaceID, policy.ActionManageWorkspace); err != nil {
+		return err
+	}
+	if workspaceID == repository.DefaultWorkspaceID {
+		return ErrDefaultWorkspaceLocked
+	}
+	return s.workspaces.DeleteWorkspace(workspaceID)
+}
+
+// GetMembers lists the members of a workspace
+func (s *WorkspaceService) GetMembers(user *models.User, workspaceID int) ([]models.Membership, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionViewWorkspace); err != nil {
+		return nil, err
+	}
+	return s.workspaces.GetMembers(workspaceID)
+}
+
+// SaveMember adds a user to a workspace or changes their role in it
+func (s *WorkspaceService) SaveMember(user *models.User, workspaceID, memberID int, req dto.MembershipRequest) (*models.Membership, error) {
+	if err := s.authorize(user, workspaceID, policy.ActionManageWorkspace); err != nil {
+		return nil, err
+	}
+	// Admins changing their own role could leave the workspace without an admin
+	if memberID == user.ID {
+		return nil, ErrCannotModifyOwnMember
+	}
+
+	role := req.Role
+	if role == "" {
+		role = string(policy.RoleMember)
+	}
+	if !policy.IsAssignable(policy.Role(role)) {
+		return nil, ErrInvalidRole
+	}
+
+	return s.workspaces.SaveMembership(&models.Membership{
+		WorkspaceID: workspaceID,
+		UserID:      memberID,
+		Role:        role,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// RemoveMember removes a user from a workspace. Their todos stay in the workspace.
+func (s *WorkspaceService) RemoveMember(user *models.User, workspaceID, memberID int) error {
+	if err := s.authorize(user, workspaceID, policy.ActionManageWorkspace); err != nil {
+		return err
+	}
+	if memberID == user.ID {
+		return ErrCannotModifyOwnMember
+	}
+	return s.workspaces.RemoveMembership(workspaceID, memberID)
+}
+
+// authorize checks action against the role user holds in the workspace
+func (s *WorkspaceService) authorize(user *models.User, workspaceID int, action policy.Action) error {
+	actor, err := workspaceActor(s.workspaces, user, workspaceID)
+	if err != nil {
+		return err
+	}
+	return can(actor, action, nil)
+}
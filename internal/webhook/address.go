@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for a webhook host that is, or resolves to,
+// an address of the server's own network
+var ErrForbiddenAddress = errors.New("webhook host must not be a loopback, private, link-local or unspecified address")
+
+// AddressFilter keeps webhooks away from the network the server runs in:
+// loopback, private, link-local and unspecified addresses are refused unless
+// one of the allowed networks contains them
+type AddressFilter struct {
+	allowed []*net.IPNet
+}
+
+// NewAddressFilter creates a filter that lets webhooks reach the allowed
+// networks, private or not
+func NewAddressFilter(allowed []*net.IPNet) *AddressFilter {
+	return &AddressFilter{allowed: allowed}
+}
+
+// Permits reports whether webhooks may reach ip
+func (f *AddressFilter) Permits(ip net.IP) bool {
+	for _, network := range f.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast()
+}
+
+// CheckHost resolves host and returns ErrForbiddenAddress when one of its
+// addresses is refused. It gives early feedback on a webhook URL; what
+// counts is the check made on every connection a delivery opens.
+func (f *AddressFilter) CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !f.Permits(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// control is a net.Dialer.Control refusing connections to refused addresses.
+// It runs for every address a dial tries, after the name was resolved, so a
+// name that resolves differently at delivery time (DNS rebinding) gets no
+// further than one checked when the webhook was registered.
+func (f *AddressFilter) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !f.Permits(ip) {
+		return fmt.Errorf("dial %s %s: %w", network, address, ErrForbiddenAddress)
+	}
+	return nil
+}
+
+// ParseNetworks parses a comma-separated list of networks in CIDR notation
+// or single addresses, as in "10.1.0.0/16,192.168.1.20"
+func ParseNetworks(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", part)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestAddressFilterPermits(t *testing.T) {
+	allowed, err := ParseNetworks("10.1.0.0/16, 192.168.1.20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := []struct {
+		ip       string
+		plain    bool // permitted without allowed networks
+		withList bool // permitted with allowed
+	}{
+		{"93.184.216.34", true, true},
+		{"2606:2800:220:1::1", true, true},
+		{"127.0.0.1", false, false},
+		{"::1", false, false},
+		{"::ffff:127.0.0.1", false, false},
+		{"0.0.0.0", false, false},
+		{"::", false, false},
+		{"10.0.0.1", false, false},
+		{"10.1.2.3", false, true},
+		{"172.16.0.1", false, false},
+		{"192.168.1.20", false, true},
+		{"192.168.1.21", false, false},
+		{"fd00::1", false, false},
+		{"169.254.169.254", false, false},
+		{"fe80::1", false, false},
+	}
+	plain := NewAddressFilter(nil)
+	withList := NewAddressFilter(allowed)
+	for _, row := range table {
+		ip := net.ParseIP(row.ip)
+		if got := plain.Permits(ip); got != row.plain {
+			t.Errorf("Permits(%s) = %v, want %v", row.ip, got, row.plain)
+		}
+		if got := withList.Permits(ip); got != row.withList {
+			t.Errorf("Permits(%s) with allowed networks = %v, want %v", row.ip, got, row.withList)
+		}
+	}
+}
+
+func TestAddressFilterControl(t *testing.T) {
+	filter := NewAddressFilter(nil)
+	table := []struct {
+		address string
+		refused bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1::1]:443", false},
+		{"127.0.0.1:8080", true},
+		{"[::1]:8080", true},
+		{"169.254.169.254:80", true},
+	}
+	for _, row := range table {
+		err := filter.control("tcp", row.address, nil)
+		if refused := errors.Is(err, ErrForbiddenAddress); refused != row.refused {
+			t.Errorf("control(%s) = %v, want refused %v", row.address, err, row.refused)
+		}
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	table := []struct {
+		in    string
+		want  []string
+		valid bool
+	}{
+		{"", nil, true},
+		{"10.0.0.0/8", []string{"10.0.0.0/8"}, true},
+		{" 192.168.1.20 , fd00::/8", []string{"192.168.1.20/32", "fd00::/8"}, true},
+		{"::1", []string{"::1/128"}, true},
+		{"10.0.0.0/33", nil, false},
+		{"localhost", nil, false},
+	}
+	for _, row := range table {
+		networks, err := ParseNetworks(row.in)
+		if (err == nil) != row.valid {
+			t.Errorf("ParseNetworks(%q) err = %v, want valid %v", row.in, err, row.valid)
+			continue
+		}
+		got := make([]string, len(networks))
+		for i, network := range networks {
+			got[i] = network.String()
+		}
+		if row.valid && fmt.Sprint(got) != fmt.Sprint(row.want) {
+			t.Errorf("ParseNetworks(%q) = %v, want %v", row.in, got, row.want)
+		}
+	}
+}
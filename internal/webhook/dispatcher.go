@@ -0,0 +1,381 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"test_mekari/internal/events"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+)
+
+// Config tunes delivery
+type Config struct {
+	MaxAttempts  int           // attempts before a delivery goes dead
+	RetryBase    time.Duration // wait after the first failed attempt, doubled after every next one
+	RetryMax     time.Duration // longest wait between two attempts
+	Timeout      time.Duration // for one attempt, including reading the response
+	PollInterval time.Duration // how often due retries are looked for
+	Workers      int           // attempts made at the same time
+	Retention    time.Duration // how long succeeded and dead deliveries are kept
+
+	// AllowedNetworks may be reached even though they are loopback, private
+	// or link-local, which deliveries are otherwise refused
+	AllowedNetworks []*net.IPNet
+}
+
+// DefaultConfig retries for about two hours before a delivery goes dead
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:  8,
+		RetryBase:    time.Minute,
+		RetryMax:     time.Hour,
+		Timeout:      10 * time.Second,
+		PollInterval: 5 * time.Second,
+		Workers:      4,
+		Retention:    7 * 24 * time.Hour,
+	}
+}
+
+// dueBatch is how many due deliveries are fetched at once
+const dueBatch = 100
+
+// Dispatcher turns the todo events on a bus into deliveries and delivers them
+type Dispatcher struct {
+	store  repository.WebhookStore
+	bus    *events.Bus
+	client *http.Client
+	config Config
+
+	slots    chan struct{} // one per worker
+	wake     chan struct{} // a worker finished, more due deliveries may be waiting
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	inflight map[int]bool // deliveries being attempted
+}
+
+// NewDispatcher creates a dispatcher queueing deliveries in store
+func NewDispatcher(store repository.WebhookStore, bus *events.Bus, config Config) *Dispatcher {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   NewAddressFilter(config.AllowedNetworks).control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Through a proxy the filter would only ever see the proxy's address
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		store: store,
+		bus:   bus,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+			// A redirect is a failed attempt, following it would turn the POST into a GET
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		config:   config,
+		slots:    make(chan struct{}, config.Workers),
+		wake:     make(chan struct{}, 1),
+		inflight: make(map[int]bool),
+	}
+}
+
+// Run queues a delivery for every event webhooks subscribed to and delivers
+// due deliveries until ctx is cancelled. It then queues the events published
+// but not queued yet, also after the bus closed, and waits for the attempts
+// in flight; cancel ctx once nothing changes todos anymore, so every event
+// committed before a shutdown is queued. Deliveries queued before a restart
+// are picked up on start. Events of changes committed right before a crash
+// may never be queued: delivery is at most once for those.
+func (d *Dispatcher) Run(ctx context.Context) {
+	sub, _, _, err := d.bus.Subscribe(events.Filter{}, "")
+	if err != nil {
+		log.Printf("⚠️  Webhooks: %v", err)
+		return
+	}
+	lastEventID := sub.Since
+	defer func() {
+		if sub != nil {
+			sub.Cancel()
+		}
+		d.wg.Wait()
+	}()
+
+	poll := time.NewTicker(d.config.PollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	d.prune(time.Now())
+	d.deliverDue()
+	for {
+		var subC <-chan events.Event
+		if sub != nil {
+			subC = sub.C
+		}
+
+		select {
+		case <-ctx.Done():
+			d.catchUp(lastEventID)
+			return
+		case e, open := <-subC:
+			if !open {
+				// Dropped for falling behind (or the bus closed on shutdown):
+				// catch up from the replay buffer
+				sub = d.resubscribe(lastEventID)
+				continue
+			}
+			d.enqueue(e)
+			lastEventID = e.ID
+			d.deliverDue()
+		case <-d.wake:
+			d.deliverDue()
+		case <-poll.C:
+			d.deliverDue()
+		case now := <-prune.C:
+			d.prune(now)
+		}
+	}
+}
+
+// resubscribe subscribes again after lastEventID, queueing what was missed
+func (d *Dispatcher) resubscribe(lastEventID string) *events.Subscription {
+	sub, replay, resync, err := d.bus.Subscribe(events.Filter{}, lastEventID)
+	if err != nil {
+		// Only on shutdown, Run returns when ctx is cancelled
+		return nil
+	}
+	if resync {
+		log.Printf("⚠️  Webhooks: events after %s were lost before they could be queued", lastEventID)
+	}
+	for _, e := range replay {
+		d.enqueue(e)
+	}
+	return sub
+}
+
+// catchUp queues the events published after lastEventID, on shutdown
+func (d *Dispatcher) catchUp(lastEventID string) {
+	missed, resync := d.bus.Replay(events.Filter{}, lastEventID)
+	if resync {
+		log.Printf("⚠️  Webhooks: events after %s were lost before they could be queued", lastEventID)
+	}
+	for _, e := range missed {
+		d.enqueue(e)
+	}
+}
+
+// enqueue queues a delivery of e for every active webhook subscribed to its type
+func (d *Dispatcher) enqueue(e events.Event) {
+	if e.Todo == nil {
+		// Presence and editing events are not delivered
+		return
+	}
+
+	webhooks, err := d.store.GetWebhooks(e.WorkspaceID)
+	if err != nil {
+		log.Printf("⚠️  Webhooks: event %s: %v", e.ID, err)
+		return
+	}
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Active || !subscribed(webhook, e.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				log.Printf("⚠️  Webhooks: event %s: %v", e.ID, err)
+				return
+			}
+		}
+
+		now := time.Now()
+		_, err := d.store.CreateDelivery(&models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			WorkspaceID:   webhook.WorkspaceID,
+			EventID:       e.ID,
+			EventType:     string(e.Type),
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+		if err != nil && !errors.Is(err, repository.ErrWebhookNotFound) {
+			log.Printf("⚠️  Webhooks: queue event %s for webhook %d: %v", e.ID, webhook.ID, err)
+		}
+	}
+}
+
+// deliverDue starts an attempt for due deliveries while workers are free
+func (d *Dispatcher) deliverDue() {
+	due, err := d.store.DueDeliveries(time.Now(), dueBatch)
+	if err != nil {
+		log.Printf("⚠️  Webhooks: %v", err)
+		return
+	}
+
+	for _, delivery := range due {
+		if !d.claim(delivery.ID) {
+			continue
+		}
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			// Every worker is busy; the next one to finish wakes Run up
+			d.unclaim(delivery.ID)
+			return
+		}
+
+		d.wg.Add(1)
+		go func(delivery models.WebhookDelivery) {
+			defer func() {
+				<-d.slots
+				d.unclaim(delivery.ID)
+				d.wg.Done()
+				select {
+				case d.wake <- struct{}{}:
+				default:
+				}
+			}()
+			d.attempt(delivery)
+		}(delivery)
+	}
+}
+
+// attempt POSTs a delivery once and records the outcome
+func (d *Dispatcher) attempt(delivery models.WebhookDelivery) {
+	webhook, err := d.store.GetWebhook(delivery.WorkspaceID, delivery.WebhookID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		// Deleted, together with its deliveries
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️  Webhooks: delivery %d: %v", delivery.ID, err)
+		return
+	}
+
+	if !webhook.Active {
+		// Deactivated after the event was queued, so no retry would ever succeed
+		now := time.Now()
+		delivery.Attempts = append(delivery.Attempts, models.DeliveryAttempt{At: now, Error: "webhook is inactive"})
+		d.complete(&delivery, models.DeliveryDead, now)
+		return
+	}
+
+	attempt := d.post(webhook, delivery)
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	switch {
+	case attempt.StatusCode >= 200 && attempt.StatusCode < 300:
+		d.complete(&delivery, models.DeliverySucceeded, time.Now())
+	case len(delivery.Attempts) >= d.config.MaxAttempts:
+		d.complete(&delivery, models.DeliveryDead, time.Now())
+	default:
+		next := time.Now().Add(d.backoff(len(delivery.Attempts)))
+		delivery.NextAttemptAt = &next
+		d.save(&delivery)
+	}
+}
+
+// post sends the payload of a delivery to its webhook
+func (d *Dispatcher) post(webhook *models.Webhook, delivery models.WebhookDelivery) models.DeliveryAttempt {
+	start := time.Now()
+	attempt := models.DeliveryAttempt{At: start}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Collaborative-Todo-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, start.Unix(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err == nil {
+		// Drain some of the body so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		attempt.StatusCode = resp.StatusCode
+	} else {
+		attempt.Error = err.Error()
+	}
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	return attempt
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (d *Dispatcher) backoff(failures int) time.Duration {
+	wait := d.config.RetryBase
+	for i := 1; i < failures && wait < d.config.RetryMax; i++ {
+		wait *= 2
+	}
+	if wait > d.config.RetryMax {
+		wait = d.config.RetryMax
+	}
+	return wait
+}
+
+// complete finishes a delivery with status
+func (d *Dispatcher) complete(delivery *models.WebhookDelivery, status string, at time.Time) {
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	delivery.CompletedAt = &at
+	d.save(delivery)
+}
+
+// save stores the outcome of an attempt
+func (d *Dispatcher) save(delivery *models.WebhookDelivery) {
+	if _, err := d.store.UpdateDelivery(delivery); err != nil && !errors.Is(err, repository.ErrDeliveryNotFound) {
+		log.Printf("⚠️  Webhooks: delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// prune deletes the deliveries completed longer than the retention ago
+func (d *Dispatcher) prune(now time.Time) {
+	if _, err := d.store.PruneDeliveries(now.Add(-d.config.Retention)); err != nil {
+		log.Printf("⚠️  Webhooks: %v", err)
+	}
+}
+
+// claim marks a delivery in flight, reporting false when it already is
+func (d *Dispatcher) claim(id int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.inflight[id] {
+		return false
+	}
+	d.inflight[id] = true
+	return true
+}
+
+func (d *Dispatcher) unclaim(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.inflight, id)
+}
+
+// subscribed reports whether a webhook wants events of eventType
+func subscribed(webhook models.Webhook, eventType events.Type) bool {
+	for _, t := range webhook.Events {
+		if t == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
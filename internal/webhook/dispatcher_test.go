@@ -0,0 +1,537 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"test_mekari/internal/events"
+	"test_mekari/internal/migrations"
+	"test_mekari/internal/models"
+	"test_mekari/internal/repository"
+	"test_mekari/internal/webhook"
+)
+
+// ws is the workspace every check works in
+const ws = repository.DefaultWorkspaceID
+
+// config makes retries fast enough to watch and lets deliveries reach the
+// local receivers
+var config = webhook.Config{
+	MaxAttempts:     3,
+	RetryBase:       50 * time.Millisecond,
+	RetryMax:        80 * time.Millisecond,
+	Timeout:         time.Second,
+	PollInterval:    10 * time.Millisecond,
+	Workers:         2,
+	Retention:       time.Hour,
+	AllowedNetworks: []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}},
+}
+
+// check is a single named delivery rule
+type check struct {
+	name string
+	run  func(store *events.PublishingStore, bus *events.Bus) error
+}
+
+var checks = []check{
+	{"deliveries are signed and succeed on 2xx", checkSigned},
+	{"failed deliveries are retried with backoff", checkRetry},
+	{"deliveries go dead after the last attempt", checkDead},
+	{"only active webhooks subscribed to the event receive it", checkSubscriptions},
+	{"deliveries queued before start are delivered", checkQueued},
+	{"events published while shutting down are queued", checkShutdown},
+	{"deliveries never reach refused addresses", checkRefused},
+}
+
+func TestMain(m *testing.M) {
+	// The dispatcher logs through the default logger, keep the output readable
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// TestDispatcher runs every delivery check against a fresh store of every
+// storage backend
+func TestDispatcher(t *testing.T) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) (repository.Store, func() error, error)
+	}{
+		{"memory", func(t *testing.T) (repository.Store, func() error, error) {
+			repo, err := repository.NewTodoRepository(nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			return repo, repo.Close, nil
+		}},
+		{"sqlite", func(t *testing.T) (repository.Store, func() error, error) {
+			return openSQLite(filepath.Join(t.TempDir(), "todo.db"))
+		}},
+	}
+	for _, backend := range backends {
+		for _, c := range checks {
+			t.Run(backend.name+"/"+c.name, func(t *testing.T) {
+				store, closeStore, err := backend.open(t)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer closeStore()
+				bus := events.NewBus(100)
+				defer bus.Close()
+				if err := c.run(events.NewPublishingStore(store, bus), bus); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+// openSQLite opens and migrates a SQLite database at path
+func openSQLite(path string) (repository.Store, func() error, error) {
+	db, err := repository.OpenSQLite(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := migrator.Up(false); err != nil {
+		return nil, nil, err
+	}
+	repo, err := repository.NewSQLiteRepository(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, repo.Close, nil
+}
+
+// receiver is a local webhook endpoint answering with the statuses it is
+// given in turn (the last one repeats) and recording every request
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+}
+
+// request is one POST a receiver got
+type request struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(statuses ...int) *receiver {
+	rc := &receiver{statuses: statuses}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		rc.mu.Lock()
+		status := rc.statuses[0]
+		if len(rc.statuses) > 1 {
+			rc.statuses = rc.statuses[1:]
+		}
+		rc.requests = append(rc.requests, request{at: time.Now(), header: r.Header.Clone(), body: body})
+		rc.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	return rc
+}
+
+func (rc *receiver) received() []request {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return append([]request(nil), rc.requests...)
+}
+
+// dispatch runs a dispatcher until the returned stop function is called
+func dispatch(store *events.PublishingStore, bus *events.Bus) (stop func()) {
+	return dispatchWith(store, bus, config)
+}
+
+// dispatchWith runs a dispatcher with config until stop is called
+func dispatchWith(store *events.PublishingStore, bus *events.Bus, config webhook.Config) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		webhook.NewDispatcher(store, bus, config).Run(ctx)
+	}()
+	// Let Run subscribe before the check publishes its first event
+	time.Sleep(20 * time.Millisecond)
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func createWebhook(store repository.Store, url string, active bool, eventTypes ...string) (*models.Webhook, error) {
+	now := time.Now()
+	return store.CreateWebhook(&models.Webhook{
+		WorkspaceID: ws, URL: url, Events: eventTypes, Secret: "check-secret-0123456789",
+		Active: active, CreatedBy: 1, CreatedAt: now, UpdatedAt: now,
+	})
+}
+
+func createTodo(store repository.Store, text string) (*models.Todo, error) {
+	now := time.Now()
+	return store.Create(&models.Todo{WorkspaceID: ws, Text: text, UserID: 1, CreatedAt: now, UpdatedAt: now})
+}
+
+// waitForDelivery waits until the only delivery of a webhook has status
+func waitForDelivery(store repository.Store, webhookID int, status string) (*models.WebhookDelivery, error) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := store.GetDeliveries(ws, webhookID, 10)
+		if err != nil {
+			return nil, err
+		}
+		if len(deliveries) > 1 {
+			return nil, fmt.Errorf("%d deliveries, want 1", len(deliveries))
+		}
+		if len(deliveries) == 1 && deliveries[0].Status == status {
+			return &deliveries[0], nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no %s delivery after 5s, got %+v", status, deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func checkSigned(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusNoContent)
+	defer rc.Close()
+	hook, err := createWebhook(store, rc.URL, true, string(events.TodoCreated))
+	if err != nil {
+		return err
+	}
+
+	stop := dispatch(store, bus)
+	defer stop()
+	todo, err := createTodo(store, "signed")
+	if err != nil {
+		return err
+	}
+	delivery, err := waitForDelivery(store, hook.ID, models.DeliverySucceeded)
+	if err != nil {
+		return err
+	}
+
+	if len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusNoContent {
+		return fmt.Errorf("attempts = %+v, want one answered 204", delivery.Attempts)
+	}
+	if delivery.CompletedAt == nil || delivery.NextAttemptAt != nil {
+		return errors.New("succeeded delivery is not completed")
+	}
+	requests := rc.received()
+	if len(requests) != 1 {
+		return fmt.Errorf("receiver got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if err := webhook.Verify(hook.Secret, req.header.Get(webhook.HeaderTimestamp), req.header.Get(webhook.HeaderSignature), req.body, time.Minute, time.Now()); err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+	if err := webhook.Verify("another-secret-0123", req.header.Get(webhook.HeaderTimestamp), req.header.Get(webhook.HeaderSignature), req.body, time.Minute, time.Now()); !errors.Is(err, webhook.ErrInvalidSignature) {
+		return fmt.Errorf("verify with the wrong secret: err = %v, want ErrInvalidSignature", err)
+	}
+	if got := req.header.Get(webhook.HeaderEvent); got != string(events.TodoCreated) {
+		return fmt.Errorf("%s = %q, want todo.created", webhook.HeaderEvent, got)
+	}
+	if got := req.header.Get(webhook.HeaderDelivery); got != fmt.Sprint(delivery.ID) {
+		return fmt.Errorf("%s = %q, want %d", webhook.HeaderDelivery, got, delivery.ID)
+	}
+
+	var event events.Event
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	if event.Type != events.TodoCreated || event.Todo == nil || event.Todo.ID != todo.ID || event.ID != delivery.EventID {
+		return fmt.Errorf("payload = %s, want the todo.created event of todo %d", req.body, todo.ID)
+	}
+	return nil
+}
+
+func checkRetry(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK)
+	defer rc.Close()
+	hook, err := createWebhook(store, rc.URL, true, string(events.TodoCreated))
+	if err != nil {
+		return err
+	}
+
+	stop := dispatch(store, bus)
+	defer stop()
+	if _, err := createTodo(store, "flaky receiver"); err != nil {
+		return err
+	}
+	delivery, err := waitForDelivery(store, hook.ID, models.DeliverySucceeded)
+	if err != nil {
+		return err
+	}
+
+	codes := make([]int, len(delivery.Attempts))
+	for i, attempt := range delivery.Attempts {
+		codes[i] = attempt.StatusCode
+	}
+	if fmt.Sprint(codes) != "[500 503 200]" {
+		return fmt.Errorf("attempt status codes = %v, want [500 503 200]", codes)
+	}
+
+	// The wait doubles after every failure, up to RetryMax
+	requests := rc.received()
+	if len(requests) != 3 {
+		return fmt.Errorf("receiver got %d requests, want 3", len(requests))
+	}
+	if gap := requests[1].at.Sub(requests[0].at); gap < config.RetryBase {
+		return fmt.Errorf("second attempt after %v, want at least %v", gap, config.RetryBase)
+	}
+	if gap := requests[2].at.Sub(requests[1].at); gap < config.RetryMax {
+		return fmt.Errorf("third attempt after %v, want at least %v", gap, config.RetryMax)
+	}
+	for _, req := range requests[1:] {
+		if req.header.Get(webhook.HeaderDelivery) != requests[0].header.Get(webhook.HeaderDelivery) {
+			return errors.New("retries changed the delivery ID")
+		}
+		if string(req.body) != string(requests[0].body) {
+			return errors.New("retries changed the payload")
+		}
+	}
+	return nil
+}
+
+func checkDead(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusGone)
+	defer rc.Close()
+	hook, err := createWebhook(store, rc.URL, true, string(events.TodoCreated))
+	if err != nil {
+		return err
+	}
+	// Nothing listens on a closed receiver: attempts fail without a status code
+	closed := newReceiver(http.StatusOK)
+	closed.Close()
+	unreachable, err := createWebhook(store, closed.URL, true, string(events.TodoCreated))
+	if err != nil {
+		return err
+	}
+
+	stop := dispatch(store, bus)
+	defer stop()
+	if _, err := createTodo(store, "gone receiver"); err != nil {
+		return err
+	}
+
+	delivery, err := waitForDelivery(store, hook.ID, models.DeliveryDead)
+	if err != nil {
+		return err
+	}
+	if len(delivery.Attempts) != config.MaxAttempts || delivery.CompletedAt == nil || delivery.NextAttemptAt != nil {
+		return fmt.Errorf("dead delivery = %+v, want %d attempts and completed", delivery, config.MaxAttempts)
+	}
+	for _, attempt := range delivery.Attempts {
+		if attempt.StatusCode != http.StatusGone {
+			return fmt.Errorf("attempt status code = %d, want 410", attempt.StatusCode)
+		}
+	}
+
+	delivery, err = waitForDelivery(store, unreachable.ID, models.DeliveryDead)
+	if err != nil {
+		return err
+	}
+	for _, attempt := range delivery.Attempts {
+		if attempt.StatusCode != 0 || attempt.Error == "" {
+			return fmt.Errorf("attempt on a closed receiver = %+v, want an error and no status code", attempt)
+		}
+	}
+
+	// Dead deliveries are not picked up again
+	time.Sleep(3 * config.RetryMax)
+	if got := len(rc.received()); got != config.MaxAttempts {
+		return fmt.Errorf("receiver got %d requests, want %d", got, config.MaxAttempts)
+	}
+	return nil
+}
+
+func checkSubscriptions(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusOK)
+	defer rc.Close()
+	updates, err := createWebhook(store, rc.URL, true, string(events.TodoUpdated))
+	if err != nil {
+		return err
+	}
+	inactive, err := createWebhook(store, rc.URL, false, string(events.TodoCreated), string(events.TodoUpdated))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	workspace, err := store.CreateWorkspace(
+		&models.Workspace{Name: "Elsewhere", CreatedAt: now},
+		models.Membership{UserID: 1, Role: "admin", CreatedAt: now},
+	)
+	if err != nil {
+		return err
+	}
+	elsewhere, err := store.CreateWebhook(&models.Webhook{
+		WorkspaceID: workspace.ID, URL: rc.URL, Events: []string{string(events.TodoCreated), string(events.TodoUpdated)},
+		Secret: "check-secret-0123456789", Active: true, CreatedBy: 1, CreatedAt: now, UpdatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	stop := dispatch(store, bus)
+	defer stop()
+	todo, err := createTodo(store, "created, then updated")
+	if err != nil {
+		return err
+	}
+	todo.Text = "updated"
+	todo.UpdatedAt = time.Now()
+	if _, err := store.Update(todo); err != nil {
+		return err
+	}
+
+	delivery, err := waitForDelivery(store, updates.ID, models.DeliverySucceeded)
+	if err != nil {
+		return err
+	}
+	if delivery.EventType != string(events.TodoUpdated) {
+		return fmt.Errorf("delivered %s, want only todo.updated", delivery.EventType)
+	}
+	deliveries, err := store.GetDeliveries(ws, inactive.ID, 10)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) != 0 {
+		return fmt.Errorf("inactive webhook got %d deliveries, want 0", len(deliveries))
+	}
+	deliveries, err = store.GetDeliveries(workspace.ID, elsewhere.ID, 10)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) != 0 {
+		return fmt.Errorf("webhook of another workspace got %d deliveries, want 0", len(deliveries))
+	}
+	if got := len(rc.received()); got != 1 {
+		return fmt.Errorf("receiver got %d requests, want 1", got)
+	}
+	return nil
+}
+
+func checkQueued(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusOK)
+	defer rc.Close()
+	hook, err := createWebhook(store, rc.URL, true, string(events.TodoDeleted))
+	if err != nil {
+		return err
+	}
+
+	// As left behind by a restart while the delivery waited for a retry
+	past := time.Now().Add(-time.Minute)
+	queued, err := store.CreateDelivery(&models.WebhookDelivery{
+		WebhookID: hook.ID, WorkspaceID: ws, EventID: "1-1", EventType: string(events.TodoDeleted),
+		Payload: json.RawMessage(`{"id":"1-1","type":"todo.deleted"}`), Status: models.DeliveryPending,
+		Attempts:      []models.DeliveryAttempt{{At: past, StatusCode: http.StatusBadGateway}},
+		NextAttemptAt: &past, CreatedAt: past,
+	})
+	if err != nil {
+		return err
+	}
+
+	stop := dispatch(store, bus)
+	defer stop()
+	delivery, err := waitForDelivery(store, hook.ID, models.DeliverySucceeded)
+	if err != nil {
+		return err
+	}
+	if delivery.ID != queued.ID || len(delivery.Attempts) != 2 {
+		return fmt.Errorf("delivery = %+v, want delivery %d with 2 attempts", delivery, queued.ID)
+	}
+	requests := rc.received()
+	if len(requests) != 1 || string(requests[0].body) != string(queued.Payload) {
+		return errors.New("receiver did not get the queued payload")
+	}
+	return nil
+}
+
+func checkShutdown(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusOK)
+	defer rc.Close()
+	hook, err := createWebhook(store, rc.URL, true, string(events.TodoCreated))
+	if err != nil {
+		return err
+	}
+
+	// The server closes the bus as it starts shutting down, requests still
+	// finishing store their changes after that
+	stop := dispatch(store, bus)
+	bus.Close()
+	if _, err := createTodo(store, "stored while shutting down"); err != nil {
+		return err
+	}
+	stop()
+
+	deliveries, err := store.GetDeliveries(ws, hook.ID, 10)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) != 1 || deliveries[0].EventType != string(events.TodoCreated) || deliveries[0].Status != models.DeliveryPending {
+		return fmt.Errorf("deliveries = %+v, want the pending todo.created", deliveries)
+	}
+	return nil
+}
+
+func checkRefused(store *events.PublishingStore, bus *events.Bus) error {
+	rc := newReceiver(http.StatusOK)
+	defer rc.Close()
+	// The receiver listens on 127.0.0.1, by name the address is only found
+	// when the delivery dials
+	byName := strings.Replace(rc.URL, "127.0.0.1", "localhost", 1)
+	hooks := make([]*models.Webhook, 0, 2)
+	for _, url := range []string{rc.URL, byName} {
+		hook, err := createWebhook(store, url, true, string(events.TodoCreated))
+		if err != nil {
+			return err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	refusing := config
+	refusing.AllowedNetworks = nil
+	refusing.MaxAttempts = 1
+	stop := dispatchWith(store, bus, refusing)
+	defer stop()
+	if _, err := createTodo(store, "for a refused address"); err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		delivery, err := waitForDelivery(store, hook.ID, models.DeliveryDead)
+		if err != nil {
+			return err
+		}
+		attempt := delivery.Attempts[0]
+		if attempt.StatusCode != 0 || !strings.Contains(attempt.Error, webhook.ErrForbiddenAddress.Error()) {
+			return fmt.Errorf("attempt on %s = %+v, want refused", hook.URL, attempt)
+		}
+	}
+	if got := len(rc.received()); got != 0 {
+		return fmt.Errorf("receiver got %d requests, want 0", got)
+	}
+	return nil
+}
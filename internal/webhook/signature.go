@@ -0,0 +1,62 @@
+// Package webhook delivers the todo events of a workspace to its webhooks.
+//
+// A Dispatcher queues a delivery in the store for every event a webhook
+// subscribed to, then POSTs it signed with the webhook's secret, retrying with
+// exponential backoff until the receiver answers 2xx or the attempts run out
+// and the delivery goes dead.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"test_mekari/internal/events"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"     // the event type
+	HeaderDelivery  = "X-Webhook-Delivery"  // the delivery ID, the same on every attempt
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix seconds the attempt was signed at
+	HeaderSignature = "X-Webhook-Signature" // see Sign
+)
+
+// EventTypes are the event types a webhook can subscribe to
+var EventTypes = []events.Type{events.TodoCreated, events.TodoUpdated, events.TodoToggled, events.TodoDeleted}
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is too old")
+)
+
+// Sign returns the signature of body sent at timestamp: "sha256=" and the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret. Signing the
+// timestamp too keeps a captured request from being replayed later.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a delivery the way a
+// receiver should, rejecting timestamps more than tolerance away from now
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
@@ -5,6 +5,13 @@
 
 BASE_URL="http://localhost:8080"
 
+# Seeded users share the password "password" (see SEEDER.md)
+login() {
+  curl -s -X POST $BASE_URL/auth/login \
+    -H "Content-Type: application/json" \
+    -d "{\"email\": \"$1\", \"password\": \"password\"}" | jq -r '.data.token'
+}
+
 echo "====================================="
 echo "Collaborative Todo List API - Tests"
 echo "====================================="
@@ -16,95 +23,114 @@ curl -s $BASE_URL/health | jq .
 echo -e "\n"
 
 echo "2. Get API Info"
-echo "GET $BASE_URL/"
-curl -s $BASE_URL/ | jq .
+echo "GET $BASE_URL/api"
+curl -s $BASE_URL/api | jq .
+echo -e "\n"
+
+echo "3. Log In as John Doe and Jane Smith"
+echo "POST $BASE_URL/auth/login"
+JOHN_TOKEN=$(login john@example.com)
+JANE_TOKEN=$(login jane@example.com)
+JOHN_AUTH="Authorization: Bearer $JOHN_TOKEN"
+JANE_AUTH="Authorization: Bearer $JANE_TOKEN"
+curl -s $BASE_URL/me -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "3. Get All Users"
+echo "4. Get All Users"
 echo "GET $BASE_URL/users"
-curl -s $BASE_URL/users | jq .
+curl -s $BASE_URL/users -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "4. Create Todo for John Doe (User 1)"
+echo "5. Create Todo as John Doe (User 1)"
 echo "POST $BASE_URL/todos"
 TODO1=$(curl -s -X POST $BASE_URL/todos \
+  -H "$JOHN_AUTH" \
   -H "Content-Type: application/json" \
-  -d '{"text": "Review code", "user_id": 1, "completed": false}')
+  -d '{"text": "Review code", "completed": false}')
 echo $TODO1 | jq .
-TODO1_ID=$(echo $TODO1 | jq -r '.id')
+TODO1_ID=$(echo $TODO1 | jq -r '.data.id')
 echo -e "\n"
 
-echo "5. Create Todo for Jane Smith (User 2)"
+echo "6. Create Todo as Jane Smith (User 2)"
 echo "POST $BASE_URL/todos"
 TODO2=$(curl -s -X POST $BASE_URL/todos \
+  -H "$JANE_AUTH" \
   -H "Content-Type: application/json" \
-  -d '{"text": "Write documentation", "user_id": 2, "completed": false}')
+  -d '{"text": "Write documentation", "completed": false}')
 echo $TODO2 | jq .
-TODO2_ID=$(echo $TODO2 | jq -r '.id')
+TODO2_ID=$(echo $TODO2 | jq -r '.data.id')
 echo -e "\n"
 
-echo "6. Create Another Todo for John Doe"
+echo "7. Create Another Todo as John Doe"
 echo "POST $BASE_URL/todos"
 TODO3=$(curl -s -X POST $BASE_URL/todos \
+  -H "$JOHN_AUTH" \
   -H "Content-Type: application/json" \
-  -d '{"text": "Fix bugs", "user_id": 1, "completed": false}')
+  -d '{"text": "Fix bugs", "completed": false}')
 echo $TODO3 | jq .
 echo -e "\n"
 
-echo "7. Get All Todos"
+echo "8. Get All Todos"
 echo "GET $BASE_URL/todos"
-curl -s $BASE_URL/todos | jq .
+curl -s $BASE_URL/todos -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "8. Get Todos for User 1 (John Doe)"
+echo "9. Get Todos for User 1 (John Doe)"
 echo "GET $BASE_URL/todos?user_id=1"
-curl -s "$BASE_URL/todos?user_id=1" | jq .
+curl -s "$BASE_URL/todos?user_id=1" -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "9. Get Todos for User 2 (Jane Smith)"
+echo "10. Get Todos for User 2 (Jane Smith)"
 echo "GET $BASE_URL/todos?user_id=2"
-curl -s "$BASE_URL/todos?user_id=2" | jq .
+curl -s "$BASE_URL/todos?user_id=2" -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "10. Toggle Todo Completion (ID: $TODO1_ID)"
+echo "11. Toggle Todo Completion (ID: $TODO1_ID)"
 echo "PATCH $BASE_URL/todos/$TODO1_ID/toggle"
-curl -s -X PATCH $BASE_URL/todos/$TODO1_ID/toggle | jq .
+curl -s -X PATCH $BASE_URL/todos/$TODO1_ID/toggle -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "11. Update Todo (ID: $TODO2_ID)"
+echo "12. Update Todo (ID: $TODO2_ID)"
 echo "PUT $BASE_URL/todos/$TODO2_ID"
 curl -s -X PUT $BASE_URL/todos/$TODO2_ID \
+  -H "$JANE_AUTH" \
   -H "Content-Type: application/json" \
-  -d '{"text": "Write documentation and examples", "user_id": 2, "completed": true}' | jq .
+  -d '{"text": "Write documentation and examples", "completed": true}' | jq .
 echo -e "\n"
 
-echo "12. Get All Todos (after updates)"
+echo "13. Get All Todos (after updates)"
 echo "GET $BASE_URL/todos"
-curl -s $BASE_URL/todos | jq .
+curl -s $BASE_URL/todos -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "13. Delete Todo (ID: $TODO1_ID)"
+echo "14. Delete Todo (ID: $TODO1_ID)"
 echo "DELETE $BASE_URL/todos/$TODO1_ID"
-curl -s -X DELETE $BASE_URL/todos/$TODO1_ID | jq .
+curl -s -X DELETE $BASE_URL/todos/$TODO1_ID -H "$JOHN_AUTH" | jq .
 echo -e "\n"
 
-echo "14. Get All Todos (after deletion)"
+echo "15. Get All Todos (after deletion)"
+echo "GET $BASE_URL/todos"
+curl -s $BASE_URL/todos -H "$JOHN_AUTH" | jq .
+echo -e "\n"
+
+echo "16. Test Error - Missing Token"
 echo "GET $BASE_URL/todos"
 curl -s $BASE_URL/todos | jq .
 echo -e "\n"
 
-echo "15. Test Error - User Not Found (user_id: 999)"
-echo "POST $BASE_URL/todos"
-curl -s -X POST $BASE_URL/todos \
+echo "17. Test Error - Wrong Password"
+echo "POST $BASE_URL/auth/login"
+curl -s -X POST $BASE_URL/auth/login \
   -H "Content-Type: application/json" \
-  -d '{"text": "Test", "user_id": 999, "completed": false}' | jq .
+  -d '{"email": "john@example.com", "password": "wrong"}' | jq .
 echo -e "\n"
 
-echo "16. Test Error - Empty Text"
+echo "18. Test Error - Empty Text"
 echo "POST $BASE_URL/todos"
 curl -s -X POST $BASE_URL/todos \
+  -H "$JOHN_AUTH" \
   -H "Content-Type: application/json" \
-  -d '{"text": "", "user_id": 1, "completed": false}' | jq .
+  -d '{"text": "", "completed": false}' | jq .
 echo -e "\n"
 
 echo "====================================="